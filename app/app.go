@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,9 +12,11 @@ import (
 	storetypes "cosmossdk.io/store/types"
 	"cosmossdk.io/x/tx/signing"
 	abci "github.com/cometbft/cometbft/abci/types"
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
 	dbm "github.com/cosmos/cosmos-db"
 	"github.com/cosmos/cosmos-sdk/baseapp"
 	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
 	codec "github.com/cosmos/cosmos-sdk/codec"
 	"github.com/cosmos/cosmos-sdk/codec/address"
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
@@ -36,18 +39,28 @@ import (
 	consensustypes "github.com/cosmos/cosmos-sdk/x/consensus/types"
 	"github.com/cosmos/cosmos-sdk/x/genutil"
 	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/cosmos/cosmos-sdk/x/gov"
+	govclient "github.com/cosmos/cosmos-sdk/x/gov/client"
+	govkeeper "github.com/cosmos/cosmos-sdk/x/gov/keeper"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
 	"github.com/cosmos/cosmos-sdk/x/staking"
 	stakingcli "github.com/cosmos/cosmos-sdk/x/staking/client/cli"
 	stakingkeeper "github.com/cosmos/cosmos-sdk/x/staking/keeper"
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/cosmos/gogoproto/proto"
+	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	// Import consensus modules
+	apptypes "github.com/fffeng99999/hcp-consensus/app/types"
 	"github.com/fffeng99999/hcp-consensus/consensus/common"
-	"github.com/fffeng99999/hcp-consensus/consensus/hotstuff"
-	"github.com/fffeng99999/hcp-consensus/consensus/raft"
 	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+
+	// Register the remaining consensus engines
+	"github.com/fffeng99999/hcp-consensus/consensus/hotstuff"
+	_ "github.com/fffeng99999/hcp-consensus/consensus/raft"
 )
 
 type StakingAppModuleBasic struct {
@@ -60,6 +73,18 @@ func (b StakingAppModuleBasic) GetTxCmd() *cobra.Command {
 	return stakingcli.NewTxCmd(valAddrCodec, addrCodec)
 }
 
+// communityPoolBurner stands in for the distribution module the app does not
+// run: the share of a cancelled proposal's deposit meant for the community
+// pool is burned
+type communityPoolBurner struct {
+	bankKeeper bankkeeper.Keeper
+}
+
+// FundCommunityPool implements govtypes.DistributionKeeper
+func (b communityPoolBurner) FundCommunityPool(ctx context.Context, amount sdk.Coins, _ sdk.AccAddress) error {
+	return b.bankKeeper.BurnCoins(ctx, govtypes.ModuleName, amount)
+}
+
 var (
 	// DefaultNodeHome default home directories for the application daemon
 	DefaultNodeHome string
@@ -73,6 +98,9 @@ var (
 		StakingAppModuleBasic{},
 		consensus.AppModuleBasic{},
 		genutil.AppModuleBasic{GenTxValidator: genutiltypes.DefaultMessageValidator},
+		gov.NewAppModuleBasic([]govclient.ProposalHandler{}),
+		tpbft.AppModuleBasic{},
+		hotstuff.AppModuleBasic{},
 	)
 )
 
@@ -104,12 +132,19 @@ type App struct {
 	BankKeeper      bankkeeper.Keeper
 	StakingKeeper   *stakingkeeper.Keeper
 	ConsensusKeeper consensuskeeper.Keeper
+	GovKeeper       *govkeeper.Keeper
 
 	// module manager
 	ModuleManager *module.Manager
 
-	// Consensus Engine
+	// Consensus Engine, and what is needed to replace it at a scheduled switch
 	ConsensusEngine common.ConsensusEngine
+	pendingEngine   common.ConsensusEngine // Switched to in the uncommitted block
+	engineDeps      common.Dependencies
+	tracerProvider  *sdktrace.TracerProvider // Set when consensus tracing is enabled
+
+	// authority is the gov module account, which schedules engine switches
+	authority string
 }
 
 // NewApp returns a reference to an initialized App.
@@ -138,6 +173,7 @@ func NewApp(
 	// Register interfaces
 	cryptocodec.RegisterInterfaces(interfaceRegistry)
 	ModuleBasics.RegisterInterfaces(interfaceRegistry)
+	apptypes.RegisterInterfaces(interfaceRegistry)
 
 	// Determine ChainID
 	var chainID string
@@ -155,7 +191,7 @@ func NewApp(
 				}
 				if err := json.Unmarshal(content, &genesis); err == nil {
 					chainID = genesis.ChainID
-					fmt.Printf("DEBUG: Found ChainID in genesis: %s\n", chainID)
+					logger.Debug("read chain ID from genesis", "chain_id", chainID)
 				}
 			}
 		}
@@ -172,27 +208,17 @@ func NewApp(
 
 	keys := storetypes.NewKVStoreKeys(
 		authtypes.StoreKey, banktypes.StoreKey, stakingtypes.StoreKey,
-		consensustypes.StoreKey,
+		consensustypes.StoreKey, govtypes.StoreKey, tpbft.StoreKey,
 	)
 
 	// Determine consensus engine from config
-	engineType := "tpbft" // Default
-	if appOpts.Get("consensus-engine") != nil {
-		if v, ok := appOpts.Get("consensus-engine").(string); ok {
-			engineType = v
+	engineName := tpbft.EngineName // Default
+	if v := appOpts.Get("consensus-engine"); v != nil {
+		name, ok := v.(string)
+		if !ok {
+			panic(fmt.Errorf("consensus-engine must be a string, got %T", v))
 		}
-	}
-
-	var consensusEngine common.ConsensusEngine
-	switch engineType {
-	case "raft":
-		consensusEngine = raft.NewRaftConsensus()
-	case "hotstuff":
-		consensusEngine = hotstuff.NewHotStuffConsensus()
-	case "tpbft":
-		fallthrough
-	default:
-		consensusEngine = tpbft.NewTPBFT()
+		engineName = name
 	}
 
 	app := &App{
@@ -202,7 +228,7 @@ func NewApp(
 		interfaceRegistry: interfaceRegistry,
 		txConfig:          txConfig,
 		keys:              keys,
-		ConsensusEngine:   consensusEngine,
+		authority:         authtypes.NewModuleAddress(govtypes.ModuleName).String(),
 	}
 
 	// Initialize keepers
@@ -213,10 +239,11 @@ func NewApp(
 		map[string][]string{
 			stakingtypes.BondedPoolName:    {authtypes.Burner, authtypes.Staking},
 			stakingtypes.NotBondedPoolName: {authtypes.Burner, authtypes.Staking},
+			govtypes.ModuleName:            {authtypes.Burner},
 		},
 		address.NewBech32Codec("hcp"),
 		"hcp",
-		authtypes.NewModuleAddress("gov").String(),
+		app.authority,
 	)
 
 	app.BankKeeper = bankkeeper.NewBaseKeeper(
@@ -227,14 +254,14 @@ func NewApp(
 			stakingtypes.BondedPoolName:    true,
 			stakingtypes.NotBondedPoolName: true,
 		},
-		authtypes.NewModuleAddress("gov").String(),
+		app.authority,
 		logger,
 	)
 
 	app.ConsensusKeeper = consensuskeeper.NewKeeper(
 		appCodec,
 		runtime.NewKVStoreService(keys[consensustypes.StoreKey]),
-		authtypes.NewModuleAddress("gov").String(),
+		app.authority,
 		runtime.EventService{},
 	)
 
@@ -246,11 +273,25 @@ func NewApp(
 		runtime.NewKVStoreService(keys[stakingtypes.StoreKey]),
 		app.AccountKeeper,
 		app.BankKeeper,
-		authtypes.NewModuleAddress("gov").String(),
+		app.authority,
 		address.NewBech32Codec("hcpvaloper"),
 		address.NewBech32Codec("hcpvalcons"),
 	)
 
+	// Proposals execute their messages, such as MsgScheduleEngineSwitch,
+	// through the message router
+	app.GovKeeper = govkeeper.NewKeeper(
+		appCodec,
+		runtime.NewKVStoreService(keys[govtypes.StoreKey]),
+		app.AccountKeeper,
+		app.BankKeeper,
+		app.StakingKeeper,
+		communityPoolBurner{app.BankKeeper},
+		app.MsgServiceRouter(),
+		govtypes.DefaultConfig(),
+		app.authority,
+	)
+
 	// Create module manager
 	app.ModuleManager = module.NewManager(
 		auth.NewAppModule(appCodec, app.AccountKeeper, nil, nil),
@@ -258,6 +299,7 @@ func NewApp(
 		staking.NewAppModule(appCodec, app.StakingKeeper, app.AccountKeeper, app.BankKeeper, nil),
 		consensus.NewAppModule(appCodec, app.ConsensusKeeper),
 		genutil.NewAppModule(app.AccountKeeper, app.StakingKeeper, app, txConfig),
+		gov.NewAppModule(appCodec, app.GovKeeper, app.AccountKeeper, app.BankKeeper, nil),
 	)
 
 	app.ModuleManager.SetOrderInitGenesis(
@@ -265,11 +307,13 @@ func NewApp(
 		banktypes.ModuleName,
 		stakingtypes.ModuleName,
 		consensustypes.ModuleName,
+		govtypes.ModuleName,
 		genutiltypes.ModuleName,
 	)
 
 	// Register services
 	app.ModuleManager.RegisterServices(module.NewConfigurator(app.appCodec, app.MsgServiceRouter(), app.GRPCQueryRouter()))
+	apptypes.RegisterMsgServer(app.MsgServiceRouter(), msgServer{app})
 
 	app.SetInitChainer(app.InitChainer)
 	app.SetBeginBlocker(app.BeginBlocker) // Register BeginBlocker
@@ -280,46 +324,161 @@ func NewApp(
 		app.MountStore(key, storetypes.StoreTypeIAVL)
 	}
 
-	if loadLatest {
-		if err := app.LoadLatestVersion(); err != nil {
+	// Build the consensus engine from the registry. Engines share the store
+	// that historically held tPBFT trust state.
+	app.engineDeps = common.Dependencies{
+		StakingKeeper: app.StakingKeeper,
+		Logger:        logger.With(log.ModuleKey, "consensus"),
+		AppOptions:    appOpts,
+		StoreKey:      keys[engineStoreKey],
+	}
+
+	// Engine metrics go to the registry served by CometBFT's Prometheus
+	// endpoint and the SDK telemetry sink, when either is enabled
+	if cast.ToBool(appOpts.Get("telemetry.enabled")) || cast.ToBool(appOpts.Get("instrumentation.prometheus")) {
+		app.engineDeps.Metrics = common.PrometheusMetrics(common.MetricsNamespace, "chain_id", app.ChainID())
+	}
+
+	// Engine spans are written as OTLP JSON to the consensus-trace-file,
+	// relative to the node's home unless absolute
+	if path := cast.ToString(appOpts.Get("consensus-trace-file")); path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cast.ToString(appOpts.Get(flags.FlagHome)), path)
+		}
+		tp, err := common.NewFileTracerProvider(path, attribute.String("chain_id", app.ChainID()))
+		if err != nil {
 			panic(err)
 		}
+		app.tracerProvider = tp
+		app.engineDeps.TracerProvider = tp
+	}
+	consensusEngine, err := common.NewEngine(engineName, app.engineDeps)
+	if err != nil {
+		panic(err)
 	}
+	app.ConsensusEngine = consensusEngine
 
-	// Initialize Consensus Engine dependencies
-	if engine, ok := app.ConsensusEngine.(*tpbft.TPBFT); ok {
-		engine.SetStakingKeeper(app.StakingKeeper)
+	if loadLatest {
+		if err := app.LoadLatestVersion(); err != nil {
+			panic(err)
+		}
+		if err := app.loadEngineState(); err != nil {
+			panic(err)
+		}
 	}
 
-	// Start Consensus Engine
-	if err := app.ConsensusEngine.Start(); err != nil {
+	// Start Consensus Engine; Close stops it
+	if err := app.ConsensusEngine.Start(context.Background()); err != nil {
 		logger.Error("Failed to start consensus engine", "error", err)
 	}
 
 	return app
 }
 
+// LoadHeight loads a particular height
+func (app *App) LoadHeight(height int64) error {
+	if err := app.LoadVersion(height); err != nil {
+		return err
+	}
+	return app.loadEngineState()
+}
+
+// loadEngineState restores the consensus engine and its state persisted at
+// the loaded height
+func (app *App) loadEngineState() error {
+	if err := app.restoreActiveEngine(); err != nil {
+		return err
+	}
+	ctx := app.NewUncachedContext(false, cmtproto.Header{Height: app.LastBlockHeight()})
+	if engine, ok := app.ConsensusEngine.(*tpbft.TPBFT); ok {
+		return engine.LoadTrustState(ctx)
+	}
+	// Other engines keep their handed over state in memory only
+	return app.reloadHandoff(ctx)
+}
+
 // InitChainer application update at chain initialization
 func (app *App) InitChainer(ctx sdk.Context, req *abci.RequestInitChain) (*abci.ResponseInitChain, error) {
 	var genesisState map[string]json.RawMessage
 	if err := json.Unmarshal(req.AppStateBytes, &genesisState); err != nil {
 		panic(err)
 	}
-	return app.ModuleManager.InitGenesis(ctx, app.appCodec, genesisState)
+
+	res, err := app.ModuleManager.InitGenesis(ctx, app.appCodec, genesisState)
+	if err != nil {
+		return res, err
+	}
+
+	// Seed tPBFT parameters and trust scores from genesis (not a module
+	// manager module)
+	trustGenesis, err := tpbft.UnmarshalGenesis(genesisState[tpbft.ModuleName])
+	if err != nil {
+		return nil, err
+	}
+	if engine, ok := app.ConsensusEngine.(*tpbft.TPBFT); ok {
+		if err := engine.InitGenesis(ctx, trustGenesis); err != nil {
+			return nil, err
+		}
+	} else {
+		// The parameters are kept for a switch to tPBFT
+		if err := trustGenesis.Validate(); err != nil {
+			return nil, err
+		}
+		if err := tpbft.SetParams(ctx, app.keys[engineStoreKey], *trustGenesis.Params); err != nil {
+			return nil, err
+		}
+
+		// Other engines take the seeded scores as if handed over, as a
+		// reputation-based HotStuff leader election ranks by them
+		state := common.HandoffState{From: tpbft.ModuleName, Height: ctx.BlockHeight(), Trust: genesisState[tpbft.ModuleName]}
+		if err := app.handOff(ctx, app.ConsensusEngine, state); err != nil {
+			return nil, err
+		}
+	}
+
+	// The HotStuff parameters are kept whichever engine runs
+	hotstuffGenesis, err := hotstuff.UnmarshalGenesis(genesisState[hotstuff.ModuleName])
+	if err != nil {
+		return nil, err
+	}
+	if err := hotstuff.SetParams(ctx, app.keys[engineStoreKey], hotstuffGenesis.Params); err != nil {
+		return nil, err
+	}
+
+	// Switching after the trust seed hands the seeded scores over
+	if err := app.initEngineSwitchGenesis(ctx, genesisState[EngineSwitchGenesisKey]); err != nil {
+		return nil, err
+	}
+
+	return res, nil
 }
 
 // BeginBlocker implementation
 func (app *App) BeginBlocker(ctx sdk.Context) (sdk.BeginBlock, error) {
 	// 1. Call standard module logic
-	_, err := app.ModuleManager.BeginBlock(ctx)
+	res, err := app.ModuleManager.BeginBlock(ctx)
 	if err != nil {
 		return sdk.BeginBlock{}, err
 	}
 
-	// 2. Consensus Engine Hook
-	app.ConsensusEngine.BeginBlock(ctx)
+	// BaseApp only records the events returned here
+	ctx = ctx.WithEventManager(sdk.NewEventManager())
+
+	// 2. Switch engines if scheduled, so the new engine sees this block. An
+	// engine switched to in a block that was not committed is dropped.
+	app.pendingEngine = nil
+	if err := app.maybeSwitchEngine(ctx); err != nil {
+		return sdk.BeginBlock{}, fmt.Errorf("consensus engine switch: %w", err)
+	}
+
+	// 3. Consensus Engine Hook
+	engine := app.blockEngine()
+	if err := engine.BeginBlock(ctx); err != nil {
+		return sdk.BeginBlock{}, fmt.Errorf("%s begin block: %w", engine.Name(), err)
+	}
 
-	return sdk.BeginBlock{}, nil
+	res.Events = append(res.Events, ctx.EventManager().ABCIEvents()...)
+	return res, nil
 }
 
 // EndBlocker implementation
@@ -330,8 +489,18 @@ func (app *App) EndBlocker(ctx sdk.Context) (sdk.EndBlock, error) {
 		return sdk.EndBlock{}, err
 	}
 
-	// 2. Consensus Engine Hook
-	validatorUpdates := app.ConsensusEngine.EndBlock(ctx)
+	// 2. Consensus Engine Hook, following the staking module's validator
+	// set changes first
+	engine := app.blockEngine()
+	if observer, ok := engine.(common.ValidatorSetObserver); ok && len(res.ValidatorUpdates) > 0 {
+		if err := observer.OnValidatorUpdates(ctx, res.ValidatorUpdates); err != nil {
+			return sdk.EndBlock{}, fmt.Errorf("%s validator updates: %w", engine.Name(), err)
+		}
+	}
+	validatorUpdates, err := engine.EndBlock(ctx)
+	if err != nil {
+		return sdk.EndBlock{}, fmt.Errorf("%s end block: %w", engine.Name(), err)
+	}
 
 	// 3. Merge validator updates (if any)
 	// If Consensus Engine provides updates, we append/override.
@@ -344,9 +513,49 @@ func (app *App) EndBlocker(ctx sdk.Context) (sdk.EndBlock, error) {
 	return res, nil
 }
 
+// blockEngine returns the engine running the current block: one switched to
+// in it, or the running one
+func (app *App) blockEngine() common.ConsensusEngine {
+	if app.pendingEngine != nil {
+		return app.pendingEngine
+	}
+	return app.ConsensusEngine
+}
+
+// Commit commits the block, then puts an engine switched to in it in place of
+// the running one, so engines only change with committed blocks
+func (app *App) Commit() (*abci.ResponseCommit, error) {
+	res, err := app.BaseApp.Commit()
+	if err != nil || app.pendingEngine == nil {
+		return res, err
+	}
+
+	old, next := app.ConsensusEngine.Name(), app.pendingEngine
+	app.pendingEngine = nil
+	if err := app.replaceEngine(next); err != nil {
+		return nil, err
+	}
+	app.Logger().Info("switched consensus engine", "from", old, "to", next.Name(), "height", app.LastBlockHeight())
+	return res, nil
+}
+
 // Name returns the name of the App
 func (app *App) Name() string { return app.BaseApp.Name() }
 
+// Close stops the consensus engine and closes the underlying BaseApp
+func (app *App) Close() error {
+	if err := app.ConsensusEngine.Stop(); err != nil {
+		return err
+	}
+	if app.tracerProvider != nil {
+		// Flush the spans still batched
+		if err := app.tracerProvider.Shutdown(context.Background()); err != nil {
+			return err
+		}
+	}
+	return app.BaseApp.Close()
+}
+
 // AppCodec returns App's codec.
 func (app *App) AppCodec() codec.Codec {
 	return app.appCodec
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/spf13/cobra"
+
+	"github.com/fffeng99999/hcp-consensus/bench"
+	"github.com/fffeng99999/hcp-consensus/bench/report"
+)
+
+const (
+	flagBenchAccounts      = "accounts"
+	flagBenchAccountSeed   = "account-seed"
+	flagBenchTxsPerAccount = "txs-per-account"
+	flagBenchTPS           = "tps"
+	flagBenchConcurrency   = "concurrency"
+	flagBenchAmount        = "amount"
+	flagBenchTimeout       = "timeout"
+	flagBenchPollInterval  = "poll-interval"
+	flagBenchEngine        = "engine"
+	flagBenchLabel         = "label"
+	flagBenchReport        = "report"
+	flagBenchFormat        = "format"
+
+	defaultBenchAccountSeed = "hcp-bench"
+)
+
+// benchCommand returns the command load testing a running node with bank
+// sends signed ahead of time
+func benchCommand() *cobra.Command {
+	defaults := bench.DefaultConfig()
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Load test a node with pre-signed bank sends",
+		Long: `Sign bank sends between the benchmark accounts offline, broadcast them to a
+node at a target rate and report the throughput, inclusion latency and
+failures. The accounts must be funded; list them with "bench accounts".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			clientCtx, err := client.GetClientQueryContext(cmd)
+			if err != nil {
+				return err
+			}
+			f := cmd.Flags()
+			n, _ := f.GetInt(flagBenchAccounts)
+			seed, _ := f.GetString(flagBenchAccountSeed)
+			perAccount, _ := f.GetInt(flagBenchTxsPerAccount)
+			gas, _ := f.GetUint64(flags.FlagGas)
+
+			sendCfg := bench.SendConfig{ChainID: clientCtx.ChainID, Gas: gas, PerAccount: perAccount}
+			amount, _ := f.GetString(flagBenchAmount)
+			if sendCfg.Amount, err = sdk.ParseCoinsNormalized(amount); err != nil {
+				return fmt.Errorf("invalid amount: %w", err)
+			}
+			fees, _ := f.GetString(flags.FlagFees)
+			if sendCfg.Fees, err = sdk.ParseCoinsNormalized(fees); err != nil {
+				return fmt.Errorf("invalid fees: %w", err)
+			}
+
+			runCfg := bench.Config{}
+			runCfg.TPS, _ = f.GetFloat64(flagBenchTPS)
+			runCfg.Concurrency, _ = f.GetInt(flagBenchConcurrency)
+			runCfg.Timeout, _ = f.GetDuration(flagBenchTimeout)
+			runCfg.PollInterval, _ = f.GetDuration(flagBenchPollInterval)
+
+			ctx := cmd.Context()
+			if sendCfg.ChainID == "" {
+				status, err := clientCtx.Client.Status(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to query node status: %w", err)
+				}
+				sendCfg.ChainID = status.NodeInfo.Network
+			}
+
+			accounts := bench.Accounts(seed, n)
+			for _, acc := range accounts {
+				acc.Number, acc.Sequence, err = authtypes.AccountRetriever{}.GetAccountNumberSequence(clientCtx, acc.Address)
+				if err != nil {
+					return fmt.Errorf("account %s is not funded: %w", acc.Address, err)
+				}
+			}
+
+			start := time.Now()
+			txs, err := bench.Generate(clientCtx.TxConfig, accounts, sendCfg)
+			if err != nil {
+				return err
+			}
+			cmd.PrintErrf("Signed %d transactions in %v\n", len(txs), time.Since(start).Round(time.Millisecond))
+
+			res, err := bench.Run(ctx, clientCtx.Client, txs, runCfg)
+			if err != nil {
+				return err
+			}
+			if err := res.WriteSummary(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+
+			path, _ := f.GetString(flagBenchReport)
+			if path == "" {
+				return nil
+			}
+			engine, _ := f.GetString(flagBenchEngine)
+			label, _ := f.GetString(flagBenchLabel)
+			var r report.Report
+			r.Add(report.FromResult(engine, label, res))
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			if err := r.WriteJSON(file); err != nil {
+				file.Close()
+				return err
+			}
+			return file.Close()
+		},
+	}
+
+	cmd.PersistentFlags().Int(flagBenchAccounts, 100, "Number of accounts sending transactions")
+	cmd.PersistentFlags().String(flagBenchAccountSeed, defaultBenchAccountSeed, "Seed the account keys are derived from")
+	cmd.Flags().Int(flagBenchTxsPerAccount, 100, "Transactions each account sends")
+	cmd.Flags().Float64(flagBenchTPS, defaults.TPS, "Target broadcast rate; 0 broadcasts as fast as possible")
+	cmd.Flags().Int(flagBenchConcurrency, defaults.Concurrency, "Concurrent broadcasting workers")
+	cmd.Flags().String(flagBenchAmount, "1stake", "Amount of each send")
+	cmd.Flags().String(flags.FlagFees, "", "Fees of each transaction")
+	cmd.Flags().Uint64(flags.FlagGas, 200000, "Gas limit of each transaction")
+	cmd.Flags().Duration(flagBenchTimeout, defaults.Timeout, "How long to wait for inclusion after the last broadcast")
+	cmd.Flags().Duration(flagBenchPollInterval, defaults.PollInterval, "How often to poll the node for new blocks")
+	cmd.Flags().String(flags.FlagNode, "tcp://localhost:26657", "<host>:<port> to CometBFT RPC interface for this chain")
+	cmd.Flags().String(flags.FlagChainID, "", "The network chain ID; queried from the node if empty")
+	cmd.Flags().String(flagBenchReport, "", "File to write a JSON report of the run to")
+	cmd.Flags().String(flagBenchEngine, "", "Consensus engine of the node, for the report")
+	cmd.Flags().String(flagBenchLabel, "", "Configuration benchmarked, for the report")
+
+	cmd.AddCommand(benchAccountsCommand(), benchReportCommand())
+	return cmd
+}
+
+// benchAccountsCommand returns the command listing the benchmark accounts,
+// so that they can be funded
+func benchAccountsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "accounts",
+		Short: "List the addresses of the benchmark accounts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			n, _ := cmd.Flags().GetInt(flagBenchAccounts)
+			seed, _ := cmd.Flags().GetString(flagBenchAccountSeed)
+			for _, acc := range bench.Accounts(seed, n) {
+				cmd.Println(acc.Address.String())
+			}
+			return nil
+		},
+	}
+}
+
+// benchReportCommand returns the command merging the JSON reports of
+// benchmark runs into one, in any report format
+func benchReportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report [report.json...]",
+		Short: "Merge benchmark reports and write them as JSON, CSV or a Markdown table",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var merged report.Report
+			for _, path := range args {
+				file, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				r, err := report.Read(file)
+				file.Close()
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				merged.Add(r.Runs...)
+			}
+			format, _ := cmd.Flags().GetString(flagBenchFormat)
+			return merged.Write(cmd.OutOrStdout(), format)
+		},
+	}
+	cmd.Flags().String(flagBenchFormat, report.FormatMarkdown, "Output format (json|csv|markdown)")
+	return cmd
+}
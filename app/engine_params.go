@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+
+	errorsmod "cosmossdk.io/errors"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+
+	apptypes "github.com/fffeng99999/hcp-consensus/app/types"
+	"github.com/fffeng99999/hcp-consensus/consensus/hotstuff"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// UpdateTPBFTParams implements apptypes.MsgServer. The parameters are stored
+// whichever engine runs, so a later switch to tPBFT runs with them.
+func (s msgServer) UpdateTPBFTParams(goCtx context.Context, msg *apptypes.MsgUpdateTPBFTParams) (*apptypes.MsgUpdateTPBFTParamsResponse, error) {
+	if msg.Authority != s.app.authority {
+		return nil, errorsmod.Wrapf(govtypes.ErrInvalidSigner, "expected %s, got %s", s.app.authority, msg.Authority)
+	}
+	if msg.Params == nil {
+		return nil, errorsmod.Wrap(sdkerrors.ErrInvalidRequest, "missing params")
+	}
+	params := tpbft.Params{
+		TrustUpdateInterval:       msg.Params.TrustUpdateInterval,
+		MinTrustThreshold:         msg.Params.MinTrustThreshold,
+		DynamicValidatorSelection: msg.Params.DynamicValidatorSelection,
+		ValidatorSelectionCount:   int(msg.Params.ValidatorSelectionCount),
+	}
+	if err := tpbft.SetParams(sdk.UnwrapSDKContext(goCtx), s.app.keys[engineStoreKey], params); err != nil {
+		return nil, err
+	}
+	return &apptypes.MsgUpdateTPBFTParamsResponse{}, nil
+}
+
+// UpdateHotStuffParams implements apptypes.MsgServer. HotStuff switches to
+// the new leader rotation at the start of the next block.
+func (s msgServer) UpdateHotStuffParams(goCtx context.Context, msg *apptypes.MsgUpdateHotStuffParams) (*apptypes.MsgUpdateHotStuffParamsResponse, error) {
+	if msg.Authority != s.app.authority {
+		return nil, errorsmod.Wrapf(govtypes.ErrInvalidSigner, "expected %s, got %s", s.app.authority, msg.Authority)
+	}
+	if msg.Params == nil {
+		return nil, errorsmod.Wrap(sdkerrors.ErrInvalidRequest, "missing params")
+	}
+	params := hotstuff.Params{
+		LeaderElection: msg.Params.LeaderElection,
+		LeaderMinTrust: msg.Params.LeaderMinTrust,
+	}
+	if err := hotstuff.SetParams(sdk.UnwrapSDKContext(goCtx), s.app.keys[engineStoreKey], params); err != nil {
+		return nil, err
+	}
+	return &apptypes.MsgUpdateHotStuffParamsResponse{}, nil
+}
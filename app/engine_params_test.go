@@ -0,0 +1,93 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apptypes "github.com/fffeng99999/hcp-consensus/app/types"
+	"github.com/fffeng99999/hcp-consensus/consensus/hotstuff"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+func TestEngineParams_TPBFT(t *testing.T) {
+	c := newTestChain(t, tpbft.EngineName, func(state map[string]json.RawMessage) {
+		state[tpbft.ModuleName] = json.RawMessage(`{"params":{"trust_update_interval":10,"min_trust_threshold":0.5,"dynamic_validator_selection":true,"validator_selection_count":4},"scores":[]}`)
+	})
+	defer func() { assert.NoError(t, c.app.Close()) }()
+	engine := c.app.ConsensusEngine.(*tpbft.TPBFT)
+	assert.Equal(t, 4, engine.ExportGenesis(c.app.NewContext(true)).Params.ValidatorSelectionCount)
+
+	// Governance changes the parameters the engine runs with, from the end
+	// of the block executing the message
+	update := &apptypes.TPBFTParams{TrustUpdateInterval: 5, MinTrustThreshold: 0.7, ValidatorSelectionCount: 7}
+	c.nextBlock(func(ctx sdk.Context) {
+		server := msgServer{c.app}
+		_, err := server.UpdateTPBFTParams(ctx, &apptypes.MsgUpdateTPBFTParams{Authority: c.operator.String(), Params: update})
+		assert.ErrorIs(t, err, govtypes.ErrInvalidSigner)
+		_, err = server.UpdateTPBFTParams(ctx, &apptypes.MsgUpdateTPBFTParams{Authority: c.app.authority, Params: &apptypes.TPBFTParams{}})
+		assert.Error(t, err)
+		_, err = server.UpdateTPBFTParams(ctx, &apptypes.MsgUpdateTPBFTParams{Authority: c.app.authority, Params: update})
+		assert.NoError(t, err)
+	})
+	c.nextBlock(nil)
+	want := &tpbft.Params{TrustUpdateInterval: 5, MinTrustThreshold: 0.7, ValidatorSelectionCount: 7}
+	assert.Equal(t, want, engine.ExportGenesis(c.app.NewContext(true)).Params)
+
+	exported, err := c.app.ExportAppStateAndValidators(false, nil, []string{tpbft.ModuleName})
+	require.NoError(t, err)
+	var state map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(exported.AppState, &state))
+	trustGenesis, err := tpbft.UnmarshalGenesis(state[tpbft.ModuleName])
+	require.NoError(t, err)
+	assert.Equal(t, want, trustGenesis.Params)
+}
+
+func TestEngineParams_KeptForSwitch(t *testing.T) {
+	// A HotStuff chain keeps the tPBFT parameters of its genesis, and a
+	// switch to tPBFT runs with them
+	c := newTestChain(t, hotstuff.EngineName, func(state map[string]json.RawMessage) {
+		state[tpbft.ModuleName] = json.RawMessage(`{"params":{"trust_update_interval":10,"min_trust_threshold":0.5,"dynamic_validator_selection":false,"validator_selection_count":4},"scores":[]}`)
+	})
+	defer func() { assert.NoError(t, c.app.Close()) }()
+	want := &tpbft.Params{TrustUpdateInterval: 10, MinTrustThreshold: 0.5, ValidatorSelectionCount: 4}
+
+	c.nextBlock(func(ctx sdk.Context) {
+		require.NoError(t, c.app.ScheduleEngineSwitch(ctx, EngineSwitchPlan{Height: c.height + 1, Engine: tpbft.EngineName}))
+	})
+	c.nextBlock(nil)
+	engine, ok := c.app.ConsensusEngine.(*tpbft.TPBFT)
+	require.True(t, ok)
+	assert.Equal(t, want, engine.ExportGenesis(c.app.NewContext(true)).Params)
+}
+
+func TestEngineParams_HotStuff(t *testing.T) {
+	c := newTestChain(t, hotstuff.EngineName, func(state map[string]json.RawMessage) {
+		state[hotstuff.ModuleName] = json.RawMessage(`{"params":{"leader_election":"reputation","leader_min_trust":0.5}}`)
+	})
+	defer func() { assert.NoError(t, c.app.Close()) }()
+
+	c.nextBlock(func(ctx sdk.Context) {
+		server := msgServer{c.app}
+		update := &apptypes.HotStuffParams{LeaderElection: hotstuff.LeaderElectionStake, LeaderMinTrust: 0.5}
+		_, err := server.UpdateHotStuffParams(ctx, &apptypes.MsgUpdateHotStuffParams{Authority: c.operator.String(), Params: update})
+		assert.ErrorIs(t, err, govtypes.ErrInvalidSigner)
+		_, err = server.UpdateHotStuffParams(ctx, &apptypes.MsgUpdateHotStuffParams{Authority: c.app.authority, Params: &apptypes.HotStuffParams{LeaderElection: "random"}})
+		assert.Error(t, err)
+		_, err = server.UpdateHotStuffParams(ctx, &apptypes.MsgUpdateHotStuffParams{Authority: c.app.authority, Params: update})
+		assert.NoError(t, err)
+	})
+	c.nextBlock(nil)
+
+	exported, err := c.app.ExportAppStateAndValidators(false, nil, []string{hotstuff.ModuleName})
+	require.NoError(t, err)
+	var state map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(exported.AppState, &state))
+	gs, err := hotstuff.UnmarshalGenesis(state[hotstuff.ModuleName])
+	require.NoError(t, err)
+	assert.Equal(t, hotstuff.Params{LeaderElection: hotstuff.LeaderElectionStake, LeaderMinTrust: 0.5}, gs.Params)
+}
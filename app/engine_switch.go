@@ -0,0 +1,288 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	errorsmod "cosmossdk.io/errors"
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+
+	apptypes "github.com/fffeng99999/hcp-consensus/app/types"
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+const (
+	// EngineSwitchGenesisKey is the app state section scheduling an engine switch
+	EngineSwitchGenesisKey = "engine_switch"
+
+	// EventTypeEngineSwitch is emitted in the block where the engine changes
+	EventTypeEngineSwitch = "consensus_engine_switch"
+
+	AttributeKeyFrom   = "from"
+	AttributeKeyTo     = "to"
+	AttributeKeyHeight = "height"
+)
+
+// engineStoreKey names the store shared by all engines
+const engineStoreKey = tpbft.StoreKey
+
+// App-level records in the engine store, above the engines' own prefixes
+var (
+	engineSwitchPlanKey = []byte{0xf0}
+	activeEngineKey     = []byte{0xf1}
+	handoffKey          = []byte{0xf2} // Handoff state the active engine was handed
+)
+
+// EngineSwitchPlan schedules a switch to Engine at the start of block Height
+type EngineSwitchPlan struct {
+	Height int64  `json:"height"`
+	Engine string `json:"engine"`
+}
+
+// Validate checks that the plan names a registered engine at a positive height
+func (p EngineSwitchPlan) Validate() error {
+	if p.Height <= 0 {
+		return fmt.Errorf("engine switch height must be positive, got %d", p.Height)
+	}
+	registered := common.RegisteredEngines()
+	for _, name := range registered {
+		if name == p.Engine {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown consensus engine %q, registered engines: %s", p.Engine, strings.Join(registered, ", "))
+}
+
+// EngineSwitchGenesis is the engine_switch section of the app state. Active
+// records the engine a previous chain had switched to, if any.
+type EngineSwitchGenesis struct {
+	Active string            `json:"active,omitempty"`
+	Plan   *EngineSwitchPlan `json:"plan,omitempty"`
+}
+
+// ScheduleEngineSwitch stores a plan to switch engines at a future height,
+// replacing any pending plan. Governance calls it through
+// MsgScheduleEngineSwitch, so every validator switches at the same height.
+func (app *App) ScheduleEngineSwitch(ctx sdk.Context, plan EngineSwitchPlan) error {
+	if err := plan.Validate(); err != nil {
+		return err
+	}
+	if plan.Height <= ctx.BlockHeight() {
+		return fmt.Errorf("engine switch height %d is not after the current height %d", plan.Height, ctx.BlockHeight())
+	}
+
+	bz, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	ctx.KVStore(app.keys[engineStoreKey]).Set(engineSwitchPlanKey, bz)
+	return nil
+}
+
+// EngineSwitchPlan returns the pending switch plan, if any
+func (app *App) EngineSwitchPlan(ctx sdk.Context) (*EngineSwitchPlan, error) {
+	bz := ctx.KVStore(app.keys[engineStoreKey]).Get(engineSwitchPlanKey)
+	if bz == nil {
+		return nil, nil
+	}
+
+	var plan EngineSwitchPlan
+	if err := json.Unmarshal(bz, &plan); err != nil {
+		return nil, fmt.Errorf("failed to decode engine switch plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// msgServer executes the app's messages, which governance passes
+type msgServer struct {
+	app *App
+}
+
+var _ apptypes.MsgServer = msgServer{}
+
+// ScheduleEngineSwitch implements apptypes.MsgServer
+func (s msgServer) ScheduleEngineSwitch(goCtx context.Context, msg *apptypes.MsgScheduleEngineSwitch) (*apptypes.MsgScheduleEngineSwitchResponse, error) {
+	if msg.Authority != s.app.authority {
+		return nil, errorsmod.Wrapf(govtypes.ErrInvalidSigner, "expected %s, got %s", s.app.authority, msg.Authority)
+	}
+	plan := EngineSwitchPlan{Height: msg.Height, Engine: msg.Engine}
+	if err := s.app.ScheduleEngineSwitch(sdk.UnwrapSDKContext(goCtx), plan); err != nil {
+		return nil, err
+	}
+	return &apptypes.MsgScheduleEngineSwitchResponse{}, nil
+}
+
+// maybeSwitchEngine switches engines if a plan is due at this block. The new
+// engine runs the rest of the block and replaces the old one once the block
+// is committed.
+func (app *App) maybeSwitchEngine(ctx sdk.Context) error {
+	plan, err := app.EngineSwitchPlan(ctx)
+	if err != nil || plan == nil || plan.Height != ctx.BlockHeight() {
+		return err
+	}
+
+	ctx.KVStore(app.keys[engineStoreKey]).Delete(engineSwitchPlanKey)
+	if plan.Engine == app.ConsensusEngine.Name() {
+		return nil
+	}
+	next, err := app.switchEngine(ctx, plan.Engine)
+	if err != nil {
+		return err
+	}
+	app.pendingEngine = next
+	return nil
+}
+
+// switchEngine builds the engine to switch to and hands it the current
+// engine's state. It only writes to the block's state: the caller puts the
+// new engine in place of the old one.
+func (app *App) switchEngine(ctx sdk.Context, name string) (common.ConsensusEngine, error) {
+	old := app.ConsensusEngine
+	next, err := common.NewEngine(name, app.engineDeps)
+	if err != nil {
+		return nil, err
+	}
+
+	state := common.HandoffState{From: old.Name(), Height: ctx.BlockHeight()}
+	if exporter, ok := old.(common.HandoffExporter); ok {
+		if state, err = exporter.ExportHandoff(ctx); err != nil {
+			return nil, fmt.Errorf("%s handoff: %w", old.Name(), err)
+		}
+	}
+	if err := app.handOff(ctx, next, state); err != nil {
+		return nil, err
+	}
+
+	ctx.KVStore(app.keys[engineStoreKey]).Set(activeEngineKey, []byte(name))
+	ctx.EventManager().EmitEvent(sdk.NewEvent(
+		EventTypeEngineSwitch,
+		sdk.NewAttribute(AttributeKeyFrom, old.Name()),
+		sdk.NewAttribute(AttributeKeyTo, name),
+		sdk.NewAttribute(AttributeKeyHeight, strconv.FormatInt(ctx.BlockHeight(), 10)),
+	))
+	return next, nil
+}
+
+// handOff gives an engine the state handed over to it and records the state,
+// so that a restarted node hands it over again
+func (app *App) handOff(ctx sdk.Context, engine common.ConsensusEngine, state common.HandoffState) error {
+	importer, ok := engine.(common.HandoffImporter)
+	if !ok {
+		return nil
+	}
+	if err := importer.ImportHandoff(ctx, state); err != nil {
+		return fmt.Errorf("%s handoff: %w", engine.Name(), err)
+	}
+
+	bz, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	ctx.KVStore(app.keys[engineStoreKey]).Set(handoffKey, bz)
+	return nil
+}
+
+// reloadHandoff hands the active engine the recorded handoff state again
+func (app *App) reloadHandoff(ctx sdk.Context) error {
+	importer, ok := app.ConsensusEngine.(common.HandoffImporter)
+	bz := ctx.KVStore(app.keys[engineStoreKey]).Get(handoffKey)
+	if !ok || bz == nil {
+		return nil
+	}
+
+	var state common.HandoffState
+	if err := json.Unmarshal(bz, &state); err != nil {
+		return fmt.Errorf("failed to decode handoff state: %w", err)
+	}
+	return importer.ImportHandoff(ctx, state)
+}
+
+// replaceEngine stops the running engine and starts next in its place, if
+// the old one was running
+func (app *App) replaceEngine(next common.ConsensusEngine) error {
+	old := app.ConsensusEngine
+	running := old.Status().Running
+	if err := old.Stop(); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", old.Name(), err)
+	}
+	if running {
+		if err := next.Start(context.Background()); err != nil {
+			return fmt.Errorf("failed to start %s: %w", next.Name(), err)
+		}
+	}
+	app.ConsensusEngine = next
+	return nil
+}
+
+// restoreActiveEngine replaces the configured engine with the one recorded
+// by a switch at or before the loaded height
+func (app *App) restoreActiveEngine() error {
+	ctx := app.NewUncachedContext(false, cmtproto.Header{Height: app.LastBlockHeight()})
+	bz := ctx.KVStore(app.keys[engineStoreKey]).Get(activeEngineKey)
+	if bz == nil || string(bz) == app.ConsensusEngine.Name() {
+		return nil
+	}
+
+	configured := app.ConsensusEngine.Name()
+	next, err := common.NewEngine(string(bz), app.engineDeps)
+	if err != nil {
+		return err
+	}
+	if err := app.replaceEngine(next); err != nil {
+		return err
+	}
+
+	app.Logger().Info("restored consensus engine from a switch", "configured", configured, "active", next.Name())
+	return nil
+}
+
+// initEngineSwitchGenesis applies the engine_switch genesis section
+func (app *App) initEngineSwitchGenesis(ctx sdk.Context, bz json.RawMessage) error {
+	if len(bz) == 0 {
+		return nil
+	}
+
+	var gs EngineSwitchGenesis
+	if err := json.Unmarshal(bz, &gs); err != nil {
+		return fmt.Errorf("failed to unmarshal %s genesis state: %w", EngineSwitchGenesisKey, err)
+	}
+	// The chain starts with the engine, so it takes over at once
+	if gs.Active != "" && gs.Active != app.ConsensusEngine.Name() {
+		next, err := app.switchEngine(ctx, gs.Active)
+		if err != nil {
+			return err
+		}
+		if err := app.replaceEngine(next); err != nil {
+			return err
+		}
+	}
+	if gs.Plan != nil {
+		return app.ScheduleEngineSwitch(ctx, *gs.Plan)
+	}
+	return nil
+}
+
+// exportEngineSwitchGenesis exports the active engine and any pending plan;
+// plans are dropped on zero-height exports, where heights start over
+func (app *App) exportEngineSwitchGenesis(ctx sdk.Context, forZeroHeight bool) (*EngineSwitchGenesis, error) {
+	gs := &EngineSwitchGenesis{}
+	if bz := ctx.KVStore(app.keys[engineStoreKey]).Get(activeEngineKey); bz != nil {
+		gs.Active = string(bz)
+	}
+	if !forZeroHeight {
+		bz := ctx.KVStore(app.keys[engineStoreKey]).Get(engineSwitchPlanKey)
+		if bz != nil {
+			gs.Plan = &EngineSwitchPlan{}
+			if err := json.Unmarshal(bz, gs.Plan); err != nil {
+				return nil, fmt.Errorf("failed to decode engine switch plan: %w", err)
+			}
+		}
+	}
+	return gs, nil
+}
@@ -0,0 +1,283 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cosmossdk.io/log"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/privval"
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	dbm "github.com/cosmos/cosmos-db"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	simtestutil "github.com/cosmos/cosmos-sdk/testutil/sims"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
+	govkeeper "github.com/cosmos/cosmos-sdk/x/gov/keeper"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	govv1 "github.com/cosmos/cosmos-sdk/x/gov/types/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apptypes "github.com/fffeng99999/hcp-consensus/app/types"
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+	"github.com/fffeng99999/hcp-consensus/testnet"
+)
+
+// testChain drives an app block by block, as CometBFT would, with a single
+// validator proposing every block
+type testChain struct {
+	t        *testing.T
+	app      *App
+	dir      string
+	chainID  string
+	operator sdk.AccAddress
+	proposer []byte // Consensus address of the validator
+	height   int64
+	time     time.Time
+}
+
+// newTestChain writes the genesis of a single-validator chain, lets edit
+// change its app state, and initializes an app running engine from it
+func newTestChain(t *testing.T, engine string, edit func(map[string]json.RawMessage)) *testChain {
+	cfg := testConfig(t)
+	cfg.Nodes = 1
+	cfg.Dir = t.TempDir()
+	cfg.GenesisTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	nodes, err := testnet.InitFiles(cfg)
+	require.NoError(t, err)
+
+	genesis, err := genutiltypes.AppGenesisFromFile(nodes[0].Config.GenesisFile())
+	require.NoError(t, err)
+	var state map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(genesis.AppState, &state))
+	if edit != nil {
+		edit(state)
+	}
+	appState, err := json.Marshal(state)
+	require.NoError(t, err)
+
+	pv := privval.LoadFilePV(nodes[0].Config.PrivValidatorKeyFile(), nodes[0].Config.PrivValidatorStateFile())
+	c := &testChain{
+		t:        t,
+		dir:      t.TempDir(),
+		chainID:  cfg.ChainID,
+		operator: nodes[0].Operator,
+		proposer: pv.Key.PubKey.Address(),
+		time:     cfg.GenesisTime,
+	}
+	c.app = c.newApp(engine)
+	c.initChain(appState, 1)
+	return c
+}
+
+// newApp opens the chain's database with an app configured to run engine
+func (c *testChain) newApp(engine string) *App {
+	db, err := dbm.NewDB("application", dbm.GoLevelDBBackend, c.dir)
+	require.NoError(c.t, err)
+	appOpts := simtestutil.AppOptionsMap{"consensus-engine": engine, flags.FlagChainID: c.chainID}
+	return NewApp(log.NewNopLogger(), db, nil, true, appOpts)
+}
+
+// initChain initializes the chain from an app state, starting at height
+func (c *testChain) initChain(appState []byte, height int64) {
+	params := cmttypes.DefaultConsensusParams().ToProto()
+	_, err := c.app.InitChain(&abci.RequestInitChain{
+		ChainId:         c.chainID,
+		Time:            c.time,
+		InitialHeight:   height,
+		ConsensusParams: &params,
+		AppStateBytes:   appState,
+	})
+	require.NoError(c.t, err)
+	c.height = height - 1
+}
+
+// restart closes the app and reopens its database with an app configured to
+// run engine
+func (c *testChain) restart(engine string) {
+	require.NoError(c.t, c.app.Close())
+	c.app = c.newApp(engine)
+}
+
+// nextBlock finalizes and commits the next block a second after the last.
+// Before the commit, during is called with a context writing to the block's
+// state.
+func (c *testChain) nextBlock(during func(ctx sdk.Context)) *abci.ResponseFinalizeBlock {
+	c.height++
+	c.time = c.time.Add(time.Second)
+	res, err := c.app.FinalizeBlock(&abci.RequestFinalizeBlock{
+		Height:          c.height,
+		Time:            c.time,
+		ProposerAddress: c.proposer,
+		DecidedLastCommit: abci.CommitInfo{Votes: []abci.VoteInfo{{
+			Validator:   abci.Validator{Address: c.proposer, Power: 100},
+			BlockIdFlag: cmtproto.BlockIDFlagCommit,
+		}}},
+	})
+	require.NoError(c.t, err)
+	if during != nil {
+		during(c.app.NewUncachedContext(false, cmtproto.Header{ChainID: c.chainID, Height: c.height, Time: c.time}))
+	}
+	_, err = c.app.Commit()
+	require.NoError(c.t, err)
+	return res
+}
+
+// shortVotingPeriod sets the gov voting periods to a few blocks
+func shortVotingPeriod(t *testing.T) func(map[string]json.RawMessage) {
+	return func(state map[string]json.RawMessage) {
+		cdc := testConfig(t).Codec
+		var gs govv1.GenesisState
+		cdc.MustUnmarshalJSON(state[govtypes.ModuleName], &gs)
+		voting, expedited := 2*time.Second, time.Second
+		gs.Params.VotingPeriod, gs.Params.ExpeditedVotingPeriod = &voting, &expedited
+		state[govtypes.ModuleName] = cdc.MustMarshalJSON(&gs)
+	}
+}
+
+// findEvent returns the attributes of the first event of type typ
+func findEvent(events []abci.Event, typ string) map[string]string {
+	for _, e := range events {
+		if e.Type != typ {
+			continue
+		}
+		attrs := make(map[string]string)
+		for _, a := range e.Attributes {
+			attrs[a.Key] = a.Value
+		}
+		return attrs
+	}
+	return nil
+}
+
+// trustScores returns the trust scores an engine would hand over
+func trustScores(t *testing.T, ctx sdk.Context, engine common.ConsensusEngine) *tpbft.GenesisState {
+	exporter, ok := engine.(common.HandoffExporter)
+	require.True(t, ok, "%s exports no handoff", engine.Name())
+	state, err := exporter.ExportHandoff(ctx)
+	require.NoError(t, err)
+	gs, err := tpbft.UnmarshalGenesis(state.Trust)
+	require.NoError(t, err)
+	return gs
+}
+
+func TestEngineSwitch_Governance(t *testing.T) {
+	c := newTestChain(t, tpbft.EngineName, shortVotingPeriod(t))
+	old := c.app.ConsensusEngine
+	require.Equal(t, tpbft.EngineName, old.Name())
+
+	// Governance passes a switch to HotStuff at height 5 in block 3
+	const switchHeight = 5
+	var proposalID uint64
+	c.nextBlock(func(ctx sdk.Context) {
+		govServer := govkeeper.NewMsgServerImpl(c.app.GovKeeper)
+		params, err := c.app.GovKeeper.Params.Get(ctx)
+		require.NoError(t, err)
+		submit, err := govv1.NewMsgSubmitProposal(
+			[]sdk.Msg{&apptypes.MsgScheduleEngineSwitch{Authority: c.app.authority, Height: switchHeight, Engine: "hotstuff"}},
+			params.MinDeposit, c.operator.String(), "", "Switch to HotStuff", "Switch consensus engines", false,
+		)
+		require.NoError(t, err)
+		res, err := govServer.SubmitProposal(ctx, submit)
+		require.NoError(t, err)
+		proposalID = res.ProposalId
+		_, err = govServer.Vote(ctx, govv1.NewMsgVote(c.operator, proposalID, govv1.OptionYes, ""))
+		require.NoError(t, err)
+	})
+	c.nextBlock(nil)
+	c.nextBlock(nil)
+
+	ctx := c.app.NewContext(true)
+	proposal, err := c.app.GovKeeper.Proposals.Get(ctx, proposalID)
+	require.NoError(t, err)
+	require.Equal(t, govv1.StatusPassed, proposal.Status, proposal.FailedReason)
+	plan, err := c.app.EngineSwitchPlan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &EngineSwitchPlan{Height: switchHeight, Engine: "hotstuff"}, plan)
+
+	c.nextBlock(nil)
+	handed := trustScores(t, c.app.NewContext(true), old)
+	require.NotEmpty(t, handed.Scores)
+
+	// The switch block hands tPBFT's trust scores to HotStuff, which replaces
+	// it once the block is committed
+	res := c.nextBlock(func(sdk.Context) {
+		assert.Same(t, old, c.app.ConsensusEngine)
+		assert.True(t, old.Status().Running)
+		assert.Equal(t, "hotstuff", c.app.blockEngine().Name())
+	})
+	assert.Equal(t, "hotstuff", c.app.ConsensusEngine.Name())
+	assert.False(t, old.Status().Running)
+	assert.True(t, c.app.ConsensusEngine.Status().Running)
+	assert.Equal(t, handed, trustScores(t, c.app.NewContext(true), c.app.ConsensusEngine))
+	event := findEvent(res.Events, EventTypeEngineSwitch)
+	assert.Equal(t, tpbft.EngineName, event[AttributeKeyFrom])
+	assert.Equal(t, "hotstuff", event[AttributeKeyTo])
+	assert.Equal(t, "5", event[AttributeKeyHeight])
+	plan, err = c.app.EngineSwitchPlan(c.app.NewContext(true))
+	require.NoError(t, err)
+	assert.Nil(t, plan)
+
+	// A restarted node runs HotStuff though configured with tPBFT, with the
+	// trust scores it was handed
+	c.restart(tpbft.EngineName)
+	defer func() { assert.NoError(t, c.app.Close()) }()
+	assert.Equal(t, "hotstuff", c.app.ConsensusEngine.Name())
+	assert.True(t, c.app.ConsensusEngine.Status().Running)
+	assert.Equal(t, handed, trustScores(t, c.app.NewContext(true), c.app.ConsensusEngine))
+	c.nextBlock(nil)
+	assert.Equal(t, "hotstuff", c.app.ConsensusEngine.Name())
+}
+
+func TestEngineSwitch_RequiresGovernance(t *testing.T) {
+	c := newTestChain(t, tpbft.EngineName, nil)
+	defer func() { assert.NoError(t, c.app.Close()) }()
+
+	c.nextBlock(func(ctx sdk.Context) {
+		server := msgServer{c.app}
+		_, err := server.ScheduleEngineSwitch(ctx, &apptypes.MsgScheduleEngineSwitch{Authority: c.operator.String(), Height: 10, Engine: "hotstuff"})
+		assert.ErrorIs(t, err, govtypes.ErrInvalidSigner)
+		_, err = server.ScheduleEngineSwitch(ctx, &apptypes.MsgScheduleEngineSwitch{Authority: c.app.authority, Height: 10, Engine: "unknown"})
+		assert.Error(t, err)
+		_, err = server.ScheduleEngineSwitch(ctx, &apptypes.MsgScheduleEngineSwitch{Authority: c.app.authority, Height: 1, Engine: "hotstuff"})
+		assert.Error(t, err, "the height has passed")
+	})
+	plan, err := c.app.EngineSwitchPlan(c.app.NewContext(true))
+	require.NoError(t, err)
+	assert.Nil(t, plan)
+}
+
+func TestEngineSwitch_GenesisRoundTrip(t *testing.T) {
+	// A chain starting from a genesis that records a switch to HotStuff runs it
+	c := newTestChain(t, tpbft.EngineName, func(state map[string]json.RawMessage) {
+		state[EngineSwitchGenesisKey] = json.RawMessage(`{"active":"hotstuff","plan":{"height":100,"engine":"raft"}}`)
+	})
+	assert.Equal(t, "hotstuff", c.app.ConsensusEngine.Name())
+	c.nextBlock(nil)
+	c.nextBlock(nil)
+
+	exported, err := c.app.ExportAppStateAndValidators(false, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.app.Close())
+	var state map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(exported.AppState, &state))
+	var gs EngineSwitchGenesis
+	require.NoError(t, json.Unmarshal(state[EngineSwitchGenesisKey], &gs))
+	want := EngineSwitchGenesis{Active: "hotstuff", Plan: &EngineSwitchPlan{Height: 100, Engine: "raft"}}
+	assert.Equal(t, want, gs)
+
+	// A new chain from the export runs HotStuff with the plan pending
+	next := &testChain{t: t, dir: t.TempDir(), chainID: c.chainID, operator: c.operator, proposer: c.proposer, time: c.time}
+	next.app = next.newApp(tpbft.EngineName)
+	defer func() { assert.NoError(t, next.app.Close()) }()
+	next.initChain(exported.AppState, exported.Height)
+	assert.Equal(t, "hotstuff", next.app.ConsensusEngine.Name())
+	next.nextBlock(nil)
+	plan, err := next.app.EngineSwitchPlan(next.app.NewContext(true))
+	require.NoError(t, err)
+	assert.Equal(t, want.Plan, plan)
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+	"github.com/fffeng99999/hcp-consensus/consensus/hotstuff"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// ExportAppStateAndValidators exports the state of the application for a genesis file,
+// including the tPBFT trust scores and their histories.
+func (app *App) ExportAppStateAndValidators(forZeroHeight bool, jailAllowedAddrs, modulesToExport []string) (servertypes.ExportedApp, error) {
+	// as if they could withdraw from the start of the next block
+	ctx := app.NewContextLegacy(true, cmtproto.Header{Height: app.LastBlockHeight()})
+
+	// We export at last height + 1, because that's the height at which
+	// CometBFT will start InitChain.
+	height := app.LastBlockHeight() + 1
+	if forZeroHeight {
+		height = 0
+		if err := app.prepForZeroHeightGenesis(ctx, jailAllowedAddrs); err != nil {
+			return servertypes.ExportedApp{}, err
+		}
+	}
+
+	// The engine and engine switch sections are not owned by the module
+	// manager, so filter them out before the manager checks that every
+	// requested module exists
+	exportTrust := len(modulesToExport) == 0
+	exportHotStuff := len(modulesToExport) == 0
+	exportSwitch := len(modulesToExport) == 0
+	var managerModules []string
+	for _, name := range modulesToExport {
+		switch name {
+		case tpbft.ModuleName:
+			exportTrust = true
+		case hotstuff.ModuleName:
+			exportHotStuff = true
+		case EngineSwitchGenesisKey:
+			exportSwitch = true
+		default:
+			managerModules = append(managerModules, name)
+		}
+	}
+
+	genState := make(map[string]json.RawMessage)
+	if len(modulesToExport) == 0 || len(managerModules) > 0 {
+		var err error
+		genState, err = app.ModuleManager.ExportGenesisForModules(ctx, app.appCodec, managerModules)
+		if err != nil {
+			return servertypes.ExportedApp{}, err
+		}
+	}
+
+	if exportTrust {
+		bz, err := app.exportTrustGenesis(ctx)
+		if err != nil {
+			return servertypes.ExportedApp{}, err
+		}
+		genState[tpbft.ModuleName] = bz
+	}
+
+	if exportHotStuff {
+		params, err := hotstuff.GetParams(ctx, app.keys[engineStoreKey])
+		if err != nil {
+			return servertypes.ExportedApp{}, err
+		}
+
+		bz, err := json.Marshal(hotstuff.GenesisState{Params: params})
+		if err != nil {
+			return servertypes.ExportedApp{}, err
+		}
+		genState[hotstuff.ModuleName] = bz
+	}
+
+	if exportSwitch {
+		switchGenesis, err := app.exportEngineSwitchGenesis(ctx, forZeroHeight)
+		if err != nil {
+			return servertypes.ExportedApp{}, err
+		}
+
+		bz, err := json.Marshal(switchGenesis)
+		if err != nil {
+			return servertypes.ExportedApp{}, err
+		}
+		genState[EngineSwitchGenesisKey] = bz
+	}
+
+	appState, err := json.MarshalIndent(genState, "", "  ")
+	if err != nil {
+		return servertypes.ExportedApp{}, err
+	}
+
+	validators, err := staking.WriteValidators(ctx, app.StakingKeeper)
+	return servertypes.ExportedApp{
+		AppState:        appState,
+		Validators:      validators,
+		Height:          height,
+		ConsensusParams: app.BaseApp.GetConsensusParams(ctx),
+	}, err
+}
+
+// exportTrustGenesis returns the tpbft section: the stored tPBFT parameters
+// and the trust scores of tPBFT, or those another engine was handed and
+// would hand on
+func (app *App) exportTrustGenesis(ctx sdk.Context) (json.RawMessage, error) {
+	trustGenesis := tpbft.DefaultGenesis()
+	switch engine := app.ConsensusEngine.(type) {
+	case *tpbft.TPBFT:
+		trustGenesis = engine.ExportGenesis(ctx)
+	case common.HandoffExporter:
+		state, err := engine.ExportHandoff(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if trustGenesis, err = tpbft.UnmarshalGenesis(state.Trust); err != nil {
+			return nil, err
+		}
+	}
+	params, err := tpbft.GetParams(ctx, app.keys[engineStoreKey])
+	if err != nil {
+		return nil, err
+	}
+	trustGenesis.Params = &params
+	return json.Marshal(trustGenesis)
+}
+
+// prepForZeroHeightGenesis prepares for a fresh start at zero height.
+// NOTE zero height genesis is a temporary feature which will be deprecated
+// in favour of export at a block height
+func (app *App) prepForZeroHeightGenesis(ctx sdk.Context, jailAllowedAddrs []string) error {
+	applyAllowedAddrs := len(jailAllowedAddrs) > 0
+
+	// check if there is a allowed address list
+	allowedAddrsMap := make(map[string]bool)
+	for _, addr := range jailAllowedAddrs {
+		if _, err := sdk.ValAddressFromBech32(addr); err != nil {
+			return fmt.Errorf("invalid jail allowed address %s: %w", addr, err)
+		}
+		allowedAddrsMap[addr] = true
+	}
+
+	/* Handle staking state. */
+
+	// iterate through redelegations, reset creation height
+	var iterErr error
+	err := app.StakingKeeper.IterateRedelegations(ctx, func(_ int64, red stakingtypes.Redelegation) (stop bool) {
+		for i := range red.Entries {
+			red.Entries[i].CreationHeight = 0
+		}
+		iterErr = app.StakingKeeper.SetRedelegation(ctx, red)
+		return iterErr != nil
+	})
+	if err != nil {
+		return err
+	}
+	if iterErr != nil {
+		return iterErr
+	}
+
+	// iterate through unbonding delegations, reset creation height
+	err = app.StakingKeeper.IterateUnbondingDelegations(ctx, func(_ int64, ubd stakingtypes.UnbondingDelegation) (stop bool) {
+		for i := range ubd.Entries {
+			ubd.Entries[i].CreationHeight = 0
+		}
+		iterErr = app.StakingKeeper.SetUnbondingDelegation(ctx, ubd)
+		return iterErr != nil
+	})
+	if err != nil {
+		return err
+	}
+	if iterErr != nil {
+		return iterErr
+	}
+
+	// reset unbonding heights and jail validators outside the allowed list
+	validators, err := app.StakingKeeper.GetAllValidators(ctx)
+	if err != nil {
+		return err
+	}
+	for _, validator := range validators {
+		validator.UnbondingHeight = 0
+		if applyAllowedAddrs && !allowedAddrsMap[validator.OperatorAddress] && !validator.Jailed {
+			// jailed validators must not remain in the power index
+			if err := app.StakingKeeper.DeleteValidatorByPowerIndex(ctx, validator); err != nil {
+				return err
+			}
+			validator.Jailed = true
+		}
+		if err := app.StakingKeeper.SetValidator(ctx, validator); err != nil {
+			return err
+		}
+	}
+
+	if _, err := app.StakingKeeper.ApplyAndReturnValidatorSetUpdates(ctx); err != nil {
+		return err
+	}
+
+	/* Handle tPBFT trust state. */
+
+	// trust windows restart with the new chain; current scores are kept as seeds
+	if engine, ok := app.ConsensusEngine.(*tpbft.TPBFT); ok {
+		engine.TrustScorer.ResetHistory()
+		return app.resetHandoffHistory(ctx, false)
+	}
+	return app.resetHandoffHistory(ctx, true)
+}
+
+// resetHandoffHistory drops the trust histories from the recorded handoff
+// state, and hands the state to the active engine again if reload is set
+func (app *App) resetHandoffHistory(ctx sdk.Context, reload bool) error {
+	store := ctx.KVStore(app.keys[engineStoreKey])
+	bz := store.Get(handoffKey)
+	if bz == nil {
+		return nil
+	}
+
+	var state common.HandoffState
+	if err := json.Unmarshal(bz, &state); err != nil {
+		return fmt.Errorf("failed to decode handoff state: %w", err)
+	}
+	if len(state.Trust) == 0 {
+		return nil
+	}
+	var trust tpbft.GenesisState
+	if err := json.Unmarshal(state.Trust, &trust); err != nil {
+		return fmt.Errorf("failed to decode handed over trust state: %w", err)
+	}
+	trust.ResetHistory()
+	var err error
+	if state.Trust, err = json.Marshal(trust); err != nil {
+		return err
+	}
+
+	if bz, err = json.Marshal(state); err != nil {
+		return err
+	}
+	store.Set(handoffKey, bz)
+	if !reload {
+		return nil
+	}
+	return app.reloadHandoff(ctx)
+}
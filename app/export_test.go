@@ -0,0 +1,131 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cosmossdk.io/log"
+	dbm "github.com/cosmos/cosmos-db"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	simtestutil "github.com/cosmos/cosmos-sdk/testutil/sims"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+func TestExport(t *testing.T) {
+	plan := &EngineSwitchPlan{Height: 100, Engine: "hotstuff"}
+	c := newTestChain(t, tpbft.EngineName, func(state map[string]json.RawMessage) {
+		seed := tpbft.GenesisState{Scores: []tpbft.TrustScoreRecord{{ValidatorAddress: "hcpvaloper1seeded", TotalScore: 0.8}}}
+		state[tpbft.ModuleName], _ = json.Marshal(seed)
+		state[EngineSwitchGenesisKey], _ = json.Marshal(EngineSwitchGenesis{Plan: plan})
+	})
+	var trustAt2 []byte
+	for c.height < 4 {
+		c.nextBlock(nil)
+		if c.height == 2 {
+			trustAt2, _ = json.Marshal(c.app.ConsensusEngine.(*tpbft.TPBFT).ExportGenesis(sdk.Context{}))
+		}
+	}
+	require.NoError(t, c.app.Close())
+	cfg := testConfig(t)
+
+	// export runs hcpd export on the chain's database
+	export := func(height int64, forZeroHeight bool) servertypes.ExportedApp {
+		db, err := dbm.NewDB("application", dbm.GoLevelDBBackend, c.dir)
+		require.NoError(t, err)
+		defer db.Close()
+		appOpts := simtestutil.AppOptionsMap{"consensus-engine": tpbft.EngineName, flags.FlagChainID: c.chainID}
+		exported, err := createHcpAppAndExport(log.NewNopLogger(), db, nil, height, forZeroHeight, nil, appOpts, nil)
+		require.NoError(t, err)
+		return exported
+	}
+
+	for _, tc := range []struct {
+		name          string
+		height        int64
+		forZeroHeight bool
+		wantHeight    int64
+		wantSwitch    EngineSwitchGenesis
+	}{
+		{"historical height", 2, false, 3, EngineSwitchGenesis{Plan: plan}},
+		{"zero height", -1, true, 0, EngineSwitchGenesis{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exported := export(tc.height, tc.forZeroHeight)
+			assert.Equal(t, tc.wantHeight, exported.Height)
+			assert.Len(t, exported.Validators, 1)
+
+			var state map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(exported.AppState, &state))
+			require.NoError(t, ModuleBasics.ValidateGenesis(cfg.Codec, cfg.TxConfig, state))
+			trust, err := tpbft.UnmarshalGenesis(state[tpbft.ModuleName])
+			require.NoError(t, err)
+			require.NoError(t, trust.Validate())
+			assert.Len(t, trust.Scores, 2, "the seeded score and the validator's")
+			if !tc.forZeroHeight {
+				assert.JSONEq(t, string(trustAt2), string(state[tpbft.ModuleName]))
+			}
+			var switchGenesis EngineSwitchGenesis
+			require.NoError(t, json.Unmarshal(state[EngineSwitchGenesisKey], &switchGenesis))
+			assert.Equal(t, tc.wantSwitch, switchGenesis)
+
+			// A new chain from the export starts with the same sections
+			next := &testChain{t: t, dir: t.TempDir(), chainID: c.chainID, operator: c.operator, proposer: c.proposer, time: c.time}
+			next.app = next.newApp(tpbft.EngineName)
+			defer func() { assert.NoError(t, next.app.Close()) }()
+			next.initChain(exported.AppState, max(exported.Height, 1))
+
+			ctx := next.app.NewContext(false)
+			got, err := next.app.exportTrustGenesis(ctx)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(state[tpbft.ModuleName]), string(got))
+			gotSwitch, err := next.app.exportEngineSwitchGenesis(ctx, false)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSwitch, *gotSwitch)
+			next.nextBlock(nil)
+		})
+	}
+}
+
+func TestExport_SwitchedEngine(t *testing.T) {
+	// HotStuff exports the trust scores it was handed
+	seed := []byte(`{"scores":[{"validator_address":"hcpvaloper1seeded","total_score":0.8,"success_history":[true,false]}]}`)
+	c := newTestChain(t, tpbft.EngineName, func(state map[string]json.RawMessage) {
+		state[tpbft.ModuleName] = seed
+		state[EngineSwitchGenesisKey] = json.RawMessage(`{"active":"hotstuff"}`)
+	})
+	defer func() { assert.NoError(t, c.app.Close()) }()
+	require.Equal(t, "hotstuff", c.app.ConsensusEngine.Name())
+	c.nextBlock(nil)
+
+	exported, err := c.app.ExportAppStateAndValidators(false, nil, []string{tpbft.ModuleName})
+	require.NoError(t, err)
+	var state map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(exported.AppState, &state))
+	trust, err := tpbft.UnmarshalGenesis(state[tpbft.ModuleName])
+	require.NoError(t, err)
+	require.Len(t, trust.Scores, 1)
+	assert.Equal(t, "hcpvaloper1seeded", trust.Scores[0].ValidatorAddress)
+	assert.Equal(t, 0.8, trust.Scores[0].TotalScore)
+	assert.Equal(t, []bool{true, false}, trust.Scores[0].SuccessHistory)
+
+	// At zero height the histories are dropped from what it was handed, and
+	// from the recorded handoff
+	exported, err = c.app.ExportAppStateAndValidators(true, nil, []string{tpbft.ModuleName})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(exported.AppState, &state))
+	trust, err = tpbft.UnmarshalGenesis(state[tpbft.ModuleName])
+	require.NoError(t, err)
+	require.Len(t, trust.Scores, 1)
+	assert.Equal(t, 0.8, trust.Scores[0].TotalScore)
+	assert.Empty(t, trust.Scores[0].SuccessHistory)
+
+	var handoff common.HandoffState
+	require.NoError(t, json.Unmarshal(c.app.NewContext(true).KVStore(c.app.keys[engineStoreKey]).Get(handoffKey), &handoff))
+	assert.NotContains(t, string(handoff.Trust), "success_history")
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+	"github.com/fffeng99999/hcp-consensus/consensus/raft"
+)
+
+func TestRaft_TracksBondedValidators(t *testing.T) {
+	c := newTestChain(t, raft.EngineName, nil)
+	defer func() { assert.NoError(t, c.app.Close()) }()
+	engine := c.app.ConsensusEngine.(*raft.RaftConsensus)
+
+	// Once the node leads, the genesis validator joins
+	operator := sdk.ValAddress(c.operator).String()
+	require.Eventually(t, func() bool {
+		c.nextBlock(nil)
+		return len(engine.Membership().Learners) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{operator}, engine.Membership().Learners)
+}
+
+// validatorSetRecorder records the validator updates an engine is given
+type validatorSetRecorder struct {
+	common.ConsensusEngine
+	updates [][]abci.ValidatorUpdate
+}
+
+func (r *validatorSetRecorder) OnValidatorUpdates(ctx sdk.Context, updates []abci.ValidatorUpdate) error {
+	r.updates = append(r.updates, updates)
+	return nil
+}
+
+func TestEndBlocker_ValidatorUpdates(t *testing.T) {
+	c := newTestChain(t, raft.EngineName, nil)
+	defer func() { assert.NoError(t, c.app.Close()) }()
+	recorder := &validatorSetRecorder{ConsensusEngine: c.app.ConsensusEngine}
+	c.app.ConsensusEngine = recorder
+
+	// Blocks leaving the validator set alone are not reported
+	c.nextBlock(nil)
+	assert.Empty(t, recorder.updates)
+
+	c.nextBlock(func(ctx sdk.Context) {
+		val, err := c.app.StakingKeeper.GetValidator(ctx, sdk.ValAddress(c.operator))
+		require.NoError(t, err)
+		_, err = c.app.StakingKeeper.Delegate(ctx, c.operator, sdk.TokensFromConsensusPower(10, sdk.DefaultPowerReduction), stakingtypes.Unbonded, val, true)
+		require.NoError(t, err)
+	})
+	c.nextBlock(nil)
+	require.Len(t, recorder.updates, 1)
+	assert.EqualValues(t, 110, recorder.updates[0][0].Power)
+}
@@ -1,7 +1,6 @@
 package app
 
 import (
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -33,6 +32,8 @@ import (
 	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
 	"github.com/cosmos/gogoproto/proto"
 	"github.com/spf13/cobra"
+
+	apptypes "github.com/fffeng99999/hcp-consensus/app/types"
 )
 
 // NewRootCmd creates a new root command for hcpd.
@@ -45,41 +46,9 @@ func NewRootCmd() *cobra.Command {
 	cfg.Seal()
 
 	// 2. Setup Encoding/TxConfig
-	signingOptions := txsigning.Options{
-		AddressCodec:          sdkaddress.NewBech32Codec("hcp"),
-		ValidatorAddressCodec: sdkaddress.NewBech32Codec("hcpvaloper"),
-	}
-
-	interfaceRegistry, err := codectypes.NewInterfaceRegistryWithOptions(codectypes.InterfaceRegistryOptions{
-		ProtoFiles:     proto.HybridResolver,
-		SigningOptions: signingOptions,
-	})
-	if err != nil {
-		panic(err)
-	}
-
-	// Set FileResolver to interfaceRegistry to ensure proper resolution in TxConfig
-	signingOptions.FileResolver = interfaceRegistry
-
-	cryptocodec.RegisterInterfaces(interfaceRegistry)
-	ModuleBasics.RegisterInterfaces(interfaceRegistry)
-	appCodec := codec.NewProtoCodec(interfaceRegistry)
+	interfaceRegistry, appCodec, txConfig := makeEncoding()
 	legacyAmino := codec.NewLegacyAmino()
 
-	txConfig, err := authtx.NewTxConfigWithOptions(appCodec, authtx.ConfigOptions{
-		SigningOptions: &signingOptions,
-	})
-	if err != nil {
-		panic(err)
-	}
-
-	if txConfig.SigningContext() == nil {
-		panic("SigningContext is nil")
-	}
-	if txConfig.SigningContext().ValidatorAddressCodec() == nil {
-		panic("ValidatorAddressCodec is nil")
-	}
-
 	// 3. Define rootCmd
 	rootCmd := &cobra.Command{
 		Use:   "hcpd",
@@ -101,7 +70,7 @@ func NewRootCmd() *cobra.Command {
 				WithHomeDir(DefaultNodeHome).
 				WithViper("HCP")
 
-			clientCtx, err = client.ReadPersistentCommandFlags(clientCtx, cmd.Flags())
+			clientCtx, err := client.ReadPersistentCommandFlags(clientCtx, cmd.Flags())
 			if err != nil {
 				return err
 			}
@@ -133,11 +102,54 @@ func NewRootCmd() *cobra.Command {
 		queryCommand(),
 		txCommand(),
 		keys.Commands(),
+		benchCommand(),
+		testnetCommand(),
 	)
 
 	return rootCmd
 }
 
+// makeEncoding returns the interface registry, codec and tx config of the
+// client commands
+func makeEncoding() (codectypes.InterfaceRegistry, codec.Codec, client.TxConfig) {
+	signingOptions := txsigning.Options{
+		AddressCodec:          sdkaddress.NewBech32Codec("hcp"),
+		ValidatorAddressCodec: sdkaddress.NewBech32Codec("hcpvaloper"),
+	}
+
+	interfaceRegistry, err := codectypes.NewInterfaceRegistryWithOptions(codectypes.InterfaceRegistryOptions{
+		ProtoFiles:     proto.HybridResolver,
+		SigningOptions: signingOptions,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Set FileResolver to interfaceRegistry to ensure proper resolution in TxConfig
+	signingOptions.FileResolver = interfaceRegistry
+
+	cryptocodec.RegisterInterfaces(interfaceRegistry)
+	ModuleBasics.RegisterInterfaces(interfaceRegistry)
+	apptypes.RegisterInterfaces(interfaceRegistry)
+	appCodec := codec.NewProtoCodec(interfaceRegistry)
+
+	txConfig, err := authtx.NewTxConfigWithOptions(appCodec, authtx.ConfigOptions{
+		SigningOptions: &signingOptions,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	if txConfig.SigningContext() == nil {
+		panic("SigningContext is nil")
+	}
+	if txConfig.SigningContext().ValidatorAddressCodec() == nil {
+		panic("ValidatorAddressCodec is nil")
+	}
+
+	return interfaceRegistry, appCodec, txConfig
+}
+
 func addModuleInitFlags(startCmd *cobra.Command) {
 	// crisistypes.ModuleCdc = app.ModuleBasics.Cdc
 }
@@ -189,7 +201,6 @@ func customCollectGenTxsCmd(genBalIterator banktypes.GenesisBalancesIterator, de
 			genTxsDir := filepath.Join(clientCtx.HomeDir, "config", "gentx")
 			initCfg := genutiltypes.NewInitConfig(genDoc.ChainID, genTxsDir, nodeID, valPubKey)
 
-			fmt.Printf("DEBUG: customCollectGenTxsCmd: valAddrCodec: %v, Type: %T\n", valAddrCodec, valAddrCodec)
 			if valAddrCodec == nil {
 				panic("valAddrCodec is nil in customCollectGenTxsCmd")
 			}
@@ -242,8 +253,22 @@ func createHcpAppAndExport(
 	appOpts servertypes.AppOptions,
 	modulesToExport []string,
 ) (servertypes.ExportedApp, error) {
-	// export logic would go here
-	return servertypes.ExportedApp{}, nil
+	var hcpApp *App
+	if height != -1 {
+		hcpApp = NewApp(logger, db, traceStore, false, appOpts)
+		if err := hcpApp.LoadHeight(height); err != nil {
+			return servertypes.ExportedApp{}, err
+		}
+	} else {
+		hcpApp = NewApp(logger, db, traceStore, true, appOpts)
+	}
+	defer func() {
+		if err := hcpApp.Close(); err != nil {
+			logger.Error("Failed to close app", "error", err)
+		}
+	}()
+
+	return hcpApp.ExportAppStateAndValidators(forZeroHeight, jailAllowedAddrs, modulesToExport)
 }
 
 // CustomGenesisCoreCommand copies logic from genutilcli.GenesisCoreCommand but allows debugging
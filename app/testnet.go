@@ -0,0 +1,213 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+	"github.com/spf13/cobra"
+
+	"github.com/fffeng99999/hcp-consensus/bench"
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+	"github.com/fffeng99999/hcp-consensus/testnet"
+)
+
+const (
+	flagTestnetNodes         = "nodes"
+	flagTestnetEngine        = "engine"
+	flagTestnetDir           = "dir"
+	flagTestnetSeed          = "seed"
+	flagTestnetHost          = "host"
+	flagTestnetBasePort      = "base-port"
+	flagTestnetTimeoutCommit = "timeout-commit"
+	flagTestnetConfigDir     = "config-dir"
+	flagTestnetGenesisTime   = "genesis-time"
+	flagTestnetHostnames     = "hostnames"
+	flagTestnetOverwrite     = "overwrite"
+
+	// defaultGenesisTime keeps generated files the same from run to run
+	defaultGenesisTime = "2024-01-01T00:00:00Z"
+)
+
+// testnetCommand returns the command running local multi-validator testnets
+func testnetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                        "testnet",
+		Short:                      "Local multi-validator testnet subcommands",
+		SuggestionsMinimumDistance: 2,
+		RunE:                       client.ValidateCmd,
+	}
+	cmd.AddCommand(testnetStartCommand(), testnetInitFilesCommand())
+	return cmd
+}
+
+// testnetStartCommand returns the command running a testnet of full nodes in
+// this process until interrupted
+func testnetStartCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Run a testnet of N validators, app and CometBFT, in this process",
+		Long: `Generate keys and a genesis for N validators and run their full nodes in
+this process on loopback ports until interrupted. Node i listens for P2P on
+base-port+2i and RPC on base-port+2i+1. The benchmark accounts are funded in
+genesis, so "hcpd bench" can be pointed at any node.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := testnetConfig(cmd)
+			if err != nil {
+				return err
+			}
+			cfg.TimeoutCommit, _ = cmd.Flags().GetDuration(flagTestnetTimeoutCommit)
+			if cfg.Dir == "" {
+				if cfg.Dir, err = os.MkdirTemp("", "hcp-testnet-"); err != nil {
+					return err
+				}
+				defer os.RemoveAll(cfg.Dir)
+			}
+
+			net, err := testnet.Start(server.GetServerContextFromCmd(cmd).Logger, cfg, newApp)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Started %d %s nodes of chain %s in %s\n", len(net.Nodes), cfg.Engine, cfg.ChainID, cfg.Dir)
+			for _, n := range net.Nodes {
+				cmd.Printf("  %s  rpc %s  p2p %s@%s\n", n.Moniker, n.RPCAddress, n.NodeID, n.P2PAddress)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			<-ctx.Done()
+			cmd.Println("Stopping testnet")
+			return net.Stop()
+		},
+	}
+
+	addTestnetFlags(cmd, "", "")
+	cmd.Flags().Duration(flagTestnetTimeoutCommit, testnet.DefaultConfig().TimeoutCommit, "CometBFT timeout_commit of the nodes, unless set by the engine's configuration")
+	return cmd
+}
+
+// testnetInitFilesCommand returns the command writing the files of a testnet
+// for its nodes to be run separately
+func testnetInitFilesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init-files",
+		Short: "Write the homes of N validators: keys, gentxs, genesis and configuration",
+		Long: `Write the home of each of N validators: its node and validator keys, derived
+from the seed, the collected genesis with every gentx, config.toml with the
+other validators as persistent peers and app.toml with the consensus engine.
+The engine's configuration from <config-dir>/<engine>-config.toml is applied
+to both. The same flags produce the same files.
+
+With --hostnames, node i is reached at host node<i> on the standard ports, as
+in docker-compose.yml; otherwise all nodes run on one host, node i listening
+for P2P on base-port+2i and RPC on base-port+2i+1.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := testnetConfig(cmd)
+			if err != nil {
+				return err
+			}
+			f := cmd.Flags()
+			cfg.Hostnames, _ = f.GetBool(flagTestnetHostnames)
+			genesisTime, _ := f.GetString(flagTestnetGenesisTime)
+			if cfg.GenesisTime, err = time.Parse(time.RFC3339, genesisTime); err != nil {
+				return fmt.Errorf("invalid genesis time: %w", err)
+			}
+
+			// Only what init-files wrote is replaced; the directory may hold
+			// anything else
+			files, err := testnet.Files(cfg.Dir)
+			if err != nil {
+				return err
+			}
+			if len(files) > 0 {
+				if overwrite, _ := f.GetBool(flagTestnetOverwrite); !overwrite {
+					return fmt.Errorf("%s holds a testnet; pass --%s to replace it", cfg.Dir, flagTestnetOverwrite)
+				}
+				for _, path := range files {
+					if err := os.RemoveAll(path); err != nil {
+						return err
+					}
+				}
+			}
+
+			nodes, err := testnet.InitFiles(cfg)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Wrote %d %s validators of chain %s to %s\n", len(nodes), cfg.Engine, cfg.ChainID, cfg.Dir)
+			for _, n := range nodes {
+				cmd.Printf("  %s  %s@%s\n", n.Moniker, n.NodeID, n.P2PAddress)
+			}
+			return nil
+		},
+	}
+
+	addTestnetFlags(cmd, "./build/testnet", "configs")
+	cmd.Flags().String(flagTestnetGenesisTime, defaultGenesisTime, "Genesis time, in RFC 3339")
+	cmd.Flags().Bool(flagTestnetHostnames, false, "Reach node i at host node<i> on the standard ports, as in docker-compose.yml")
+	cmd.Flags().Bool(flagTestnetOverwrite, false, "Remove the node homes and gentxs already in the directory first")
+	return cmd
+}
+
+// addTestnetFlags adds the flags testnetConfig reads to cmd
+func addTestnetFlags(cmd *cobra.Command, dir, configDir string) {
+	defaults := testnet.DefaultConfig()
+	dirUsage := "Directory for the node homes"
+	if dir == "" {
+		dirUsage += "; a temporary one, removed on exit, if empty"
+	}
+	cmd.Flags().Int(flagTestnetNodes, defaults.Nodes, "Number of validators")
+	cmd.Flags().String(flagTestnetEngine, defaults.Engine, fmt.Sprintf("Consensus engine (%s)", strings.Join(common.RegisteredEngines(), "|")))
+	cmd.Flags().String(flags.FlagChainID, defaults.ChainID, "The network chain ID")
+	cmd.Flags().String(flagTestnetDir, dir, dirUsage)
+	cmd.Flags().String(flagTestnetConfigDir, configDir, "Directory of the per-engine configurations; none are applied if empty")
+	cmd.Flags().String(flagTestnetSeed, defaults.Seed, "Seed the node and validator keys are derived from")
+	cmd.Flags().String(flagTestnetHost, defaults.Host, "Host the nodes listen on")
+	cmd.Flags().Int(flagTestnetBasePort, defaults.BasePort, "First of the ports the nodes listen on")
+	cmd.Flags().Int(flagBenchAccounts, 100, "Number of benchmark accounts to fund")
+	cmd.Flags().String(flagBenchAccountSeed, defaultBenchAccountSeed, "Seed the benchmark account keys are derived from")
+}
+
+// testnetConfig returns the testnet configuration set by the flags of cmd
+func testnetConfig(cmd *cobra.Command) (testnet.Config, error) {
+	clientCtx := client.GetClientContextFromCmd(cmd)
+	f := cmd.Flags()
+	cfg := testnet.DefaultConfig()
+	cfg.Codec = clientCtx.Codec
+	cfg.TxConfig = clientCtx.TxConfig
+	cfg.ModuleBasics = ModuleBasics
+
+	cfg.Nodes, _ = f.GetInt(flagTestnetNodes)
+	cfg.Engine, _ = f.GetString(flagTestnetEngine)
+	cfg.ChainID, _ = f.GetString(flags.FlagChainID)
+	cfg.Dir, _ = f.GetString(flagTestnetDir)
+	cfg.Seed, _ = f.GetString(flagTestnetSeed)
+	cfg.Host, _ = f.GetString(flagTestnetHost)
+	cfg.BasePort, _ = f.GetInt(flagTestnetBasePort)
+	if registered := common.RegisteredEngines(); !slices.Contains(registered, cfg.Engine) {
+		return cfg, fmt.Errorf("unknown consensus engine %q, registered engines: %s", cfg.Engine, strings.Join(registered, ", "))
+	}
+	if dir, _ := f.GetString(flagTestnetConfigDir); dir != "" {
+		overlay, err := testnet.LoadOverlay(dir, cfg.Engine)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Overlay = overlay
+	}
+
+	n, _ := f.GetInt(flagBenchAccounts)
+	seed, _ := f.GetString(flagBenchAccountSeed)
+	for _, acc := range bench.Accounts(seed, n) {
+		cfg.Accounts = append(cfg.Accounts, acc.Address)
+	}
+	return cfg, nil
+}
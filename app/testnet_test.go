@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cosmossdk.io/log"
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/bench"
+	"github.com/fffeng99999/hcp-consensus/consensus/hotstuff"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+	"github.com/fffeng99999/hcp-consensus/testnet"
+)
+
+// testConfig returns the default testnet configuration with the app's
+// codecs, setting the address prefixes the genesis is collected with
+func testConfig(t *testing.T) testnet.Config {
+	config := sdk.GetConfig()
+	config.SetBech32PrefixForAccount("hcp", "hcppub")
+	config.SetBech32PrefixForValidator("hcpvaloper", "hcpvaloperpub")
+	config.SetBech32PrefixForConsensusNode("hcpvalcons", "hcpvalconspub")
+
+	cfg := testnet.DefaultConfig()
+	_, cfg.Codec, cfg.TxConfig = makeEncoding()
+	cfg.ModuleBasics = ModuleBasics
+	return cfg
+}
+
+// freeBasePort returns the first of n consecutive free loopback ports
+func freeBasePort(t *testing.T, n int) int {
+free:
+	for attempt := 0; attempt < 100; attempt++ {
+		base := 20000 + rand.Intn(30000)
+		for port := base; port < base+n; port++ {
+			l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+			if err != nil {
+				continue free
+			}
+			l.Close()
+		}
+		return base
+	}
+	t.Fatal("no free ports")
+	return 0
+}
+
+func TestTestnet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs full nodes")
+	}
+	cfg := testConfig(t)
+	txConfig := cfg.TxConfig
+	accounts := bench.Accounts("test", 2)
+	cfg.Dir = t.TempDir()
+	cfg.BasePort = freeBasePort(t, 2*cfg.Nodes)
+	cfg.TimeoutCommit = 100 * time.Millisecond
+	for _, acc := range accounts {
+		cfg.Accounts = append(cfg.Accounts, acc.Address)
+	}
+
+	network, err := testnet.Start(log.NewNopLogger(), cfg, newApp)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, network.Stop()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	require.NoError(t, network.WaitForHeight(ctx, 2))
+
+	// Every validator is bonded, and the funded accounts can send
+	client := network.Client(1)
+	height := int64(2)
+	validators, err := client.Validators(ctx, &height, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Nodes, validators.Total)
+
+	txs, err := bench.Generate(txConfig, accounts, bench.SendConfig{
+		ChainID:    cfg.ChainID,
+		Amount:     sdk.NewCoins(sdk.NewInt64Coin(cfg.Denom, 1)),
+		Gas:        200000,
+		PerAccount: 5,
+	})
+	require.NoError(t, err)
+	res, err := bench.Run(ctx, client, txs, bench.Config{Concurrency: 2, Timeout: 30 * time.Second, PollInterval: 50 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, len(txs), res.Included, "%+v", res.Failures)
+
+	// The nodes agree on the chain
+	status, err := client.Status(ctx)
+	require.NoError(t, err)
+	height = status.SyncInfo.LatestBlockHeight
+	require.NoError(t, network.WaitForHeight(ctx, height))
+	want, err := client.Block(ctx, &height)
+	require.NoError(t, err)
+	for i := range network.Nodes {
+		got, err := network.Client(i).Block(ctx, &height)
+		require.NoError(t, err)
+		assert.Equal(t, want.BlockID, got.BlockID, network.Nodes[i].Moniker)
+	}
+}
+
+func TestTestnet_InitFiles(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Engine = "hotstuff"
+	cfg.Hostnames = true
+	cfg.GenesisTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg.Accounts = []sdk.AccAddress{bench.Accounts("test", 1)[0].Address}
+	overlay, err := testnet.LoadOverlay("../configs", cfg.Engine)
+	require.NoError(t, err)
+	cfg.Overlay = overlay
+
+	// The same configuration writes the same files
+	var files [2]map[string]string
+	for i := range files {
+		cfg.Dir = t.TempDir()
+		_, err := testnet.InitFiles(cfg)
+		require.NoError(t, err)
+		files[i] = make(map[string]string)
+		require.NoError(t, filepath.WalkDir(cfg.Dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			content, err := os.ReadFile(path)
+			rel, _ := filepath.Rel(cfg.Dir, path)
+			files[i][rel] = string(content)
+			return err
+		}))
+	}
+	assert.Equal(t, files[0], files[1])
+
+	genesis, err := genutiltypes.AppGenesisFromFile(filepath.Join(cfg.Dir, "node0", "config", "genesis.json"))
+	require.NoError(t, err)
+	var state map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(genesis.AppState, &state))
+	var genutilState genutiltypes.GenesisState
+	cfg.Codec.MustUnmarshalJSON(state[genutiltypes.ModuleName], &genutilState)
+	assert.Len(t, genutilState.GenTxs, cfg.Nodes)
+	assert.Equal(t, files[0]["node0/config/genesis.json"], files[0]["node3/config/genesis.json"])
+
+	config := files[0]["node1/config/config.toml"]
+	for _, peer := range []string{"@node0:26656", "@node2:26656", "@node3:26656"} {
+		assert.Contains(t, config, peer)
+	}
+	assert.NotContains(t, config, "@node1:26656")
+	assert.Contains(t, config, `timeout_commit = "2s"`, "the overlay is applied")
+
+	app := files[0]["node1/config/app.toml"]
+	assert.Contains(t, app, `consensus-engine = "hotstuff"`)
+	assert.Contains(t, app, "[hotstuff]")
+	// The leader rotation goes to genesis
+	assert.NotContains(t, app, "leader_election")
+	assert.JSONEq(t, `{"params":{"leader_election":"round_robin","leader_min_trust":0.6}}`, string(state[hotstuff.ModuleName]))
+}
+
+func TestTestnet_TPBFTOverlay(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Dir = t.TempDir()
+	cfg.GenesisTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	overlay, err := testnet.LoadOverlay("../configs", cfg.Engine)
+	require.NoError(t, err)
+	cfg.Overlay = overlay
+	_, err = testnet.InitFiles(cfg)
+	require.NoError(t, err)
+
+	// The overlay sets the engine's parameters in genesis, not app.toml
+	appGenesis, err := genutiltypes.AppGenesisFromFile(filepath.Join(cfg.Dir, "node0", "config", "genesis.json"))
+	require.NoError(t, err)
+	var appState map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(appGenesis.AppState, &appState))
+	trustGenesis, err := tpbft.UnmarshalGenesis(appState[tpbft.ModuleName])
+	require.NoError(t, err)
+	assert.Equal(t, &tpbft.Params{
+		TrustUpdateInterval:       10,
+		MinTrustThreshold:         0.5,
+		DynamicValidatorSelection: true,
+		ValidatorSelectionCount:   4,
+	}, trustGenesis.Params)
+
+	content, err := os.ReadFile(filepath.Join(cfg.Dir, "node0", "config", "app.toml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "[tpbft]")
+	assert.NotContains(t, string(content), "[genesis.")
+}
+
+func TestTestnet_InitFilesOverwrite(t *testing.T) {
+	cfg := testConfig(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "testnet.go"), []byte("package testnet\n"), 0o644))
+
+	initFiles := func(args ...string) error {
+		cmd := testnetInitFilesCommand()
+		clientCtx := client.Context{}.WithCodec(cfg.Codec).WithTxConfig(cfg.TxConfig)
+		cmd.SetContext(context.WithValue(context.Background(), client.ClientContextKey, &clientCtx))
+		cmd.SetArgs(append([]string{"--dir", dir, "--nodes", "1", "--config-dir", ""}, args...))
+		cmd.SetOut(io.Discard)
+		return cmd.Execute()
+	}
+	require.NoError(t, initFiles())
+	assert.ErrorContains(t, initFiles(), "--overwrite")
+
+	// Overwriting replaces the node homes and gentxs, and nothing else
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node0", "stale"), nil, 0o644))
+	require.NoError(t, initFiles("--overwrite"))
+	assert.FileExists(t, filepath.Join(dir, "testnet.go"))
+	assert.NoFileExists(t, filepath.Join(dir, "node0", "stale"))
+	assert.FileExists(t, filepath.Join(dir, "node0", "config", "genesis.json"))
+	assert.DirExists(t, filepath.Join(dir, "gentxs"))
+}
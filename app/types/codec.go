@@ -0,0 +1,19 @@
+// Package types holds the app's own messages, which schedule consensus
+// engine switches and update engine parameters.
+package types
+
+import (
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/msgservice"
+)
+
+// RegisterInterfaces registers the app's messages
+func RegisterInterfaces(registry codectypes.InterfaceRegistry) {
+	registry.RegisterImplementations((*sdk.Msg)(nil),
+		&MsgScheduleEngineSwitch{},
+		&MsgUpdateTPBFTParams{},
+		&MsgUpdateHotStuffParams{},
+	)
+	msgservice.RegisterMsgServiceDesc(registry, &_Msg_serviceDesc)
+}
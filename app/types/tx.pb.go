@@ -0,0 +1,1899 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: hcp/consensus/v1/tx.proto
+
+package types
+
+import (
+	context "context"
+	encoding_binary "encoding/binary"
+	fmt "fmt"
+	_ "github.com/cosmos/cosmos-proto"
+	_ "github.com/cosmos/cosmos-sdk/types/msgservice"
+	grpc1 "github.com/cosmos/gogoproto/grpc"
+	proto "github.com/cosmos/gogoproto/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+// MsgScheduleEngineSwitch schedules a switch to engine at the start of block
+// height.
+type MsgScheduleEngineSwitch struct {
+	// authority is the address that controls the switch, the gov module account.
+	Authority string `protobuf:"bytes,1,opt,name=authority,proto3" json:"authority,omitempty"`
+	Height    int64  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Engine    string `protobuf:"bytes,3,opt,name=engine,proto3" json:"engine,omitempty"`
+}
+
+func (m *MsgScheduleEngineSwitch) Reset()         { *m = MsgScheduleEngineSwitch{} }
+func (m *MsgScheduleEngineSwitch) String() string { return proto.CompactTextString(m) }
+func (*MsgScheduleEngineSwitch) ProtoMessage()    {}
+func (*MsgScheduleEngineSwitch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eaa1f1faba25a898, []int{0}
+}
+func (m *MsgScheduleEngineSwitch) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgScheduleEngineSwitch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgScheduleEngineSwitch.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgScheduleEngineSwitch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgScheduleEngineSwitch.Merge(m, src)
+}
+func (m *MsgScheduleEngineSwitch) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgScheduleEngineSwitch) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgScheduleEngineSwitch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgScheduleEngineSwitch proto.InternalMessageInfo
+
+func (m *MsgScheduleEngineSwitch) GetAuthority() string {
+	if m != nil {
+		return m.Authority
+	}
+	return ""
+}
+
+func (m *MsgScheduleEngineSwitch) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *MsgScheduleEngineSwitch) GetEngine() string {
+	if m != nil {
+		return m.Engine
+	}
+	return ""
+}
+
+// MsgScheduleEngineSwitchResponse is the response of MsgScheduleEngineSwitch.
+type MsgScheduleEngineSwitchResponse struct {
+}
+
+func (m *MsgScheduleEngineSwitchResponse) Reset()         { *m = MsgScheduleEngineSwitchResponse{} }
+func (m *MsgScheduleEngineSwitchResponse) String() string { return proto.CompactTextString(m) }
+func (*MsgScheduleEngineSwitchResponse) ProtoMessage()    {}
+func (*MsgScheduleEngineSwitchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eaa1f1faba25a898, []int{1}
+}
+func (m *MsgScheduleEngineSwitchResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgScheduleEngineSwitchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgScheduleEngineSwitchResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgScheduleEngineSwitchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgScheduleEngineSwitchResponse.Merge(m, src)
+}
+func (m *MsgScheduleEngineSwitchResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgScheduleEngineSwitchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgScheduleEngineSwitchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgScheduleEngineSwitchResponse proto.InternalMessageInfo
+
+// TPBFTParams are the parameters of the tPBFT engine.
+type TPBFTParams struct {
+	// trust_update_interval is the number of blocks between trust score updates.
+	TrustUpdateInterval int64 `protobuf:"varint,1,opt,name=trust_update_interval,json=trustUpdateInterval,proto3" json:"trust_update_interval,omitempty"`
+	// min_trust_threshold is the trust score validators need to be selected.
+	MinTrustThreshold float64 `protobuf:"fixed64,2,opt,name=min_trust_threshold,json=minTrustThreshold,proto3" json:"min_trust_threshold,omitempty"`
+	// dynamic_validator_selection makes the selected validators the next
+	// validator set.
+	DynamicValidatorSelection bool `protobuf:"varint,3,opt,name=dynamic_validator_selection,json=dynamicValidatorSelection,proto3" json:"dynamic_validator_selection,omitempty"`
+	// validator_selection_count is the most validators selected.
+	ValidatorSelectionCount int64 `protobuf:"varint,4,opt,name=validator_selection_count,json=validatorSelectionCount,proto3" json:"validator_selection_count,omitempty"`
+}
+
+func (m *TPBFTParams) Reset()         { *m = TPBFTParams{} }
+func (m *TPBFTParams) String() string { return proto.CompactTextString(m) }
+func (*TPBFTParams) ProtoMessage()    {}
+func (*TPBFTParams) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eaa1f1faba25a898, []int{2}
+}
+func (m *TPBFTParams) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TPBFTParams) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TPBFTParams.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TPBFTParams) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TPBFTParams.Merge(m, src)
+}
+func (m *TPBFTParams) XXX_Size() int {
+	return m.Size()
+}
+func (m *TPBFTParams) XXX_DiscardUnknown() {
+	xxx_messageInfo_TPBFTParams.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TPBFTParams proto.InternalMessageInfo
+
+func (m *TPBFTParams) GetTrustUpdateInterval() int64 {
+	if m != nil {
+		return m.TrustUpdateInterval
+	}
+	return 0
+}
+
+func (m *TPBFTParams) GetMinTrustThreshold() float64 {
+	if m != nil {
+		return m.MinTrustThreshold
+	}
+	return 0
+}
+
+func (m *TPBFTParams) GetDynamicValidatorSelection() bool {
+	if m != nil {
+		return m.DynamicValidatorSelection
+	}
+	return false
+}
+
+func (m *TPBFTParams) GetValidatorSelectionCount() int64 {
+	if m != nil {
+		return m.ValidatorSelectionCount
+	}
+	return 0
+}
+
+// MsgUpdateTPBFTParams replaces the parameters of the tPBFT engine, taking
+// effect at the end of the block.
+type MsgUpdateTPBFTParams struct {
+	// authority is the address that controls the parameters, the gov module
+	// account.
+	Authority string       `protobuf:"bytes,1,opt,name=authority,proto3" json:"authority,omitempty"`
+	Params    *TPBFTParams `protobuf:"bytes,2,opt,name=params,proto3" json:"params,omitempty"`
+}
+
+func (m *MsgUpdateTPBFTParams) Reset()         { *m = MsgUpdateTPBFTParams{} }
+func (m *MsgUpdateTPBFTParams) String() string { return proto.CompactTextString(m) }
+func (*MsgUpdateTPBFTParams) ProtoMessage()    {}
+func (*MsgUpdateTPBFTParams) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eaa1f1faba25a898, []int{3}
+}
+func (m *MsgUpdateTPBFTParams) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgUpdateTPBFTParams) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgUpdateTPBFTParams.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgUpdateTPBFTParams) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgUpdateTPBFTParams.Merge(m, src)
+}
+func (m *MsgUpdateTPBFTParams) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgUpdateTPBFTParams) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgUpdateTPBFTParams.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgUpdateTPBFTParams proto.InternalMessageInfo
+
+func (m *MsgUpdateTPBFTParams) GetAuthority() string {
+	if m != nil {
+		return m.Authority
+	}
+	return ""
+}
+
+func (m *MsgUpdateTPBFTParams) GetParams() *TPBFTParams {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+// MsgUpdateTPBFTParamsResponse is the response of MsgUpdateTPBFTParams.
+type MsgUpdateTPBFTParamsResponse struct {
+}
+
+func (m *MsgUpdateTPBFTParamsResponse) Reset()         { *m = MsgUpdateTPBFTParamsResponse{} }
+func (m *MsgUpdateTPBFTParamsResponse) String() string { return proto.CompactTextString(m) }
+func (*MsgUpdateTPBFTParamsResponse) ProtoMessage()    {}
+func (*MsgUpdateTPBFTParamsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eaa1f1faba25a898, []int{4}
+}
+func (m *MsgUpdateTPBFTParamsResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgUpdateTPBFTParamsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgUpdateTPBFTParamsResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgUpdateTPBFTParamsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgUpdateTPBFTParamsResponse.Merge(m, src)
+}
+func (m *MsgUpdateTPBFTParamsResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgUpdateTPBFTParamsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgUpdateTPBFTParamsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgUpdateTPBFTParamsResponse proto.InternalMessageInfo
+
+// HotStuffParams are the parameters of the HotStuff engine.
+type HotStuffParams struct {
+	// leader_election names the leader rotation: round_robin, stake or
+	// reputation.
+	LeaderElection string `protobuf:"bytes,1,opt,name=leader_election,json=leaderElection,proto3" json:"leader_election,omitempty"`
+	// leader_min_trust is the trust score replicas need to lead under
+	// reputation.
+	LeaderMinTrust float64 `protobuf:"fixed64,2,opt,name=leader_min_trust,json=leaderMinTrust,proto3" json:"leader_min_trust,omitempty"`
+}
+
+func (m *HotStuffParams) Reset()         { *m = HotStuffParams{} }
+func (m *HotStuffParams) String() string { return proto.CompactTextString(m) }
+func (*HotStuffParams) ProtoMessage()    {}
+func (*HotStuffParams) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eaa1f1faba25a898, []int{5}
+}
+func (m *HotStuffParams) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *HotStuffParams) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_HotStuffParams.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *HotStuffParams) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HotStuffParams.Merge(m, src)
+}
+func (m *HotStuffParams) XXX_Size() int {
+	return m.Size()
+}
+func (m *HotStuffParams) XXX_DiscardUnknown() {
+	xxx_messageInfo_HotStuffParams.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HotStuffParams proto.InternalMessageInfo
+
+func (m *HotStuffParams) GetLeaderElection() string {
+	if m != nil {
+		return m.LeaderElection
+	}
+	return ""
+}
+
+func (m *HotStuffParams) GetLeaderMinTrust() float64 {
+	if m != nil {
+		return m.LeaderMinTrust
+	}
+	return 0
+}
+
+// MsgUpdateHotStuffParams replaces the parameters of the HotStuff engine,
+// taking effect at the start of the next block.
+type MsgUpdateHotStuffParams struct {
+	// authority is the address that controls the parameters, the gov module
+	// account.
+	Authority string          `protobuf:"bytes,1,opt,name=authority,proto3" json:"authority,omitempty"`
+	Params    *HotStuffParams `protobuf:"bytes,2,opt,name=params,proto3" json:"params,omitempty"`
+}
+
+func (m *MsgUpdateHotStuffParams) Reset()         { *m = MsgUpdateHotStuffParams{} }
+func (m *MsgUpdateHotStuffParams) String() string { return proto.CompactTextString(m) }
+func (*MsgUpdateHotStuffParams) ProtoMessage()    {}
+func (*MsgUpdateHotStuffParams) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eaa1f1faba25a898, []int{6}
+}
+func (m *MsgUpdateHotStuffParams) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgUpdateHotStuffParams) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgUpdateHotStuffParams.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgUpdateHotStuffParams) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgUpdateHotStuffParams.Merge(m, src)
+}
+func (m *MsgUpdateHotStuffParams) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgUpdateHotStuffParams) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgUpdateHotStuffParams.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgUpdateHotStuffParams proto.InternalMessageInfo
+
+func (m *MsgUpdateHotStuffParams) GetAuthority() string {
+	if m != nil {
+		return m.Authority
+	}
+	return ""
+}
+
+func (m *MsgUpdateHotStuffParams) GetParams() *HotStuffParams {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+// MsgUpdateHotStuffParamsResponse is the response of MsgUpdateHotStuffParams.
+type MsgUpdateHotStuffParamsResponse struct {
+}
+
+func (m *MsgUpdateHotStuffParamsResponse) Reset()         { *m = MsgUpdateHotStuffParamsResponse{} }
+func (m *MsgUpdateHotStuffParamsResponse) String() string { return proto.CompactTextString(m) }
+func (*MsgUpdateHotStuffParamsResponse) ProtoMessage()    {}
+func (*MsgUpdateHotStuffParamsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eaa1f1faba25a898, []int{7}
+}
+func (m *MsgUpdateHotStuffParamsResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgUpdateHotStuffParamsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgUpdateHotStuffParamsResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgUpdateHotStuffParamsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgUpdateHotStuffParamsResponse.Merge(m, src)
+}
+func (m *MsgUpdateHotStuffParamsResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgUpdateHotStuffParamsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgUpdateHotStuffParamsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgUpdateHotStuffParamsResponse proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*MsgScheduleEngineSwitch)(nil), "hcp.consensus.v1.MsgScheduleEngineSwitch")
+	proto.RegisterType((*MsgScheduleEngineSwitchResponse)(nil), "hcp.consensus.v1.MsgScheduleEngineSwitchResponse")
+	proto.RegisterType((*TPBFTParams)(nil), "hcp.consensus.v1.TPBFTParams")
+	proto.RegisterType((*MsgUpdateTPBFTParams)(nil), "hcp.consensus.v1.MsgUpdateTPBFTParams")
+	proto.RegisterType((*MsgUpdateTPBFTParamsResponse)(nil), "hcp.consensus.v1.MsgUpdateTPBFTParamsResponse")
+	proto.RegisterType((*HotStuffParams)(nil), "hcp.consensus.v1.HotStuffParams")
+	proto.RegisterType((*MsgUpdateHotStuffParams)(nil), "hcp.consensus.v1.MsgUpdateHotStuffParams")
+	proto.RegisterType((*MsgUpdateHotStuffParamsResponse)(nil), "hcp.consensus.v1.MsgUpdateHotStuffParamsResponse")
+}
+
+func init() { proto.RegisterFile("hcp/consensus/v1/tx.proto", fileDescriptor_eaa1f1faba25a898) }
+
+var fileDescriptor_eaa1f1faba25a898 = []byte{
+	// 611 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0xc1, 0x6e, 0xd3, 0x40,
+	0x10, 0xad, 0x1b, 0xa8, 0xe8, 0x56, 0x2a, 0xad, 0x1b, 0x68, 0x62, 0xc0, 0x84, 0x1c, 0x20, 0xad,
+	0x54, 0x5b, 0x09, 0x02, 0x41, 0x0f, 0x48, 0x04, 0x15, 0x95, 0x43, 0xa4, 0xca, 0x09, 0x1c, 0xb8,
+	0x58, 0xee, 0x7a, 0x63, 0xaf, 0xb0, 0x77, 0x2d, 0xef, 0x3a, 0x90, 0x1b, 0xe2, 0x0b, 0xe0, 0xc0,
+	0x89, 0x9f, 0xe8, 0x81, 0x8f, 0xe0, 0x58, 0x71, 0xe2, 0x88, 0x12, 0xa1, 0x9e, 0xf8, 0x07, 0xe4,
+	0xb5, 0x9d, 0xb4, 0xb1, 0x83, 0xa2, 0xfa, 0x36, 0x7e, 0x6f, 0x66, 0xde, 0xcc, 0x1b, 0x1b, 0x54,
+	0x5d, 0x18, 0xe8, 0x90, 0x12, 0x86, 0x08, 0x8b, 0x98, 0x3e, 0x68, 0xea, 0xfc, 0x83, 0x16, 0x84,
+	0x94, 0x53, 0x79, 0xc3, 0x85, 0x81, 0x36, 0x81, 0xb4, 0x41, 0x53, 0xd9, 0x86, 0x94, 0xf9, 0x94,
+	0xe9, 0x3e, 0x73, 0x62, 0xa6, 0xcf, 0x9c, 0x84, 0xaa, 0x54, 0x13, 0xc0, 0x14, 0x91, 0x9e, 0x04,
+	0x09, 0x54, 0xff, 0x22, 0x81, 0xed, 0x0e, 0x73, 0xba, 0xd0, 0x45, 0x76, 0xe4, 0xa1, 0x03, 0xe2,
+	0x60, 0x82, 0xba, 0xef, 0x31, 0x87, 0xae, 0xfc, 0x18, 0xac, 0x5a, 0x11, 0x77, 0x69, 0x88, 0xf9,
+	0xb0, 0x22, 0xd5, 0xa4, 0xc6, 0x6a, 0xbb, 0xf2, 0xf3, 0xfb, 0x5e, 0x39, 0x2d, 0xf0, 0xdc, 0xb6,
+	0x43, 0xc4, 0x58, 0x97, 0x87, 0x98, 0x38, 0xc6, 0x94, 0x2a, 0xdf, 0x04, 0x2b, 0x2e, 0xc2, 0x8e,
+	0xcb, 0x2b, 0xcb, 0x35, 0xa9, 0x51, 0x32, 0xd2, 0x28, 0x7e, 0x8f, 0x44, 0xfd, 0x4a, 0x29, 0x2e,
+	0x66, 0xa4, 0xd1, 0xfe, 0xfa, 0xa7, 0xb3, 0x93, 0xdd, 0x69, 0x7e, 0xfd, 0x1e, 0xb8, 0x3b, 0x47,
+	0x92, 0x81, 0x58, 0x10, 0x4f, 0x5c, 0xff, 0x2b, 0x81, 0xb5, 0xde, 0x51, 0xfb, 0x65, 0xef, 0xc8,
+	0x0a, 0x2d, 0x9f, 0xc9, 0x2d, 0x70, 0x83, 0x87, 0x11, 0xe3, 0x66, 0x14, 0xd8, 0x16, 0x47, 0x26,
+	0x26, 0x1c, 0x85, 0x03, 0xcb, 0x13, 0xb2, 0x4b, 0xc6, 0x96, 0x00, 0x5f, 0x0b, 0xec, 0x55, 0x0a,
+	0xc9, 0x1a, 0xd8, 0xf2, 0x31, 0x31, 0x93, 0x3c, 0xee, 0x86, 0x88, 0xb9, 0xd4, 0xb3, 0x85, 0x66,
+	0xc9, 0xd8, 0xf4, 0x31, 0xe9, 0xc5, 0x48, 0x2f, 0x03, 0xe4, 0x67, 0xe0, 0x96, 0x3d, 0x24, 0x96,
+	0x8f, 0xa1, 0x39, 0xb0, 0x3c, 0x6c, 0x5b, 0x9c, 0x86, 0x26, 0x43, 0x1e, 0x82, 0x1c, 0x53, 0x22,
+	0x66, 0xba, 0x66, 0x54, 0x53, 0xca, 0x9b, 0x8c, 0xd1, 0xcd, 0x08, 0xf2, 0x3e, 0xa8, 0x16, 0xe4,
+	0x99, 0x90, 0x46, 0x84, 0x57, 0xae, 0x08, 0x9d, 0xdb, 0x83, 0x5c, 0xda, 0x8b, 0x18, 0xae, 0x7f,
+	0x95, 0x40, 0xb9, 0xc3, 0x9c, 0x64, 0x82, 0xf3, 0x83, 0x5f, 0xd6, 0xa3, 0x47, 0x60, 0x25, 0x10,
+	0x15, 0xc4, 0xbc, 0x6b, 0xad, 0x3b, 0xda, 0xec, 0x39, 0x69, 0xe7, 0xda, 0x18, 0x29, 0x39, 0x67,
+	0x95, 0x0a, 0x6e, 0x17, 0xc9, 0x9a, 0xf8, 0x04, 0xc1, 0xfa, 0x21, 0xe5, 0x5d, 0x1e, 0xf5, 0xfb,
+	0xa9, 0xe0, 0x07, 0xe0, 0xba, 0x87, 0x2c, 0x1b, 0x85, 0xe6, 0x64, 0x73, 0x42, 0xb6, 0xb1, 0x9e,
+	0xbc, 0x3e, 0xc8, 0xd6, 0xd5, 0x00, 0x1b, 0x29, 0x71, 0xe2, 0x52, 0xea, 0x4d, 0xca, 0xec, 0xa4,
+	0x0e, 0xd5, 0xbf, 0x25, 0x37, 0x9c, 0xa8, 0x98, 0x69, 0x77, 0xd9, 0xfd, 0x3c, 0x99, 0xd9, 0x4f,
+	0x2d, 0xbf, 0x9f, 0x8b, 0x9d, 0xe6, 0xae, 0x28, 0xb9, 0xe6, 0x22, 0x71, 0xd9, 0x96, 0x5a, 0x7f,
+	0x96, 0x41, 0xa9, 0xc3, 0x1c, 0x99, 0x83, 0x72, 0xe1, 0x87, 0xb8, 0x93, 0x6f, 0x3e, 0xe7, 0x03,
+	0x51, 0x9a, 0x0b, 0x53, 0xb3, 0xee, 0xf2, 0x3b, 0xb0, 0x99, 0xbf, 0xab, 0xfb, 0x85, 0x75, 0x72,
+	0x3c, 0x45, 0x5b, 0x8c, 0x37, 0x69, 0xc6, 0x41, 0xb9, 0xd0, 0xa7, 0x9d, 0xff, 0xd4, 0xb9, 0x48,
+	0x55, 0x9a, 0x0b, 0x53, 0xb3, 0xae, 0xca, 0xd5, 0x8f, 0x67, 0x27, 0xbb, 0x52, 0xfb, 0xf0, 0xc7,
+	0x48, 0x95, 0x4e, 0x47, 0xaa, 0xf4, 0x7b, 0xa4, 0x4a, 0x9f, 0xc7, 0xea, 0xd2, 0xe9, 0x58, 0x5d,
+	0xfa, 0x35, 0x56, 0x97, 0xde, 0x6a, 0x0e, 0xe6, 0x6e, 0x74, 0xac, 0x41, 0xea, 0xeb, 0xfd, 0x7e,
+	0x1f, 0x11, 0xe7, 0x69, 0xfc, 0xe8, 0x2e, 0x0c, 0xf6, 0xa6, 0xbf, 0x5f, 0x2b, 0x08, 0x74, 0x3e,
+	0x0c, 0x10, 0x3b, 0x5e, 0x11, 0x7f, 0xcf, 0x87, 0xff, 0x06, 0x00, 0x9b, 0x55, 0xc6, 0xee, 0xa0,
+	0x05, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MsgClient is the client API for Msg service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MsgClient interface {
+	// ScheduleEngineSwitch schedules a switch of consensus engines at a future
+	// height, replacing any pending switch. It is executed by governance.
+	ScheduleEngineSwitch(ctx context.Context, in *MsgScheduleEngineSwitch, opts ...grpc.CallOption) (*MsgScheduleEngineSwitchResponse, error)
+	// UpdateTPBFTParams replaces the parameters of the tPBFT engine. It is
+	// executed by governance.
+	UpdateTPBFTParams(ctx context.Context, in *MsgUpdateTPBFTParams, opts ...grpc.CallOption) (*MsgUpdateTPBFTParamsResponse, error)
+	// UpdateHotStuffParams replaces the parameters of the HotStuff engine. It
+	// is executed by governance.
+	UpdateHotStuffParams(ctx context.Context, in *MsgUpdateHotStuffParams, opts ...grpc.CallOption) (*MsgUpdateHotStuffParamsResponse, error)
+}
+
+type msgClient struct {
+	cc grpc1.ClientConn
+}
+
+func NewMsgClient(cc grpc1.ClientConn) MsgClient {
+	return &msgClient{cc}
+}
+
+func (c *msgClient) ScheduleEngineSwitch(ctx context.Context, in *MsgScheduleEngineSwitch, opts ...grpc.CallOption) (*MsgScheduleEngineSwitchResponse, error) {
+	out := new(MsgScheduleEngineSwitchResponse)
+	err := c.cc.Invoke(ctx, "/hcp.consensus.v1.Msg/ScheduleEngineSwitch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *msgClient) UpdateTPBFTParams(ctx context.Context, in *MsgUpdateTPBFTParams, opts ...grpc.CallOption) (*MsgUpdateTPBFTParamsResponse, error) {
+	out := new(MsgUpdateTPBFTParamsResponse)
+	err := c.cc.Invoke(ctx, "/hcp.consensus.v1.Msg/UpdateTPBFTParams", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *msgClient) UpdateHotStuffParams(ctx context.Context, in *MsgUpdateHotStuffParams, opts ...grpc.CallOption) (*MsgUpdateHotStuffParamsResponse, error) {
+	out := new(MsgUpdateHotStuffParamsResponse)
+	err := c.cc.Invoke(ctx, "/hcp.consensus.v1.Msg/UpdateHotStuffParams", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MsgServer is the server API for Msg service.
+type MsgServer interface {
+	// ScheduleEngineSwitch schedules a switch of consensus engines at a future
+	// height, replacing any pending switch. It is executed by governance.
+	ScheduleEngineSwitch(context.Context, *MsgScheduleEngineSwitch) (*MsgScheduleEngineSwitchResponse, error)
+	// UpdateTPBFTParams replaces the parameters of the tPBFT engine. It is
+	// executed by governance.
+	UpdateTPBFTParams(context.Context, *MsgUpdateTPBFTParams) (*MsgUpdateTPBFTParamsResponse, error)
+	// UpdateHotStuffParams replaces the parameters of the HotStuff engine. It
+	// is executed by governance.
+	UpdateHotStuffParams(context.Context, *MsgUpdateHotStuffParams) (*MsgUpdateHotStuffParamsResponse, error)
+}
+
+// UnimplementedMsgServer can be embedded to have forward compatible implementations.
+type UnimplementedMsgServer struct {
+}
+
+func (*UnimplementedMsgServer) ScheduleEngineSwitch(ctx context.Context, req *MsgScheduleEngineSwitch) (*MsgScheduleEngineSwitchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScheduleEngineSwitch not implemented")
+}
+func (*UnimplementedMsgServer) UpdateTPBFTParams(ctx context.Context, req *MsgUpdateTPBFTParams) (*MsgUpdateTPBFTParamsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTPBFTParams not implemented")
+}
+func (*UnimplementedMsgServer) UpdateHotStuffParams(ctx context.Context, req *MsgUpdateHotStuffParams) (*MsgUpdateHotStuffParamsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateHotStuffParams not implemented")
+}
+
+func RegisterMsgServer(s grpc1.Server, srv MsgServer) {
+	s.RegisterService(&_Msg_serviceDesc, srv)
+}
+
+func _Msg_ScheduleEngineSwitch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MsgScheduleEngineSwitch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgServer).ScheduleEngineSwitch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hcp.consensus.v1.Msg/ScheduleEngineSwitch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgServer).ScheduleEngineSwitch(ctx, req.(*MsgScheduleEngineSwitch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Msg_UpdateTPBFTParams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MsgUpdateTPBFTParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgServer).UpdateTPBFTParams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hcp.consensus.v1.Msg/UpdateTPBFTParams",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgServer).UpdateTPBFTParams(ctx, req.(*MsgUpdateTPBFTParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Msg_UpdateHotStuffParams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MsgUpdateHotStuffParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgServer).UpdateHotStuffParams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hcp.consensus.v1.Msg/UpdateHotStuffParams",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgServer).UpdateHotStuffParams(ctx, req.(*MsgUpdateHotStuffParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Msg_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "hcp.consensus.v1.Msg",
+	HandlerType: (*MsgServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ScheduleEngineSwitch",
+			Handler:    _Msg_ScheduleEngineSwitch_Handler,
+		},
+		{
+			MethodName: "UpdateTPBFTParams",
+			Handler:    _Msg_UpdateTPBFTParams_Handler,
+		},
+		{
+			MethodName: "UpdateHotStuffParams",
+			Handler:    _Msg_UpdateHotStuffParams_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "hcp/consensus/v1/tx.proto",
+}
+
+func (m *MsgScheduleEngineSwitch) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgScheduleEngineSwitch) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgScheduleEngineSwitch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Engine) > 0 {
+		i -= len(m.Engine)
+		copy(dAtA[i:], m.Engine)
+		i = encodeVarintTx(dAtA, i, uint64(len(m.Engine)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Height != 0 {
+		i = encodeVarintTx(dAtA, i, uint64(m.Height))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Authority) > 0 {
+		i -= len(m.Authority)
+		copy(dAtA[i:], m.Authority)
+		i = encodeVarintTx(dAtA, i, uint64(len(m.Authority)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MsgScheduleEngineSwitchResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgScheduleEngineSwitchResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgScheduleEngineSwitchResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
+func (m *TPBFTParams) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TPBFTParams) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TPBFTParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.ValidatorSelectionCount != 0 {
+		i = encodeVarintTx(dAtA, i, uint64(m.ValidatorSelectionCount))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.DynamicValidatorSelection {
+		i--
+		if m.DynamicValidatorSelection {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.MinTrustThreshold != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.MinTrustThreshold))))
+		i--
+		dAtA[i] = 0x11
+	}
+	if m.TrustUpdateInterval != 0 {
+		i = encodeVarintTx(dAtA, i, uint64(m.TrustUpdateInterval))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MsgUpdateTPBFTParams) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgUpdateTPBFTParams) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgUpdateTPBFTParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Params != nil {
+		{
+			size, err := m.Params.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTx(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Authority) > 0 {
+		i -= len(m.Authority)
+		copy(dAtA[i:], m.Authority)
+		i = encodeVarintTx(dAtA, i, uint64(len(m.Authority)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MsgUpdateTPBFTParamsResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgUpdateTPBFTParamsResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgUpdateTPBFTParamsResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
+func (m *HotStuffParams) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *HotStuffParams) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *HotStuffParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.LeaderMinTrust != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.LeaderMinTrust))))
+		i--
+		dAtA[i] = 0x11
+	}
+	if len(m.LeaderElection) > 0 {
+		i -= len(m.LeaderElection)
+		copy(dAtA[i:], m.LeaderElection)
+		i = encodeVarintTx(dAtA, i, uint64(len(m.LeaderElection)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MsgUpdateHotStuffParams) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgUpdateHotStuffParams) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgUpdateHotStuffParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Params != nil {
+		{
+			size, err := m.Params.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTx(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Authority) > 0 {
+		i -= len(m.Authority)
+		copy(dAtA[i:], m.Authority)
+		i = encodeVarintTx(dAtA, i, uint64(len(m.Authority)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MsgUpdateHotStuffParamsResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgUpdateHotStuffParamsResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgUpdateHotStuffParamsResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintTx(dAtA []byte, offset int, v uint64) int {
+	offset -= sovTx(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *MsgScheduleEngineSwitch) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Authority)
+	if l > 0 {
+		n += 1 + l + sovTx(uint64(l))
+	}
+	if m.Height != 0 {
+		n += 1 + sovTx(uint64(m.Height))
+	}
+	l = len(m.Engine)
+	if l > 0 {
+		n += 1 + l + sovTx(uint64(l))
+	}
+	return n
+}
+
+func (m *MsgScheduleEngineSwitchResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func (m *TPBFTParams) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.TrustUpdateInterval != 0 {
+		n += 1 + sovTx(uint64(m.TrustUpdateInterval))
+	}
+	if m.MinTrustThreshold != 0 {
+		n += 9
+	}
+	if m.DynamicValidatorSelection {
+		n += 2
+	}
+	if m.ValidatorSelectionCount != 0 {
+		n += 1 + sovTx(uint64(m.ValidatorSelectionCount))
+	}
+	return n
+}
+
+func (m *MsgUpdateTPBFTParams) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Authority)
+	if l > 0 {
+		n += 1 + l + sovTx(uint64(l))
+	}
+	if m.Params != nil {
+		l = m.Params.Size()
+		n += 1 + l + sovTx(uint64(l))
+	}
+	return n
+}
+
+func (m *MsgUpdateTPBFTParamsResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func (m *HotStuffParams) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.LeaderElection)
+	if l > 0 {
+		n += 1 + l + sovTx(uint64(l))
+	}
+	if m.LeaderMinTrust != 0 {
+		n += 9
+	}
+	return n
+}
+
+func (m *MsgUpdateHotStuffParams) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Authority)
+	if l > 0 {
+		n += 1 + l + sovTx(uint64(l))
+	}
+	if m.Params != nil {
+		l = m.Params.Size()
+		n += 1 + l + sovTx(uint64(l))
+	}
+	return n
+}
+
+func (m *MsgUpdateHotStuffParamsResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func sovTx(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozTx(x uint64) (n int) {
+	return sovTx(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *MsgScheduleEngineSwitch) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgScheduleEngineSwitch: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgScheduleEngineSwitch: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Authority", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Authority = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Height", wireType)
+			}
+			m.Height = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Height |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Engine", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Engine = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MsgScheduleEngineSwitchResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgScheduleEngineSwitchResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgScheduleEngineSwitchResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TPBFTParams) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TPBFTParams: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TPBFTParams: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TrustUpdateInterval", wireType)
+			}
+			m.TrustUpdateInterval = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TrustUpdateInterval |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MinTrustThreshold", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.MinTrustThreshold = float64(math.Float64frombits(v))
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DynamicValidatorSelection", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.DynamicValidatorSelection = bool(v != 0)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValidatorSelectionCount", wireType)
+			}
+			m.ValidatorSelectionCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ValidatorSelectionCount |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MsgUpdateTPBFTParams) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgUpdateTPBFTParams: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgUpdateTPBFTParams: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Authority", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Authority = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Params", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Params == nil {
+				m.Params = &TPBFTParams{}
+			}
+			if err := m.Params.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MsgUpdateTPBFTParamsResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgUpdateTPBFTParamsResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgUpdateTPBFTParamsResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *HotStuffParams) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: HotStuffParams: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: HotStuffParams: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaderElection", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LeaderElection = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaderMinTrust", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.LeaderMinTrust = float64(math.Float64frombits(v))
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MsgUpdateHotStuffParams) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgUpdateHotStuffParams: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgUpdateHotStuffParams: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Authority", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Authority = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Params", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Params == nil {
+				m.Params = &HotStuffParams{}
+			}
+			if err := m.Params.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MsgUpdateHotStuffParamsResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgUpdateHotStuffParamsResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgUpdateHotStuffParamsResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipTx(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthTx
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupTx
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthTx
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthTx        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowTx          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupTx = fmt.Errorf("proto: unexpected end of group")
+)
@@ -0,0 +1,30 @@
+// Package bench drives a running chain with signed bank sends and measures
+// how quickly they are included in blocks.
+package bench
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Account is an account transactions are sent from
+type Account struct {
+	Key     *secp256k1.PrivKey
+	Address sdk.AccAddress
+
+	Number   uint64 // Account number, as assigned on chain
+	Sequence uint64 // Next sequence to sign with, tracked locally
+}
+
+// Accounts derives n accounts from seed. The same seed always gives the
+// same accounts, so a genesis can fund the accounts a benchmark sends from.
+func Accounts(seed string, n int) []*Account {
+	accounts := make([]*Account, n)
+	for i := range accounts {
+		key := secp256k1.GenPrivKeyFromSecret([]byte(fmt.Sprintf("%s/%d", seed, i)))
+		accounts[i] = &Account{Key: key, Address: sdk.AccAddress(key.PubKey().Address())}
+	}
+	return accounts
+}
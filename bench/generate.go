@@ -0,0 +1,100 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/cosmos/cosmos-sdk/client"
+	clienttx "github.com/cosmos/cosmos-sdk/client/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// SendConfig describes the bank sends Generate signs
+type SendConfig struct {
+	ChainID    string
+	Amount     sdk.Coins // Sent by each transaction
+	Fees       sdk.Coins
+	Gas        uint64
+	PerAccount int // Transactions per account
+}
+
+// Tx is a signed transaction, ready to broadcast
+type Tx struct {
+	Account  int // Index of the sending account
+	Sequence uint64
+	Bytes    []byte
+	Hash     string // Upper-case hex, as CometBFT reports it
+}
+
+// Generate signs cfg.PerAccount sends from every account to the next, with
+// consecutive sequences from each account's Sequence, which it advances.
+// Transactions are interleaved across accounts, so that sending them in
+// order spreads the load while keeping each account's sequences in order.
+func Generate(txConfig client.TxConfig, accounts []*Account, cfg SendConfig) ([]Tx, error) {
+	txs := make([]Tx, 0, len(accounts)*cfg.PerAccount)
+	for j := 0; j < cfg.PerAccount; j++ {
+		for i, acc := range accounts {
+			to := accounts[(i+1)%len(accounts)]
+			bz, err := signSend(txConfig, acc, to.Address, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to sign transaction %d of %s: %w", j, acc.Address, err)
+			}
+			txs = append(txs, Tx{
+				Account:  i,
+				Sequence: acc.Sequence,
+				Bytes:    bz,
+				Hash:     txHash(bz),
+			})
+			acc.Sequence++
+		}
+	}
+	return txs, nil
+}
+
+// signSend signs a bank send from acc at its current sequence
+func signSend(txConfig client.TxConfig, acc *Account, to sdk.AccAddress, cfg SendConfig) ([]byte, error) {
+	builder := txConfig.NewTxBuilder()
+	if err := builder.SetMsgs(banktypes.NewMsgSend(acc.Address, to, cfg.Amount)); err != nil {
+		return nil, err
+	}
+	builder.SetFeeAmount(cfg.Fees)
+	builder.SetGasLimit(cfg.Gas)
+
+	// The signer info is part of the signed bytes, so it is set first with
+	// an empty signature
+	signMode := signing.SignMode_SIGN_MODE_DIRECT
+	sig := signing.SignatureV2{
+		PubKey:   acc.Key.PubKey(),
+		Data:     &signing.SingleSignatureData{SignMode: signMode},
+		Sequence: acc.Sequence,
+	}
+	if err := builder.SetSignatures(sig); err != nil {
+		return nil, err
+	}
+
+	signerData := authsigning.SignerData{
+		Address:       acc.Address.String(),
+		ChainID:       cfg.ChainID,
+		AccountNumber: acc.Number,
+		Sequence:      acc.Sequence,
+		PubKey:        acc.Key.PubKey(),
+	}
+	sig, err := clienttx.SignWithPrivKey(context.Background(), signMode, signerData, builder, acc.Key, txConfig, acc.Sequence)
+	if err != nil {
+		return nil, err
+	}
+	if err := builder.SetSignatures(sig); err != nil {
+		return nil, err
+	}
+	return txConfig.TxEncoder()(builder.GetTx())
+}
+
+// txHash returns the hash CometBFT reports for a transaction
+func txHash(tx []byte) string {
+	return strings.ToUpper(fmt.Sprintf("%x", cmttypes.Tx(tx).Hash()))
+}
@@ -0,0 +1,90 @@
+package bench
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTxConfig() client.TxConfig {
+	registry := codectypes.NewInterfaceRegistry()
+	cryptocodec.RegisterInterfaces(registry)
+	banktypes.RegisterInterfaces(registry)
+	return authtx.NewTxConfig(codec.NewProtoCodec(registry), authtx.DefaultSignModes)
+}
+
+func TestAccounts_Deterministic(t *testing.T) {
+	a, b := Accounts("seed", 3), Accounts("seed", 3)
+	for i := range a {
+		assert.Equal(t, a[i].Address, b[i].Address)
+	}
+	assert.NotEqual(t, a[0].Address, a[1].Address)
+	assert.NotEqual(t, a[0].Address, Accounts("other", 1)[0].Address)
+}
+
+func TestGenerate(t *testing.T) {
+	txConfig := testTxConfig()
+	accounts := Accounts("seed", 3)
+	for i, acc := range accounts {
+		acc.Number = uint64(10 + i)
+		acc.Sequence = uint64(5 * i)
+	}
+	cfg := SendConfig{
+		ChainID:    "hcp-test",
+		Amount:     sdk.NewCoins(sdk.NewInt64Coin("stake", 1)),
+		Fees:       sdk.NewCoins(sdk.NewInt64Coin("stake", 2)),
+		Gas:        100000,
+		PerAccount: 2,
+	}
+	txs, err := Generate(txConfig, accounts, cfg)
+	require.NoError(t, err)
+	require.Len(t, txs, 6)
+
+	for i, tx := range txs {
+		// Interleaved across accounts, with consecutive sequences
+		acc := accounts[tx.Account]
+		require.Equal(t, i%3, tx.Account)
+		assert.Equal(t, uint64(5*tx.Account+i/3), tx.Sequence)
+
+		decoded, err := txConfig.TxDecoder()(tx.Bytes)
+		require.NoError(t, err)
+		msgs := decoded.GetMsgs()
+		require.Len(t, msgs, 1)
+		send := msgs[0].(*banktypes.MsgSend)
+		assert.Equal(t, acc.Address.String(), send.FromAddress)
+		assert.Equal(t, accounts[(tx.Account+1)%3].Address.String(), send.ToAddress)
+		assert.Equal(t, cfg.Amount, send.Amount)
+
+		sigTx := decoded.(authsigning.Tx)
+		assert.Equal(t, cfg.Fees, sigTx.GetFee())
+		sigs, err := sigTx.GetSignaturesV2()
+		require.NoError(t, err)
+		require.Len(t, sigs, 1)
+		assert.Equal(t, tx.Sequence, sigs[0].Sequence)
+
+		signBytes, err := authsigning.GetSignBytesAdapter(context.Background(), txConfig.SignModeHandler(), signing.SignMode_SIGN_MODE_DIRECT, authsigning.SignerData{
+			Address:       acc.Address.String(),
+			ChainID:       cfg.ChainID,
+			AccountNumber: acc.Number,
+			Sequence:      tx.Sequence,
+			PubKey:        acc.Key.PubKey(),
+		}, decoded)
+		require.NoError(t, err)
+		assert.True(t, acc.Key.PubKey().VerifySignature(signBytes, sigs[0].Data.(*signing.SingleSignatureData).Signature))
+	}
+
+	// Sequences carry on from where they were left
+	assert.Equal(t, uint64(2), accounts[0].Sequence)
+	assert.Equal(t, uint64(7), accounts[1].Sequence)
+}
@@ -0,0 +1,171 @@
+// Package report summarises benchmark runs of the consensus engines for
+// comparison, as JSON and CSV to be committed and diffed across versions
+// and as a Markdown table.
+package report
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/fffeng99999/hcp-consensus/bench"
+)
+
+// Formats a report can be written in
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+// Run summarises a benchmark of one engine under one configuration.
+// Durations are in milliseconds.
+type Run struct {
+	Engine string `json:"engine"`
+	Config string `json:"config"`
+
+	Sent     int `json:"sent"`
+	Included int `json:"included"`
+	Failed   int `json:"failed"`
+
+	TPS        float64 `json:"tps"`
+	LatencyP50 float64 `json:"latency_p50_ms"`
+	LatencyP95 float64 `json:"latency_p95_ms"`
+	LatencyP99 float64 `json:"latency_p99_ms"`
+
+	Blocks        int     `json:"blocks"`
+	BlockInterval float64 `json:"block_interval_ms"` // Mean time between blocks
+	// Blocks committed by a different validator set than the block before
+	ValidatorChanges int `json:"validator_changes"`
+}
+
+// FromResult summarises a benchmark result
+func FromResult(engine, config string, res *bench.Result) Run {
+	run := Run{
+		Engine:     engine,
+		Config:     config,
+		Sent:       res.Sent,
+		Included:   res.Included,
+		Failed:     res.Failed(),
+		TPS:        round(res.TPS()),
+		LatencyP50: millis(res.Percentile(50)),
+		LatencyP95: millis(res.Percentile(95)),
+		LatencyP99: millis(res.Percentile(99)),
+		Blocks:     len(res.Blocks),
+	}
+	if n := len(res.Blocks); n > 1 {
+		span := res.Blocks[n-1].Time.Sub(res.Blocks[0].Time)
+		run.BlockInterval = millis(span / time.Duration(n-1))
+	}
+	for i := 1; i < len(res.Blocks); i++ {
+		if res.Blocks[i].Validators != res.Blocks[i-1].Validators {
+			run.ValidatorChanges++
+		}
+	}
+	return run
+}
+
+// millis converts a duration to milliseconds, to the microsecond
+func millis(d time.Duration) float64 {
+	return round(float64(d) / float64(time.Millisecond))
+}
+
+// round rounds to three decimal places, so reports diff cleanly
+func round(x float64) float64 {
+	return math.Round(x*1000) / 1000
+}
+
+// Report is a set of runs, ordered by engine and then configuration
+type Report struct {
+	Runs []Run `json:"runs"`
+}
+
+// Add adds runs to the report, after any of the same engine and
+// configuration
+func (r *Report) Add(runs ...Run) {
+	r.Runs = append(r.Runs, runs...)
+	slices.SortStableFunc(r.Runs, func(a, b Run) int {
+		return cmp.Or(cmp.Compare(a.Engine, b.Engine), cmp.Compare(a.Config, b.Config))
+	})
+}
+
+// Read reads a report written by WriteJSON
+func Read(r io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to decode report: %w", err)
+	}
+	report.Add()
+	return &report, nil
+}
+
+// Write writes the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		return r.WriteJSON(w)
+	case FormatCSV:
+		return r.WriteCSV(w)
+	case FormatMarkdown:
+		return r.WriteMarkdown(w)
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// columns are the CSV header, matching the JSON field names
+var columns = []string{
+	"engine", "config", "sent", "included", "failed",
+	"tps", "latency_p50_ms", "latency_p95_ms", "latency_p99_ms",
+	"blocks", "block_interval_ms", "validator_changes",
+}
+
+// WriteCSV writes the report as CSV with a header row
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	f := func(x float64) string { return strconv.FormatFloat(x, 'f', -1, 64) }
+	for _, run := range r.Runs {
+		err := cw.Write([]string{
+			run.Engine, run.Config, strconv.Itoa(run.Sent), strconv.Itoa(run.Included), strconv.Itoa(run.Failed),
+			f(run.TPS), f(run.LatencyP50), f(run.LatencyP95), f(run.LatencyP99),
+			strconv.Itoa(run.Blocks), f(run.BlockInterval), strconv.Itoa(run.ValidatorChanges),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMarkdown writes the report as a Markdown table comparing the runs
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	if _, err := fmt.Fprint(w, "| Engine | Config | TPS | P50 (ms) | P95 (ms) | P99 (ms) | Block interval (ms) | Validator changes | Included | Failed |\n"+
+		"|---|---|--:|--:|--:|--:|--:|--:|--:|--:|\n"); err != nil {
+		return err
+	}
+	for _, run := range r.Runs {
+		_, err := fmt.Fprintf(w, "| %s | %s | %.1f | %.1f | %.1f | %.1f | %.1f | %d | %d/%d | %d |\n",
+			run.Engine, run.Config, run.TPS, run.LatencyP50, run.LatencyP95, run.LatencyP99,
+			run.BlockInterval, run.ValidatorChanges, run.Included, run.Sent, run.Failed)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
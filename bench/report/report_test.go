@@ -0,0 +1,85 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/bench"
+)
+
+func TestFromResult(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	res := &bench.Result{
+		Sent:          10,
+		Included:      8,
+		Latencies:     []time.Duration{4 * time.Millisecond, time.Millisecond, 3 * time.Millisecond, 2 * time.Millisecond},
+		Failures:      []bench.Failure{{Reason: bench.ReasonSkipped, Count: 2}},
+		Start:         start,
+		LastInclusion: start.Add(2 * time.Second),
+		Blocks: []bench.Block{
+			{Height: 1, Time: start, Validators: "A"},
+			{Height: 2, Time: start.Add(300 * time.Millisecond), Validators: "A"},
+			{Height: 3, Time: start.Add(700 * time.Millisecond), Validators: "B"},
+			{Height: 4, Time: start.Add(1000 * time.Millisecond), Validators: "A"},
+		},
+	}
+	assert.Equal(t, Run{
+		Engine:           "tpbft",
+		Config:           "4 nodes",
+		Sent:             10,
+		Included:         8,
+		Failed:           2,
+		TPS:              4,
+		LatencyP50:       2,
+		LatencyP95:       4,
+		LatencyP99:       4,
+		Blocks:           4,
+		BlockInterval:    333.333,
+		ValidatorChanges: 2,
+	}, FromResult("tpbft", "4 nodes", res))
+
+	assert.Zero(t, FromResult("raft", "", &bench.Result{Blocks: res.Blocks[:1]}).BlockInterval)
+}
+
+func TestReport(t *testing.T) {
+	var r Report
+	r.Add(
+		Run{Engine: "tpbft", Config: "7 nodes", TPS: 900, LatencyP50: 12.5, Sent: 10, Included: 9, Failed: 1},
+		Run{Engine: "raft", Config: "4 nodes", TPS: 1500.25, LatencyP99: 3, Blocks: 2, BlockInterval: 100},
+	)
+	r.Add(Run{Engine: "tpbft", Config: "4 nodes", TPS: 1000})
+	var order []string
+	for _, run := range r.Runs {
+		order = append(order, run.Engine+"/"+run.Config)
+	}
+	assert.Equal(t, []string{"raft/4 nodes", "tpbft/4 nodes", "tpbft/7 nodes"}, order)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, FormatJSON))
+	read, err := Read(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, &r, read)
+
+	buf.Reset()
+	require.NoError(t, r.Write(&buf, FormatCSV))
+	assert.Equal(t, `engine,config,sent,included,failed,tps,latency_p50_ms,latency_p95_ms,latency_p99_ms,blocks,block_interval_ms,validator_changes
+raft,4 nodes,0,0,0,1500.25,0,0,3,2,100,0
+tpbft,4 nodes,0,0,0,1000,0,0,0,0,0,0
+tpbft,7 nodes,10,9,1,900,12.5,0,0,0,0,0
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, r.Write(&buf, FormatMarkdown))
+	assert.Equal(t, `| Engine | Config | TPS | P50 (ms) | P95 (ms) | P99 (ms) | Block interval (ms) | Validator changes | Included | Failed |
+|---|---|--:|--:|--:|--:|--:|--:|--:|--:|
+| raft | 4 nodes | 1500.2 | 0.0 | 0.0 | 3.0 | 100.0 | 0 | 0/0 | 0 |
+| tpbft | 4 nodes | 1000.0 | 0.0 | 0.0 | 0.0 | 0.0 | 0 | 0/0 | 0 |
+| tpbft | 7 nodes | 900.0 | 12.5 | 0.0 | 0.0 | 0.0 | 0 | 9/10 | 1 |
+`, buf.String())
+
+	assert.ErrorContains(t, r.Write(&buf, "xml"), "unknown report format")
+}
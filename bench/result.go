@@ -0,0 +1,151 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Result is what a benchmark measured
+type Result struct {
+	Sent     int // Broadcast
+	Accepted int // Passed CheckTx
+	Included int // Executed successfully in a block
+
+	// Inclusion latency of each included transaction, from its broadcast
+	// to the block including it being seen
+	Latencies []time.Duration
+	// Failed transactions by reason, most frequent first
+	Failures []Failure
+	// Blocks committed while the benchmark ran
+	Blocks []Block
+
+	Start         time.Time
+	BroadcastEnd  time.Time
+	LastInclusion time.Time
+	End           time.Time
+
+	failures map[string]*Failure
+}
+
+// Failure counts the transactions that failed for one reason
+type Failure struct {
+	Reason  string // ABCI codespace/code, or one of the Reason constants
+	Count   int
+	Example string // Log or error of one of them
+}
+
+// Block is a block committed during a benchmark
+type Block struct {
+	Height     int64
+	Time       time.Time
+	Txs        int
+	Validators string // Hash of the validator set that committed it
+}
+
+func newResult() *Result {
+	return &Result{failures: make(map[string]*Failure)}
+}
+
+// fail counts a failed transaction
+func (r *Result) fail(reason, example string) {
+	f, ok := r.failures[reason]
+	if !ok {
+		f = &Failure{Reason: reason, Example: example}
+		r.failures[reason] = f
+	}
+	f.Count++
+}
+
+// finish lists the failures, most frequent first
+func (r *Result) finish() {
+	r.Failures = nil
+	for _, f := range r.failures {
+		r.Failures = append(r.Failures, *f)
+	}
+	slices.SortFunc(r.Failures, func(a, b Failure) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Reason, b.Reason)
+	})
+}
+
+// Failed returns the number of transactions that failed
+func (r *Result) Failed() int {
+	n := 0
+	for _, f := range r.Failures {
+		n += f.Count
+	}
+	return n
+}
+
+// TPS returns the rate transactions were included at, from the start of the
+// benchmark to the last inclusion
+func (r *Result) TPS() float64 {
+	d := r.LastInclusion.Sub(r.Start)
+	if r.Included == 0 || d <= 0 {
+		return 0
+	}
+	return float64(r.Included) / d.Seconds()
+}
+
+// BroadcastTPS returns the rate transactions were broadcast at
+func (r *Result) BroadcastTPS() float64 {
+	d := r.BroadcastEnd.Sub(r.Start)
+	if d <= 0 {
+		return 0
+	}
+	return float64(r.Sent) / d.Seconds()
+}
+
+// Percentile returns the p-th percentile (0-100) of the inclusion latencies
+func (r *Result) Percentile(p float64) time.Duration {
+	return Percentile(r.Latencies, p)
+}
+
+// Percentile returns the p-th percentile (0-100) of durations by the
+// nearest-rank method, or zero if there are none
+func Percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// WriteSummary writes a human-readable summary of the result
+func (r *Result) WriteSummary(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `Sent:        %d (%.1f TPS)
+Accepted:    %d
+Included:    %d (%.1f TPS)
+Failed:      %d
+Latency:     p50 %v, p95 %v, p99 %v
+Blocks:      %d
+`,
+		r.Sent, r.BroadcastTPS(),
+		r.Accepted,
+		r.Included, r.TPS(),
+		r.Failed(),
+		r.Percentile(50), r.Percentile(95), r.Percentile(99),
+		len(r.Blocks),
+	)
+	if err != nil {
+		return err
+	}
+	for _, f := range r.Failures {
+		line := fmt.Sprintf("  %6d  %s", f.Count, f.Reason)
+		if f.Example != "" {
+			line += ": " + f.Example
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
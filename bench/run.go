@@ -0,0 +1,289 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+// Client is the part of the CometBFT RPC client a benchmark uses
+type Client interface {
+	BroadcastTxSync(ctx context.Context, tx cmttypes.Tx) (*coretypes.ResultBroadcastTx, error)
+	Status(ctx context.Context) (*coretypes.ResultStatus, error)
+	Block(ctx context.Context, height *int64) (*coretypes.ResultBlock, error)
+	BlockResults(ctx context.Context, height *int64) (*coretypes.ResultBlockResults, error)
+}
+
+// Config controls how transactions are broadcast
+type Config struct {
+	TPS          float64       // Target broadcast rate; zero broadcasts as fast as possible
+	Concurrency  int           // Broadcasting workers; an account's transactions all go through one
+	Timeout      time.Duration // How long to wait for inclusion after the last broadcast
+	PollInterval time.Duration // How often to look for new blocks
+}
+
+// DefaultConfig returns the default broadcast configuration
+func DefaultConfig() Config {
+	return Config{
+		TPS:          1000,
+		Concurrency:  16,
+		Timeout:      30 * time.Second,
+		PollInterval: 100 * time.Millisecond,
+	}
+}
+
+// Failure reasons not reported by the chain
+const (
+	ReasonBroadcast   = "broadcast error"
+	ReasonNotIncluded = "not included before timeout"
+	ReasonSkipped     = "skipped after an earlier transaction of the account failed"
+)
+
+// run is the state of a benchmark in progress
+type run struct {
+	client Client
+	cfg    Config
+	result *Result
+
+	mu      sync.Mutex
+	pending map[string]time.Time // Hash -> broadcast time of accepted transactions
+}
+
+// Run broadcasts txs in order at the configured rate and follows new blocks
+// until each is included or the timeout passes. Once a transaction of an
+// account fails, the account's later transactions are skipped, as their
+// sequences can no longer be valid.
+func Run(ctx context.Context, client Client, txs []Tx, cfg Config) (*Result, error) {
+	if cfg.Concurrency <= 0 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", cfg.Concurrency)
+	}
+	status, err := client.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node status: %w", err)
+	}
+
+	r := &run{
+		client:  client,
+		cfg:     cfg,
+		result:  newResult(),
+		pending: make(map[string]time.Time),
+	}
+	r.result.Start = time.Now()
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	watched := make(chan error, 1)
+	go func() { watched <- r.watch(watchCtx, status.SyncInfo.LatestBlockHeight+1) }()
+
+	r.broadcast(ctx, txs)
+	r.result.BroadcastEnd = time.Now()
+
+	// Wait for the stragglers
+	deadline := time.NewTimer(cfg.Timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(cfg.PollInterval)
+	defer poll.Stop()
+wait:
+	for r.pendingCount() > 0 {
+		select {
+		case <-ctx.Done():
+			break wait
+		case <-deadline.C:
+			break wait
+		case err := <-watched:
+			stopWatch()
+			return nil, err
+		case <-poll.C:
+		}
+	}
+	stopWatch()
+	if err := <-watched; err != nil && err != context.Canceled {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for range r.pending {
+		r.result.fail(ReasonNotIncluded, "")
+	}
+	r.result.End = time.Now()
+	r.result.finish()
+	return r.result, ctx.Err()
+}
+
+// broadcast sends txs through the workers, pacing them to the target rate
+func (r *run) broadcast(ctx context.Context, txs []Tx) {
+	queues := make([]chan Tx, r.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan Tx, 64)
+		wg.Add(1)
+		go func(queue <-chan Tx) {
+			defer wg.Done()
+			r.worker(ctx, queue)
+		}(queues[i])
+	}
+
+	var interval time.Duration
+	if r.cfg.TPS > 0 {
+		interval = time.Duration(float64(time.Second) / r.cfg.TPS)
+	}
+	start := time.Now()
+dispatch:
+	for i, tx := range txs {
+		if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+			select {
+			case <-ctx.Done():
+				break dispatch
+			case <-time.After(wait):
+			}
+		}
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case queues[tx.Account%len(queues)] <- tx:
+		}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+}
+
+// worker broadcasts the transactions of the accounts it serves, in order
+func (r *run) worker(ctx context.Context, queue <-chan Tx) {
+	failed := make(map[int]bool) // Accounts with a failed transaction
+	for tx := range queue {
+		if failed[tx.Account] {
+			r.failed(ReasonSkipped, "")
+			continue
+		}
+
+		// Pending before it is sent, as it may be included before the
+		// broadcast returns
+		r.mu.Lock()
+		r.result.Sent++
+		r.pending[tx.Hash] = time.Now()
+		r.mu.Unlock()
+
+		res, err := r.client.BroadcastTxSync(ctx, tx.Bytes)
+		if err == nil && res.Code == 0 {
+			r.mu.Lock()
+			r.result.Accepted++
+			r.mu.Unlock()
+			continue
+		}
+
+		failed[tx.Account] = true
+		r.mu.Lock()
+		delete(r.pending, tx.Hash)
+		if err != nil {
+			r.result.fail(ReasonBroadcast, err.Error())
+		} else {
+			r.result.fail(reason(res.Codespace, res.Code), res.Log)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// watch follows blocks from height on, resolving the pending transactions
+// they include, until ctx is cancelled
+func (r *run) watch(ctx context.Context, height int64) error {
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+	for {
+		status, err := r.client.Status(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to query node status: %w", err)
+		}
+		for ; height <= status.SyncInfo.LatestBlockHeight; height++ {
+			if err := r.collect(ctx, height); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-poll.C:
+		}
+	}
+}
+
+// collect records a block and resolves the pending transactions it includes
+func (r *run) collect(ctx context.Context, height int64) error {
+	block, err := r.client.Block(ctx, &height)
+	if err != nil {
+		return fmt.Errorf("failed to query block %d: %w", height, err)
+	}
+	seen := time.Now()
+
+	// Transactions of ours in the block, by position
+	included := make(map[int]time.Time)
+	r.mu.Lock()
+	r.result.Blocks = append(r.result.Blocks, Block{
+		Height:     height,
+		Time:       block.Block.Time,
+		Txs:        len(block.Block.Txs),
+		Validators: block.Block.ValidatorsHash.String(),
+	})
+	for i, tx := range block.Block.Txs {
+		hash := txHash(tx)
+		if sent, ok := r.pending[hash]; ok {
+			included[i] = sent
+			delete(r.pending, hash)
+		}
+	}
+	r.mu.Unlock()
+	if len(included) == 0 {
+		return nil
+	}
+
+	results, err := r.client.BlockResults(ctx, &height)
+	if err != nil {
+		return fmt.Errorf("failed to query results of block %d: %w", height, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, sent := range included {
+		if i < len(results.TxsResults) && results.TxsResults[i].Code != 0 {
+			res := results.TxsResults[i]
+			r.result.fail(reason(res.Codespace, res.Code), res.Log)
+			continue
+		}
+		r.result.Included++
+		r.result.Latencies = append(r.result.Latencies, seen.Sub(sent))
+		r.result.LastInclusion = seen
+	}
+	return nil
+}
+
+// failed records a failure under the lock
+func (r *run) failed(reason, example string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.result.fail(reason, example)
+}
+
+// pendingCount returns the number of transactions awaiting inclusion
+func (r *run) pendingCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.pending)
+}
+
+// reason names an ABCI error by its codespace and code
+func reason(codespace string, code uint32) string {
+	return fmt.Sprintf("%s/%d", codespace, code)
+}
@@ -0,0 +1,129 @@
+package bench
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChain cuts a block from its mempool each time its status is queried
+type fakeChain struct {
+	mu      sync.Mutex
+	mempool []cmttypes.Tx
+	blocks  []*cmttypes.Block
+	results [][]*abci.ExecTxResult
+
+	rejected map[string]bool // Transactions failing CheckTx
+	reverted map[string]bool // Transactions failing execution
+	lost     map[string]bool // Transactions accepted but never included
+}
+
+func (c *fakeChain) BroadcastTxSync(_ context.Context, tx cmttypes.Tx) (*coretypes.ResultBroadcastTx, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash := tx.Hash()
+	if c.rejected[string(tx)] {
+		return &coretypes.ResultBroadcastTx{Code: 5, Codespace: "sdk", Log: "insufficient funds", Hash: hash}, nil
+	}
+	if !c.lost[string(tx)] {
+		c.mempool = append(c.mempool, tx)
+	}
+	return &coretypes.ResultBroadcastTx{Hash: hash}, nil
+}
+
+func (c *fakeChain) Status(context.Context) (*coretypes.ResultStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	block := &cmttypes.Block{Data: cmttypes.Data{Txs: c.mempool}}
+	block.Height = int64(len(c.blocks) + 1)
+	block.Time = time.Now()
+	results := make([]*abci.ExecTxResult, len(c.mempool))
+	for i, tx := range c.mempool {
+		results[i] = &abci.ExecTxResult{}
+		if c.reverted[string(tx)] {
+			results[i] = &abci.ExecTxResult{Code: 11, Codespace: "sdk", Log: "out of gas"}
+		}
+	}
+	c.blocks = append(c.blocks, block)
+	c.results = append(c.results, results)
+	c.mempool = nil
+
+	status := &coretypes.ResultStatus{}
+	status.SyncInfo.LatestBlockHeight = block.Height
+	return status, nil
+}
+
+func (c *fakeChain) Block(_ context.Context, height *int64) (*coretypes.ResultBlock, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &coretypes.ResultBlock{Block: c.blocks[*height-1]}, nil
+}
+
+func (c *fakeChain) BlockResults(_ context.Context, height *int64) (*coretypes.ResultBlockResults, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &coretypes.ResultBlockResults{Height: *height, TxsResults: c.results[*height-1]}, nil
+}
+
+func TestRun(t *testing.T) {
+	// 3 accounts with 4 transactions each, interleaved as Generate does
+	var txs []Tx
+	for seq := 0; seq < 4; seq++ {
+		for acc := 0; acc < 3; acc++ {
+			bytes := []byte{byte(acc), byte(seq)}
+			txs = append(txs, Tx{Account: acc, Sequence: uint64(seq), Bytes: bytes, Hash: txHash(bytes)})
+		}
+	}
+	key := func(acc, seq int) string { return string([]byte{byte(acc), byte(seq)}) }
+	chain := &fakeChain{
+		rejected: map[string]bool{key(1, 1): true}, // Skips the account's last 2
+		reverted: map[string]bool{key(2, 0): true},
+		lost:     map[string]bool{key(0, 3): true},
+	}
+
+	res, err := Run(context.Background(), chain, txs, Config{
+		Concurrency:  2,
+		Timeout:      200 * time.Millisecond,
+		PollInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 10, res.Sent)
+	assert.Equal(t, 9, res.Accepted)
+	assert.Equal(t, 7, res.Included)
+	assert.Len(t, res.Latencies, 7)
+	assert.Equal(t, []Failure{
+		{Reason: ReasonSkipped, Count: 2},
+		{Reason: ReasonNotIncluded, Count: 1},
+		{Reason: "sdk/11", Count: 1, Example: "out of gas"},
+		{Reason: "sdk/5", Count: 1, Example: "insufficient funds"},
+	}, res.Failures)
+	assert.Equal(t, 5, res.Failed())
+	assert.NotEmpty(t, res.Blocks)
+	assert.Greater(t, res.TPS(), 0.0)
+	assert.Greater(t, res.Percentile(99), time.Duration(0))
+}
+
+func TestPercentile(t *testing.T) {
+	var durations []time.Duration
+	for i := 100; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, Percentile(durations, 50))
+	assert.Equal(t, 95*time.Millisecond, Percentile(durations, 95))
+	assert.Equal(t, 100*time.Millisecond, Percentile(durations, 100))
+	assert.Equal(t, time.Millisecond, Percentile(durations, 0))
+	assert.Equal(t, time.Duration(0), Percentile(nil, 50))
+	assert.Equal(t, 100*time.Millisecond, durations[0], "the input is left unsorted")
+}
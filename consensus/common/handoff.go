@@ -0,0 +1,30 @@
+package common
+
+import (
+	"encoding/json"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// HandoffState is the state an engine passes to its successor when the app
+// switches engines at a scheduled height
+type HandoffState struct {
+	From   string `json:"from"`
+	Height int64  `json:"height"`
+
+	// Trust holds validator trust records in the tPBFT genesis format, if the
+	// outgoing engine keeps any
+	Trust json.RawMessage `json:"trust,omitempty"`
+}
+
+// HandoffExporter is implemented by engines with state worth carrying over
+// to the next engine. It is called before the engine stops.
+type HandoffExporter interface {
+	ExportHandoff(ctx sdk.Context) (HandoffState, error)
+}
+
+// HandoffImporter is implemented by engines that can pick up state from
+// their predecessor. It is called before the engine starts.
+type HandoffImporter interface {
+	ImportHandoff(ctx sdk.Context, state HandoffState) error
+}
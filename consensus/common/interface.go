@@ -1,18 +1,57 @@
 package common
 
 import (
+	"context"
+
 	abci "github.com/cometbft/cometbft/abci/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 )
 
 // ConsensusEngine defines the interface for pluggable consensus modules
 type ConsensusEngine interface {
-	Start() error
+	// Name returns the engine's name, as used in the consensus-engine option
+	Name() string
+
+	// Start launches the engine's background goroutines. They run until ctx
+	// is cancelled or Stop is called.
+	Start(ctx context.Context) error
+
+	// Stop signals the background goroutines to exit and waits for them
 	Stop() error
-	
+
 	// BeginBlock is called at the beginning of each block
-	BeginBlock(ctx sdk.Context)
-	
+	BeginBlock(ctx sdk.Context) error
+
 	// EndBlock is called at the end of each block and returns validator updates
-	EndBlock(ctx sdk.Context) []abci.ValidatorUpdate
+	EndBlock(ctx sdk.Context) ([]abci.ValidatorUpdate, error)
+
+	// Status reports the engine's current consensus state
+	Status() Status
+
+	// Health returns an error describing why the engine cannot make progress,
+	// or nil if it can
+	Health() error
+}
+
+// ValidatorSetObserver is implemented by engines that follow the validator
+// set. The app calls OnValidatorUpdates with the updates the staking module
+// returns at the end of a block, when there are any, before the engine's
+// EndBlock.
+type ValidatorSetObserver interface {
+	OnValidatorUpdates(ctx sdk.Context, updates []abci.ValidatorUpdate) error
+}
+
+// Status is a snapshot of an engine's consensus state
+type Status struct {
+	Engine  string
+	NodeID  string
+	Running bool
+
+	// Round is the engine's leadership epoch: the PBFT or HotStuff view, or
+	// the Raft term
+	Round  uint64
+	Leader string // Empty if unknown
+
+	// Committed is the highest committed sequence, log index or block height
+	Committed uint64
 }
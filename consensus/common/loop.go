@@ -0,0 +1,69 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Loop runs an engine's background goroutine and lets Stop wait for it to
+// exit. The zero value is ready to use.
+type Loop struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start runs fn in a goroutine with a context cancelled by Stop or when ctx
+// is done. It fails if the previous run has not exited yet.
+func (l *Loop) Start(ctx context.Context, fn func(ctx context.Context)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.running() {
+		return fmt.Errorf("already running")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	l.cancel, l.done = cancel, done
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+	return nil
+}
+
+// Stop cancels the goroutine's context and waits for it to return
+func (l *Loop) Stop() {
+	l.mu.Lock()
+	cancel, done := l.cancel, l.done
+	l.cancel, l.done = nil, nil
+	l.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Running reports whether the goroutine is still running
+func (l *Loop) Running() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.running()
+}
+
+func (l *Loop) running() bool {
+	if l.done == nil {
+		return false
+	}
+	select {
+	case <-l.done:
+		return false
+	default:
+		return true
+	}
+}
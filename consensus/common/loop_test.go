@@ -0,0 +1,48 @@
+package common
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoop_StopWaitsForExit(t *testing.T) {
+	var l Loop
+	var exited atomic.Bool
+
+	require.NoError(t, l.Start(context.Background(), func(ctx context.Context) {
+		<-ctx.Done()
+		exited.Store(true)
+	}))
+	assert.True(t, l.Running())
+	assert.Error(t, l.Start(context.Background(), func(context.Context) {}))
+
+	l.Stop()
+	assert.True(t, exited.Load())
+	assert.False(t, l.Running())
+
+	// Stopping twice is a no-op, and the loop can run again
+	l.Stop()
+	require.NoError(t, l.Start(context.Background(), func(ctx context.Context) { <-ctx.Done() }))
+	l.Stop()
+}
+
+func TestLoop_ParentCancellation(t *testing.T) {
+	var l Loop
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	require.NoError(t, l.Start(ctx, func(ctx context.Context) {
+		<-ctx.Done()
+		close(done)
+	}))
+	cancel()
+	<-done
+
+	assert.Eventually(t, func() bool { return !l.Running() }, time.Second, time.Millisecond)
+	l.Stop()
+}
@@ -0,0 +1,259 @@
+package common
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsNamespace prefixes every engine metric
+const MetricsNamespace = "hcp"
+
+// Metrics holds the collectors of every engine. The app builds one set and
+// hands it to whichever engine is running, so it survives engine switches.
+type Metrics struct {
+	TPBFT    *TPBFTMetrics
+	Raft     *RaftMetrics
+	HotStuff *HotStuffMetrics
+}
+
+// DeletableGauge is a gauge whose series can be removed, for labels whose
+// values come and go
+type DeletableGauge interface {
+	metrics.Gauge
+	// Delete removes the series with the gauge's label values and
+	// labelValues
+	Delete(labelValues ...string)
+}
+
+// TPBFTMetrics are the tPBFT engine's metrics
+type TPBFTMetrics struct {
+	// Trust score per bonded validator
+	TrustScore DeletableGauge
+	// Number of validators in the selected set
+	SelectedValidators metrics.Gauge
+	// Validators entering or leaving the selected set
+	SelectionChurn metrics.Counter
+	// Seconds from pre-prepare to prepared and to committed, by phase
+	PhaseLatency metrics.Histogram
+	// View changes, counted as rounds beyond the first
+	ViewChanges metrics.Counter
+}
+
+// RaftMetrics are the Raft engine's metrics
+type RaftMetrics struct {
+	// Current term
+	Term metrics.Gauge
+	// Times a different leader became known
+	LeaderChanges metrics.Counter
+	// Entries the leader holds that a peer has not acknowledged, by peer
+	ReplicationLag metrics.Gauge
+}
+
+// HotStuffMetrics are the HotStuff engine's metrics
+type HotStuffMetrics struct {
+	// Current view
+	View metrics.Gauge
+	// Seconds from the start of a view to its QC, measured by the leader
+	// collecting the votes
+	QCLatency metrics.Histogram
+	// Views ended by a timeout certificate
+	Timeouts metrics.Counter
+}
+
+// PrometheusMetrics returns metrics registered with the default Prometheus
+// registry, which backs both the node's metrics endpoint and the SDK's
+// telemetry. Collectors already registered, as by another app in the same
+// process, are reused. Optionally, labels can be provided along with their
+// values ("foo", "fooValue").
+func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
+	labels := []string{}
+	for i := 0; i < len(labelsAndValues); i += 2 {
+		labels = append(labels, labelsAndValues[i])
+	}
+	with := func(extra ...string) []string {
+		return append(append([]string{}, labels...), extra...)
+	}
+
+	return &Metrics{
+		TPBFT: &TPBFTMetrics{
+			TrustScore: newDeletableGauge(stdprometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "tpbft",
+				Name:      "trust_score",
+				Help:      "Trust score of a validator.",
+			}, with("validator"), labelsAndValues),
+			SelectedValidators: newGauge(stdprometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "tpbft",
+				Name:      "selected_validators",
+				Help:      "Number of validators in the selected set.",
+			}, labels).With(labelsAndValues...),
+			SelectionChurn: newCounter(stdprometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "tpbft",
+				Name:      "selection_churn",
+				Help:      "Validators entering or leaving the selected set.",
+			}, labels).With(labelsAndValues...),
+			PhaseLatency: newHistogram(stdprometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: "tpbft",
+				Name:      "phase_latency_seconds",
+				Help:      "Time from pre-prepare to the prepared and committed phases.",
+				Buckets:   stdprometheus.ExponentialBuckets(0.001, 2, 14),
+			}, with("phase")).With(labelsAndValues...),
+			ViewChanges: newCounter(stdprometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "tpbft",
+				Name:      "view_changes",
+				Help:      "Number of view changes.",
+			}, labels).With(labelsAndValues...),
+		},
+		Raft: &RaftMetrics{
+			Term: newGauge(stdprometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "raft",
+				Name:      "term",
+				Help:      "Current term.",
+			}, labels).With(labelsAndValues...),
+			LeaderChanges: newCounter(stdprometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "raft",
+				Name:      "leader_changes",
+				Help:      "Number of times a different leader became known.",
+			}, labels).With(labelsAndValues...),
+			ReplicationLag: newGauge(stdprometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "raft",
+				Name:      "replication_lag",
+				Help:      "Log entries a peer has not acknowledged, as seen by the leader.",
+			}, with("peer")).With(labelsAndValues...),
+		},
+		HotStuff: &HotStuffMetrics{
+			View: newGauge(stdprometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "hotstuff",
+				Name:      "view",
+				Help:      "Current view.",
+			}, labels).With(labelsAndValues...),
+			QCLatency: newHistogram(stdprometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: "hotstuff",
+				Name:      "qc_latency_seconds",
+				Help:      "Time from the start of a view to its quorum certificate.",
+				Buckets:   stdprometheus.ExponentialBuckets(0.001, 2, 14),
+			}, labels).With(labelsAndValues...),
+			Timeouts: newCounter(stdprometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "hotstuff",
+				Name:      "timeouts",
+				Help:      "Number of views ended by a timeout certificate.",
+			}, labels).With(labelsAndValues...),
+		},
+	}
+}
+
+// NopMetrics returns no-op metrics
+func NopMetrics() *Metrics {
+	return &Metrics{
+		TPBFT: &TPBFTMetrics{
+			TrustScore:         nopGauge{discard.NewGauge()},
+			SelectedValidators: discard.NewGauge(),
+			SelectionChurn:     discard.NewCounter(),
+			PhaseLatency:       discard.NewHistogram(),
+			ViewChanges:        discard.NewCounter(),
+		},
+		Raft: &RaftMetrics{
+			Term:           discard.NewGauge(),
+			LeaderChanges:  discard.NewCounter(),
+			ReplicationLag: discard.NewGauge(),
+		},
+		HotStuff: &HotStuffMetrics{
+			View:      discard.NewGauge(),
+			QCLatency: discard.NewHistogram(),
+			Timeouts:  discard.NewCounter(),
+		},
+	}
+}
+
+func newGauge(opts stdprometheus.GaugeOpts, labels []string) *prometheus.Gauge {
+	return prometheus.NewGauge(register(stdprometheus.NewGaugeVec(opts, labels)))
+}
+
+func newDeletableGauge(opts stdprometheus.GaugeOpts, labels, labelsAndValues []string) DeletableGauge {
+	return &deletableGauge{gv: register(stdprometheus.NewGaugeVec(opts, labels)), lvs: labelsAndValues}
+}
+
+// deletableGauge is a Prometheus gauge vector with some label values bound
+type deletableGauge struct {
+	gv  *stdprometheus.GaugeVec
+	lvs []string // Label names and values
+}
+
+// With implements metrics.Gauge
+func (g *deletableGauge) With(labelValues ...string) metrics.Gauge {
+	return &deletableGauge{gv: g.gv, lvs: append(slices.Clone(g.lvs), labelValues...)}
+}
+
+// Set implements metrics.Gauge
+func (g *deletableGauge) Set(value float64) {
+	g.gv.With(promLabels(g.lvs)).Set(value)
+}
+
+// Add implements metrics.Gauge
+func (g *deletableGauge) Add(delta float64) {
+	g.gv.With(promLabels(g.lvs)).Add(delta)
+}
+
+// Delete implements DeletableGauge
+func (g *deletableGauge) Delete(labelValues ...string) {
+	g.gv.Delete(promLabels(append(slices.Clone(g.lvs), labelValues...)))
+}
+
+// promLabels pairs label names with their values; a missing value is
+// "unknown", as go-kit has it
+func promLabels(lvs []string) stdprometheus.Labels {
+	labels := make(stdprometheus.Labels, len(lvs)/2)
+	for i := 0; i < len(lvs); i += 2 {
+		value := "unknown"
+		if i+1 < len(lvs) {
+			value = lvs[i+1]
+		}
+		labels[lvs[i]] = value
+	}
+	return labels
+}
+
+// nopGauge is a no-op DeletableGauge
+type nopGauge struct {
+	metrics.Gauge
+}
+
+// Delete implements DeletableGauge
+func (nopGauge) Delete(...string) {}
+
+func newCounter(opts stdprometheus.CounterOpts, labels []string) *prometheus.Counter {
+	return prometheus.NewCounter(register(stdprometheus.NewCounterVec(opts, labels)))
+}
+
+func newHistogram(opts stdprometheus.HistogramOpts, labels []string) *prometheus.Histogram {
+	return prometheus.NewHistogram(register(stdprometheus.NewHistogramVec(opts, labels)))
+}
+
+// register adds c to the default registry, returning the collector already
+// registered under the same name if there is one
+func register[C stdprometheus.Collector](c C) C {
+	err := stdprometheus.Register(c)
+	var are stdprometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		return are.ExistingCollector.(C)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
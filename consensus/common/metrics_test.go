@@ -0,0 +1,40 @@
+package common
+
+import (
+	"testing"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusMetrics_SharedRegistry(t *testing.T) {
+	m := PrometheusMetrics("test", "chain_id", "a")
+	m.Raft.Term.Set(3)
+	m.TPBFT.TrustScore.With("validator", "val0").Set(0.8)
+	m.TPBFT.TrustScore.With("validator", "val1").Set(0.5)
+	m.TPBFT.TrustScore.Delete("validator", "val1")
+
+	// A second app in the same process reuses the registered collectors
+	other := PrometheusMetrics("test", "chain_id", "b")
+	other.Raft.Term.Set(5)
+
+	families, err := stdprometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	terms := map[string]float64{}
+	var trust float64
+	for _, f := range families {
+		switch f.GetName() {
+		case "test_raft_term":
+			for _, metric := range f.GetMetric() {
+				terms[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+			}
+		case "test_tpbft_trust_score":
+			require.Len(t, f.GetMetric(), 1, "deleted series are not exported")
+			trust = f.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{"a": 3, "b": 5}, terms)
+	assert.Equal(t, 0.8, trust)
+}
@@ -0,0 +1,100 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"cosmossdk.io/log"
+	"cosmossdk.io/math"
+	storetypes "cosmossdk.io/store/types"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StakingKeeper is the staking functionality available to engines. Engines
+// keep their own narrower interfaces, which this one satisfies.
+type StakingKeeper interface {
+	GetValidatorByConsAddr(ctx context.Context, consAddr sdk.ConsAddress) (stakingtypes.Validator, error)
+	GetValidator(ctx context.Context, addr sdk.ValAddress) (stakingtypes.Validator, error)
+	GetAllValidators(ctx context.Context) ([]stakingtypes.Validator, error)
+	GetBondedValidatorsByPower(ctx context.Context) ([]stakingtypes.Validator, error)
+	TotalBondedTokens(ctx context.Context) (math.Int, error)
+}
+
+// Dependencies is the bundle of app services handed to engine factories.
+// Any field may be nil when the engine runs outside an app, as in tests.
+type Dependencies struct {
+	StakingKeeper StakingKeeper
+	Logger        log.Logger
+
+	// AppOptions holds the node configuration, including the engine's own
+	// section of config.toml
+	AppOptions servertypes.AppOptions
+
+	// StoreKey is the KV store engines persist consensus state in. Engines
+	// share it, so each keeps its records under its own prefix.
+	StoreKey storetypes.StoreKey
+
+	// Metrics are shared by every engine the app runs; nil disables them
+	Metrics *Metrics
+
+	// TracerProvider supplies the engine's tracer; nil disables tracing
+	TracerProvider trace.TracerProvider
+}
+
+// EngineFactory builds an engine from the app's dependencies
+type EngineFactory func(deps Dependencies) (ConsensusEngine, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]EngineFactory)
+)
+
+// RegisterEngine makes an engine available under name, typically from the
+// engine package's init. It panics if the name is taken.
+func RegisterEngine(name string, factory EngineFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic(fmt.Sprintf("consensus engine %q registered without a factory", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("consensus engine %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// NewEngine builds the engine registered under name
+func NewEngine(name string, deps Dependencies) (ConsensusEngine, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown consensus engine %q, registered engines: %s", name, strings.Join(RegisteredEngines(), ", "))
+	}
+	engine, err := factory(deps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consensus engine %q: %w", name, err)
+	}
+	return engine, nil
+}
+
+// RegisteredEngines returns the names of all registered engines, sorted
+func RegisteredEngines() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
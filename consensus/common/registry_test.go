@@ -0,0 +1,56 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	storetypes "cosmossdk.io/store/types"
+	abci "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEngine records the dependencies it was built with
+type fakeEngine struct {
+	deps Dependencies
+}
+
+func (e *fakeEngine) Name() string                    { return "fake" }
+func (e *fakeEngine) Start(ctx context.Context) error { return nil }
+func (e *fakeEngine) Stop() error                     { return nil }
+func (e *fakeEngine) BeginBlock(sdk.Context) error    { return nil }
+func (e *fakeEngine) EndBlock(sdk.Context) ([]abci.ValidatorUpdate, error) {
+	return nil, nil
+}
+func (e *fakeEngine) Status() Status { return Status{Engine: "fake"} }
+func (e *fakeEngine) Health() error  { return nil }
+
+func TestRegistry_NewEngine(t *testing.T) {
+	RegisterEngine("test-fake", func(deps Dependencies) (ConsensusEngine, error) {
+		return &fakeEngine{deps: deps}, nil
+	})
+	RegisterEngine("test-broken", func(Dependencies) (ConsensusEngine, error) {
+		return nil, errors.New("bad config")
+	})
+
+	assert.Subset(t, RegisteredEngines(), []string{"test-broken", "test-fake"})
+
+	key := storetypes.NewKVStoreKey("consensus")
+	engine, err := NewEngine("test-fake", Dependencies{StoreKey: key})
+	require.NoError(t, err)
+	assert.Same(t, key, engine.(*fakeEngine).deps.StoreKey)
+
+	_, err = NewEngine("test-broken", Dependencies{})
+	assert.ErrorContains(t, err, "bad config")
+
+	// Unknown names fail instead of falling back to a default
+	_, err = NewEngine("pbft", Dependencies{})
+	assert.ErrorContains(t, err, `unknown consensus engine "pbft"`)
+	assert.ErrorContains(t, err, "test-fake")
+
+	assert.Panics(t, func() {
+		RegisterEngine("test-fake", func(Dependencies) (ConsensusEngine, error) { return nil, nil })
+	})
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName names the tracer engines create their spans with
+const TracerName = "github.com/fffeng99999/hcp-consensus/consensus"
+
+// Span attributes shared by the engines
+const (
+	AttributeEngine = attribute.Key("engine")
+	AttributeNodeID = attribute.Key("node_id")
+	AttributeView   = attribute.Key("view")
+	AttributeSeq    = attribute.Key("seq")
+	AttributeFrom   = attribute.Key("from")
+)
+
+var propagator = propagation.TraceContext{}
+
+// NopTracer returns a tracer that records nothing
+func NopTracer() trace.Tracer {
+	return trace.NewNoopTracerProvider().Tracer(TracerName)
+}
+
+// InjectTrace returns the W3C trace context of the span in ctx, for carrying
+// in a consensus message. It returns nil when there is no span to carry.
+func InjectTrace(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier
+}
+
+// ExtractTrace returns a context holding the remote span carried in a
+// consensus message, to parent spans on the receiving node
+func ExtractTrace(carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return context.Background()
+	}
+	return propagator.Extract(context.Background(), propagation.MapCarrier(carrier))
+}
+
+// NewFileTracerProvider returns a tracer provider batching spans to an OTLP
+// JSON file at path, appending to it if it exists. Shutting the provider
+// down flushes and closes the file.
+func NewFileTracerProvider(path string, attrs ...attribute.KeyValue) (*sdktrace.TracerProvider, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+
+	attrs = append([]attribute.KeyValue{attribute.String("service.name", "hcpd")}, attrs...)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(NewOTLPFileExporter(f)),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+	), nil
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceContext_RoundTrip(t *testing.T) {
+	assert.Nil(t, InjectTrace(context.Background()))
+	assert.False(t, trace.SpanContextFromContext(ExtractTrace(nil)).IsValid())
+
+	tracer := sdktrace.NewTracerProvider().Tracer(TracerName)
+	ctx, span := tracer.Start(context.Background(), "sender")
+	defer span.End()
+
+	carrier := InjectTrace(ctx)
+	require.Contains(t, carrier, "traceparent")
+
+	remote := trace.SpanContextFromContext(ExtractTrace(carrier))
+	assert.True(t, remote.IsRemote())
+	assert.Equal(t, span.SpanContext().TraceID(), remote.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), remote.SpanID())
+}
+
+func TestOTLPFileExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(NewOTLPFileExporter(&buf)))
+	tracer := tp.Tracer(TracerName)
+
+	start := time.Unix(1700000000, 0)
+	ctx, parent := tracer.Start(context.Background(), "parent", trace.WithTimestamp(start))
+	_, child := tracer.Start(ctx, "child",
+		trace.WithTimestamp(start),
+		trace.WithAttributes(AttributeSeq.Int64(7), AttributeNodeID.String("node0")),
+	)
+	child.AddEvent("Prepare", trace.WithTimestamp(start), trace.WithAttributes(attribute.Bool("ok", true)))
+	child.End(trace.WithTimestamp(start.Add(time.Millisecond)))
+	parent.End(trace.WithTimestamp(start.Add(2 * time.Millisecond)))
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	// One request per exported batch, one batch per span with a syncer
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var req otlpTraces
+	require.NoError(t, json.Unmarshal(lines[0], &req))
+	require.Len(t, req.ResourceSpans, 1)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans, 1)
+	assert.Equal(t, TracerName, req.ResourceSpans[0].ScopeSpans[0].Scope.Name)
+
+	span := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "child", span.Name)
+	assert.Equal(t, parent.SpanContext().TraceID().String(), span.TraceID)
+	assert.Equal(t, parent.SpanContext().SpanID().String(), span.ParentSpanID)
+	assert.Equal(t, "1700000000000000000", span.StartTimeUnixNano)
+	assert.Equal(t, "1700000000001000000", span.EndTimeUnixNano)
+	require.Len(t, span.Attributes, 2)
+	assert.Equal(t, "seq", span.Attributes[0].Key)
+	assert.Equal(t, "7", *span.Attributes[0].Value.IntValue)
+	assert.Equal(t, "node0", *span.Attributes[1].Value.StringValue)
+	require.Len(t, span.Events, 1)
+	assert.True(t, *span.Events[0].Attributes[0].Value.BoolValue)
+
+	// Spans after shutdown are dropped
+	n := buf.Len()
+	_, late := tracer.Start(context.Background(), "late")
+	late.End()
+	assert.Equal(t, n, buf.Len())
+}
+
+func TestNewFileTracerProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.json")
+	tp, err := NewFileTracerProvider(path, attribute.String("chain_id", "hcp-test"))
+	require.NoError(t, err)
+
+	_, span := tp.Tracer(TracerName).Start(context.Background(), "span")
+	span.End()
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	bz, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var req otlpTraces
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(bz), &req))
+	assert.Contains(t, string(bz), `"key":"service.name","value":{"stringValue":"hcpd"}`)
+	assert.Contains(t, string(bz), `"key":"chain_id","value":{"stringValue":"hcp-test"}`)
+}
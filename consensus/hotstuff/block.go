@@ -0,0 +1,60 @@
+package hotstuff
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// Hash identifies a block
+type Hash [32]byte
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:4])
+}
+
+// Block is a node of the chained HotStuff block tree. Each block carries the
+// QC certifying its parent, so votes for it also advance earlier phases.
+type Block struct {
+	View     uint64
+	Height   uint64
+	Parent   Hash
+	Justify  *QuorumCert // Certifies Parent; nil only for genesis
+	Proposer string
+	Payload  []byte
+}
+
+// Hash returns the block's identity over every field
+func (b *Block) Hash() Hash {
+	h := sha256.New()
+	var buf [8]byte
+
+	binary.BigEndian.PutUint64(buf[:], b.View)
+	h.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], b.Height)
+	h.Write(buf[:])
+	h.Write(b.Parent[:])
+	if b.Justify != nil {
+		binary.BigEndian.PutUint64(buf[:], b.Justify.View)
+		h.Write(buf[:])
+		h.Write(b.Justify.BlockHash[:])
+	}
+	binary.BigEndian.PutUint64(buf[:], uint64(len(b.Proposer)))
+	h.Write(buf[:])
+	h.Write([]byte(b.Proposer))
+	h.Write(b.Payload)
+
+	var out Hash
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// genesisBlock is the common root of every replica's block tree
+func genesisBlock() *Block {
+	return &Block{}
+}
+
+// genesisQC certifies the genesis block without votes
+func genesisQC(genesis *Block) *QuorumCert {
+	return &QuorumCert{View: 0, BlockHash: genesis.Hash()}
+}
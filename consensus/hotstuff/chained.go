@@ -0,0 +1,348 @@
+package hotstuff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+// propose creates a block extending the highest certified block, if we lead
+// the current view and have not proposed in it yet. The block carries the
+// TC that ended the previous view unless its QC did.
+func (h *HotStuffConsensus) propose() {
+	if h.leader(h.view) != h.id || h.lastProposed >= h.view {
+		return
+	}
+	highQC := h.safety.HighQC()
+	var tc *TimeoutCert
+	if highQC.View+1 != h.view {
+		if h.lastTC == nil || h.lastTC.View+1 != h.view {
+			return
+		}
+		tc = h.lastTC
+	}
+
+	parent, ok := h.blocks[highQC.BlockHash]
+	if !ok {
+		// A high QC restored from storage may certify a block we lost
+		return
+	}
+	payload := h.nextPayload(parent)
+
+	h.lastProposed = h.view
+	h.broadcast(&Message{
+		Type:         MessageTypeProposal,
+		View:         h.view,
+		TC:           tc,
+		TraceContext: common.InjectTrace(h.viewCtx),
+		Block: &Block{
+			View:     h.view,
+			Height:   parent.Height + 1,
+			Parent:   highQC.BlockHash,
+			Justify:  highQC,
+			Proposer: h.id,
+			Payload:  payload,
+		},
+	})
+}
+
+// nextPayload picks the first submitted payload not already carried by an
+// uncommitted ancestor. Payloads stay queued until they commit, so those in
+// abandoned forks are proposed again.
+func (h *HotStuffConsensus) nextPayload(parent *Block) []byte {
+	inFlight := make(map[string]int)
+	for b := parent; b.Height > h.committed.Height; b = h.blocks[b.Parent] {
+		if b.Proposer == h.id && len(b.Payload) > 0 {
+			inFlight[string(b.Payload)]++
+		}
+	}
+	for _, payload := range h.pendingPayload {
+		if inFlight[string(payload)] > 0 {
+			inFlight[string(payload)]--
+			continue
+		}
+		return payload
+	}
+	return nil
+}
+
+// hasWork reports whether there is anything left to commit: a submitted
+// payload, or an uncommitted block carrying one. Idle replicas stop
+// proposing once the pipeline has drained.
+func (h *HotStuffConsensus) hasWork() bool {
+	if len(h.pendingPayload) > 0 {
+		return true
+	}
+	for b := h.leaf; b != nil && b.Height > h.committed.Height; b = h.blocks[b.Parent] {
+		if len(b.Payload) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HotStuffConsensus) handleProposal(msg *Message) error {
+	b := msg.Block
+	if b == nil {
+		return fmt.Errorf("proposal from %s carries no block", msg.From)
+	}
+	if msg.From != h.leader(b.View) || b.Proposer != msg.From {
+		return fmt.Errorf("proposal for view %d from %s, expected leader %s", b.View, msg.From, h.leader(b.View))
+	}
+	if err := h.verifyQC(b.Justify); err != nil {
+		return fmt.Errorf("proposal for view %d: %w", b.View, err)
+	}
+	if msg.TC != nil {
+		if err := h.verifyTC(msg.TC); err != nil {
+			return fmt.Errorf("proposal for view %d: %w", b.View, err)
+		}
+	}
+	parent, ok := h.blocks[b.Parent]
+	if !ok {
+		return fmt.Errorf("proposal for view %d extends unknown block %s", b.View, b.Parent)
+	}
+	if b.Justify.BlockHash != b.Parent || b.View <= b.Justify.View || b.Height != parent.Height+1 {
+		return fmt.Errorf("proposal for view %d does not extend its justify QC", b.View)
+	}
+
+	// A leader may only propose in the view right after the one its QC or
+	// TC ended, and after a TC must extend the highest QC it carries
+	enteredByQC := b.Justify.View+1 == b.View
+	enteredByTC := msg.TC != nil && msg.TC.View+1 == b.View && b.Justify.View >= msg.TC.HighQC.View
+	if !enteredByQC && !enteredByTC {
+		return fmt.Errorf("proposal for view %d is not justified by a QC or TC for view %d", b.View, b.View-1)
+	}
+
+	hash := b.Hash()
+	if _, ok := h.blocks[hash]; ok {
+		return nil
+	}
+	ctx, span := h.startProposalSpan(msg)
+	defer func() { span.End(trace.WithTimestamp(h.now())) }()
+	h.blocks[hash] = b
+	if h.leaf == nil || b.View > h.leaf.View {
+		h.leaf = b
+	}
+
+	if err := h.update(b); err != nil {
+		return err
+	}
+	if enteredByQC {
+		h.advanceView(b.View, nil)
+	} else {
+		h.advanceView(b.View, msg.TC)
+	}
+
+	// Vote only in the current view; the safety rules refuse a second vote
+	// in it and blocks conflicting with our lock
+	if b.View != h.view {
+		return nil
+	}
+	sig, err := h.safety.Vote(b, parent)
+	if errors.Is(err, ErrUnsafeVote) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("not voting for view %d: %w", b.View, err)
+	}
+	h.send(&Message{
+		Type:         MessageTypeVote,
+		To:           h.leader(b.View + 1),
+		View:         b.View,
+		BlockHash:    hash,
+		Signature:    sig,
+		TraceContext: common.InjectTrace(ctx),
+	})
+	return nil
+}
+
+// update runs the chained HotStuff state machine on a new block. Following
+// its justify links back, b2 <- b1 <- b0: the QC for b2 becomes the high QC,
+// b1 is locked on, and b0 commits when b0, b1, b2 were proposed in
+// consecutive views (the three-chain rule).
+func (h *HotStuffConsensus) update(bStar *Block) error {
+	b2 := h.blocks[bStar.Justify.BlockHash]
+	if err := h.safety.ObserveBlock(bStar, b2); err != nil {
+		return err
+	}
+	if b2.Justify == nil {
+		return nil
+	}
+
+	b1 := h.blocks[b2.Justify.BlockHash]
+	if b1.Justify == nil {
+		return nil
+	}
+	b0 := h.blocks[b1.Justify.BlockHash]
+	if b2.View == b1.View+1 && b1.View == b0.View+1 {
+		h.commit(b0)
+	}
+	return nil
+}
+
+// commit marks b and its uncommitted ancestors committed
+func (h *HotStuffConsensus) commit(b *Block) {
+	if b.Height <= h.committed.Height {
+		return
+	}
+	if !h.extends(b, h.committed.Hash()) {
+		// Two conflicting blocks cannot both gather a three-chain unless
+		// more than f replicas are Byzantine
+		panic(fmt.Errorf("hotstuff replica %s: block %s at height %d conflicts with committed block %s",
+			h.id, b.Hash(), b.Height, h.committed.Hash()))
+	}
+	observer, _ := h.election.(CommitObserver)
+	for _, blk := range h.chain(b, h.committed) {
+		h.logger.Debug("committed block", "height", blk.Height, "view", blk.View, "hash", blk.Hash().String())
+		if blk.Proposer == h.id && len(blk.Payload) > 0 {
+			h.dequeuePayload(blk.Payload)
+		}
+		if observer != nil {
+			var failed []uint64
+			for view := h.blocks[blk.Parent].View + 1; view < blk.View; view++ {
+				failed = append(failed, view)
+			}
+			observer.OnCommit(blk, failed)
+		}
+	}
+	h.committed = b
+}
+
+// dequeuePayload removes a committed payload from the submission queue
+func (h *HotStuffConsensus) dequeuePayload(payload []byte) {
+	for i, p := range h.pendingPayload {
+		if bytes.Equal(p, payload) {
+			h.pendingPayload = append(h.pendingPayload[:i], h.pendingPayload[i+1:]...)
+			return
+		}
+	}
+}
+
+// extends reports whether the block with the given hash is b or an ancestor
+func (h *HotStuffConsensus) extends(b *Block, ancestor Hash) bool {
+	anc, ok := h.blocks[ancestor]
+	if !ok {
+		return false
+	}
+	for b != nil && b.Height > anc.Height {
+		b = h.blocks[b.Parent]
+	}
+	return b != nil && b.Hash() == ancestor
+}
+
+// chain returns the blocks after from up to and including to, in order
+func (h *HotStuffConsensus) chain(to, from *Block) []*Block {
+	var blocks []*Block
+	for b := to; b.Height > from.Height; b = h.blocks[b.Parent] {
+		blocks = append(blocks, b)
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks
+}
+
+func (h *HotStuffConsensus) handleVote(msg *Message) error {
+	if h.leader(msg.View+1) != h.id {
+		// Votes go to the next view's leader only
+		return nil
+	}
+	if !h.isReplica(msg.From) {
+		return fmt.Errorf("vote from unknown replica %s", msg.From)
+	}
+	b, ok := h.blocks[msg.BlockHash]
+	if !ok || b.View != msg.View {
+		return fmt.Errorf("vote from %s for unknown block %s in view %d", msg.From, msg.BlockHash, msg.View)
+	}
+
+	if h.validators != nil {
+		if err := h.validators.verifySignature(h.scheme, msg.From, voteMessage(msg.View, msg.BlockHash), msg.Signature); err != nil {
+			return fmt.Errorf("vote for view %d: %w", msg.View, err)
+		}
+	}
+
+	h.traceEvent(msg)
+	voters := h.votes[msg.BlockHash]
+	if voters == nil {
+		voters = make(map[string][]byte)
+		h.votes[msg.BlockHash] = voters
+	}
+	voters[msg.From] = msg.Signature
+	if len(voters) != h.quorum() {
+		return nil
+	}
+
+	qc, err := h.newQC(b.View, msg.BlockHash, voters)
+	if err != nil {
+		return err
+	}
+	if err := h.safety.ObserveQC(qc); err != nil {
+		return err
+	}
+	if qc.View == h.view && !h.viewStarted.IsZero() {
+		h.metrics.QCLatency.Observe(h.now().Sub(h.viewStarted).Seconds())
+	}
+	for hash := range h.votes {
+		if blk := h.blocks[hash]; blk.View < b.View {
+			delete(h.votes, hash)
+		}
+	}
+
+	// The QC ends view b.View; lead the next one
+	h.advanceView(qc.View+1, nil)
+	return nil
+}
+
+// newQC certifies a block from its votes, aggregating their signatures when
+// keys are configured
+func (h *HotStuffConsensus) newQC(view uint64, hash Hash, votes map[string][]byte) (*QuorumCert, error) {
+	if h.validators != nil {
+		return NewQuorumCert(h.scheme, view, hash, votes)
+	}
+	signers := make([]string, 0, len(votes))
+	for id := range votes {
+		signers = append(signers, id)
+	}
+	sort.Strings(signers)
+	return &QuorumCert{View: view, BlockHash: hash, Signers: signers}, nil
+}
+
+// verifyQC checks that a QC is signed by a quorum of distinct replicas
+func (h *HotStuffConsensus) verifyQC(qc *QuorumCert) error {
+	if qc == nil {
+		return fmt.Errorf("missing QC")
+	}
+	if qc.View == 0 {
+		if qc.BlockHash != h.genesis.Hash() {
+			return fmt.Errorf("view 0 QC does not certify genesis")
+		}
+		return nil
+	}
+	if h.validators != nil {
+		if qc.Scheme != h.scheme {
+			return fmt.Errorf("QC for view %d uses scheme %s, expected %s", qc.View, qc.Scheme, h.scheme)
+		}
+		return qc.Verify(h.validators, h.quorum())
+	}
+
+	signers := make(map[string]bool, len(qc.Signers))
+	for _, id := range qc.Signers {
+		if !h.isReplica(id) {
+			return fmt.Errorf("QC for view %d signed by unknown replica %s", qc.View, id)
+		}
+		signers[id] = true
+	}
+	if len(signers) < h.quorum() {
+		return fmt.Errorf("QC for view %d has %d signers, need %d", qc.View, len(signers), h.quorum())
+	}
+	return nil
+}
+
+func (h *HotStuffConsensus) isReplica(id string) bool {
+	i := sort.SearchStrings(h.replicas, id)
+	return i < len(h.replicas) && h.replicas[i] == id
+}
@@ -0,0 +1,91 @@
+package hotstuff
+
+import (
+	"fmt"
+	"time"
+
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	"github.com/spf13/cast"
+)
+
+// Config holds the pacemaker parameters of a HotStuff replica
+type Config struct {
+	TickInterval time.Duration
+
+	// A view times out after BaseTimeout, multiplied by TimeoutMultiplier
+	// for every consecutive view that ended without a QC, up to MaxTimeout
+	BaseTimeout       time.Duration
+	MaxTimeout        time.Duration
+	TimeoutMultiplier float64
+}
+
+// DefaultConfig returns the default pacemaker configuration
+func DefaultConfig() Config {
+	return Config{
+		TickInterval:      10 * time.Millisecond,
+		BaseTimeout:       1000 * time.Millisecond,
+		MaxTimeout:        30 * time.Second,
+		TimeoutMultiplier: 2,
+	}
+}
+
+// Validate checks the configuration for consistency
+func (c Config) Validate() error {
+	if c.TickInterval <= 0 {
+		return fmt.Errorf("tick interval must be positive")
+	}
+	if c.BaseTimeout < c.TickInterval {
+		return fmt.Errorf("base timeout %s is shorter than the tick interval %s", c.BaseTimeout, c.TickInterval)
+	}
+	if c.MaxTimeout < c.BaseTimeout {
+		return fmt.Errorf("max timeout %s is shorter than the base timeout %s", c.MaxTimeout, c.BaseTimeout)
+	}
+	if c.TimeoutMultiplier < 1 {
+		return fmt.Errorf("timeout multiplier %v must be at least 1", c.TimeoutMultiplier)
+	}
+	return nil
+}
+
+// ConfigFromAppOptions reads the [hotstuff] section of the node
+// configuration, keeping defaults for missing keys
+func ConfigFromAppOptions(appOpts servertypes.AppOptions) (Config, error) {
+	cfg := DefaultConfig()
+
+	durations := map[string]*time.Duration{
+		"hotstuff.tick_interval": &cfg.TickInterval,
+		"hotstuff.base_timeout":  &cfg.BaseTimeout,
+		"hotstuff.max_timeout":   &cfg.MaxTimeout,
+	}
+	for key, field := range durations {
+		if v := appOpts.Get(key); v != nil {
+			d, err := cast.ToDurationE(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*field = d
+		}
+	}
+
+	if v := appOpts.Get("hotstuff.timeout_multiplier"); v != nil {
+		f, err := cast.ToFloat64E(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid hotstuff.timeout_multiplier: %w", err)
+		}
+		cfg.TimeoutMultiplier = f
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// SetConfig applies cfg. It must be called before the replica starts.
+func (h *HotStuffConsensus) SetConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.config = cfg
+	return nil
+}
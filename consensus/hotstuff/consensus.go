@@ -1,89 +1,444 @@
 package hotstuff
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"cosmossdk.io/log"
+	storetypes "cosmossdk.io/store/types"
 	abci "github.com/cometbft/cometbft/abci/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
 )
 
-// HotStuffConsensus implements the HotStuff consensus engine
+// EngineName is the name selecting HotStuff in the consensus-engine option
+const EngineName = "hotstuff"
+
+// HotStuffConsensus implements the chained HotStuff consensus engine
 type HotStuffConsensus struct {
-	mu      sync.RWMutex
-	running bool
+	mu   sync.RWMutex
+	loop common.Loop
+
+	// Replicas
+	id        string
+	replicas  []string // Sorted
+	election  LeaderElection
+	transport Transport
+	selfQueue []*Message // Messages to ourselves, handled after the current one
+
+	// Block tree, rooted at genesis
+	blocks    map[Hash]*Block
+	genesis   *Block
+	committed *Block // Highest committed block
+	leaf      *Block // Highest-view block received
 
 	// HotStuff specific fields
-	view uint64
-	qc   interface{} // Quorum Certificate
+	view           uint64                     // Current view
+	lastProposed   uint64                     // Last view we proposed in as leader
+	safety         *SafetyRules               // Owns the voting state and the signing key
+	votes          map[Hash]map[string][]byte // Block -> voter -> signature, collected as next leader
+	pendingPayload [][]byte                   // Submitted payloads, proposed when we lead
+
+	// Committed blocks are delivered in order to onCommit, outside mu
+	onCommit    func(*Block)
+	commitQueue []*Block
+	delivering  bool
+
+	// Pacemaker: the current view's timer, timeouts collected per view, and
+	// the TC that ended the previous view, if any
+	config          Config
+	viewElapsed     time.Duration
+	consecutiveTCs  int // Views ended by timeout since the last QC
+	lastTimeoutView uint64
+	timeouts        map[uint64]map[string]Timeout // View -> signer -> its timeout
+	lastTC          *TimeoutCert
+
+	// Vote and timeout verification; without keys QCs and TCs only list
+	// their signers
+	validators *ValidatorSet
+	scheme     QCScheme
+
+	// QC latency is measured from viewStarted on the now clock
+	metrics     *common.HotStuffMetrics
+	now         func() time.Time
+	viewStarted time.Time
 
-	// Config
-	viewTimeout time.Duration
+	// A span per view; messages carry it to link traces across replicas
+	tracer   trace.Tracer
+	viewCtx  context.Context
+	viewSpan trace.Span
+
+	logger log.Logger
+
+	// The leader rotation follows the parameters in the store
+	storeKey storetypes.StoreKey
+	params   Params
+
+	// Trust scores handed over by the previous engine, passed on unchanged:
+	// what a reputation election learns from the replica's own commits is
+	// local to the node
+	trust json.RawMessage
 }
 
 // NewHotStuffConsensus creates a new HotStuff consensus instance
 func NewHotStuffConsensus() *HotStuffConsensus {
+	// Single-replica setup, to be configured if running with peers
+	return NewHotStuffNode("local-node", []string{})
+}
+
+// NewHotStuffNode creates a replica with the given ID and peer IDs (excluding itself)
+func NewHotStuffNode(id string, peers []string) *HotStuffConsensus {
+	replicas := append([]string{id}, peers...)
+	sort.Strings(replicas)
+
+	genesis := genesisBlock()
+	qc := genesisQC(genesis)
 	return &HotStuffConsensus{
-		viewTimeout: 1000 * time.Millisecond,
+		id:        id,
+		replicas:  replicas,
+		election:  NewRoundRobinElection(replicas),
+		params:    DefaultParams(),
+		blocks:    map[Hash]*Block{qc.BlockHash: genesis},
+		genesis:   genesis,
+		committed: genesis,
+		leaf:      genesis,
+		view:      1,
+		safety:    NewSafetyRules(qc),
+		votes:     make(map[Hash]map[string][]byte),
+		config:    DefaultConfig(),
+		timeouts:  make(map[uint64]map[string]Timeout),
+		metrics:   common.NopMetrics().HotStuff,
+		now:       time.Now,
+		tracer:    common.NopTracer(),
+		viewCtx:   context.Background(),
+		viewSpan:  trace.SpanFromContext(context.Background()),
+		logger:    log.NewNopLogger(),
 	}
 }
 
-// Start starts the consensus engine
-func (h *HotStuffConsensus) Start() error {
+// SetTransport sets the transport used to reach other replicas
+func (h *HotStuffConsensus) SetTransport(t Transport) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if h.running {
-		return fmt.Errorf("HotStuff engine already running")
+	h.transport = t
+}
+
+// SetKeys makes the replica sign its votes with key and require QCs signed
+// under scheme by the given validator set, which must match the replicas
+func (h *HotStuffConsensus) SetKeys(key *PrivateKey, vs *ValidatorSet, scheme QCScheme) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ids := vs.IDs()
+	if len(ids) != len(h.replicas) {
+		return fmt.Errorf("validator set has %d members, expected %d replicas", len(ids), len(h.replicas))
+	}
+	for i, id := range ids {
+		if id != h.replicas[i] {
+			return fmt.Errorf("validator %s is not a replica", id)
+		}
+	}
+	own, err := key.Validator(h.id)
+	if err != nil {
+		return err
+	}
+	if v := vs.validators[vs.index[h.id]]; string(v.BLSKey) != string(own.BLSKey) || !v.Ed25519Key.Equal(own.Ed25519Key) {
+		return fmt.Errorf("key does not match validator %s", h.id)
 	}
 
-	h.running = true
-	go h.runLoop()
+	h.safety.SetSigner(key, scheme)
+	h.validators, h.scheme = vs, scheme
 	return nil
 }
 
-// Stop stops the consensus engine
-func (h *HotStuffConsensus) Stop() error {
+// SetSafetyStorage attaches stable storage for the voting state and restores
+// any state persisted by a previous run. Blocks are not persisted, so a
+// restarted replica votes again only once it has the blocks proposals extend.
+func (h *HotStuffConsensus) SetSafetyStorage(s SafetyStorage) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if !h.running {
-		return nil
+	if err := h.safety.SetStorage(s); err != nil {
+		return err
+	}
+	if v := h.safety.LastVotedView(); v > h.view {
+		h.view = v
+		h.metrics.View.Set(float64(v))
 	}
+	return nil
+}
+
+// SetLogger sets the logger view changes and commits are logged to, scoped
+// to the engine and replica
+func (h *HotStuffConsensus) SetLogger(l log.Logger) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.logger = l.With("engine", EngineName, "node_id", h.id)
+}
 
-	h.running = false
+// SetMetrics sets the metrics the replica reports to
+func (h *HotStuffConsensus) SetMetrics(m *common.HotStuffMetrics) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.metrics = m
+	h.metrics.View.Set(float64(h.view))
+}
+
+// SetLeaderElection replaces the default round-robin leader rotation. Every
+// replica must use the same strategy.
+func (h *HotStuffConsensus) SetLeaderElection(e LeaderElection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.election = e
+}
+
+// SetCommitCallback sets the function receiving committed blocks, in chain
+// order. It is called without the node's lock held.
+func (h *HotStuffConsensus) SetCommitCallback(fn func(*Block)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onCommit = fn
+}
+
+// ID returns the replica ID
+func (h *HotStuffConsensus) ID() string {
+	return h.id
+}
+
+// View returns the current view
+func (h *HotStuffConsensus) View() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.view
+}
+
+// HighQC returns the highest QC known to this replica
+func (h *HotStuffConsensus) HighQC() *QuorumCert {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.safety.HighQC()
+}
+
+// LockedQC returns the QC this replica is locked on
+func (h *HotStuffConsensus) LockedQC() *QuorumCert {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.safety.LockedQC()
+}
+
+// Committed returns the highest committed block
+func (h *HotStuffConsensus) Committed() *Block {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.committed
+}
+
+// Name implements ConsensusEngine
+func (h *HotStuffConsensus) Name() string {
+	return EngineName
+}
+
+// Start starts the consensus engine
+func (h *HotStuffConsensus) Start(ctx context.Context) error {
+	if err := h.loop.Start(ctx, h.runLoop); err != nil {
+		return fmt.Errorf("HotStuff engine %w", err)
+	}
 	return nil
 }
 
-func (h *HotStuffConsensus) runLoop() {
-	ticker := time.NewTicker(h.viewTimeout)
+// Stop stops the consensus engine and waits for its tick loop to exit
+func (h *HotStuffConsensus) Stop() error {
+	h.loop.Stop()
+	return nil
+}
+
+func (h *HotStuffConsensus) runLoop(ctx context.Context) {
+	h.mu.RLock()
+	interval := h.config.TickInterval
+	h.mu.RUnlock()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for h.running {
+	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-ticker.C:
-			// Handle view timeout
-			h.newView()
+			// Advance the view timer
+			h.tick()
 		}
 	}
 }
 
-func (h *HotStuffConsensus) newView() {
+// Status implements ConsensusEngine
+func (h *HotStuffConsensus) Status() common.Status {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return common.Status{
+		Engine:    EngineName,
+		NodeID:    h.id,
+		Running:   h.loop.Running(),
+		Round:     h.view,
+		Leader:    h.leader(h.view),
+		Committed: h.committed.Height,
+	}
+}
+
+// Health implements ConsensusEngine
+func (h *HotStuffConsensus) Health() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.loop.Running() {
+		return fmt.Errorf("HotStuff engine not running")
+	}
+	if h.viewTimeout() >= h.config.MaxTimeout {
+		return fmt.Errorf("view %d stalled: %d views in a row ended by timeout", h.view, h.consecutiveTCs)
+	}
+	return nil
+}
+
+// Propose submits a payload, included in a block the next time this
+// replica leads a view
+func (h *HotStuffConsensus) Propose(payload []byte) error {
+	h.process(func() {
+		h.pendingPayload = append(h.pendingPayload, payload)
+		// Without the QC or TC ending the previous view, the proposal
+		// waits for it
+		if h.safety.HighQC().View+1 == h.view || (h.lastTC != nil && h.lastTC.View+1 == h.view) {
+			h.propose()
+		}
+	})
+	return nil
+}
+
+// HandleMessage processes an incoming HotStuff message
+func (h *HotStuffConsensus) HandleMessage(msg *Message) error {
+	var err error
+	h.process(func() {
+		err = h.handle(msg)
+	})
+	return err
+}
+
+func (h *HotStuffConsensus) handle(msg *Message) error {
+	switch msg.Type {
+	case MessageTypeProposal:
+		return h.handleProposal(msg)
+	case MessageTypeVote:
+		return h.handleVote(msg)
+	case MessageTypeTimeout:
+		return h.handleTimeout(msg)
+	default:
+		return fmt.Errorf("unknown hotstuff message type %d", msg.Type)
+	}
+}
+
+// process runs fn with the lock held, then handles the messages we sent to
+// ourselves and delivers the blocks committed meanwhile
+func (h *HotStuffConsensus) process(fn func()) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+	start := h.committed
+	fn()
+	for len(h.selfQueue) > 0 {
+		msg := h.selfQueue[0]
+		h.selfQueue = h.selfQueue[1:]
+		h.handle(msg)
+	}
+
+	h.commitQueue = append(h.commitQueue, h.chain(h.committed, start)...)
+	deliver := !h.delivering && len(h.commitQueue) > 0
+	if deliver {
+		h.delivering = true
+	}
+	h.mu.Unlock()
+
+	if deliver {
+		h.deliverCommits()
+	}
+}
+
+// deliverCommits passes queued blocks to the commit callback without the lock
+// held. Only one caller delivers at a time, which keeps blocks in chain order
+// and lets the callback call back into the node.
+func (h *HotStuffConsensus) deliverCommits() {
+	for {
+		h.mu.Lock()
+		blocks, onCommit := h.commitQueue, h.onCommit
+		h.commitQueue = nil
+		if len(blocks) == 0 {
+			h.delivering = false
+			h.mu.Unlock()
+			return
+		}
+		h.mu.Unlock()
+
+		if onCommit == nil {
+			continue
+		}
+		for _, b := range blocks {
+			onCommit(b)
+		}
+	}
+}
+
+// send stamps the message with our ID and hands it to the transport
+func (h *HotStuffConsensus) send(msg *Message) {
+	msg.From = h.id
+	if msg.To == h.id {
+		h.selfQueue = append(h.selfQueue, msg)
+		return
+	}
+	if h.transport != nil {
+		h.transport.Send(msg)
+	}
+}
 
-	h.view++
-	// Send NewView message
+// broadcast sends the message to every replica, including ourselves
+func (h *HotStuffConsensus) broadcast(msg *Message) {
+	for _, id := range h.replicas {
+		m := *msg
+		m.To = id
+		h.send(&m)
+	}
+}
+
+// leader returns the leader of a view
+func (h *HotStuffConsensus) leader(view uint64) string {
+	return h.election.Leader(view)
+}
+
+// quorum returns the number of votes needed for a QC: n - f, where the
+// replicas tolerate f = (n-1)/3 Byzantine faults
+func (h *HotStuffConsensus) quorum() int {
+	n := len(h.replicas)
+	return n - (n-1)/3
 }
 
 // BeginBlock implements ConsensusEngine
-func (h *HotStuffConsensus) BeginBlock(ctx sdk.Context) {
-	// No-op for now
+func (h *HotStuffConsensus) BeginBlock(ctx sdk.Context) error {
+	return h.loadParams(ctx)
 }
 
 // EndBlock implements ConsensusEngine
-func (h *HotStuffConsensus) EndBlock(ctx sdk.Context) []abci.ValidatorUpdate {
+func (h *HotStuffConsensus) EndBlock(ctx sdk.Context) ([]abci.ValidatorUpdate, error) {
 	// No-op for now
-	return nil
+	return nil, nil
 }
@@ -0,0 +1,221 @@
+package hotstuff
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+func TestHotStuff_CommitsInOrder(t *testing.T) {
+	net := newSimNetwork(4)
+	leader := net.nodes[net.order[1]] // Leads view 1
+
+	var want []string
+	for i := 0; i < 5; i++ {
+		want = append(want, fmt.Sprintf("tx%d", i))
+		require.NoError(t, leader.Propose([]byte(want[i])))
+	}
+	net.run(0)
+
+	for _, id := range net.order {
+		assert.Equal(t, want, net.payloads(id), id)
+
+		// Every block is delivered once, parent first
+		blocks := net.committed[id]
+		for i, b := range blocks {
+			assert.Equal(t, uint64(i+1), b.Height, id)
+			if i > 0 {
+				assert.Equal(t, blocks[i-1].Hash(), b.Parent, id)
+			}
+		}
+		assert.Equal(t, blocks[len(blocks)-1], net.nodes[id].Committed(), id)
+	}
+
+	// Idle replicas stop proposing once everything committed
+	assert.Empty(t, net.queue)
+}
+
+func TestHotStuff_ThreeChainLocksAndCommits(t *testing.T) {
+	net := newSimNetwork(4)
+	require.NoError(t, net.nodes["node1"].Propose([]byte("tx")))
+	net.run(0)
+
+	// The payload block commits once three consecutive views certify it; the
+	// replica locks on the second and the high QC covers the third
+	node := net.nodes["node0"]
+	committed := node.Committed()
+	assert.Equal(t, "tx", string(committed.Payload))
+	assert.Equal(t, committed.View+1, node.LockedQC().View)
+	assert.Equal(t, committed.View+2, node.HighQC().View)
+	assert.GreaterOrEqual(t, len(node.HighQC().Signers), node.quorum())
+}
+
+func TestHotStuff_ViewTimeoutRotatesLeader(t *testing.T) {
+	net := newSimNetwork(4)
+
+	// Payloads submitted to replicas that don't lead the current view wait
+	// until a timeout makes them leader
+	for _, id := range net.order {
+		require.NoError(t, net.nodes[id].Propose([]byte("tx-"+id)))
+	}
+	net.run(20 * timeoutTicks)
+
+	want := net.payloads("node0")
+	assert.ElementsMatch(t, []string{"tx-node0", "tx-node1", "tx-node2", "tx-node3"}, want)
+	for _, id := range net.order {
+		assert.Equal(t, want, net.payloads(id), id)
+	}
+}
+
+func TestHotStuff_ToleratesCrashedReplica(t *testing.T) {
+	// Committing needs three consecutive certified views, so the crashed
+	// leader must leave runs of at least four correct leaders
+	net := newSimNetwork(7)
+	net.isolate("node6")
+	live := net.order[:6]
+
+	// node5's votes go to the crashed leader of the following view, so its
+	// own blocks are never certified under round-robin rotation
+	for i := 0; i < 2; i++ {
+		for _, id := range live[:5] {
+			require.NoError(t, net.nodes[id].Propose([]byte(fmt.Sprintf("tx-%s-%d", id, i))))
+		}
+	}
+	net.run(100 * timeoutTicks)
+
+	want := net.payloads("node0")
+	assert.Len(t, want, 10)
+	for _, id := range live {
+		assert.Equal(t, want, net.payloads(id), id)
+	}
+	assert.Empty(t, net.payloads("node6"))
+}
+
+func TestHotStuff_VoteRespectsLock(t *testing.T) {
+	net := newSimNetwork(4)
+	require.NoError(t, net.nodes["node1"].Propose([]byte("tx")))
+	net.run(0)
+
+	node := net.nodes["node0"]
+	locked := node.LockedQC()
+	require.NotZero(t, locked.View)
+
+	// A fork from genesis, entering its view through a TC, carries a QC
+	// older than the lock and is refused
+	view := node.View() + 1
+	genesis := node.genesis
+	signers := []string{"node0", "node1", "node2"}
+	tc := &TimeoutCert{View: view - 1, HighQC: genesisQC(genesis), Signers: signers, HighQCViews: []uint64{0, 0, 0}}
+	fork := &Block{View: view, Height: 1, Parent: genesis.Hash(), Justify: genesisQC(genesis), Proposer: node.leader(view)}
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeProposal, From: fork.Proposer, To: "node0", View: view, TC: tc, Block: fork}))
+	assert.Equal(t, view, node.View())
+	assert.Empty(t, net.queue, "no vote against the lock")
+
+	// A block extending the lock gets the vote, once per view
+	view++
+	high := node.HighQC().View
+	tc = &TimeoutCert{View: view - 1, HighQC: node.HighQC(), Signers: signers, HighQCViews: []uint64{high, high, high}}
+	parent := node.blocks[node.HighQC().BlockHash]
+	ok := &Block{View: view, Height: parent.Height + 1, Parent: parent.Hash(), Justify: node.HighQC(), Proposer: node.leader(view)}
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeProposal, From: ok.Proposer, To: "node0", View: view, TC: tc, Block: ok}))
+	require.Len(t, net.queue, 1)
+	assert.Equal(t, MessageTypeVote, net.queue[0].Type)
+
+	twin := *ok
+	twin.Payload = []byte("equivocation")
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeProposal, From: twin.Proposer, To: "node0", View: view, TC: tc, Block: &twin}))
+	assert.Len(t, net.queue, 1, "voted once in the view")
+}
+
+func TestHotStuff_RejectsInvalidProposals(t *testing.T) {
+	node := NewHotStuffNode("node0", []string{"node1", "node2", "node3"})
+	genesis := node.genesis
+
+	block := &Block{View: 1, Height: 1, Parent: genesis.Hash(), Justify: genesisQC(genesis), Proposer: "node2"}
+	err := node.HandleMessage(&Message{Type: MessageTypeProposal, From: "node2", Block: block})
+	assert.ErrorContains(t, err, "expected leader")
+
+	weakQC := &QuorumCert{View: 1, BlockHash: genesis.Hash(), Signers: []string{"node0", "node1"}}
+	block = &Block{View: 2, Height: 1, Parent: genesis.Hash(), Justify: weakQC, Proposer: "node2"}
+	err = node.HandleMessage(&Message{Type: MessageTypeProposal, From: "node2", Block: block})
+	assert.ErrorContains(t, err, "need 3")
+
+	block = &Block{View: 1, Height: 2, Parent: genesis.Hash(), Justify: genesisQC(genesis), Proposer: "node1"}
+	err = node.HandleMessage(&Message{Type: MessageTypeProposal, From: "node1", Block: block})
+	assert.ErrorContains(t, err, "does not extend")
+}
+
+func TestHotStuff_SingleReplica(t *testing.T) {
+	node := NewHotStuffConsensus()
+	var committed []string
+	node.SetCommitCallback(func(b *Block) {
+		if len(b.Payload) > 0 {
+			committed = append(committed, string(b.Payload))
+		}
+	})
+
+	require.NoError(t, node.Propose([]byte("a")))
+	require.NoError(t, node.Propose([]byte("b")))
+	assert.Equal(t, []string{"a", "b"}, committed)
+}
+
+func TestHotStuff_Lifecycle(t *testing.T) {
+	node := NewHotStuffConsensus()
+	assert.Equal(t, "hotstuff", node.Name())
+	assert.ErrorContains(t, node.Health(), "not running")
+
+	require.NoError(t, node.Start(context.Background()))
+	assert.Error(t, node.Start(context.Background()))
+	assert.NoError(t, node.Health())
+
+	// A single replica commits on its own
+	require.NoError(t, node.Propose([]byte("tx")))
+	assert.Eventually(t, func() bool { return node.Status().Committed > 0 }, time.Second, time.Millisecond)
+	status := node.Status()
+	assert.True(t, status.Running)
+	assert.Equal(t, node.ID(), status.Leader)
+
+	require.NoError(t, node.Stop())
+	assert.False(t, node.Status().Running)
+}
+
+func TestHotStuff_TracesProposals(t *testing.T) {
+	net := newSimNetwork(4)
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer(common.TracerName)
+	for _, node := range net.nodes {
+		node.SetTracer(tracer)
+	}
+
+	leader := net.nodes[net.order[1]] // Leads view 1
+	require.NoError(t, leader.Propose([]byte("tx")))
+	leaderView := leader.viewSpan.SpanContext()
+	net.run(0)
+
+	// Every replica's handling of the view 1 proposal joins the leader's view
+	// span, and links to the replica's own
+	var replicas []string
+	for _, s := range rec.Ended() {
+		if s.Name() != "hotstuff.proposal" || !slices.Contains(s.Attributes(), common.AttributeView.Int64(1)) {
+			continue
+		}
+		assert.Equal(t, leaderView.TraceID(), s.SpanContext().TraceID())
+		assert.Equal(t, leaderView.SpanID(), s.Parent().SpanID())
+		require.Len(t, s.Links(), 1)
+		for _, kv := range s.Attributes() {
+			if kv.Key == common.AttributeNodeID {
+				replicas = append(replicas, kv.Value.AsString())
+			}
+		}
+	}
+	assert.ElementsMatch(t, net.order, replicas)
+}
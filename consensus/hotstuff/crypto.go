@@ -0,0 +1,191 @@
+package hotstuff
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/cloudflare/circl/ecc/bls12381"
+	"github.com/cloudflare/circl/sign/bls"
+)
+
+// Domain separation tags for everything a replica signs
+const (
+	popDomain     = "HCP-HOTSTUFF-POP"
+	voteDomain    = "HCP-HOTSTUFF-VOTE"
+	timeoutDomain = "HCP-HOTSTUFF-TIMEOUT"
+)
+
+// blsScheme selects BLS12-381 with public keys in G1 and signatures in G2,
+// which keeps the aggregated public key cheap to compute
+type blsScheme = bls.KeyG1SigG2
+
+// PrivateKey holds a replica's signing keys: a BLS key for aggregate QCs and
+// an Ed25519 key for the multi-signature fallback
+type PrivateKey struct {
+	bls     *bls.PrivateKey[blsScheme]
+	ed25519 ed25519.PrivateKey
+}
+
+// GenerateKey derives a replica's keys from a seed of at least 32 bytes
+func GenerateKey(seed []byte) (*PrivateKey, error) {
+	if len(seed) < 32 {
+		return nil, fmt.Errorf("key seed must be at least 32 bytes, got %d", len(seed))
+	}
+	blsKey, err := bls.KeyGen[blsScheme](seed, nil, []byte(popDomain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive BLS key: %w", err)
+	}
+	edSeed := sha256.Sum256(append([]byte(voteDomain), seed...))
+	return &PrivateKey{bls: blsKey, ed25519: ed25519.NewKeyFromSeed(edSeed[:])}, nil
+}
+
+// Validator returns the public identity of the key holder, including a BLS
+// proof of possession
+func (k *PrivateKey) Validator(id string) (Validator, error) {
+	pub, err := k.bls.PublicKey().MarshalBinary()
+	if err != nil {
+		return Validator{}, err
+	}
+	return Validator{
+		ID:         id,
+		BLSKey:     pub,
+		PoP:        bls.Sign(k.bls, popMessage(pub)),
+		Ed25519Key: k.ed25519.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+// sign signs msg under the given QC scheme
+func (k *PrivateKey) sign(scheme QCScheme, msg []byte) []byte {
+	if scheme == QCSchemeMultiSig {
+		return ed25519.Sign(k.ed25519, msg)
+	}
+	return bls.Sign(k.bls, msg)
+}
+
+// Validator is a replica's public signing identity
+type Validator struct {
+	ID         string
+	BLSKey     []byte // Compressed G1 point
+	PoP        []byte // BLS signature over the public key
+	Ed25519Key ed25519.PublicKey
+}
+
+// ValidatorSet holds the verified keys of every replica, sorted by ID
+type ValidatorSet struct {
+	validators []Validator
+	index      map[string]int
+	points     []bls12381.G1 // Parsed BLS keys, aligned with validators
+}
+
+// NewValidatorSet checks each validator's keys and proof of possession. The
+// proof rules out rogue-key attacks on aggregated public keys.
+func NewValidatorSet(vals []Validator) (*ValidatorSet, error) {
+	vs := &ValidatorSet{
+		validators: append([]Validator(nil), vals...),
+		index:      make(map[string]int, len(vals)),
+		points:     make([]bls12381.G1, len(vals)),
+	}
+	sort.Slice(vs.validators, func(i, j int) bool { return vs.validators[i].ID < vs.validators[j].ID })
+
+	for i, v := range vs.validators {
+		if _, ok := vs.index[v.ID]; ok {
+			return nil, fmt.Errorf("duplicate validator %s", v.ID)
+		}
+		if len(v.Ed25519Key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("validator %s has an invalid Ed25519 key", v.ID)
+		}
+		var pub bls.PublicKey[blsScheme]
+		if err := pub.UnmarshalBinary(v.BLSKey); err != nil {
+			return nil, fmt.Errorf("validator %s has an invalid BLS key: %w", v.ID, err)
+		}
+		if !bls.Verify(&pub, popMessage(v.BLSKey), v.PoP) {
+			return nil, fmt.Errorf("validator %s has an invalid proof of possession", v.ID)
+		}
+		if err := vs.points[i].SetBytes(v.BLSKey); err != nil {
+			return nil, fmt.Errorf("validator %s has an invalid BLS key: %w", v.ID, err)
+		}
+		vs.index[v.ID] = i
+	}
+	return vs, nil
+}
+
+// Size returns the number of validators
+func (vs *ValidatorSet) Size() int {
+	return len(vs.validators)
+}
+
+// IDs returns the validator IDs in sorted order
+func (vs *ValidatorSet) IDs() []string {
+	ids := make([]string, len(vs.validators))
+	for i, v := range vs.validators {
+		ids[i] = v.ID
+	}
+	return ids
+}
+
+// verifySignature checks a single replica's signature under the given scheme
+func (vs *ValidatorSet) verifySignature(scheme QCScheme, id string, msg, sig []byte) error {
+	i, ok := vs.index[id]
+	if !ok {
+		return fmt.Errorf("unknown validator %s", id)
+	}
+	if scheme == QCSchemeMultiSig {
+		if !ed25519.Verify(vs.validators[i].Ed25519Key, msg, sig) {
+			return fmt.Errorf("invalid signature from %s", id)
+		}
+		return nil
+	}
+	var pub bls.PublicKey[blsScheme]
+	if err := pub.UnmarshalBinary(vs.validators[i].BLSKey); err != nil {
+		return err
+	}
+	if !bls.Verify(&pub, msg, sig) {
+		return fmt.Errorf("invalid signature from %s", id)
+	}
+	return nil
+}
+
+// aggregateKey sums the BLS keys of the given validators
+func (vs *ValidatorSet) aggregateKey(ids []string) (*bls.PublicKey[blsScheme], error) {
+	var sum bls12381.G1
+	sum.SetIdentity()
+	for _, id := range ids {
+		i, ok := vs.index[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown validator %s", id)
+		}
+		sum.Add(&sum, &vs.points[i])
+	}
+	if sum.IsIdentity() {
+		return nil, fmt.Errorf("aggregated key is the identity")
+	}
+	var pub bls.PublicKey[blsScheme]
+	if err := pub.UnmarshalBinary(sum.BytesCompressed()); err != nil {
+		return nil, err
+	}
+	return &pub, nil
+}
+
+func popMessage(pub []byte) []byte {
+	return append([]byte(popDomain), pub...)
+}
+
+// voteMessage is what replicas sign when voting for a block in a view
+func voteMessage(view uint64, hash Hash) []byte {
+	msg := make([]byte, 0, len(voteDomain)+8+len(hash))
+	msg = append(msg, voteDomain...)
+	msg = binary.BigEndian.AppendUint64(msg, view)
+	return append(msg, hash[:]...)
+}
+
+// timeoutMessage is what replicas sign when giving up on a view, along with
+// the view of their highest QC
+func timeoutMessage(view, highQCView uint64) []byte {
+	msg := make([]byte, 0, len(timeoutDomain)+16)
+	msg = append(msg, timeoutDomain...)
+	msg = binary.BigEndian.AppendUint64(msg, view)
+	return binary.BigEndian.AppendUint64(msg, highQCView)
+}
@@ -0,0 +1,35 @@
+package hotstuff
+
+import "github.com/fffeng99999/hcp-consensus/consensus/common"
+
+func init() {
+	common.RegisterEngine(EngineName, newEngine)
+}
+
+// newEngine builds a HotStuff replica with the pacemaker configured from the
+// [hotstuff] section. Its leader rotation is read from the store.
+func newEngine(deps common.Dependencies) (common.ConsensusEngine, error) {
+	engine := NewHotStuffConsensus()
+	if deps.AppOptions != nil {
+		cfg, err := ConfigFromAppOptions(deps.AppOptions)
+		if err != nil {
+			return nil, err
+		}
+		if err := engine.SetConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if deps.StoreKey != nil {
+		engine.SetStoreKey(deps.StoreKey)
+	}
+	if deps.Metrics != nil {
+		engine.SetMetrics(deps.Metrics.HotStuff)
+	}
+	if deps.Logger != nil {
+		engine.SetLogger(deps.Logger)
+	}
+	if deps.TracerProvider != nil {
+		engine.SetTracer(deps.TracerProvider.Tracer(common.TracerName))
+	}
+	return engine, nil
+}
@@ -0,0 +1,40 @@
+package hotstuff
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ModuleName is the name used for the HotStuff genesis section
+const ModuleName = EngineName
+
+// GenesisState defines the HotStuff parameters carried in the app genesis
+type GenesisState struct {
+	Params Params `json:"params"`
+}
+
+// DefaultGenesis returns the default HotStuff genesis state
+func DefaultGenesis() *GenesisState {
+	return &GenesisState{Params: DefaultParams()}
+}
+
+// Validate performs basic validation of the HotStuff genesis state
+func (gs GenesisState) Validate() error {
+	if err := gs.Params.Validate(); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	return nil
+}
+
+// UnmarshalGenesis decodes a raw genesis message, treating an empty message
+// as the default
+func UnmarshalGenesis(bz json.RawMessage) (*GenesisState, error) {
+	gs := DefaultGenesis()
+	if len(bz) == 0 || string(bz) == "null" {
+		return gs, nil
+	}
+	if err := json.Unmarshal(bz, gs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s genesis state: %w", ModuleName, err)
+	}
+	return gs, nil
+}
@@ -0,0 +1,45 @@
+package hotstuff
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// ImportHandoff implements common.HandoffImporter. Trust scores seed a
+// reputation election if one is set, and are passed on to the next engine
+// unchanged.
+func (h *HotStuffConsensus) ImportHandoff(ctx sdk.Context, state common.HandoffState) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(state.Trust) == 0 {
+		return nil
+	}
+	gs, err := tpbft.UnmarshalGenesis(state.Trust)
+	if err != nil {
+		return err
+	}
+	if err := gs.Validate(); err != nil {
+		return err
+	}
+
+	if e, ok := h.election.(*ReputationElection); ok && e.scorer != nil {
+		e.scorer.ImportGenesis(gs)
+		e.reset()
+	}
+	h.trust = state.Trust
+	return nil
+}
+
+// ExportHandoff implements common.HandoffExporter. It passes on the trust
+// scores the engine was handed: those a reputation election updates from
+// the replica's commits differ between nodes, so they stay out of the
+// replicated state.
+func (h *HotStuffConsensus) ExportHandoff(ctx sdk.Context) (common.HandoffState, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return common.HandoffState{From: EngineName, Height: ctx.BlockHeight(), Trust: h.trust}, nil
+}
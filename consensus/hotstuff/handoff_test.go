@@ -0,0 +1,50 @@
+package hotstuff
+
+import (
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+func TestHotStuff_Handoff(t *testing.T) {
+	ctx := sdk.Context{}.WithBlockHeight(10)
+	gs := &tpbft.GenesisState{Scores: []tpbft.TrustScoreRecord{
+		{ValidatorAddress: "node0", TotalScore: 0.1},
+		{ValidatorAddress: "node1", TotalScore: 0.9},
+	}}
+	trust, err := json.Marshal(gs)
+	require.NoError(t, err)
+	state := common.HandoffState{From: tpbft.EngineName, Height: 10, Trust: trust}
+
+	// Trust scores seed a reputation election
+	h := NewHotStuffNode("node0", []string{"node1", "node2", "node3"})
+	scorer := tpbft.NewTrustScorer()
+	h.SetLeaderElection(NewReputationElection(h.replicas, nil, scorer, 0.5))
+	require.NoError(t, h.ImportHandoff(ctx, state))
+	assert.Equal(t, 0.9, scorer.GetScore("node1").TotalScore)
+
+	// What the election learns from the replica's own commits is not handed
+	// on, as it differs between nodes
+	h.election.(*ReputationElection).OnCommit(&Block{View: 1, Proposer: "node0"}, nil)
+	assert.NotEqual(t, 0.1, scorer.GetScore("node0").TotalScore)
+	exported, err := h.ExportHandoff(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, EngineName, exported.From)
+	assert.Equal(t, json.RawMessage(trust), exported.Trust)
+
+	// Without one they are passed on unchanged
+	plain := NewHotStuffConsensus()
+	require.NoError(t, plain.ImportHandoff(ctx, state))
+	exported, err = plain.ExportHandoff(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, json.RawMessage(trust), exported.Trust)
+
+	state.Trust = json.RawMessage(`{"scores":[{"validator_address":""}]}`)
+	assert.Error(t, plain.ImportHandoff(ctx, state))
+}
@@ -0,0 +1,241 @@
+package hotstuff
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// LeaderElection picks the leader of each view. Replicas must agree on the
+// leader of a view, so strategies may only depend on the view and on the
+// committed chain.
+type LeaderElection interface {
+	Leader(view uint64) string
+}
+
+// CommitObserver is implemented by strategies that learn from the committed
+// chain. The engine calls OnCommit for every committed block in chain order,
+// with the views between its parent's and its own that certified no block on
+// the chain.
+type CommitObserver interface {
+	OnCommit(b *Block, failedViews []uint64)
+}
+
+// NewLeaderElection builds the leader rotation p names over the replicas.
+// Stakes weigh replicas under stake and reputation, equally if nil, and
+// reputation ranks replicas by the scores in scorer.
+func NewLeaderElection(p Params, replicas []string, stakes map[string]uint64, scorer *tpbft.TrustScorer) (LeaderElection, error) {
+	switch p.LeaderElection {
+	case "", LeaderElectionRoundRobin:
+		return NewRoundRobinElection(replicas), nil
+	case LeaderElectionStake:
+		if stakes == nil {
+			stakes = make(map[string]uint64, len(replicas))
+			for _, id := range replicas {
+				stakes[id] = 1
+			}
+		}
+		return NewStakeWeightedElection(stakes)
+	case LeaderElectionReputation:
+		return NewReputationElection(replicas, stakes, scorer, p.LeaderMinTrust), nil
+	}
+	return nil, fmt.Errorf("unknown leader election %q", p.LeaderElection)
+}
+
+// RoundRobinElection rotates the leader through the replicas by view
+type RoundRobinElection struct {
+	replicas []string
+}
+
+// NewRoundRobinElection creates a round-robin election over the replicas
+func NewRoundRobinElection(replicas []string) *RoundRobinElection {
+	sorted := append([]string(nil), replicas...)
+	sort.Strings(sorted)
+	return &RoundRobinElection{replicas: sorted}
+}
+
+// Leader implements LeaderElection
+func (e *RoundRobinElection) Leader(view uint64) string {
+	return e.replicas[view%uint64(len(e.replicas))]
+}
+
+// StakeWeightedElection draws each view's leader with probability
+// proportional to stake, from a hash of the view so every replica draws the
+// same one
+type StakeWeightedElection struct {
+	replicas []string // Sorted
+	cumStake []uint64 // Running stake totals, aligned with replicas
+}
+
+// NewStakeWeightedElection creates a stake-weighted election. Replicas with
+// zero stake never lead.
+func NewStakeWeightedElection(stakes map[string]uint64) (*StakeWeightedElection, error) {
+	e := &StakeWeightedElection{}
+	for id := range stakes {
+		e.replicas = append(e.replicas, id)
+	}
+	sort.Strings(e.replicas)
+
+	var total uint64
+	for _, id := range e.replicas {
+		if total+stakes[id] < total {
+			return nil, fmt.Errorf("total stake overflows")
+		}
+		total += stakes[id]
+		e.cumStake = append(e.cumStake, total)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("stake-weighted election needs a replica with stake")
+	}
+	return e, nil
+}
+
+// Leader implements LeaderElection
+func (e *StakeWeightedElection) Leader(view uint64) string {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], view)
+	sum := sha256.Sum256(append([]byte("HCP-HOTSTUFF-LEADER"), buf[:]...))
+
+	// The modulo bias is negligible for stakes far below 2^64
+	total := e.cumStake[len(e.cumStake)-1]
+	point := binary.BigEndian.Uint64(sum[:8]) % total
+	i := sort.Search(len(e.cumStake), func(i int) bool { return e.cumStake[i] > point })
+	return e.replicas[i]
+}
+
+// ReputationLag is the number of views after a committed block before the
+// trust it changed moves leaders. A block commits a few views after its own
+// once the chain is healthy, so replicas keeping up with commits have all
+// applied it by then.
+const ReputationLag = 10
+
+// ReputationElection rotates the leader among replicas trusted by a tPBFT
+// TrustScorer. Each committed block counts as a success for its proposer and
+// for the replicas whose votes certified its parent. Views that certified
+// nothing count as failures for their leaders, except the first of several in
+// a row: votes for its block go to the next leader, which may be the one at
+// fault. Replicas below the trust threshold stop leading, as in tPBFT's
+// validator selection.
+//
+// The ranking after the block of view v commits applies from view
+// v+ReputationLag, so the leader of a view depends only on the blocks
+// committed at least ReputationLag views earlier; replicas that have
+// committed those agree on it. It is not safe for concurrent use.
+type ReputationElection struct {
+	replicas []string // Sorted
+	stakes   map[string]float64
+	total    float64
+	scorer   *tpbft.TrustScorer
+	minTrust float64
+
+	// Trusted replicas in rotation order, by the view they apply from. The
+	// first applies to every earlier view.
+	rankings []ranking
+}
+
+// ranking is the rotation of trusted replicas from a view on
+type ranking struct {
+	from       uint64
+	candidates []string
+}
+
+// NewReputationElection creates a reputation-based election over the
+// replicas. A nil stakes map weighs replicas equally; a scorer shared with a
+// tPBFT engine lets both engines rank validators on the same history.
+func NewReputationElection(replicas []string, stakes map[string]uint64, scorer *tpbft.TrustScorer, minTrust float64) *ReputationElection {
+	e := &ReputationElection{
+		replicas: append([]string(nil), replicas...),
+		stakes:   make(map[string]float64, len(replicas)),
+		scorer:   scorer,
+		minTrust: minTrust,
+	}
+	sort.Strings(e.replicas)
+	for _, id := range e.replicas {
+		stake := 1.0
+		if stakes != nil {
+			stake = float64(stakes[id])
+		}
+		e.stakes[id] = stake
+		e.total += stake
+	}
+	e.reset()
+	return e
+}
+
+// Leader implements LeaderElection
+func (e *ReputationElection) Leader(view uint64) string {
+	candidates := e.rankings[0].candidates
+	for _, r := range e.rankings[1:] {
+		if r.from > view {
+			break
+		}
+		candidates = r.candidates
+	}
+	return candidates[view%uint64(len(candidates))]
+}
+
+// Candidates returns the replicas eligible to lead by the latest ranking,
+// which may not apply yet
+func (e *ReputationElection) Candidates() []string {
+	return append([]string(nil), e.rankings[len(e.rankings)-1].candidates...)
+}
+
+// OnCommit implements CommitObserver
+func (e *ReputationElection) OnCommit(b *Block, failedViews []uint64) {
+	blamed := failedViews
+	if len(blamed) > 1 {
+		blamed = blamed[1:]
+	}
+	for _, view := range blamed {
+		e.record(e.Leader(view), false)
+	}
+	e.record(b.Proposer, true)
+	if b.Justify != nil {
+		for _, id := range b.Justify.Signers {
+			if id != b.Proposer {
+				e.record(id, true)
+			}
+		}
+	}
+
+	e.rankings = append(e.rankings, ranking{from: b.View + ReputationLag, candidates: e.rank()})
+	// Later commits only ask about views after b's, so only the last
+	// ranking applying by then is still needed before the pending ones
+	for len(e.rankings) > 1 && e.rankings[1].from <= b.View {
+		e.rankings = e.rankings[1:]
+	}
+}
+
+// reset makes the current scores rank the replicas for every view, as when
+// they were imported ahead of the first view
+func (e *ReputationElection) reset() {
+	e.rankings = []ranking{{candidates: e.rank()}}
+}
+
+func (e *ReputationElection) record(id string, success bool) {
+	if _, ok := e.stakes[id]; !ok {
+		return
+	}
+	e.scorer.UpdateScore(id, success, 0, e.stakes[id], e.total)
+}
+
+// rank returns the replicas meeting the trust threshold, or every replica if
+// fewer than a quorum do, so the rotation never shrinks below a quorum
+func (e *ReputationElection) rank() []string {
+	n := len(e.replicas)
+	quorum := n - (n-1)/3
+
+	var trusted []string
+	for _, id := range e.replicas {
+		if e.scorer.GetScore(id).TotalScore >= e.minTrust {
+			trusted = append(trusted, id)
+		}
+	}
+	if len(trusted) < quorum {
+		return e.replicas
+	}
+	return trusted
+}
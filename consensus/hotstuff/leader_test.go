@@ -0,0 +1,183 @@
+package hotstuff
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	storetypes "cosmossdk.io/store/types"
+	"github.com/cosmos/cosmos-sdk/testutil"
+	simtestutil "github.com/cosmos/cosmos-sdk/testutil/sims"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+func TestRoundRobinElection(t *testing.T) {
+	e := NewRoundRobinElection([]string{"c", "a", "b"})
+	var leaders []string
+	for view := uint64(0); view < 6; view++ {
+		leaders = append(leaders, e.Leader(view))
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, leaders)
+}
+
+func TestStakeWeightedElection(t *testing.T) {
+	_, err := NewStakeWeightedElection(map[string]uint64{"a": 0})
+	assert.Error(t, err)
+
+	e, err := NewStakeWeightedElection(map[string]uint64{"a": 1, "b": 3, "c": 0})
+	require.NoError(t, err)
+
+	counts := make(map[string]int)
+	for view := uint64(0); view < 4000; view++ {
+		counts[e.Leader(view)]++
+	}
+	assert.Zero(t, counts["c"])
+	assert.InDelta(t, 1000, counts["a"], 150)
+	assert.InDelta(t, 3000, counts["b"], 150)
+
+	// Every replica draws the same leader
+	other, err := NewStakeWeightedElection(map[string]uint64{"c": 0, "b": 3, "a": 1})
+	require.NoError(t, err)
+	for view := uint64(0); view < 100; view++ {
+		assert.Equal(t, e.Leader(view), other.Leader(view))
+	}
+}
+
+func TestReputationElection_DropsFailingLeaders(t *testing.T) {
+	replicas := []string{"a", "b", "c", "d"}
+	e := NewReputationElection(replicas, nil, tpbft.NewTrustScorer(), 0.6)
+	assert.Equal(t, replicas, e.Candidates())
+
+	// View 3, led by d, certified nothing
+	require.Equal(t, "d", e.Leader(3))
+	qc := &QuorumCert{View: 2, Signers: []string{"a", "b", "c"}}
+	e.OnCommit(&Block{View: 4, Proposer: "a", Justify: qc}, []uint64{3})
+	assert.Equal(t, []string{"a", "b", "c"}, e.Candidates())
+
+	// d comes back by voting
+	for view := uint64(5); view < 10; view++ {
+		qc := &QuorumCert{View: view - 1, Signers: []string{"b", "c", "d"}}
+		e.OnCommit(&Block{View: view, Proposer: "a", Justify: qc}, nil)
+	}
+	assert.Equal(t, replicas, e.Candidates())
+
+	// The rotation never drops below a quorum
+	for view := uint64(10); view < 20; view++ {
+		e.OnCommit(&Block{View: view + 1, Proposer: "a", Justify: &QuorumCert{}}, []uint64{view})
+	}
+	assert.GreaterOrEqual(t, len(e.Candidates()), 3)
+}
+
+func TestReputationElection_LeadersDependOnCommittedChain(t *testing.T) {
+	replicas := []string{"a", "b", "c", "d"}
+	asking := NewReputationElection(replicas, nil, tpbft.NewTrustScorer(), 0.6)
+	silent := NewReputationElection(replicas, nil, tpbft.NewTrustScorer(), 0.6)
+
+	// d fails its views; one replica asks for leaders all along, the other
+	// never does, and both commit the same chain
+	for view := uint64(3); view < 40; view += 4 {
+		for v := view; v < view+ReputationLag; v++ {
+			asking.Leader(v)
+		}
+		qc := &QuorumCert{View: view - 1, Signers: []string{"a", "b", "c"}}
+		b := &Block{View: view + 1, Proposer: "a", Justify: qc}
+		asking.OnCommit(b, []uint64{view})
+		silent.OnCommit(b, []uint64{view})
+	}
+	for view := uint64(0); view < 60; view++ {
+		assert.Equal(t, silent.Leader(view), asking.Leader(view), "view %d", view)
+	}
+
+	// The ranking that drops d applies ReputationLag views after the commit
+	e := NewReputationElection(replicas, nil, tpbft.NewTrustScorer(), 0.6)
+	before := e.Leader(4 + ReputationLag - 1)
+	require.Equal(t, "d", e.Leader(3))
+	e.OnCommit(&Block{View: 4, Proposer: "a", Justify: &QuorumCert{View: 2, Signers: []string{"a", "b", "c"}}}, []uint64{3})
+	assert.Equal(t, before, e.Leader(4+ReputationLag-1))
+	assert.NotEqual(t, "d", e.Leader(4+ReputationLag+3))
+
+	// Rankings no longer needed are dropped
+	assert.LessOrEqual(t, len(asking.rankings), ReputationLag/4+2)
+}
+
+func TestNewEngine_LeaderElection(t *testing.T) {
+	key := storetypes.NewKVStoreKey(tpbft.StoreKey)
+	ctx := testutil.DefaultContext(key, storetypes.NewTransientStoreKey("transient_test"))
+	for name, want := range map[string]LeaderElection{
+		LeaderElectionRoundRobin: &RoundRobinElection{},
+		LeaderElectionStake:      &StakeWeightedElection{},
+		LeaderElectionReputation: &ReputationElection{},
+	} {
+		// The rotation is chain state, so the node's configuration has no
+		// say in it
+		engine, err := newEngine(common.Dependencies{
+			StoreKey:   key,
+			AppOptions: simtestutil.AppOptionsMap{"hotstuff.leader_election": LeaderElectionStake},
+		})
+		require.NoError(t, err, name)
+		require.NoError(t, SetParams(ctx, key, Params{LeaderElection: name, LeaderMinTrust: 0.6}))
+		h := engine.(*HotStuffConsensus)
+		require.NoError(t, h.BeginBlock(ctx))
+		assert.IsType(t, want, h.election, name)
+		assert.Equal(t, h.id, h.Status().Leader, name)
+	}
+	assert.Error(t, SetParams(ctx, key, Params{LeaderElection: "random"}))
+
+	// Trust handed over at a switch reaches the reputation election, also
+	// when governance selects it later
+	trust, err := json.Marshal(&tpbft.GenesisState{Scores: []tpbft.TrustScoreRecord{{ValidatorAddress: "local-node", TotalScore: 0.9}}})
+	require.NoError(t, err)
+	for _, before := range []string{LeaderElectionReputation, LeaderElectionRoundRobin} {
+		require.NoError(t, SetParams(ctx, key, Params{LeaderElection: before}))
+		engine, err := newEngine(common.Dependencies{StoreKey: key})
+		require.NoError(t, err)
+		h := engine.(*HotStuffConsensus)
+		require.NoError(t, h.BeginBlock(ctx))
+		require.NoError(t, h.ImportHandoff(sdk.Context{}, common.HandoffState{From: tpbft.EngineName, Trust: trust}))
+		require.NoError(t, SetParams(ctx, key, Params{LeaderElection: LeaderElectionReputation}))
+		require.NoError(t, h.BeginBlock(ctx))
+		assert.Equal(t, 0.9, h.election.(*ReputationElection).scorer.GetScore("local-node").TotalScore, before)
+	}
+}
+
+func TestHotStuff_ReputationSkipsCrashedLeader(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		reputation bool
+	}{
+		{"round-robin", false},
+		{"reputation", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			net := newSimNetwork(7)
+			if tc.reputation {
+				for _, id := range net.order {
+					net.nodes[id].SetLeaderElection(NewReputationElection(net.order, nil, tpbft.NewTrustScorer(), 0.6))
+				}
+			}
+			net.isolate("node6")
+			live := net.order[:6]
+
+			for _, id := range live {
+				require.NoError(t, net.nodes[id].Propose([]byte(fmt.Sprintf("tx-%s", id))))
+			}
+			net.run(100 * timeoutTicks)
+
+			// Under round-robin node5's blocks need votes collected by the
+			// crashed node6 and never certify; reputation rotates node6 out
+			want := 5
+			if tc.reputation {
+				want = 6
+			}
+			assert.Len(t, net.payloads("node0"), want)
+			for _, id := range live {
+				assert.Equal(t, net.payloads("node0"), net.payloads(id), id)
+			}
+		})
+	}
+}
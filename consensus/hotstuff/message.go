@@ -0,0 +1,54 @@
+package hotstuff
+
+// MessageType represents the type of HotStuff message
+type MessageType int
+
+const (
+	MessageTypeProposal MessageType = iota
+	MessageTypeVote
+	MessageTypeTimeout // Broadcast when a replica gives up on a view
+)
+
+func (t MessageType) String() string {
+	switch t {
+	case MessageTypeProposal:
+		return "Proposal"
+	case MessageTypeVote:
+		return "Vote"
+	case MessageTypeTimeout:
+		return "Timeout"
+	}
+	return "Unknown"
+}
+
+// Message represents a HotStuff protocol message
+type Message struct {
+	Type MessageType
+	View uint64
+	From string
+	To   string
+
+	// Proposal: the proposed block, and the TC that ended the previous view
+	// when the block's justify QC does not
+	Block *Block
+	TC    *TimeoutCert
+
+	// Vote: the block voted for in View, signed when keys are configured
+	BlockHash Hash
+	Signature []byte // Also the signature of a timeout
+
+	// Timeout: the sender's highest QC. The sender signs View and the QC's
+	// view when keys are configured.
+	HighQC *QuorumCert
+
+	// W3C trace context: the leader's view span on proposals, the voter's
+	// proposal span on votes, and the sender's view span on timeouts
+	TraceContext map[string]string
+}
+
+// Transport delivers HotStuff messages to replicas. Send is called with the
+// node's lock held, so implementations must not block or call back into the
+// node.
+type Transport interface {
+	Send(msg *Message)
+}
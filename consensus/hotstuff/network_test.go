@@ -0,0 +1,105 @@
+package hotstuff
+
+import "fmt"
+
+// simNetwork is an in-memory transport that delivers messages in FIFO order
+// and can isolate replicas to simulate crashes and partitions
+type simNetwork struct {
+	nodes     map[string]*HotStuffConsensus
+	order     []string
+	queue     []*Message
+	isolated  map[string]bool
+	committed map[string][]*Block // Blocks delivered to each commit callback
+}
+
+func newSimNetwork(size int) *simNetwork {
+	n := &simNetwork{
+		nodes:     make(map[string]*HotStuffConsensus),
+		isolated:  make(map[string]bool),
+		committed: make(map[string][]*Block),
+	}
+
+	for i := 0; i < size; i++ {
+		n.order = append(n.order, fmt.Sprintf("node%d", i))
+	}
+	for _, id := range n.order {
+		var peers []string
+		for _, pid := range n.order {
+			if pid != id {
+				peers = append(peers, pid)
+			}
+		}
+
+		id := id
+		node := NewHotStuffNode(id, peers)
+		node.SetTransport(n)
+		node.SetCommitCallback(func(b *Block) {
+			n.committed[id] = append(n.committed[id], b)
+		})
+		n.nodes[id] = node
+	}
+	return n
+}
+
+// Send implements Transport
+func (n *simNetwork) Send(msg *Message) {
+	n.queue = append(n.queue, msg)
+}
+
+// deliver drains the queue, dropping messages to or from isolated replicas
+func (n *simNetwork) deliver() {
+	for len(n.queue) > 0 {
+		msg := n.queue[0]
+		n.queue = n.queue[1:]
+		if n.isolated[msg.From] || n.isolated[msg.To] {
+			continue
+		}
+		if node, ok := n.nodes[msg.To]; ok {
+			node.HandleMessage(msg)
+		}
+	}
+}
+
+// timeoutTicks is the number of ticks in a base view timeout
+const timeoutTicks = 100
+
+// run advances every live replica by the given number of ticks, delivering
+// messages after each tick
+func (n *simNetwork) run(ticks int) {
+	n.deliver()
+	for i := 0; i < ticks; i++ {
+		for _, id := range n.order {
+			if !n.isolated[id] {
+				n.nodes[id].tick()
+			}
+		}
+		n.deliver()
+	}
+}
+
+func (n *simNetwork) isolate(id string) { n.isolated[id] = true }
+
+// useKeys gives every replica deterministic keys, signing votes under scheme
+func (n *simNetwork) useKeys(scheme QCScheme) error {
+	keys, vs, err := testValidators(n.order)
+	if err != nil {
+		return err
+	}
+	for _, id := range n.order {
+		if err := n.nodes[id].SetKeys(keys[id], vs, scheme); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// payloads returns the non-empty payloads a replica committed, in order
+func (n *simNetwork) payloads(id string) []string {
+	var out []string
+	for _, b := range n.committed[id] {
+		if len(b.Payload) > 0 {
+			out = append(out, string(b.Payload))
+		}
+	}
+	return out
+}
@@ -0,0 +1,203 @@
+package hotstuff
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+// timeoutWindow bounds how far ahead of the current view timeouts are
+// collected. A replica further behind catches up through the QCs and TCs
+// carried by proposals.
+const timeoutWindow = 64
+
+// Tick advances the view timer by one tick interval. Start drives it from a
+// ticker; simulations call it on a virtual clock instead.
+func (h *HotStuffConsensus) Tick() {
+	h.tick()
+}
+
+// SetClock sets the clock QC latencies and span timestamps are measured on
+func (h *HotStuffConsensus) SetClock(now func() time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.now = now
+}
+
+// tick advances the view timer by one tick interval
+func (h *HotStuffConsensus) tick() {
+	h.process(func() {
+		h.viewElapsed += h.config.TickInterval
+		if h.viewElapsed >= h.viewTimeout() {
+			h.viewElapsed = 0
+			h.localTimeout()
+		}
+	})
+}
+
+// viewTimeout returns the current view's timeout, backing off exponentially
+// while views keep ending without a QC
+func (h *HotStuffConsensus) viewTimeout() time.Duration {
+	d := float64(h.config.BaseTimeout) * math.Pow(h.config.TimeoutMultiplier, float64(h.consecutiveTCs))
+	if d >= float64(h.config.MaxTimeout) {
+		return h.config.MaxTimeout
+	}
+	return time.Duration(d)
+}
+
+// localTimeout gives up on the current view. A replica with nothing to
+// commit lets the view lapse unless others are timing out too.
+func (h *HotStuffConsensus) localTimeout() {
+	if !h.hasWork() && len(h.timeouts[h.view]) == 0 {
+		return
+	}
+	// Repeated while the view lasts, in case earlier timeouts were lost. A
+	// failure to persist leaves the view running, and we retry next timeout.
+	_ = h.sendTimeout()
+}
+
+// sendTimeout broadcasts a timeout for the current view. We never vote in a
+// view after giving up on it.
+func (h *HotStuffConsensus) sendTimeout() error {
+	sig, err := h.safety.Timeout(h.view)
+	if err != nil {
+		return err
+	}
+	h.lastTimeoutView = h.view
+	h.broadcast(&Message{
+		Type:         MessageTypeTimeout,
+		View:         h.view,
+		HighQC:       h.safety.HighQC(),
+		Signature:    sig,
+		TraceContext: common.InjectTrace(h.viewCtx),
+	})
+	return nil
+}
+
+func (h *HotStuffConsensus) handleTimeout(msg *Message) error {
+	if !h.isReplica(msg.From) {
+		return fmt.Errorf("timeout from unknown replica %s", msg.From)
+	}
+	if msg.View < h.view {
+		return nil
+	}
+	if msg.View > h.view+timeoutWindow {
+		return fmt.Errorf("timeout from %s for view %d is too far ahead of view %d", msg.From, msg.View, h.view)
+	}
+	if err := h.verifyQC(msg.HighQC); err != nil {
+		return fmt.Errorf("timeout from %s: %w", msg.From, err)
+	}
+	if h.validators != nil {
+		if err := h.validators.verifySignature(h.scheme, msg.From, timeoutMessage(msg.View, msg.HighQC.View), msg.Signature); err != nil {
+			return fmt.Errorf("timeout for view %d: %w", msg.View, err)
+		}
+	}
+	if _, ok := h.blocks[msg.HighQC.BlockHash]; ok {
+		if err := h.safety.ObserveQC(msg.HighQC); err != nil {
+			return err
+		}
+	}
+
+	h.traceEvent(msg)
+	signers := h.timeouts[msg.View]
+	if signers == nil {
+		signers = make(map[string]Timeout)
+		h.timeouts[msg.View] = signers
+	}
+	signers[msg.From] = Timeout{HighQC: msg.HighQC, Signature: msg.Signature}
+
+	// f+1 timeouts include a correct replica, so join them even if our own
+	// timer has not fired; otherwise a TC may never form
+	if len(signers) > h.faulty() && h.lastTimeoutView < msg.View {
+		h.view = msg.View
+		h.viewElapsed = 0
+		h.viewStarted = h.now()
+		h.metrics.View.Set(float64(h.view))
+		h.startViewSpan("timeouts")
+		h.pruneTimeouts()
+		if err := h.sendTimeout(); err != nil {
+			return err
+		}
+	}
+
+	if len(signers) >= h.quorum() {
+		tc, err := h.newTC(msg.View, signers)
+		if err != nil {
+			return err
+		}
+		h.advanceView(msg.View+1, tc)
+	}
+	return nil
+}
+
+// newTC aggregates the timeouts collected for a view, and their signatures
+// when keys are configured
+func (h *HotStuffConsensus) newTC(view uint64, timeouts map[string]Timeout) (*TimeoutCert, error) {
+	if h.validators != nil {
+		return NewTimeoutCert(h.scheme, view, timeouts)
+	}
+	return unsignedTimeoutCert(view, timeouts), nil
+}
+
+// verifyTC checks that a TC is signed by a quorum of distinct replicas and
+// carries a valid QC
+func (h *HotStuffConsensus) verifyTC(tc *TimeoutCert) error {
+	if h.validators != nil {
+		if tc.Scheme != h.scheme {
+			return fmt.Errorf("TC for view %d uses scheme %s, expected %s", tc.View, tc.Scheme, h.scheme)
+		}
+		if err := tc.Verify(h.validators, h.quorum()); err != nil {
+			return err
+		}
+	} else if err := tc.checkSigners(h.isReplica, h.quorum()); err != nil {
+		return err
+	}
+	return h.verifyQC(tc.HighQC)
+}
+
+// advanceView enters a later view, ended either by a QC (tc == nil) or by a
+// TC. The new leader proposes at once after a TC, and after a QC while
+// there is work to commit.
+func (h *HotStuffConsensus) advanceView(view uint64, tc *TimeoutCert) {
+	if view <= h.view {
+		return
+	}
+
+	h.view = view
+	h.viewElapsed = 0
+	h.lastTC = tc
+	h.viewStarted = h.now()
+	h.metrics.View.Set(float64(view))
+	if tc != nil {
+		h.consecutiveTCs++
+		h.metrics.Timeouts.Add(1)
+		h.startViewSpan("tc")
+		h.logger.Info("view timed out", "view", tc.View, "next_timeout", h.viewTimeout())
+	} else {
+		h.consecutiveTCs = 0
+		h.startViewSpan("qc")
+		h.logger.Debug("entered view", "view", view)
+	}
+	h.pruneTimeouts()
+
+	if h.leader(view) == h.id && (tc != nil || h.hasWork()) {
+		h.propose()
+	}
+}
+
+// pruneTimeouts drops the timeouts collected for views before the current one
+func (h *HotStuffConsensus) pruneTimeouts() {
+	for v := range h.timeouts {
+		if v < h.view {
+			delete(h.timeouts, v)
+		}
+	}
+}
+
+// faulty returns the number of Byzantine replicas tolerated
+func (h *HotStuffConsensus) faulty() int {
+	return (len(h.replicas) - 1) / 3
+}
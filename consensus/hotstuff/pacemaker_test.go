@@ -0,0 +1,199 @@
+package hotstuff
+
+import (
+	"testing"
+	"time"
+
+	simtestutil "github.com/cosmos/cosmos-sdk/testutil/sims"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+func TestPacemaker_IdleReplicasKeepView(t *testing.T) {
+	net := newSimNetwork(4)
+	net.run(5 * timeoutTicks)
+
+	for _, id := range net.order {
+		assert.Equal(t, uint64(1), net.nodes[id].View(), id)
+	}
+	assert.Empty(t, net.queue)
+}
+
+func TestPacemaker_TimeoutCertificateEndsView(t *testing.T) {
+	net := newSimNetwork(4)
+	net.isolate("node1") // Leads view 1
+	require.NoError(t, net.nodes["node0"].Propose([]byte("tx")))
+
+	// The replica with work times out first; idle replicas join when their
+	// own timers fire, and the TC hands view 2 to node2, which proposes
+	// without waiting for work
+	net.run(2 * timeoutTicks)
+	for _, id := range []string{"node0", "node2"} {
+		node := net.nodes[id]
+		assert.Equal(t, uint64(2), node.View(), id)
+		require.NotNil(t, node.lastTC, id)
+		assert.Equal(t, uint64(1), node.lastTC.View, id)
+	}
+
+	// node3 certifies view 2 and enters view 3 by QC, which resets the
+	// back-off
+	node3 := net.nodes["node3"]
+	assert.Equal(t, uint64(3), node3.View())
+	assert.Equal(t, uint64(2), node3.HighQC().View)
+	assert.Zero(t, node3.consecutiveTCs)
+	assert.Equal(t, 1, net.nodes["node0"].consecutiveTCs)
+}
+
+func TestPacemaker_JoinsAfterFPlusOneTimeouts(t *testing.T) {
+	net := newSimNetwork(4)
+	node := net.nodes["node0"]
+	qc := node.HighQC()
+
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeTimeout, From: "node1", To: "node0", View: 1, HighQC: qc}))
+	assert.Empty(t, net.queue, "a single timeout may come from a faulty replica")
+
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeTimeout, From: "node2", To: "node0", View: 1, HighQC: qc}))
+	require.NotEmpty(t, net.queue)
+	assert.Equal(t, MessageTypeTimeout, net.queue[0].Type)
+	assert.Equal(t, "node0", net.queue[0].From)
+
+	// Its own timeout completes the quorum of three
+	assert.Equal(t, uint64(2), node.View())
+	assert.Equal(t, 1, node.consecutiveTCs)
+}
+
+func TestPacemaker_SignedTimeoutCerts(t *testing.T) {
+	for _, scheme := range []QCScheme{QCSchemeBLS, QCSchemeMultiSig} {
+		t.Run(scheme.String(), func(t *testing.T) {
+			net := newSimNetwork(4)
+			require.NoError(t, net.useKeys(scheme))
+			net.isolate("node1")
+			require.NoError(t, net.nodes["node0"].Propose([]byte("tx")))
+			net.run(2 * timeoutTicks)
+
+			// The signed TC ends view 1, and node3 certifies view 2
+			node := net.nodes["node0"]
+			require.NotNil(t, node.lastTC)
+			assert.Equal(t, uint64(1), node.lastTC.View)
+			assert.Equal(t, scheme, node.lastTC.Scheme)
+			assert.NoError(t, node.lastTC.Verify(node.validators, 3))
+			assert.Equal(t, uint64(2), net.nodes["node3"].HighQC().View)
+		})
+	}
+}
+
+func TestPacemaker_RejectsForgedTimeouts(t *testing.T) {
+	net := newSimNetwork(4)
+	require.NoError(t, net.useKeys(QCSchemeBLS))
+	node := net.nodes["node0"]
+	qc := node.HighQC()
+
+	// An unsigned timeout
+	err := node.HandleMessage(&Message{Type: MessageTypeTimeout, From: "node1", To: "node0", View: 1, HighQC: qc})
+	assert.ErrorContains(t, err, "invalid signature")
+
+	// A TC listing signers without their signatures cannot skip views
+	forged := &TimeoutCert{View: 9, HighQC: qc, Signers: []string{"node0", "node1", "node2"}, HighQCViews: []uint64{0, 0, 0}, Scheme: QCSchemeBLS}
+	leader := node.leader(10)
+	err = node.HandleMessage(&Message{
+		Type: MessageTypeProposal, From: leader, To: "node0", View: 10, TC: forged,
+		Block: &Block{View: 10, Height: 1, Parent: qc.BlockHash, Justify: qc, Proposer: leader},
+	})
+	assert.ErrorContains(t, err, "aggregate signature")
+	assert.Equal(t, uint64(1), node.View())
+	assert.Zero(t, node.consecutiveTCs)
+}
+
+func TestPacemaker_BoundsCollectedTimeouts(t *testing.T) {
+	net := newSimNetwork(4)
+	node := net.nodes["node0"]
+	qc := node.HighQC()
+
+	err := node.HandleMessage(&Message{Type: MessageTypeTimeout, From: "node1", To: "node0", View: 1 + timeoutWindow + 1, HighQC: qc})
+	assert.ErrorContains(t, err, "too far ahead")
+	assert.Empty(t, node.timeouts)
+
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeTimeout, From: "node1", To: "node0", View: 1 + timeoutWindow, HighQC: qc}))
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeTimeout, From: "node1", To: "node0", View: 1, HighQC: qc}))
+	assert.Len(t, node.timeouts, 2)
+
+	// Joining f+1 timeouts for a later view completes a TC with our own,
+	// and the timeouts of earlier views are dropped
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeTimeout, From: "node2", To: "node0", View: 1 + timeoutWindow, HighQC: qc}))
+	assert.Equal(t, uint64(2+timeoutWindow), node.View())
+	assert.Empty(t, node.timeouts)
+}
+
+func TestPacemaker_ExponentialBackoff(t *testing.T) {
+	node := NewHotStuffConsensus()
+	require.NoError(t, node.SetConfig(Config{
+		TickInterval:      10 * time.Millisecond,
+		BaseTimeout:       100 * time.Millisecond,
+		MaxTimeout:        time.Second,
+		TimeoutMultiplier: 2,
+	}))
+
+	var got []time.Duration
+	for i := 0; i < 6; i++ {
+		node.consecutiveTCs = i
+		got = append(got, node.viewTimeout())
+	}
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		800 * time.Millisecond, time.Second, time.Second,
+	}, got)
+}
+
+func TestConfigFromAppOptions(t *testing.T) {
+	cfg, err := ConfigFromAppOptions(simtestutil.AppOptionsMap{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfig(), cfg)
+
+	cfg, err = ConfigFromAppOptions(simtestutil.AppOptionsMap{
+		"hotstuff.base_timeout":       "500ms",
+		"hotstuff.max_timeout":        "10s",
+		"hotstuff.timeout_multiplier": "1.5",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, cfg.BaseTimeout)
+	assert.Equal(t, 10*time.Second, cfg.MaxTimeout)
+	assert.Equal(t, 1.5, cfg.TimeoutMultiplier)
+
+	_, err = ConfigFromAppOptions(simtestutil.AppOptionsMap{"hotstuff.timeout_multiplier": 0.5})
+	assert.Error(t, err)
+}
+
+func TestPacemaker_Metrics(t *testing.T) {
+	net := newSimNetwork(4)
+	type recorded struct {
+		view     *generic.Gauge
+		timeouts *generic.Counter
+		latency  *generic.SimpleHistogram
+	}
+	// Every reading of the clock moves it forward
+	clock := time.Unix(1700000000, 0)
+	rec := make(map[string]recorded)
+	for _, id := range net.order {
+		net.nodes[id].now = func() time.Time {
+			clock = clock.Add(time.Millisecond)
+			return clock
+		}
+		r := recorded{generic.NewGauge("view"), generic.NewCounter("timeouts"), generic.NewSimpleHistogram()}
+		rec[id] = r
+		net.nodes[id].SetMetrics(&common.HotStuffMetrics{View: r.view, Timeouts: r.timeouts, QCLatency: r.latency})
+	}
+
+	net.isolate("node1")
+	require.NoError(t, net.nodes["node0"].Propose([]byte("tx")))
+	net.run(2 * timeoutTicks)
+
+	assert.Equal(t, 2.0, rec["node0"].view.Value())
+	assert.Equal(t, 1.0, rec["node0"].timeouts.Value())
+
+	// node3 formed the QC for view 2
+	assert.Equal(t, 3.0, rec["node3"].view.Value())
+	assert.Greater(t, rec["node3"].latency.ApproximateMovingAverage(), 0.0)
+}
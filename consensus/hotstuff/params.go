@@ -0,0 +1,138 @@
+package hotstuff
+
+import (
+	"encoding/json"
+	"fmt"
+
+	storetypes "cosmossdk.io/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// Params are the HotStuff parameters replicas must agree on. They are chain
+// state, set in genesis and changed by governance.
+type Params struct {
+	// LeaderElection names the leader rotation, one of the LeaderElection*
+	// constants, round-robin if empty. Under reputation, replicas trusted
+	// below LeaderMinTrust stop leading.
+	LeaderElection string  `json:"leader_election"`
+	LeaderMinTrust float64 `json:"leader_min_trust"`
+}
+
+// Leader rotations selectable with the leader_election parameter
+const (
+	LeaderElectionRoundRobin = "round_robin"
+	LeaderElectionStake      = "stake"
+	LeaderElectionReputation = "reputation"
+)
+
+// ParamsKey holds the parameters in the engine store, next to the tPBFT
+// records
+var ParamsKey = []byte{0x10}
+
+// DefaultParams returns the parameters of a chain whose genesis sets none
+func DefaultParams() Params {
+	return Params{
+		LeaderElection: LeaderElectionRoundRobin,
+		LeaderMinTrust: 0.6,
+	}
+}
+
+// Validate checks the parameters for consistency
+func (p Params) Validate() error {
+	switch p.LeaderElection {
+	case "", LeaderElectionRoundRobin, LeaderElectionStake, LeaderElectionReputation:
+	default:
+		return fmt.Errorf("unknown leader election %q, expected %s, %s or %s", p.LeaderElection,
+			LeaderElectionRoundRobin, LeaderElectionStake, LeaderElectionReputation)
+	}
+	if p.LeaderMinTrust < 0 || p.LeaderMinTrust > 1 {
+		return fmt.Errorf("leader min trust %v must be between 0 and 1", p.LeaderMinTrust)
+	}
+	return nil
+}
+
+// GetParams returns the parameters stored under key, the defaults if none
+// are
+func GetParams(ctx sdk.Context, key storetypes.StoreKey) (Params, error) {
+	bz := ctx.KVStore(key).Get(ParamsKey)
+	if bz == nil {
+		return DefaultParams(), nil
+	}
+
+	var p Params
+	if err := json.Unmarshal(bz, &p); err != nil {
+		return p, fmt.Errorf("failed to decode %s params: %w", ModuleName, err)
+	}
+	return p, nil
+}
+
+// SetParams validates the parameters and stores them under key
+func SetParams(ctx sdk.Context, key storetypes.StoreKey, p Params) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	bz, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	ctx.KVStore(key).Set(ParamsKey, bz)
+	return nil
+}
+
+// SetStoreKey sets the store the parameters are read from
+func (h *HotStuffConsensus) SetStoreKey(key storetypes.StoreKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.storeKey = key
+}
+
+// SetParams makes the replica rotate leaders as the parameters say.
+// Reputation ranks replicas by the trust scores handed over to the engine.
+func (h *HotStuffConsensus) SetParams(p Params) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.setParams(p)
+}
+
+func (h *HotStuffConsensus) setParams(p Params) error {
+	scorer := tpbft.NewTrustScorer()
+	if len(h.trust) > 0 {
+		gs, err := tpbft.UnmarshalGenesis(h.trust)
+		if err != nil {
+			return err
+		}
+		scorer.ImportGenesis(gs)
+	}
+	// The node's replica runs without peers, so stakes weigh it alone
+	election, err := NewLeaderElection(p, h.replicas, nil, scorer)
+	if err != nil {
+		return err
+	}
+	h.election, h.params = election, p
+	return nil
+}
+
+// loadParams switches to the leader rotation the stored parameters name, if
+// it changed. Without a store the replica keeps its own.
+func (h *HotStuffConsensus) loadParams(ctx sdk.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.storeKey == nil {
+		return nil
+	}
+	p, err := GetParams(ctx, h.storeKey)
+	if err != nil || p == h.params {
+		return err
+	}
+	return h.setParams(p)
+}
@@ -0,0 +1,427 @@
+package hotstuff
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/cloudflare/circl/sign/bls"
+)
+
+// QCScheme selects how a quorum certificate carries its votes
+type QCScheme int
+
+const (
+	// QCSchemeBLS aggregates the votes into a single BLS12-381 signature,
+	// verified with one pairing check against the summed public keys
+	QCSchemeBLS QCScheme = iota
+	// QCSchemeMultiSig keeps one Ed25519 signature per signer, for
+	// deployments without BLS keys
+	QCSchemeMultiSig
+)
+
+func (s QCScheme) String() string {
+	switch s {
+	case QCSchemeBLS:
+		return "bls"
+	case QCSchemeMultiSig:
+		return "multisig"
+	}
+	return "unknown"
+}
+
+// Signature sizes on the wire
+const (
+	blsSignatureSize     = 96 // Compressed G2 point
+	ed25519SignatureSize = 64
+)
+
+// QuorumCert proves that a quorum of replicas voted for a block in a view.
+// Without configured keys only Signers is set.
+type QuorumCert struct {
+	View      uint64
+	BlockHash Hash
+	Signers   []string // Sorted
+
+	Scheme     QCScheme
+	Signature  []byte   // BLS: the aggregate of every signer's vote
+	Signatures [][]byte // Multi-signature: one per signer, aligned with Signers
+}
+
+// NewQuorumCert builds a QC from the signed votes for a block, keyed by signer
+func NewQuorumCert(scheme QCScheme, view uint64, hash Hash, votes map[string][]byte) (*QuorumCert, error) {
+	qc := &QuorumCert{View: view, BlockHash: hash, Scheme: scheme}
+	for id := range votes {
+		qc.Signers = append(qc.Signers, id)
+	}
+	sort.Strings(qc.Signers)
+
+	sigs := make([][]byte, len(qc.Signers))
+	for i, id := range qc.Signers {
+		sigs[i] = votes[id]
+	}
+
+	switch scheme {
+	case QCSchemeBLS:
+		agg, err := bls.Aggregate(blsScheme{}, sigs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate votes for view %d: %w", view, err)
+		}
+		qc.Signature = agg
+	case QCSchemeMultiSig:
+		qc.Signatures = sigs
+	default:
+		return nil, fmt.Errorf("unknown QC scheme %d", scheme)
+	}
+	return qc, nil
+}
+
+// Verify checks that the QC carries valid votes from at least quorum distinct
+// members of the validator set
+func (qc *QuorumCert) Verify(vs *ValidatorSet, quorum int) error {
+	for i, id := range qc.Signers {
+		if _, ok := vs.index[id]; !ok {
+			return fmt.Errorf("QC for view %d signed by unknown replica %s", qc.View, id)
+		}
+		if i > 0 && qc.Signers[i-1] >= id {
+			return fmt.Errorf("QC for view %d has unsorted or duplicate signers", qc.View)
+		}
+	}
+	if len(qc.Signers) < quorum {
+		return fmt.Errorf("QC for view %d has %d signers, need %d", qc.View, len(qc.Signers), quorum)
+	}
+
+	msg := voteMessage(qc.View, qc.BlockHash)
+	switch qc.Scheme {
+	case QCSchemeBLS:
+		pub, err := vs.aggregateKey(qc.Signers)
+		if err != nil {
+			return fmt.Errorf("QC for view %d: %w", qc.View, err)
+		}
+		if !bls.Verify(pub, msg, qc.Signature) {
+			return fmt.Errorf("QC for view %d has an invalid aggregate signature", qc.View)
+		}
+	case QCSchemeMultiSig:
+		if len(qc.Signatures) != len(qc.Signers) {
+			return fmt.Errorf("QC for view %d has %d signatures for %d signers", qc.View, len(qc.Signatures), len(qc.Signers))
+		}
+		for i, id := range qc.Signers {
+			if err := vs.verifySignature(QCSchemeMultiSig, id, msg, qc.Signatures[i]); err != nil {
+				return fmt.Errorf("QC for view %d: %w", qc.View, err)
+			}
+		}
+	default:
+		return fmt.Errorf("QC for view %d uses unknown scheme %d", qc.View, qc.Scheme)
+	}
+	return nil
+}
+
+// Encode serializes a signed QC compactly, naming signers by a bitmap over
+// the validator set: view, block hash, scheme, bitmap, then the signatures
+func (qc *QuorumCert) Encode(vs *ValidatorSet) ([]byte, error) {
+	bitmap := make([]byte, (vs.Size()+7)/8)
+	for _, id := range qc.Signers {
+		i, ok := vs.index[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown validator %s", id)
+		}
+		bitmap[i/8] |= 1 << (i % 8)
+	}
+
+	out := binary.BigEndian.AppendUint64(nil, qc.View)
+	out = append(out, qc.BlockHash[:]...)
+	out = append(out, byte(qc.Scheme))
+	out = append(out, bitmap...)
+	switch qc.Scheme {
+	case QCSchemeBLS:
+		out = append(out, qc.Signature...)
+	case QCSchemeMultiSig:
+		for _, sig := range qc.Signatures {
+			out = append(out, sig...)
+		}
+	default:
+		return nil, fmt.Errorf("unknown QC scheme %d", qc.Scheme)
+	}
+	return out, nil
+}
+
+// DecodeQuorumCert parses a QC produced by Encode over the same validator set.
+// It does not verify the signatures.
+func DecodeQuorumCert(vs *ValidatorSet, data []byte) (*QuorumCert, error) {
+	header := 8 + len(Hash{}) + 1 + (vs.Size()+7)/8
+	if len(data) < header {
+		return nil, fmt.Errorf("QC too short: %d bytes", len(data))
+	}
+
+	qc := &QuorumCert{View: binary.BigEndian.Uint64(data)}
+	copy(qc.BlockHash[:], data[8:])
+	qc.Scheme = QCScheme(data[8+len(Hash{})])
+	bitmap := data[8+len(Hash{})+1 : header]
+	for i, v := range vs.validators {
+		if bitmap[i/8]&(1<<(i%8)) != 0 {
+			qc.Signers = append(qc.Signers, v.ID)
+		}
+	}
+
+	sigs := data[header:]
+	if qc.View == 0 && len(sigs) == 0 {
+		// The genesis QC carries no votes
+		return qc, nil
+	}
+	switch qc.Scheme {
+	case QCSchemeBLS:
+		if len(sigs) != blsSignatureSize {
+			return nil, fmt.Errorf("BLS QC signature is %d bytes, expected %d", len(sigs), blsSignatureSize)
+		}
+		qc.Signature = append([]byte(nil), sigs...)
+	case QCSchemeMultiSig:
+		if len(sigs) != len(qc.Signers)*ed25519SignatureSize {
+			return nil, fmt.Errorf("multi-signature QC carries %d signature bytes for %d signers", len(sigs), len(qc.Signers))
+		}
+		for i := range qc.Signers {
+			sig := sigs[i*ed25519SignatureSize : (i+1)*ed25519SignatureSize]
+			qc.Signatures = append(qc.Signatures, append([]byte(nil), sig...))
+		}
+	default:
+		return nil, fmt.Errorf("unknown QC scheme %d", qc.Scheme)
+	}
+	return qc, nil
+}
+
+// Timeout is a replica's timeout for a view: its highest QC, and its
+// signature over the view and that QC's view when keys are configured
+type Timeout struct {
+	HighQC    *QuorumCert
+	Signature []byte
+}
+
+// TimeoutCert proves that a quorum of replicas gave up on a view. It carries
+// the highest QC among theirs, which the next leader must extend; as every
+// signer signed the view of its own high QC, a TC cannot hide a higher one.
+// Without configured keys only the signers and their QC views are set.
+type TimeoutCert struct {
+	View        uint64
+	HighQC      *QuorumCert
+	Signers     []string // Sorted
+	HighQCViews []uint64 // The view of each signer's high QC, aligned with Signers
+
+	Scheme     QCScheme
+	Signature  []byte   // BLS: the aggregate of every signer's timeout
+	Signatures [][]byte // Multi-signature: one per signer, aligned with Signers
+}
+
+// NewTimeoutCert builds a TC from the signed timeouts for a view, keyed by
+// signer
+func NewTimeoutCert(scheme QCScheme, view uint64, timeouts map[string]Timeout) (*TimeoutCert, error) {
+	tc := unsignedTimeoutCert(view, timeouts)
+	tc.Scheme = scheme
+	sigs := make([][]byte, len(tc.Signers))
+	for i, id := range tc.Signers {
+		sigs[i] = timeouts[id].Signature
+	}
+
+	switch scheme {
+	case QCSchemeBLS:
+		agg, err := bls.Aggregate(blsScheme{}, sigs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate timeouts for view %d: %w", view, err)
+		}
+		tc.Signature = agg
+	case QCSchemeMultiSig:
+		tc.Signatures = sigs
+	default:
+		return nil, fmt.Errorf("unknown QC scheme %d", scheme)
+	}
+	return tc, nil
+}
+
+// unsignedTimeoutCert lists the signers of the timeouts for a view and picks
+// the highest QC among theirs
+func unsignedTimeoutCert(view uint64, timeouts map[string]Timeout) *TimeoutCert {
+	tc := &TimeoutCert{View: view}
+	for id, t := range timeouts {
+		tc.Signers = append(tc.Signers, id)
+		if tc.HighQC == nil || t.HighQC.View > tc.HighQC.View {
+			tc.HighQC = t.HighQC
+		}
+	}
+	sort.Strings(tc.Signers)
+	tc.HighQCViews = make([]uint64, len(tc.Signers))
+	for i, id := range tc.Signers {
+		tc.HighQCViews[i] = timeouts[id].HighQC.View
+	}
+	return tc
+}
+
+// checkSigners checks that the TC lists at least quorum distinct members,
+// each with the view of its high QC, and that HighQC is the highest of those
+func (tc *TimeoutCert) checkSigners(isMember func(string) bool, quorum int) error {
+	if tc.HighQC == nil {
+		return fmt.Errorf("TC for view %d carries no QC", tc.View)
+	}
+	for i, id := range tc.Signers {
+		if !isMember(id) {
+			return fmt.Errorf("TC for view %d signed by unknown replica %s", tc.View, id)
+		}
+		if i > 0 && tc.Signers[i-1] >= id {
+			return fmt.Errorf("TC for view %d has unsorted or duplicate signers", tc.View)
+		}
+	}
+	if len(tc.Signers) < quorum {
+		return fmt.Errorf("TC for view %d has %d signers, need %d", tc.View, len(tc.Signers), quorum)
+	}
+	if len(tc.HighQCViews) != len(tc.Signers) {
+		return fmt.Errorf("TC for view %d has %d QC views for %d signers", tc.View, len(tc.HighQCViews), len(tc.Signers))
+	}
+	if highest := slices.Max(tc.HighQCViews); highest != tc.HighQC.View {
+		return fmt.Errorf("TC for view %d carries a QC for view %d, but a signer reported view %d", tc.View, tc.HighQC.View, highest)
+	}
+	return nil
+}
+
+// Verify checks that the TC carries valid timeouts from at least quorum
+// distinct members of the validator set. The QC it carries is verified
+// separately.
+func (tc *TimeoutCert) Verify(vs *ValidatorSet, quorum int) error {
+	isMember := func(id string) bool {
+		_, ok := vs.index[id]
+		return ok
+	}
+	if err := tc.checkSigners(isMember, quorum); err != nil {
+		return err
+	}
+
+	switch tc.Scheme {
+	case QCSchemeBLS:
+		// Signers reporting the same QC view signed the same message, so
+		// their keys are summed and one pairing is checked per distinct view
+		byView := make(map[uint64][]string)
+		var views []uint64
+		for i, id := range tc.Signers {
+			v := tc.HighQCViews[i]
+			if byView[v] == nil {
+				views = append(views, v)
+			}
+			byView[v] = append(byView[v], id)
+		}
+		pubs := make([]*bls.PublicKey[blsScheme], len(views))
+		msgs := make([][]byte, len(views))
+		for i, v := range views {
+			pub, err := vs.aggregateKey(byView[v])
+			if err != nil {
+				return fmt.Errorf("TC for view %d: %w", tc.View, err)
+			}
+			pubs[i], msgs[i] = pub, timeoutMessage(tc.View, v)
+		}
+		if !bls.VerifyAggregate(pubs, msgs, tc.Signature) {
+			return fmt.Errorf("TC for view %d has an invalid aggregate signature", tc.View)
+		}
+	case QCSchemeMultiSig:
+		if len(tc.Signatures) != len(tc.Signers) {
+			return fmt.Errorf("TC for view %d has %d signatures for %d signers", tc.View, len(tc.Signatures), len(tc.Signers))
+		}
+		for i, id := range tc.Signers {
+			if err := vs.verifySignature(QCSchemeMultiSig, id, timeoutMessage(tc.View, tc.HighQCViews[i]), tc.Signatures[i]); err != nil {
+				return fmt.Errorf("TC for view %d: %w", tc.View, err)
+			}
+		}
+	default:
+		return fmt.Errorf("TC for view %d uses unknown scheme %d", tc.View, tc.Scheme)
+	}
+	return nil
+}
+
+// Encode serializes a signed TC like a QC: view, scheme, signer bitmap, the
+// view of each signer's high QC and the signatures, followed by the encoded
+// high QC
+func (tc *TimeoutCert) Encode(vs *ValidatorSet) ([]byte, error) {
+	bitmap := make([]byte, (vs.Size()+7)/8)
+	for _, id := range tc.Signers {
+		i, ok := vs.index[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown validator %s", id)
+		}
+		bitmap[i/8] |= 1 << (i % 8)
+	}
+	if len(tc.HighQCViews) != len(tc.Signers) {
+		return nil, fmt.Errorf("%d QC views for %d signers", len(tc.HighQCViews), len(tc.Signers))
+	}
+
+	out := binary.BigEndian.AppendUint64(nil, tc.View)
+	out = append(out, byte(tc.Scheme))
+	out = append(out, bitmap...)
+	for _, v := range tc.HighQCViews {
+		out = binary.BigEndian.AppendUint64(out, v)
+	}
+	switch tc.Scheme {
+	case QCSchemeBLS:
+		out = append(out, tc.Signature...)
+	case QCSchemeMultiSig:
+		for _, sig := range tc.Signatures {
+			out = append(out, sig...)
+		}
+	default:
+		return nil, fmt.Errorf("unknown QC scheme %d", tc.Scheme)
+	}
+
+	qc, err := tc.HighQC.Encode(vs)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, qc...), nil
+}
+
+// DecodeTimeoutCert parses a TC produced by Encode over the same validator
+// set. It does not verify the signatures.
+func DecodeTimeoutCert(vs *ValidatorSet, data []byte) (*TimeoutCert, error) {
+	header := 8 + 1 + (vs.Size()+7)/8
+	if len(data) < header {
+		return nil, fmt.Errorf("TC too short: %d bytes", len(data))
+	}
+
+	tc := &TimeoutCert{View: binary.BigEndian.Uint64(data), Scheme: QCScheme(data[8])}
+	bitmap := data[9:header]
+	for i, v := range vs.validators {
+		if bitmap[i/8]&(1<<(i%8)) != 0 {
+			tc.Signers = append(tc.Signers, v.ID)
+		}
+	}
+
+	rest := data[header:]
+	if len(rest) < 8*len(tc.Signers) {
+		return nil, fmt.Errorf("TC carries %d bytes of QC views for %d signers", len(rest), len(tc.Signers))
+	}
+	for i := range tc.Signers {
+		tc.HighQCViews = append(tc.HighQCViews, binary.BigEndian.Uint64(rest[8*i:]))
+	}
+	rest = rest[8*len(tc.Signers):]
+
+	var sigSize int
+	switch tc.Scheme {
+	case QCSchemeBLS:
+		sigSize = blsSignatureSize
+	case QCSchemeMultiSig:
+		sigSize = len(tc.Signers) * ed25519SignatureSize
+	default:
+		return nil, fmt.Errorf("unknown QC scheme %d", tc.Scheme)
+	}
+	if len(rest) < sigSize {
+		return nil, fmt.Errorf("TC carries %d signature bytes, expected %d", len(rest), sigSize)
+	}
+	if tc.Scheme == QCSchemeBLS {
+		tc.Signature = append([]byte(nil), rest[:sigSize]...)
+	} else {
+		for i := range tc.Signers {
+			sig := rest[i*ed25519SignatureSize : (i+1)*ed25519SignatureSize]
+			tc.Signatures = append(tc.Signatures, append([]byte(nil), sig...))
+		}
+	}
+
+	qc, err := DecodeQuorumCert(vs, rest[sigSize:])
+	if err != nil {
+		return nil, fmt.Errorf("TC for view %d: %w", tc.View, err)
+	}
+	tc.HighQC = qc
+	return tc, nil
+}
@@ -0,0 +1,341 @@
+package hotstuff
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testValidators derives keys for the given replica IDs from their names
+func testValidators(ids []string) (map[string]*PrivateKey, *ValidatorSet, error) {
+	keys := make(map[string]*PrivateKey, len(ids))
+	var vals []Validator
+	for _, id := range ids {
+		seed := sha256.Sum256([]byte(id))
+		key, err := GenerateKey(seed[:])
+		if err != nil {
+			return nil, nil, err
+		}
+		v, err := key.Validator(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[id] = key
+		vals = append(vals, v)
+	}
+	vs, err := NewValidatorSet(vals)
+	return keys, vs, err
+}
+
+func testIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("node%03d", i)
+	}
+	return ids
+}
+
+// signedQC collects votes from the first signers validators
+func signedQC(t testing.TB, scheme QCScheme, keys map[string]*PrivateKey, ids []string, signers int) *QuorumCert {
+	hash := Hash{1, 2, 3}
+	votes := make(map[string][]byte)
+	for _, id := range ids[:signers] {
+		votes[id] = keys[id].sign(scheme, voteMessage(7, hash))
+	}
+	qc, err := NewQuorumCert(scheme, 7, hash, votes)
+	require.NoError(t, err)
+	return qc
+}
+
+func TestQuorumCert_Verify(t *testing.T) {
+	ids := testIDs(4)
+	keys, vs, err := testValidators(ids)
+	require.NoError(t, err)
+
+	for _, scheme := range []QCScheme{QCSchemeBLS, QCSchemeMultiSig} {
+		t.Run(scheme.String(), func(t *testing.T) {
+			qc := signedQC(t, scheme, keys, ids, 3)
+			require.NoError(t, qc.Verify(vs, 3))
+
+			// Too few signers
+			assert.Error(t, qc.Verify(vs, 4))
+
+			// Votes for another block or view
+			tampered := *qc
+			tampered.BlockHash = Hash{9}
+			assert.Error(t, tampered.Verify(vs, 3))
+			tampered = *qc
+			tampered.View++
+			assert.Error(t, tampered.Verify(vs, 3))
+
+			// Claiming a signer that did not vote
+			tampered = *qc
+			tampered.Signers = []string{ids[0], ids[1], ids[3]}
+			assert.Error(t, tampered.Verify(vs, 3))
+
+			// Duplicate signers
+			tampered = *qc
+			tampered.Signers = []string{ids[0], ids[0], ids[1]}
+			assert.Error(t, tampered.Verify(vs, 3))
+		})
+	}
+}
+
+func TestQuorumCert_EncodeRoundTrip(t *testing.T) {
+	ids := testIDs(10)
+	keys, vs, err := testValidators(ids)
+	require.NoError(t, err)
+
+	for _, scheme := range []QCScheme{QCSchemeBLS, QCSchemeMultiSig} {
+		qc := signedQC(t, scheme, keys, ids, 7)
+		data, err := qc.Encode(vs)
+		require.NoError(t, err)
+
+		decoded, err := DecodeQuorumCert(vs, data)
+		require.NoError(t, err)
+		assert.Equal(t, qc, decoded, scheme.String())
+		assert.NoError(t, decoded.Verify(vs, 7))
+
+		_, err = DecodeQuorumCert(vs, data[:len(data)-1])
+		assert.Error(t, err)
+	}
+
+	// The aggregate stays constant size while the list grows per signer
+	bls, err := signedQC(t, QCSchemeBLS, keys, ids, 7).Encode(vs)
+	require.NoError(t, err)
+	multi, err := signedQC(t, QCSchemeMultiSig, keys, ids, 7).Encode(vs)
+	require.NoError(t, err)
+	assert.Equal(t, 8+32+1+2+blsSignatureSize, len(bls))
+	assert.Equal(t, 8+32+1+2+7*ed25519SignatureSize, len(multi))
+}
+
+// signedTC collects timeouts for view 8 from the first signers validators,
+// every other one reporting an older high QC than the TC carries
+func signedTC(t testing.TB, scheme QCScheme, keys map[string]*PrivateKey, ids []string, signers int) *TimeoutCert {
+	high := signedQC(t, scheme, keys, ids, signers)
+	older := &QuorumCert{View: 5}
+	timeouts := make(map[string]Timeout)
+	for i, id := range ids[:signers] {
+		qc := high
+		if i%2 == 1 {
+			qc = older
+		}
+		timeouts[id] = Timeout{HighQC: qc, Signature: keys[id].sign(scheme, timeoutMessage(8, qc.View))}
+	}
+	tc, err := NewTimeoutCert(scheme, 8, timeouts)
+	require.NoError(t, err)
+	return tc
+}
+
+func TestTimeoutCert_Verify(t *testing.T) {
+	ids := testIDs(4)
+	keys, vs, err := testValidators(ids)
+	require.NoError(t, err)
+
+	for _, scheme := range []QCScheme{QCSchemeBLS, QCSchemeMultiSig} {
+		t.Run(scheme.String(), func(t *testing.T) {
+			tc := signedTC(t, scheme, keys, ids, 3)
+			require.NoError(t, tc.Verify(vs, 3))
+			assert.Equal(t, []uint64{7, 5, 7}, tc.HighQCViews)
+			assert.Equal(t, uint64(7), tc.HighQC.View)
+
+			// Too few signers
+			assert.Error(t, tc.Verify(vs, 4))
+
+			// Timeouts for another view
+			tampered := *tc
+			tampered.View++
+			assert.Error(t, tampered.Verify(vs, 3))
+
+			// Hiding the highest QC a signer reported
+			tampered = *tc
+			tampered.HighQC = &QuorumCert{View: 5}
+			assert.ErrorContains(t, tampered.Verify(vs, 3), "reported view 7")
+
+			// Misreporting a signer's QC view
+			tampered = *tc
+			tampered.HighQCViews = []uint64{7, 6, 7}
+			assert.Error(t, tampered.Verify(vs, 3))
+
+			// Claiming a signer that did not time out
+			tampered = *tc
+			tampered.Signers = []string{ids[0], ids[1], ids[3]}
+			assert.Error(t, tampered.Verify(vs, 3))
+
+			// A signer list without signatures
+			tampered = *tc
+			tampered.Signature, tampered.Signatures = nil, nil
+			assert.Error(t, tampered.Verify(vs, 3))
+		})
+	}
+}
+
+func TestTimeoutCert_EncodeRoundTrip(t *testing.T) {
+	ids := testIDs(10)
+	keys, vs, err := testValidators(ids)
+	require.NoError(t, err)
+
+	for _, scheme := range []QCScheme{QCSchemeBLS, QCSchemeMultiSig} {
+		tc := signedTC(t, scheme, keys, ids, 7)
+		data, err := tc.Encode(vs)
+		require.NoError(t, err)
+
+		decoded, err := DecodeTimeoutCert(vs, data)
+		require.NoError(t, err)
+		assert.Equal(t, tc, decoded, scheme.String())
+		assert.NoError(t, decoded.Verify(vs, 7))
+
+		_, err = DecodeTimeoutCert(vs, data[:len(data)-1])
+		assert.Error(t, err)
+	}
+
+	// A TC extending genesis carries the genesis QC, which has no votes
+	genesis := genesisQC(genesisBlock())
+	tc := &TimeoutCert{View: 1, HighQC: genesis, Signers: ids[:1], HighQCViews: []uint64{0}, Scheme: QCSchemeMultiSig, Signatures: [][]byte{make([]byte, ed25519SignatureSize)}}
+	data, err := tc.Encode(vs)
+	require.NoError(t, err)
+	decoded, err := DecodeTimeoutCert(vs, data)
+	require.NoError(t, err)
+	assert.Equal(t, genesis, decoded.HighQC)
+}
+
+func TestValidatorSet_RejectsBadProofOfPossession(t *testing.T) {
+	keys, _, err := testValidators([]string{"a", "b"})
+	require.NoError(t, err)
+
+	a, err := keys["a"].Validator("a")
+	require.NoError(t, err)
+	b, err := keys["b"].Validator("b")
+	require.NoError(t, err)
+
+	// b claims a's proof for its own key
+	b.PoP = a.PoP
+	_, err = NewValidatorSet([]Validator{a, b})
+	assert.ErrorContains(t, err, "proof of possession")
+
+	_, err = NewValidatorSet([]Validator{a, a})
+	assert.ErrorContains(t, err, "duplicate")
+}
+
+func TestHotStuff_SignedQuorumCerts(t *testing.T) {
+	for _, scheme := range []QCScheme{QCSchemeBLS, QCSchemeMultiSig} {
+		t.Run(scheme.String(), func(t *testing.T) {
+			net := newSimNetwork(4)
+			require.NoError(t, net.useKeys(scheme))
+
+			require.NoError(t, net.nodes["node1"].Propose([]byte("tx0")))
+			require.NoError(t, net.nodes["node1"].Propose([]byte("tx1")))
+			net.run(0)
+
+			for _, id := range net.order {
+				assert.Equal(t, []string{"tx0", "tx1"}, net.payloads(id), id)
+			}
+			qc := net.nodes["node0"].HighQC()
+			assert.Equal(t, scheme, qc.Scheme)
+			assert.NoError(t, qc.Verify(net.nodes["node0"].validators, 3))
+		})
+	}
+}
+
+func TestHotStuff_RejectsForgedVotesAndQCs(t *testing.T) {
+	net := newSimNetwork(4)
+	require.NoError(t, net.useKeys(QCSchemeBLS))
+	leader := net.nodes["node1"]
+	require.NoError(t, leader.Propose([]byte("tx")))
+
+	// Deliver the proposal only, then forge node3's vote to view 2's leader
+	for len(net.queue) > 0 {
+		msg := net.queue[0]
+		net.queue = net.queue[1:]
+		if msg.Type == MessageTypeProposal {
+			net.nodes[msg.To].HandleMessage(msg)
+		}
+	}
+	hash := net.nodes["node2"].leaf.Hash()
+	err := net.nodes["node2"].HandleMessage(&Message{
+		Type: MessageTypeVote, From: "node3", To: "node2", View: 1, BlockHash: hash,
+		Signature: make([]byte, blsSignatureSize),
+	})
+	assert.Error(t, err)
+
+	// A QC listing signers without their signatures is rejected
+	forged := &QuorumCert{View: 1, BlockHash: hash, Signers: []string{"node0", "node1", "node2"}}
+	err = net.nodes["node3"].HandleMessage(&Message{
+		Type: MessageTypeProposal, From: "node2", To: "node3", View: 2,
+		Block: &Block{View: 2, Height: 2, Parent: hash, Justify: forged, Proposer: "node2"},
+	})
+	assert.ErrorContains(t, err, "aggregate signature")
+}
+
+func BenchmarkQuorumCert_Verify(b *testing.B) {
+	for _, n := range []int{4, 16, 64, 128} {
+		ids := testIDs(n)
+		keys, vs, err := testValidators(ids)
+		require.NoError(b, err)
+		quorum := n - (n-1)/3
+
+		for _, scheme := range []QCScheme{QCSchemeBLS, QCSchemeMultiSig} {
+			qc := signedQC(b, scheme, keys, ids, quorum)
+			data, err := qc.Encode(vs)
+			require.NoError(b, err)
+
+			b.Run(fmt.Sprintf("%s/n=%d", scheme, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if err := qc.Verify(vs, quorum); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(len(data)), "bytes/qc")
+			})
+		}
+	}
+}
+
+func BenchmarkTimeoutCert_Verify(b *testing.B) {
+	for _, n := range []int{4, 16, 64, 128} {
+		ids := testIDs(n)
+		keys, vs, err := testValidators(ids)
+		require.NoError(b, err)
+		quorum := n - (n-1)/3
+
+		for _, scheme := range []QCScheme{QCSchemeBLS, QCSchemeMultiSig} {
+			tc := signedTC(b, scheme, keys, ids, quorum)
+			data, err := tc.Encode(vs)
+			require.NoError(b, err)
+
+			b.Run(fmt.Sprintf("%s/n=%d", scheme, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if err := tc.Verify(vs, quorum); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(len(data)), "bytes/tc")
+			})
+		}
+	}
+}
+
+func BenchmarkQuorumCert_Aggregate(b *testing.B) {
+	for _, n := range []int{4, 16, 64, 128} {
+		ids := testIDs(n)
+		keys, _, err := testValidators(ids)
+		require.NoError(b, err)
+		quorum := n - (n-1)/3
+
+		hash := Hash{1}
+		votes := make(map[string][]byte)
+		for _, id := range ids[:quorum] {
+			votes[id] = keys[id].sign(QCSchemeBLS, voteMessage(1, hash))
+		}
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := NewQuorumCert(QCSchemeBLS, 1, hash, votes); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package hotstuff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	dbm "github.com/cosmos/cosmos-db"
+)
+
+// SafetyData is the voting state a replica must keep across restarts: voting
+// twice in a view or against the lock could let conflicting blocks commit
+type SafetyData struct {
+	LastVotedView uint64      `json:"last_voted_view"`
+	LockedQC      *QuorumCert `json:"locked_qc"`
+	HighQC        *QuorumCert `json:"high_qc"`
+}
+
+// SafetyStorage persists SafetyData. Saves must be durable when they return,
+// since a vote is released right after.
+type SafetyStorage interface {
+	SaveSafetyData(data SafetyData) error
+
+	// LoadSafetyData returns the saved state, or false for a new replica
+	LoadSafetyData() (SafetyData, bool, error)
+}
+
+var safetyDataKey = []byte{0x01}
+
+// ErrUnsafeVote is returned when the voting rules forbid a vote
+var ErrUnsafeVote = errors.New("unsafe vote")
+
+// DBSafetyStorage implements SafetyStorage on top of a cosmos-db database
+type DBSafetyStorage struct {
+	db dbm.DB
+}
+
+// NewDBSafetyStorage creates a storage backed by db
+func NewDBSafetyStorage(db dbm.DB) *DBSafetyStorage {
+	return &DBSafetyStorage{db: db}
+}
+
+// SaveSafetyData implements SafetyStorage
+func (s *DBSafetyStorage) SaveSafetyData(data SafetyData) error {
+	bz, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.db.SetSync(safetyDataKey, bz)
+}
+
+// LoadSafetyData implements SafetyStorage
+func (s *DBSafetyStorage) LoadSafetyData() (SafetyData, bool, error) {
+	var data SafetyData
+	bz, err := s.db.Get(safetyDataKey)
+	if err != nil || bz == nil {
+		return data, false, err
+	}
+	if err := json.Unmarshal(bz, &data); err != nil {
+		return data, false, fmt.Errorf("failed to decode hotstuff safety data: %w", err)
+	}
+	return data, true, nil
+}
+
+// SafetyRules owns a replica's voting state and is the only holder of its
+// signing key. Every vote and timeout goes through it, so it checks the
+// voting rules and persists the new state before releasing a signature.
+// It is not safe for concurrent use; the engine calls it under its lock.
+type SafetyRules struct {
+	data    SafetyData
+	storage SafetyStorage
+	key     *PrivateKey
+	scheme  QCScheme
+}
+
+// NewSafetyRules creates safety rules for a replica that has not voted yet
+func NewSafetyRules(genesis *QuorumCert) *SafetyRules {
+	return &SafetyRules{data: SafetyData{LockedQC: genesis, HighQC: genesis}}
+}
+
+// SetStorage attaches stable storage and restores any state it holds
+func (s *SafetyRules) SetStorage(storage SafetyStorage) error {
+	data, ok, err := storage.LoadSafetyData()
+	if err != nil {
+		return fmt.Errorf("failed to load hotstuff safety data: %w", err)
+	}
+	s.storage = storage
+	if ok {
+		s.data = data
+	}
+	return nil
+}
+
+// SetSigner makes votes carry signatures under scheme
+func (s *SafetyRules) SetSigner(key *PrivateKey, scheme QCScheme) {
+	s.key, s.scheme = key, scheme
+}
+
+// LastVotedView returns the last view the replica voted or timed out in
+func (s *SafetyRules) LastVotedView() uint64 { return s.data.LastVotedView }
+
+// LockedQC returns the QC the replica is locked on
+func (s *SafetyRules) LockedQC() *QuorumCert { return s.data.LockedQC }
+
+// HighQC returns the highest QC the replica has seen
+func (s *SafetyRules) HighQC() *QuorumCert { return s.data.HighQC }
+
+// ObserveQC raises the high QC
+func (s *SafetyRules) ObserveQC(qc *QuorumCert) error {
+	if qc.View <= s.data.HighQC.View {
+		return nil
+	}
+	next := s.data
+	next.HighQC = qc
+	return s.save(next)
+}
+
+// ObserveBlock updates the state from a verified block whose justify QC
+// certifies parent: that QC becomes the high QC, and the parent's own QC the
+// lock (the second phase of the chain)
+func (s *SafetyRules) ObserveBlock(b, parent *Block) error {
+	if b.Justify == nil || parent.Hash() != b.Justify.BlockHash {
+		return fmt.Errorf("block for view %d does not extend its justify QC", b.View)
+	}
+	next := s.data
+	if b.Justify.View > next.HighQC.View {
+		next.HighQC = b.Justify
+	}
+	if parent.Justify != nil && parent.Justify.View > next.LockedQC.View {
+		next.LockedQC = parent.Justify
+	}
+	if next == s.data {
+		return nil
+	}
+	return s.save(next)
+}
+
+// Vote checks that the replica may vote for b, records the vote and returns
+// its signature, which is nil without a signing key. A replica votes at most
+// once per view, and only for blocks extending the locked block or carrying a
+// QC newer than the lock, which may then safely be released.
+func (s *SafetyRules) Vote(b, parent *Block) ([]byte, error) {
+	if err := s.ObserveBlock(b, parent); err != nil {
+		return nil, err
+	}
+	if b.View <= s.data.LastVotedView {
+		return nil, fmt.Errorf("%w: already voted in view %d, refusing view %d", ErrUnsafeVote, s.data.LastVotedView, b.View)
+	}
+	// Views increase along a chain, so a block whose parent precedes the
+	// locked view cannot extend the locked block
+	locked := s.data.LockedQC
+	if b.Justify.View < locked.View || (b.Justify.View == locked.View && b.Justify.BlockHash != locked.BlockHash) {
+		return nil, fmt.Errorf("%w: block for view %d conflicts with the lock at view %d", ErrUnsafeVote, b.View, locked.View)
+	}
+
+	next := s.data
+	next.LastVotedView = b.View
+	if err := s.save(next); err != nil {
+		return nil, err
+	}
+	if s.key == nil {
+		return nil, nil
+	}
+	return s.key.sign(s.scheme, voteMessage(b.View, b.Hash())), nil
+}
+
+// Timeout records that the replica gave up on view, after which it never
+// votes in that view, and returns its signature over the view and the view
+// of its high QC, which is nil without a signing key
+func (s *SafetyRules) Timeout(view uint64) ([]byte, error) {
+	if view > s.data.LastVotedView {
+		next := s.data
+		next.LastVotedView = view
+		if err := s.save(next); err != nil {
+			return nil, err
+		}
+	}
+	if s.key == nil {
+		return nil, nil
+	}
+	return s.key.sign(s.scheme, timeoutMessage(view, s.data.HighQC.View)), nil
+}
+
+// save persists next before adopting it
+func (s *SafetyRules) save(next SafetyData) error {
+	if s.storage != nil {
+		if err := s.storage.SaveSafetyData(next); err != nil {
+			return fmt.Errorf("failed to persist hotstuff safety data: %w", err)
+		}
+	}
+	s.data = next
+	return nil
+}
@@ -0,0 +1,137 @@
+package hotstuff
+
+import (
+	"errors"
+	"testing"
+
+	dbm "github.com/cosmos/cosmos-db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingStorage rejects every save
+type failingStorage struct{}
+
+func (failingStorage) SaveSafetyData(SafetyData) error {
+	return errors.New("disk full")
+}
+
+func (failingStorage) LoadSafetyData() (SafetyData, bool, error) {
+	return SafetyData{}, false, nil
+}
+
+// testChain builds genesis <- b1 <- b2, each justified by a QC for its parent
+func testChain() (genesis, b1, b2 *Block) {
+	genesis = genesisBlock()
+	b1 = &Block{View: 1, Height: 1, Parent: genesis.Hash(), Justify: genesisQC(genesis), Proposer: "a"}
+	b2 = &Block{View: 2, Height: 2, Parent: b1.Hash(), Justify: &QuorumCert{View: 1, BlockHash: b1.Hash()}, Proposer: "b"}
+	return genesis, b1, b2
+}
+
+func TestSafetyRules_VotesOncePerView(t *testing.T) {
+	genesis, b1, _ := testChain()
+	s := NewSafetyRules(genesisQC(genesis))
+
+	_, err := s.Vote(b1, genesis)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), s.LastVotedView())
+
+	// An equivocating block in the same view
+	twin := *b1
+	twin.Payload = []byte("twin")
+	_, err = s.Vote(&twin, genesis)
+	assert.ErrorIs(t, err, ErrUnsafeVote)
+
+	// After timing out of a view, no vote in it
+	_, err = s.Timeout(3)
+	require.NoError(t, err)
+	late := &Block{View: 3, Height: 2, Parent: b1.Hash(), Justify: &QuorumCert{View: 1, BlockHash: b1.Hash()}}
+	_, err = s.Vote(late, b1)
+	assert.ErrorIs(t, err, ErrUnsafeVote)
+}
+
+func TestSafetyRules_RespectsLock(t *testing.T) {
+	genesis, b1, b2 := testChain()
+	s := NewSafetyRules(genesisQC(genesis))
+
+	// b3 justified by b2's QC locks on b1
+	b3 := &Block{View: 3, Height: 3, Parent: b2.Hash(), Justify: &QuorumCert{View: 2, BlockHash: b2.Hash()}}
+	_, err := s.Vote(b3, b2)
+	require.NoError(t, err)
+	assert.Equal(t, b2.Justify, s.LockedQC())
+	assert.Equal(t, b3.Justify, s.HighQC())
+
+	// A fork from genesis conflicts with the lock
+	fork := &Block{View: 4, Height: 1, Parent: genesis.Hash(), Justify: genesisQC(genesis)}
+	_, err = s.Vote(fork, genesis)
+	assert.ErrorIs(t, err, ErrUnsafeVote)
+
+	// A block on the locked branch is fine
+	b4 := &Block{View: 4, Height: 2, Parent: b1.Hash(), Justify: b2.Justify}
+	_, err = s.Vote(b4, b1)
+	assert.NoError(t, err)
+
+	// The parent must be the block the justify QC certifies
+	b5 := &Block{View: 5, Height: 3, Parent: b2.Hash(), Justify: b3.Justify}
+	_, err = s.Vote(b5, b1)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnsafeVote)
+}
+
+func TestSafetyRules_PersistsBeforeSigning(t *testing.T) {
+	genesis, b1, _ := testChain()
+	keys, _, err := testValidators([]string{"a"})
+	require.NoError(t, err)
+
+	s := NewSafetyRules(genesisQC(genesis))
+	s.SetSigner(keys["a"], QCSchemeBLS)
+	require.NoError(t, s.SetStorage(failingStorage{}))
+
+	sig, err := s.Vote(b1, genesis)
+	assert.ErrorContains(t, err, "disk full")
+	assert.Nil(t, sig)
+	assert.Zero(t, s.LastVotedView())
+
+	// With working storage the vote is signed and survives a restart
+	storage := NewDBSafetyStorage(dbm.NewMemDB())
+	require.NoError(t, s.SetStorage(storage))
+	sig, err = s.Vote(b1, genesis)
+	require.NoError(t, err)
+	assert.NotEmpty(t, sig)
+
+	restarted := NewSafetyRules(genesisQC(genesis))
+	require.NoError(t, restarted.SetStorage(storage))
+	assert.Equal(t, uint64(1), restarted.LastVotedView())
+	_, err = restarted.Vote(b1, genesis)
+	assert.ErrorIs(t, err, ErrUnsafeVote)
+}
+
+func TestHotStuff_NoDoubleVoteAfterRestart(t *testing.T) {
+	net := newSimNetwork(4)
+	storage := NewDBSafetyStorage(dbm.NewMemDB())
+	require.NoError(t, net.nodes["node0"].SetSafetyStorage(storage))
+	require.NoError(t, net.nodes["node1"].Propose([]byte("tx")))
+
+	// Capture the view 1 proposal to node0 and let node0 vote
+	var proposal *Message
+	for _, msg := range net.queue {
+		if msg.Type == MessageTypeProposal && msg.To == "node0" {
+			proposal = msg
+		}
+	}
+	require.NotNil(t, proposal)
+	net.queue = nil
+	require.NoError(t, net.nodes["node0"].HandleMessage(proposal))
+	require.Len(t, net.queue, 1)
+	assert.Equal(t, MessageTypeVote, net.queue[0].Type)
+	net.queue = nil
+
+	// After a restart the replica remembers its vote and stays in its view
+	restarted := NewHotStuffNode("node0", []string{"node1", "node2", "node3"})
+	restarted.SetTransport(net)
+	require.NoError(t, restarted.SetSafetyStorage(storage))
+	assert.Equal(t, uint64(1), restarted.View())
+
+	require.NoError(t, restarted.HandleMessage(proposal))
+	assert.Empty(t, net.queue, "no second vote in view 1")
+}
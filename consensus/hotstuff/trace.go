@@ -0,0 +1,66 @@
+package hotstuff
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+// SetTracer sets the tracer the replica records view spans with, starting
+// with a span for the current view
+func (h *HotStuffConsensus) SetTracer(t trace.Tracer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.tracer = t
+	h.startViewSpan("")
+}
+
+// startViewSpan ends the previous view's span, recording what ended it, and
+// starts one for the current view. Proposals carry the leader's view span,
+// so replicas' handling of a proposal joins the leader's trace.
+func (h *HotStuffConsensus) startViewSpan(endedBy string) {
+	now := h.now()
+	if endedBy != "" {
+		h.viewSpan.SetAttributes(attribute.String("ended_by", endedBy))
+	}
+	h.viewSpan.End(trace.WithTimestamp(now))
+
+	h.viewCtx, h.viewSpan = h.tracer.Start(context.Background(), "hotstuff.view",
+		trace.WithTimestamp(now),
+		trace.WithAttributes(
+			common.AttributeEngine.String(EngineName),
+			common.AttributeNodeID.String(h.id),
+			common.AttributeView.Int64(int64(h.view)),
+		),
+	)
+}
+
+// startProposalSpan starts the span of handling a proposal, as a child of
+// the leader's view span and linked to our own
+func (h *HotStuffConsensus) startProposalSpan(msg *Message) (context.Context, trace.Span) {
+	return h.tracer.Start(common.ExtractTrace(msg.TraceContext), "hotstuff.proposal",
+		trace.WithTimestamp(h.now()),
+		trace.WithLinks(trace.LinkFromContext(h.viewCtx)),
+		trace.WithAttributes(
+			common.AttributeEngine.String(EngineName),
+			common.AttributeNodeID.String(h.id),
+			common.AttributeView.Int64(int64(msg.Block.View)),
+			common.AttributeFrom.String(msg.From),
+		),
+	)
+}
+
+// traceEvent records a message on the current view's span
+func (h *HotStuffConsensus) traceEvent(msg *Message) {
+	h.viewSpan.AddEvent(msg.Type.String(),
+		trace.WithTimestamp(h.now()),
+		trace.WithAttributes(
+			common.AttributeFrom.String(msg.From),
+			common.AttributeView.Int64(int64(msg.View)),
+		),
+	)
+}
@@ -0,0 +1,124 @@
+package raft
+
+import (
+	"fmt"
+	"time"
+
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	"github.com/spf13/cast"
+)
+
+// Config holds the tunable parameters of a Raft node
+type Config struct {
+	ElectionTimeout   time.Duration
+	HeartbeatInterval time.Duration
+	TickInterval      time.Duration
+	MaxEntriesPerMsg  int
+
+	// PreVote makes a node win a pre-election before bumping its term, so a
+	// partitioned node rejoining cannot disrupt the cluster with a high term
+	PreVote bool
+
+	// CheckQuorum makes a leader step down when it has not heard from a
+	// majority within an election timeout. Followers that heard from a
+	// leader within that time also ignore vote requests.
+	CheckQuorum bool
+
+	// LeaseRead lets the leader serve linearizable reads without a heartbeat
+	// round while a majority acknowledged it within an election timeout.
+	// Requires CheckQuorum and assumes bounded clock drift.
+	LeaseRead bool
+}
+
+// DefaultConfig returns the configuration of a classic Raft node
+func DefaultConfig() Config {
+	return Config{
+		ElectionTimeout:   150 * time.Millisecond,
+		HeartbeatInterval: 50 * time.Millisecond,
+		TickInterval:      10 * time.Millisecond,
+		MaxEntriesPerMsg:  64,
+	}
+}
+
+// Validate checks the configuration for consistency
+func (c Config) Validate() error {
+	if c.TickInterval <= 0 {
+		return fmt.Errorf("tick interval must be positive")
+	}
+	if c.HeartbeatInterval < c.TickInterval {
+		return fmt.Errorf("heartbeat interval %s is shorter than the tick interval %s", c.HeartbeatInterval, c.TickInterval)
+	}
+	if c.ElectionTimeout <= c.HeartbeatInterval {
+		return fmt.Errorf("election timeout %s must exceed the heartbeat interval %s", c.ElectionTimeout, c.HeartbeatInterval)
+	}
+	if c.MaxEntriesPerMsg <= 0 {
+		return fmt.Errorf("max entries per message must be positive")
+	}
+	if c.LeaseRead && !c.CheckQuorum {
+		return fmt.Errorf("lease reads require check_quorum")
+	}
+	return nil
+}
+
+// ConfigFromAppOptions reads the [raft] section of the node configuration,
+// keeping defaults for missing keys
+func ConfigFromAppOptions(appOpts servertypes.AppOptions) (Config, error) {
+	cfg := DefaultConfig()
+
+	durations := map[string]*time.Duration{
+		"raft.election_timeout":   &cfg.ElectionTimeout,
+		"raft.heartbeat_interval": &cfg.HeartbeatInterval,
+		"raft.tick_interval":      &cfg.TickInterval,
+	}
+	for key, field := range durations {
+		if v := appOpts.Get(key); v != nil {
+			d, err := cast.ToDurationE(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*field = d
+		}
+	}
+
+	if v := appOpts.Get("raft.max_entries_per_msg"); v != nil {
+		n, err := cast.ToIntE(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid raft.max_entries_per_msg: %w", err)
+		}
+		cfg.MaxEntriesPerMsg = n
+	}
+
+	toggles := map[string]*bool{
+		"raft.pre_vote":     &cfg.PreVote,
+		"raft.check_quorum": &cfg.CheckQuorum,
+		"raft.lease_read":   &cfg.LeaseRead,
+	}
+	for key, field := range toggles {
+		if v := appOpts.Get(key); v != nil {
+			b, err := cast.ToBoolE(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*field = b
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// SetConfig applies cfg. It must be called before the node starts.
+func (r *RaftConsensus) SetConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := cfg.ElectionTimeout != r.config.ElectionTimeout
+	r.config = cfg
+	if changed {
+		r.resetElectionTimer()
+	}
+	return nil
+}
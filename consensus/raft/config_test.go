@@ -0,0 +1,49 @@
+package raft
+
+import (
+	"testing"
+	"time"
+
+	simtestutil "github.com/cosmos/cosmos-sdk/testutil/sims"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+func TestConfigFromAppOptions(t *testing.T) {
+	cfg, err := ConfigFromAppOptions(simtestutil.AppOptionsMap{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfig(), cfg)
+
+	cfg, err = ConfigFromAppOptions(simtestutil.AppOptionsMap{
+		"raft.election_timeout": "300ms",
+		"raft.pre_vote":         true,
+		"raft.check_quorum":     "true",
+		"raft.lease_read":       true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 300*time.Millisecond, cfg.ElectionTimeout)
+	assert.True(t, cfg.PreVote)
+	assert.True(t, cfg.CheckQuorum)
+	assert.True(t, cfg.LeaseRead)
+
+	_, err = ConfigFromAppOptions(simtestutil.AppOptionsMap{"raft.lease_read": true})
+	assert.ErrorContains(t, err, "check_quorum")
+
+	_, err = ConfigFromAppOptions(simtestutil.AppOptionsMap{"raft.pre_vote": "maybe"})
+	assert.Error(t, err)
+}
+
+func TestRegisteredEngine(t *testing.T) {
+	engine, err := common.NewEngine("raft", common.Dependencies{
+		AppOptions: simtestutil.AppOptionsMap{"raft.election_timeout": "300ms"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 300*time.Millisecond, engine.(*RaftConsensus).config.ElectionTimeout)
+
+	_, err = common.NewEngine("raft", common.Dependencies{
+		AppOptions: simtestutil.AppOptionsMap{"raft.lease_read": true},
+	})
+	assert.ErrorContains(t, err, "check_quorum")
+}
@@ -1,80 +1,236 @@
 package raft
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
+	"math/rand"
 	"sync"
 	"time"
 
+	"cosmossdk.io/log"
 	abci "github.com/cometbft/cometbft/abci/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
 )
 
 // RaftConsensus implements the Raft consensus engine
 type RaftConsensus struct {
-	mu      sync.RWMutex
-	running bool
+	mu   sync.RWMutex
+	loop common.Loop
+
+	// Cluster
+	id            string
+	transport     Transport
+	storage       Storage // Optional stable storage; nil keeps state in memory only
+	stakingKeeper StakingKeeper
+
+	// Membership: the base configuration as of the log's first index, and
+	// the current one with every conf change in the log applied
+	baseMembership   Membership
+	voters           map[string]bool
+	learners         map[string]bool
+	pendingConfIndex uint64          // Index of the latest conf change
+	autoPromote      map[string]bool // Learners to promote once caught up
+	membershipStale  bool            // Bonded validators changed since the membership last matched them
 
 	// Raft specific fields
 	currentTerm uint64
 	votedFor    string
-	log         []interface{} // Placeholder for log entries
+	log         *raftLog
 	role        Role
+	leaderID    string
+	votes       map[string]bool // Peer -> granted, for the current candidacy
+
+	// Leader replication state, reinitialized after election
+	nextIndex  map[string]uint64 // Peer -> next log index to send
+	matchIndex map[string]uint64 // Peer -> highest index known replicated
+
+	// Latest snapshot, and one waiting to be delivered on applyCh
+	snapshot        *Snapshot
+	pendingSnapshot *Snapshot
+
+	// Committed entries are delivered in order on applyCh
+	applyCh      chan LogEntry
+	applyCond    *sync.Cond
+	applyOnce    sync.Once
+	applyStopped bool
+
+	config Config
 
-	// Config
-	electionTimeout   time.Duration
-	heartbeatInterval time.Duration
+	metrics    *common.RaftMetrics
+	lastLeader string // Last known leader, for counting leader changes
+
+	tracer      trace.Tracer
+	entryTraces map[uint64]entryTrace // Index -> span, for the leader's uncommitted entries
+	now         func() time.Time      // Wall clock for span timestamps
+
+	logger log.Logger
+
+	// Timers, advanced by tick
+	randomizedElectionTimeout time.Duration // In [electionTimeout, 2*electionTimeout)
+	electionElapsed           time.Duration
+	heartbeatElapsed          time.Duration
+	clock                     time.Duration // Total time ticked
+	rand                      *rand.Rand
+
+	// CheckQuorum and reads: peers heard from since the last quorum check,
+	// and the heartbeat round each peer last acknowledged
+	recentActive map[string]bool
+	heartbeatSeq uint64
+	roundSentAt  map[uint64]time.Duration // Round -> clock when it started
+	ackedRound   map[string]uint64
+	pendingReads []*readRequest
 }
 
+// EngineName is the name selecting Raft in the consensus-engine option
+const EngineName = "raft"
+
 type Role int
 
 const (
 	Follower Role = iota
 	Candidate
 	Leader
+	PreCandidate // Polling peers before starting an election (PreVote)
 )
 
+func (r Role) String() string {
+	switch r {
+	case Follower:
+		return "Follower"
+	case Candidate:
+		return "Candidate"
+	case Leader:
+		return "Leader"
+	case PreCandidate:
+		return "PreCandidate"
+	}
+	return "Unknown"
+}
+
 // NewRaftConsensus creates a new Raft consensus instance
 func NewRaftConsensus() *RaftConsensus {
-	return &RaftConsensus{
-		role:              Follower,
-		electionTimeout:   150 * time.Millisecond, // Randomize in real impl
-		heartbeatInterval: 50 * time.Millisecond,
+	// Single-node cluster, to be configured if running with peers
+	return NewRaftNode("local-node", []string{})
+}
+
+// NewRaftNode creates a Raft node with the given ID and peer IDs (excluding itself)
+func NewRaftNode(id string, peers []string) *RaftConsensus {
+	// Seed from the node ID so simulated runs are reproducible while
+	// different nodes still draw different election timeouts
+	h := fnv.New64a()
+	h.Write([]byte(id))
+
+	r := &RaftConsensus{
+		id:              id,
+		baseMembership:  Membership{Voters: append([]string{id}, peers...)},
+		autoPromote:     make(map[string]bool),
+		membershipStale: true,
+		log:             newRaftLog(),
+		role:            Follower,
+		config:          DefaultConfig(),
+		metrics:         common.NopMetrics().Raft,
+		tracer:          common.NopTracer(),
+		entryTraces:     make(map[uint64]entryTrace),
+		now:             time.Now,
+		logger:          log.NewNopLogger(),
+		rand:            rand.New(rand.NewSource(int64(h.Sum64()))),
 	}
+	r.applyCond = sync.NewCond(&r.mu)
+	r.recomputeMembership()
+	r.resetElectionTimer()
+	return r
 }
 
-// Start starts the consensus engine
-func (r *RaftConsensus) Start() error {
+// SetTransport sets the transport used to reach peers
+func (r *RaftConsensus) SetTransport(t Transport) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if r.running {
-		return fmt.Errorf("Raft engine already running")
-	}
+	r.transport = t
+}
 
-	r.running = true
-	go r.runLoop()
-	return nil
+// SetMetrics sets the metrics the node reports to
+func (r *RaftConsensus) SetMetrics(m *common.RaftMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = m
+	r.observeState()
 }
 
-// Stop stops the consensus engine
-func (r *RaftConsensus) Stop() error {
+// SetLogger sets the logger elections and membership changes are logged
+// to, scoped to the engine and node
+func (r *RaftConsensus) SetLogger(l log.Logger) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if !r.running {
-		return nil
+	r.logger = l.With("engine", EngineName, "node_id", r.id)
+}
+
+// observeState reports the term and counts a change of known leader
+func (r *RaftConsensus) observeState() {
+	r.metrics.Term.Set(float64(r.currentTerm))
+	if r.leaderID != "" && r.leaderID != r.lastLeader {
+		r.lastLeader = r.leaderID
+		r.metrics.LeaderChanges.Add(1)
+		r.logger.Info("new leader", "leader", r.leaderID, "view", r.currentTerm)
 	}
+}
+
+// ID returns the node ID
+func (r *RaftConsensus) ID() string {
+	return r.id
+}
 
-	r.running = false
+// State returns the current term, role and known leader
+func (r *RaftConsensus) State() (term uint64, role Role, leader string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.currentTerm, r.role, r.leaderID
+}
+
+// Name implements ConsensusEngine
+func (r *RaftConsensus) Name() string {
+	return EngineName
+}
+
+// Start starts the consensus engine
+func (r *RaftConsensus) Start(ctx context.Context) error {
+	if err := r.loop.Start(ctx, r.runLoop); err != nil {
+		return fmt.Errorf("Raft engine %w", err)
+	}
+	return nil
+}
+
+// Stop stops the consensus engine and waits for its tick loop to exit
+func (r *RaftConsensus) Stop() error {
+	r.mu.Lock()
+	// Release the apply loop even if the node was only driven manually
+	r.applyStopped = true
+	r.applyCond.Broadcast()
+	r.mu.Unlock()
+
+	r.loop.Stop()
 	return nil
 }
 
-func (r *RaftConsensus) runLoop() {
-	ticker := time.NewTicker(r.heartbeatInterval)
+func (r *RaftConsensus) runLoop(ctx context.Context) {
+	r.mu.RLock()
+	interval := r.config.TickInterval
+	r.mu.RUnlock()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for r.running {
+	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-ticker.C:
 			// Handle election timeout / heartbeats
 			r.tick()
@@ -82,25 +238,186 @@ func (r *RaftConsensus) runLoop() {
 	}
 }
 
+// Status implements ConsensusEngine
+func (r *RaftConsensus) Status() common.Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return common.Status{
+		Engine:    EngineName,
+		NodeID:    r.id,
+		Running:   r.loop.Running(),
+		Round:     r.currentTerm,
+		Leader:    r.leaderID,
+		Committed: r.log.committed,
+	}
+}
+
+// Health implements ConsensusEngine
+func (r *RaftConsensus) Health() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.loop.Running() {
+		return fmt.Errorf("Raft engine not running")
+	}
+	if r.leaderID == "" {
+		return fmt.Errorf("no known leader in term %d", r.currentTerm)
+	}
+	return nil
+}
+
+// Tick advances the node's timers by one tick interval. Start drives it from
+// a ticker; simulations call it on a virtual clock instead.
+func (r *RaftConsensus) Tick() {
+	r.tick()
+}
+
+// SetClock sets the clock span timestamps are taken from
+func (r *RaftConsensus) SetClock(now func() time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.now = now
+}
+
+// tick advances the logical clock by one tick interval
 func (r *RaftConsensus) tick() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Simplified logic
+	r.clock += r.config.TickInterval
+
 	if r.role == Leader {
-		// Send heartbeats
-	} else {
-		// Check election timeout
+		r.electionElapsed += r.config.TickInterval
+		if r.electionElapsed >= r.config.ElectionTimeout {
+			r.electionElapsed = 0
+			if r.config.CheckQuorum && !r.quorumActive() {
+				// Partitioned from the majority; stop accepting proposals
+				r.becomeFollower(r.currentTerm, "")
+				return
+			}
+		}
+
+		r.heartbeatElapsed += r.config.TickInterval
+		if r.heartbeatElapsed >= r.config.HeartbeatInterval {
+			r.heartbeatElapsed = 0
+			r.broadcastAppend()
+		}
+		return
+	}
+
+	r.electionElapsed += r.config.TickInterval
+	if r.electionElapsed >= r.randomizedElectionTimeout {
+		if !r.voters[r.id] {
+			// Learners and removed nodes never campaign
+			r.resetElectionTimer()
+			return
+		}
+		if r.config.PreVote {
+			r.preCampaign()
+		} else {
+			r.campaign()
+		}
 	}
 }
 
-// BeginBlock implements ConsensusEngine
-func (r *RaftConsensus) BeginBlock(ctx sdk.Context) {
-	// No-op for now
+// HandleMessage processes an incoming Raft message
+func (r *RaftConsensus) HandleMessage(msg *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case msg.Term > r.currentTerm:
+		if (msg.Type == MessageTypeRequestVote || msg.Type == MessageTypePreVote) && r.inLease() {
+			// A leader was heard from recently, so the sender is likely
+			// partitioned or removed; don't let it disrupt the cluster
+			return nil
+		}
+		if msg.Type == MessageTypePreVote || (msg.Type == MessageTypePreVoteResponse && !msg.Reject) {
+			// Pre-votes are for a future term and don't change ours
+			break
+		}
+		// Any RPC with a higher term moves us to that term as follower
+		leader := ""
+		if msg.Type == MessageTypeAppendEntries || msg.Type == MessageTypeInstallSnapshot {
+			leader = msg.From
+		}
+		r.becomeFollower(msg.Term, leader)
+	case msg.Term < r.currentTerm:
+		// Stale sender; answer requests so it learns the newer term
+		switch msg.Type {
+		case MessageTypeRequestVote:
+			r.send(&Message{Type: MessageTypeRequestVoteResponse, To: msg.From, Reject: true})
+		case MessageTypePreVote:
+			r.send(&Message{Type: MessageTypePreVoteResponse, To: msg.From, Reject: true})
+		case MessageTypeAppendEntries, MessageTypeInstallSnapshot:
+			r.send(&Message{Type: MessageTypeAppendEntriesResponse, To: msg.From, Reject: true})
+		}
+		return nil
+	}
+
+	switch msg.Type {
+	case MessageTypeRequestVote:
+		r.handleRequestVote(msg)
+	case MessageTypeRequestVoteResponse:
+		r.handleRequestVoteResponse(msg)
+	case MessageTypePreVote:
+		r.handlePreVote(msg)
+	case MessageTypePreVoteResponse:
+		r.handlePreVoteResponse(msg)
+	case MessageTypeAppendEntries:
+		r.handleAppendEntries(msg)
+	case MessageTypeAppendEntriesResponse:
+		r.handleAppendEntriesResponse(msg)
+	case MessageTypeInstallSnapshot:
+		r.handleInstallSnapshot(msg)
+	default:
+		return fmt.Errorf("unknown raft message type %d", msg.Type)
+	}
+	return nil
 }
 
-// EndBlock implements ConsensusEngine
-func (r *RaftConsensus) EndBlock(ctx sdk.Context) []abci.ValidatorUpdate {
+// send stamps the message with our ID and term and hands it to the transport
+func (r *RaftConsensus) send(msg *Message) {
+	r.sendAtTerm(msg, r.currentTerm)
+}
+
+// sendAtTerm sends a message for a term other than the current one, as
+// pre-votes do
+func (r *RaftConsensus) sendAtTerm(msg *Message, term uint64) {
+	if r.transport == nil {
+		return
+	}
+	msg.From = r.id
+	msg.Term = term
+	r.transport.Send(msg)
+}
+
+// quorum returns the number of votes needed for a majority of the cluster
+func (r *RaftConsensus) quorum() int {
+	return len(r.voters)/2 + 1
+}
+
+// BeginBlock implements ConsensusEngine
+func (r *RaftConsensus) BeginBlock(ctx sdk.Context) error {
 	// No-op for now
 	return nil
 }
+
+// EndBlock implements ConsensusEngine. After the bonded validators change,
+// the leader moves the membership a step towards them every block until it
+// matches.
+func (r *RaftConsensus) EndBlock(ctx sdk.Context) ([]abci.ValidatorUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.membershipStale {
+		synced, err := r.reconcileMembership(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r.membershipStale = !synced
+	}
+	return nil, nil
+}
@@ -0,0 +1,29 @@
+package raft
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaft_Lifecycle(t *testing.T) {
+	node := NewRaftConsensus()
+	assert.Equal(t, "raft", node.Name())
+	assert.ErrorContains(t, node.Health(), "not running")
+
+	require.NoError(t, node.Start(context.Background()))
+	assert.Error(t, node.Start(context.Background()))
+
+	// A single-node cluster elects itself once the election timer fires
+	assert.Eventually(t, func() bool { return node.Health() == nil }, 2*time.Second, 10*time.Millisecond)
+	status := node.Status()
+	assert.True(t, status.Running)
+	assert.Equal(t, node.ID(), status.Leader)
+	assert.NotZero(t, status.Round)
+
+	require.NoError(t, node.Stop())
+	assert.False(t, node.Status().Running)
+}
@@ -0,0 +1,213 @@
+package raft
+
+import (
+	"fmt"
+	"time"
+)
+
+// resetElectionTimer restarts the election timer with a fresh random timeout
+func (r *RaftConsensus) resetElectionTimer() {
+	r.electionElapsed = 0
+	r.randomizedElectionTimeout = r.config.ElectionTimeout +
+		time.Duration(r.rand.Int63n(int64(r.config.ElectionTimeout)))
+}
+
+// becomeFollower steps down to follower in the given term
+func (r *RaftConsensus) becomeFollower(term uint64, leader string) {
+	if term > r.currentTerm {
+		r.currentTerm = term
+		r.votedFor = ""
+		r.saveHardState()
+	}
+	if r.role == Leader {
+		r.failReads(fmt.Errorf("node %s lost leadership", r.id))
+		r.traceAbandon()
+	}
+	r.role = Follower
+	r.leaderID = leader
+	r.votes = nil
+	r.resetElectionTimer()
+	r.observeState()
+}
+
+// becomePreCandidate starts polling peers without changing the term
+func (r *RaftConsensus) becomePreCandidate() {
+	r.role = PreCandidate
+	r.leaderID = ""
+	r.votes = map[string]bool{r.id: true}
+	r.resetElectionTimer()
+}
+
+// becomeCandidate starts a new term and votes for itself
+func (r *RaftConsensus) becomeCandidate() {
+	r.currentTerm++
+	r.votedFor = r.id
+	r.saveHardState()
+	r.role = Candidate
+	r.leaderID = ""
+	r.votes = map[string]bool{r.id: true}
+	r.resetElectionTimer()
+	r.observeState()
+	r.logger.Debug("starting election", "view", r.currentTerm)
+}
+
+// becomeLeader takes leadership of the current term
+func (r *RaftConsensus) becomeLeader() {
+	r.role = Leader
+	r.leaderID = r.id
+	r.votes = nil
+	r.observeState()
+	r.heartbeatElapsed = 0
+	r.electionElapsed = 0
+	r.recentActive = make(map[string]bool)
+	r.roundSentAt = make(map[uint64]time.Duration)
+	r.ackedRound = make(map[string]uint64)
+
+	peers := r.peers()
+	r.nextIndex = make(map[string]uint64, len(peers))
+	r.matchIndex = make(map[string]uint64, len(peers))
+	for _, peer := range peers {
+		r.nextIndex[peer] = r.log.lastIndex() + 1
+	}
+
+	// An uncommitted conf change from an earlier term may still be in the
+	// log; hold further changes until this term's first entry commits
+	r.pendingConfIndex = r.log.lastIndex()
+
+	// Entries from earlier terms only commit once an entry from the current
+	// term does (Raft §5.4.2), so start the term with a no-op
+	r.appendEntry(EntryNoop, nil)
+
+	// Assert leadership immediately so other candidates step down
+	r.broadcastAppend()
+}
+
+// campaign starts an election for the next term
+func (r *RaftConsensus) campaign() {
+	r.becomeCandidate()
+
+	if r.grantedVotes() >= r.quorum() {
+		// Single-node cluster
+		r.becomeLeader()
+		return
+	}
+
+	for _, peer := range r.peers() {
+		if !r.voters[peer] {
+			continue
+		}
+		r.send(&Message{
+			Type:     MessageTypeRequestVote,
+			To:       peer,
+			LogIndex: r.log.lastIndex(),
+			LogTerm:  r.log.lastTerm(),
+		})
+	}
+}
+
+// preCampaign asks voters whether they would grant a vote in the next term
+// (Raft thesis §9.6). The term is only bumped once a majority agrees.
+func (r *RaftConsensus) preCampaign() {
+	r.becomePreCandidate()
+
+	if r.grantedVotes() >= r.quorum() {
+		r.campaign()
+		return
+	}
+
+	for _, peer := range r.peers() {
+		if !r.voters[peer] {
+			continue
+		}
+		r.sendAtTerm(&Message{
+			Type:     MessageTypePreVote,
+			To:       peer,
+			LogIndex: r.log.lastIndex(),
+			LogTerm:  r.log.lastTerm(),
+		}, r.currentTerm+1)
+	}
+}
+
+func (r *RaftConsensus) handlePreVote(msg *Message) {
+	// Grant if we would vote in that term and have not heard from a leader
+	// within the minimum election timeout. Nothing is persisted.
+	heardFromLeader := r.leaderID != "" && r.electionElapsed < r.config.ElectionTimeout
+	if msg.Term <= r.currentTerm || heardFromLeader || !r.voters[r.id] || !r.log.isUpToDate(msg.LogIndex, msg.LogTerm) {
+		r.send(&Message{Type: MessageTypePreVoteResponse, To: msg.From, Reject: true})
+		return
+	}
+	r.sendAtTerm(&Message{Type: MessageTypePreVoteResponse, To: msg.From}, msg.Term)
+}
+
+func (r *RaftConsensus) handlePreVoteResponse(msg *Message) {
+	if r.role != PreCandidate {
+		return
+	}
+
+	r.votes[msg.From] = !msg.Reject
+
+	if r.grantedVotes() >= r.quorum() {
+		r.campaign()
+	} else if len(r.votes)-r.grantedVotes() >= r.quorum() {
+		r.becomeFollower(r.currentTerm, "")
+	}
+}
+
+// inLease reports whether a leader was heard from within the election
+// timeout, during which CheckQuorum nodes ignore vote requests
+func (r *RaftConsensus) inLease() bool {
+	return r.config.CheckQuorum && r.leaderID != "" && r.electionElapsed < r.config.ElectionTimeout
+}
+
+// quorumActive reports whether a majority of voters was heard from since
+// the last check, and starts a new check
+func (r *RaftConsensus) quorumActive() bool {
+	active := 0
+	for id := range r.voters {
+		if id == r.id || r.recentActive[id] {
+			active++
+		}
+	}
+	r.recentActive = make(map[string]bool)
+	return active >= r.quorum()
+}
+
+func (r *RaftConsensus) handleRequestVote(msg *Message) {
+	// At most one vote per term, first come first served, and only for
+	// candidates whose log contains every entry we may have acknowledged
+	canVote := r.votedFor == "" || r.votedFor == msg.From
+	if !canVote || r.role == Leader || !r.voters[r.id] || !r.log.isUpToDate(msg.LogIndex, msg.LogTerm) {
+		r.send(&Message{Type: MessageTypeRequestVoteResponse, To: msg.From, Reject: true})
+		return
+	}
+
+	r.votedFor = msg.From
+	r.saveHardState()
+	r.resetElectionTimer()
+	r.send(&Message{Type: MessageTypeRequestVoteResponse, To: msg.From})
+}
+
+func (r *RaftConsensus) handleRequestVoteResponse(msg *Message) {
+	if r.role != Candidate {
+		return
+	}
+
+	r.votes[msg.From] = !msg.Reject
+
+	if r.grantedVotes() >= r.quorum() {
+		r.becomeLeader()
+	} else if len(r.votes)-r.grantedVotes() >= r.quorum() {
+		// Lost the election; wait for the winner or the next timeout
+		r.becomeFollower(r.currentTerm, "")
+	}
+}
+
+func (r *RaftConsensus) grantedVotes() int {
+	granted := 0
+	for _, v := range r.votes {
+		if v {
+			granted++
+		}
+	}
+	return granted
+}
@@ -0,0 +1,255 @@
+package raft
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+// electionTicks is enough ticks for at least one election round to complete
+const electionTicks = 100
+
+func TestRaft_ElectsSingleLeader(t *testing.T) {
+	for _, size := range []int{3, 5, 7} {
+		t.Run(fmt.Sprintf("%d nodes", size), func(t *testing.T) {
+			net := newSimNetwork(size)
+			net.run(electionTicks)
+
+			leaders := net.leaders(false)
+			require.Len(t, leaders, 1)
+
+			// Every node agrees on the term and the leader
+			leaderTerm, _, _ := net.nodes[leaders[0]].State()
+			for _, id := range net.order {
+				term, _, leader := net.nodes[id].State()
+				assert.Equal(t, leaderTerm, term, id)
+				assert.Equal(t, leaders[0], leader, id)
+			}
+
+			// Heartbeats keep the leader in place
+			net.run(electionTicks)
+			assert.Equal(t, leaders, net.leaders(false))
+		})
+	}
+}
+
+func TestRaft_ReelectsAfterLeaderPartition(t *testing.T) {
+	for _, size := range []int{3, 5, 7} {
+		t.Run(fmt.Sprintf("%d nodes", size), func(t *testing.T) {
+			net := newSimNetwork(size)
+			net.run(electionTicks)
+			require.Len(t, net.leaders(false), 1)
+
+			oldLeader := net.leaders(false)[0]
+			oldTerm, _, _ := net.nodes[oldLeader].State()
+
+			// The majority side elects a new leader in a higher term
+			net.isolate(oldLeader)
+			net.run(electionTicks)
+
+			newLeaders := net.leaders(true)
+			require.Len(t, newLeaders, 1)
+			assert.NotEqual(t, oldLeader, newLeaders[0])
+			newTerm, _, _ := net.nodes[newLeaders[0]].State()
+			assert.Greater(t, newTerm, oldTerm)
+
+			// The old leader steps down once it hears the higher term
+			net.heal()
+			net.run(electionTicks)
+
+			require.Len(t, net.leaders(false), 1)
+			_, role, _ := net.nodes[oldLeader].State()
+			assert.Equal(t, Follower, role)
+		})
+	}
+}
+
+func TestRaft_MinorityCannotElect(t *testing.T) {
+	net := newSimNetwork(5)
+	net.run(electionTicks)
+	leader := net.leaders(false)[0]
+
+	// Isolate three followers: they keep campaigning but never win
+	isolated := 0
+	for _, id := range net.order {
+		if id != leader && isolated < 3 {
+			net.isolate(id)
+			isolated++
+		}
+	}
+	net.run(electionTicks)
+
+	for _, id := range net.order {
+		if net.isolated[id] {
+			_, role, _ := net.nodes[id].State()
+			assert.NotEqual(t, Leader, role, id)
+		}
+	}
+}
+
+func TestRaft_VotesOncePerTerm(t *testing.T) {
+	node := NewRaftNode("node0", []string{"node1", "node2"})
+	net := &simNetwork{nodes: map[string]*RaftConsensus{}, isolated: map[string]bool{}}
+	node.SetTransport(net)
+
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeRequestVote, Term: 1, From: "node1", To: "node0"}))
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeRequestVote, Term: 1, From: "node2", To: "node0"}))
+
+	require.Len(t, net.queue, 2)
+	assert.False(t, net.queue[0].Reject, "first candidate should get the vote")
+	assert.True(t, net.queue[1].Reject, "second candidate in the same term should be rejected")
+
+	// A stale-term request is rejected with our term
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeRequestVote, Term: 0, From: "node2", To: "node0"}))
+	assert.True(t, net.queue[2].Reject)
+	assert.Equal(t, uint64(1), net.queue[2].Term)
+}
+
+func TestRaft_SingleNodeBecomesLeader(t *testing.T) {
+	node := NewRaftConsensus()
+	for i := 0; i < electionTicks; i++ {
+		node.tick()
+	}
+
+	term, role, leader := node.State()
+	assert.Equal(t, Leader, role)
+	assert.Equal(t, uint64(1), term)
+	assert.Equal(t, node.ID(), leader)
+}
+
+// partitionFollower isolates a follower long enough for it to time out
+// repeatedly, then heals the network
+func partitionFollower(t *testing.T, net *simNetwork) (leader, follower string) {
+	t.Helper()
+
+	net.run(electionTicks)
+	leaders := net.leaders(false)
+	require.Len(t, leaders, 1)
+	leader = leaders[0]
+	for _, id := range net.order {
+		if id != leader {
+			follower = id
+			break
+		}
+	}
+
+	net.isolate(follower)
+	net.run(5 * electionTicks)
+	net.heal()
+	return leader, follower
+}
+
+func TestRaft_PreVotePreventsTermInflation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PreVote = true
+	net := newSimNetworkWithConfig(3, cfg)
+	leader, follower := partitionFollower(t, net)
+	leaderTerm, _, _ := net.nodes[leader].State()
+
+	// The partitioned node never won a pre-vote, so its term did not move
+	term, role, _ := net.nodes[follower].State()
+	assert.Equal(t, leaderTerm, term)
+	assert.Equal(t, PreCandidate, role)
+
+	// It rejoins without disturbing the leader
+	net.run(electionTicks)
+	assert.Equal(t, []string{leader}, net.leaders(false))
+	term, _, _ = net.nodes[leader].State()
+	assert.Equal(t, leaderTerm, term)
+}
+
+func TestRaft_WithoutPreVoteRejoiningNodeDisrupts(t *testing.T) {
+	net := newSimNetwork(3)
+	leader, follower := partitionFollower(t, net)
+	leaderTerm, _, _ := net.nodes[leader].State()
+
+	term, _, _ := net.nodes[follower].State()
+	assert.Greater(t, term, leaderTerm)
+
+	// Its higher term forces a new election
+	net.run(electionTicks)
+	term, _, _ = net.nodes[net.leaders(false)[0]].State()
+	assert.Greater(t, term, leaderTerm)
+}
+
+func TestRaft_CheckQuorumStepsDownIsolatedLeader(t *testing.T) {
+	for _, checkQuorum := range []bool{false, true} {
+		t.Run(fmt.Sprintf("check_quorum=%v", checkQuorum), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.CheckQuorum = checkQuorum
+			net := newSimNetworkWithConfig(3, cfg)
+			net.run(electionTicks)
+			leader := net.leaders(false)[0]
+
+			net.isolate(leader)
+			net.run(electionTicks)
+
+			_, role, _ := net.nodes[leader].State()
+			if checkQuorum {
+				assert.NotEqual(t, Leader, role)
+				_, _, err := net.nodes[leader].Propose([]byte("tx"))
+				assert.Error(t, err)
+			} else {
+				// A stale leader keeps accepting proposals it cannot commit
+				assert.Equal(t, Leader, role)
+			}
+		})
+	}
+}
+
+func TestRaft_CheckQuorumIgnoresVotesInLease(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CheckQuorum = true
+	net := newSimNetworkWithConfig(3, cfg)
+	net.run(electionTicks)
+	leader := net.leaders(false)[0]
+	follower := "node0"
+	if follower == leader {
+		follower = "node1"
+	}
+	term, _, _ := net.nodes[follower].State()
+
+	// A follower that just heard from the leader ignores a disruptive vote
+	require.NoError(t, net.nodes[follower].HandleMessage(&Message{
+		Type: MessageTypeRequestVote, Term: term + 5, From: "node9", To: follower,
+		LogIndex: 100, LogTerm: term + 4,
+	}))
+	newTerm, _, leaderID := net.nodes[follower].State()
+	assert.Equal(t, term, newTerm)
+	assert.Equal(t, leader, leaderID)
+}
+
+func TestRaft_Metrics(t *testing.T) {
+	net := newSimNetwork(3)
+	terms := make(map[string]*generic.Gauge)
+	changes := make(map[string]*generic.Counter)
+	for _, id := range net.order {
+		m := common.NopMetrics().Raft
+		terms[id], changes[id] = generic.NewGauge("term"), generic.NewCounter("leader_changes")
+		m.Term, m.LeaderChanges = terms[id], changes[id]
+		net.nodes[id].SetMetrics(m)
+	}
+
+	net.run(electionTicks)
+	require.Len(t, net.leaders(false), 1)
+	oldLeader := net.leaders(false)[0]
+	for _, id := range net.order {
+		term, _, _ := net.nodes[id].State()
+		assert.Equal(t, float64(term), terms[id].Value(), id)
+		assert.Equal(t, 1.0, changes[id].Value(), id)
+	}
+
+	// The majority side sees a second leader
+	net.isolate(oldLeader)
+	net.run(electionTicks)
+	for _, id := range net.order {
+		if id != oldLeader {
+			assert.Equal(t, 2.0, changes[id].Value(), id)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package raft
+
+import "github.com/fffeng99999/hcp-consensus/consensus/common"
+
+func init() {
+	common.RegisterEngine(EngineName, newEngine)
+}
+
+// newEngine builds a Raft node configured from the [raft] section. The app
+// runs it as a single-node side-car with no transport: it follows the
+// staking module's validator set changes by adding the bonded validators as
+// learners, which it cannot reach to promote. Clusters electing among the
+// bonded validators are built from NewRaftNode with operator addresses as
+// IDs and a transport.
+func newEngine(deps common.Dependencies) (common.ConsensusEngine, error) {
+	engine := NewRaftConsensus()
+	if deps.AppOptions != nil {
+		cfg, err := ConfigFromAppOptions(deps.AppOptions)
+		if err != nil {
+			return nil, err
+		}
+		if err := engine.SetConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if deps.StakingKeeper != nil {
+		engine.SetStakingKeeper(deps.StakingKeeper)
+	}
+	if deps.Metrics != nil {
+		engine.SetMetrics(deps.Metrics.Raft)
+	}
+	if deps.Logger != nil {
+		engine.SetLogger(deps.Logger)
+	}
+	if deps.TracerProvider != nil {
+		engine.SetTracer(deps.TracerProvider.Tracer(common.TracerName))
+	}
+	return engine, nil
+}
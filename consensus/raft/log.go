@@ -0,0 +1,110 @@
+package raft
+
+// EntryType distinguishes application entries from protocol entries
+type EntryType int
+
+const (
+	EntryNormal     EntryType = iota
+	EntryNoop                 // Appended by a new leader to commit earlier terms
+	EntrySnapshot             // Delivered on apply when state is replaced by a snapshot; Data holds it
+	EntryConfChange           // Membership change; Data holds a JSON ConfChange
+)
+
+func (t EntryType) String() string {
+	switch t {
+	case EntryNormal:
+		return "Normal"
+	case EntryNoop:
+		return "Noop"
+	case EntrySnapshot:
+		return "Snapshot"
+	case EntryConfChange:
+		return "ConfChange"
+	}
+	return "Unknown"
+}
+
+// LogEntry is a single entry in the replicated log
+type LogEntry struct {
+	Index uint64
+	Term  uint64
+	Type  EntryType
+	Data  []byte
+}
+
+// raftLog holds the in-memory log. entries[0] is a sentinel carrying the
+// index and term of the entry preceding the first stored entry.
+type raftLog struct {
+	entries   []LogEntry
+	committed uint64
+	applied   uint64
+}
+
+func newRaftLog() *raftLog {
+	return &raftLog{entries: []LogEntry{{}}}
+}
+
+func (l *raftLog) firstIndex() uint64 { return l.entries[0].Index + 1 }
+func (l *raftLog) lastIndex() uint64  { return l.entries[len(l.entries)-1].Index }
+func (l *raftLog) lastTerm() uint64   { return l.entries[len(l.entries)-1].Term }
+
+// term returns the term of the entry at index, if the log still holds it
+func (l *raftLog) term(index uint64) (uint64, bool) {
+	offset := l.entries[0].Index
+	if index < offset || index > l.lastIndex() {
+		return 0, false
+	}
+	return l.entries[index-offset].Term, true
+}
+
+// matchTerm reports whether the entry at index has the given term
+func (l *raftLog) matchTerm(index, term uint64) bool {
+	t, ok := l.term(index)
+	return ok && t == term
+}
+
+// entriesFrom returns a copy of up to max entries starting at index
+func (l *raftLog) entriesFrom(index uint64, max int) []LogEntry {
+	if index < l.firstIndex() || index > l.lastIndex() {
+		return nil
+	}
+	offset := l.entries[0].Index
+	ents := l.entries[index-offset:]
+	if len(ents) > max {
+		ents = ents[:max]
+	}
+	return append([]LogEntry(nil), ents...)
+}
+
+// append adds an entry at the end of the log
+func (l *raftLog) append(entry LogEntry) {
+	l.entries = append(l.entries, entry)
+}
+
+// truncateFrom removes the entry at index and everything after it
+func (l *raftLog) truncateFrom(index uint64) {
+	offset := l.entries[0].Index
+	l.entries = l.entries[:index-offset]
+}
+
+// isUpToDate reports whether a log ending at (lastIndex, lastTerm) is at
+// least as up-to-date as ours (Raft §5.4.1)
+func (l *raftLog) isUpToDate(lastIndex, lastTerm uint64) bool {
+	return lastTerm > l.lastTerm() || (lastTerm == l.lastTerm() && lastIndex >= l.lastIndex())
+}
+
+// compact discards entries up to and including index, which must be applied
+func (l *raftLog) compact(index, term uint64) {
+	offset := l.entries[0].Index
+	if index <= offset {
+		return
+	}
+	remaining := l.entries[index-offset+1:]
+	l.entries = append([]LogEntry{{Index: index, Term: term}}, remaining...)
+}
+
+// restore replaces the whole log with the state of a snapshot
+func (l *raftLog) restore(snap Snapshot) {
+	l.entries = []LogEntry{{Index: snap.Index, Term: snap.Term}}
+	l.committed = snap.Index
+}
@@ -0,0 +1,305 @@
+package raft
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// StakingKeeper defines the interface needed from the staking module
+type StakingKeeper interface {
+	GetBondedValidatorsByPower(ctx context.Context) ([]stakingtypes.Validator, error)
+}
+
+// ConfChangeType is the kind of single-server membership change
+type ConfChangeType int
+
+const (
+	ConfChangeAddLearner     ConfChangeType = iota // Join as a non-voting member
+	ConfChangePromoteLearner                       // Learner becomes a voter
+	ConfChangeRemoveNode                           // Voter or learner leaves
+)
+
+func (t ConfChangeType) String() string {
+	switch t {
+	case ConfChangeAddLearner:
+		return "AddLearner"
+	case ConfChangePromoteLearner:
+		return "PromoteLearner"
+	case ConfChangeRemoveNode:
+		return "RemoveNode"
+	}
+	return "Unknown"
+}
+
+// ConfChange is a membership change carried in an EntryConfChange log entry
+type ConfChange struct {
+	Type   ConfChangeType `json:"type"`
+	NodeID string         `json:"node_id"`
+}
+
+// Membership is a cluster configuration. Only voters count towards quorums;
+// learners receive the log but neither vote nor campaign.
+type Membership struct {
+	Voters   []string `json:"voters"`
+	Learners []string `json:"learners,omitempty"`
+}
+
+// NewRaftLearner creates a node that joins a running cluster. It starts
+// outside the configuration given by voters and waits for the leader to add it.
+func NewRaftLearner(id string, voters []string) *RaftConsensus {
+	r := NewRaftNode(id, voters)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.baseMembership = Membership{Voters: append([]string(nil), voters...)}
+	r.recomputeMembership()
+	return r
+}
+
+// SetStakingKeeper sets the staking keeper used to track bonded validators.
+// Node IDs are then validator operator addresses.
+func (r *RaftConsensus) SetStakingKeeper(k StakingKeeper) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stakingKeeper = k
+}
+
+// OnValidatorUpdates implements common.ValidatorSetObserver, making the
+// leader track the bonded validators again from the end of the block
+func (r *RaftConsensus) OnValidatorUpdates(ctx sdk.Context, updates []abci.ValidatorUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.membershipStale = true
+	return nil
+}
+
+// Membership returns the current configuration, including uncommitted changes
+func (r *RaftConsensus) Membership() Membership {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return Membership{Voters: sortedKeys(r.voters), Learners: sortedKeys(r.learners)}
+}
+
+// ProposeConfChange appends a membership change. Changes take effect as soon
+// as they are appended, so only one may be in flight at a time.
+func (r *RaftConsensus) ProposeConfChange(cc ConfChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.proposeConfChange(cc)
+}
+
+// AddNode adds a node as a learner and promotes it to voter once it has
+// caught up with the leader's commit index
+func (r *RaftConsensus) AddNode(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.proposeConfChange(ConfChange{Type: ConfChangeAddLearner, NodeID: id}); err != nil {
+		return err
+	}
+	r.autoPromote[id] = true
+	return nil
+}
+
+func (r *RaftConsensus) proposeConfChange(cc ConfChange) error {
+	if r.role != Leader {
+		return fmt.Errorf("node %s is not the leader (leader: %q)", r.id, r.leaderID)
+	}
+	if r.pendingConfIndex > r.log.committed {
+		return fmt.Errorf("membership change at index %d is still pending", r.pendingConfIndex)
+	}
+
+	switch cc.Type {
+	case ConfChangeAddLearner:
+		if r.voters[cc.NodeID] || r.learners[cc.NodeID] {
+			return fmt.Errorf("node %s is already a member", cc.NodeID)
+		}
+	case ConfChangePromoteLearner:
+		if !r.learners[cc.NodeID] {
+			return fmt.Errorf("node %s is not a learner", cc.NodeID)
+		}
+		if r.matchIndex[cc.NodeID] < r.log.committed {
+			return fmt.Errorf("learner %s has not caught up (%d < %d)", cc.NodeID, r.matchIndex[cc.NodeID], r.log.committed)
+		}
+	case ConfChangeRemoveNode:
+		if !r.voters[cc.NodeID] && !r.learners[cc.NodeID] {
+			return fmt.Errorf("node %s is not a member", cc.NodeID)
+		}
+		if r.voters[cc.NodeID] && len(r.voters) == 1 {
+			return fmt.Errorf("cannot remove the last voter %s", cc.NodeID)
+		}
+	default:
+		return fmt.Errorf("unknown membership change type %d", cc.Type)
+	}
+
+	data, err := json.Marshal(cc)
+	if err != nil {
+		return err
+	}
+	r.pendingConfIndex = r.log.lastIndex() + 1
+	r.appendEntry(EntryConfChange, data)
+	r.logger.Info("proposed membership change", "type", cc.Type.String(), "node", cc.NodeID, "view", r.currentTerm, "seq", r.pendingConfIndex)
+
+	if cc.Type == ConfChangeAddLearner {
+		r.nextIndex[cc.NodeID] = r.log.lastIndex() + 1
+		r.matchIndex[cc.NodeID] = 0
+	}
+	if cc.Type == ConfChangeRemoveNode {
+		delete(r.autoPromote, cc.NodeID)
+	}
+
+	r.broadcastAppend()
+	return nil
+}
+
+// maybePromoteLearners promotes one caught-up learner added through AddNode
+func (r *RaftConsensus) maybePromoteLearners() {
+	if r.role != Leader || r.pendingConfIndex > r.log.committed {
+		return
+	}
+	for _, id := range sortedKeys(r.autoPromote) {
+		if r.learners[id] && r.matchIndex[id] >= r.log.committed {
+			delete(r.autoPromote, id)
+			if err := r.proposeConfChange(ConfChange{Type: ConfChangePromoteLearner, NodeID: id}); err != nil {
+				r.logger.Error("failed to promote learner", "node", id, "err", err)
+			}
+			return
+		}
+	}
+}
+
+// checkRemoved steps down a leader whose own removal has committed
+func (r *RaftConsensus) checkRemoved() {
+	if r.role == Leader && !r.voters[r.id] && r.log.committed >= r.pendingConfIndex {
+		r.becomeFollower(r.currentTerm, "")
+	}
+}
+
+// recomputeMembership rebuilds the configuration from the base (as of the
+// log's first index) and every conf change still in the log
+func (r *RaftConsensus) recomputeMembership() {
+	r.voters, r.learners = r.membershipAt(r.log.lastIndex())
+}
+
+// membershipAt returns the configuration in effect at the given index
+func (r *RaftConsensus) membershipAt(index uint64) (voters, learners map[string]bool) {
+	voters = make(map[string]bool)
+	learners = make(map[string]bool)
+	for _, id := range r.baseMembership.Voters {
+		voters[id] = true
+	}
+	for _, id := range r.baseMembership.Learners {
+		learners[id] = true
+	}
+
+	for _, entry := range r.log.entriesFrom(r.log.firstIndex(), int(index-r.log.firstIndex()+1)) {
+		if entry.Type != EntryConfChange {
+			continue
+		}
+		var cc ConfChange
+		if err := json.Unmarshal(entry.Data, &cc); err != nil {
+			panic(fmt.Errorf("raft node %s: corrupt membership change at index %d: %w", r.id, entry.Index, err))
+		}
+
+		switch cc.Type {
+		case ConfChangeAddLearner:
+			learners[cc.NodeID] = true
+		case ConfChangePromoteLearner:
+			delete(learners, cc.NodeID)
+			voters[cc.NodeID] = true
+		case ConfChangeRemoveNode:
+			delete(learners, cc.NodeID)
+			delete(voters, cc.NodeID)
+		}
+	}
+	return voters, learners
+}
+
+// peers returns every other member (voters and learners), sorted
+func (r *RaftConsensus) peers() []string {
+	var ids []string
+	for id := range r.voters {
+		if id != r.id {
+			ids = append(ids, id)
+		}
+	}
+	for id := range r.learners {
+		if id != r.id {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// reconcileMembership moves the configuration one step towards the set of
+// bonded validators, reporting whether it already matched them. Removals go
+// first so the cluster never grows past the validator set; additions go
+// through learner catch-up. Only the leader knows whether the membership
+// matches, as followers may lag behind it.
+func (r *RaftConsensus) reconcileMembership(ctx sdk.Context) (bool, error) {
+	if r.stakingKeeper == nil {
+		return true, nil
+	}
+	if r.role != Leader || r.pendingConfIndex > r.log.committed {
+		return false, nil
+	}
+
+	bonded, err := r.stakingKeeper.GetBondedValidatorsByPower(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list bonded validators: %w", err)
+	}
+	if len(bonded) == 0 {
+		return true, nil
+	}
+	desired := make(map[string]bool, len(bonded))
+	for _, val := range bonded {
+		desired[val.OperatorAddress] = true
+	}
+
+	for _, id := range r.peers() {
+		if !desired[id] {
+			return false, r.reconcile(ConfChange{Type: ConfChangeRemoveNode, NodeID: id})
+		}
+	}
+	if !desired[r.id] && len(r.voters) > 1 {
+		return false, r.reconcile(ConfChange{Type: ConfChangeRemoveNode, NodeID: r.id})
+	}
+	for _, id := range sortedKeys(desired) {
+		if !r.voters[id] && !r.learners[id] {
+			if err := r.reconcile(ConfChange{Type: ConfChangeAddLearner, NodeID: id}); err != nil {
+				return false, err
+			}
+			r.autoPromote[id] = true
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// reconcile proposes a change towards the bonded validator set
+func (r *RaftConsensus) reconcile(cc ConfChange) error {
+	if err := r.proposeConfChange(cc); err != nil {
+		return fmt.Errorf("failed to track bonded validators: %s %s: %w", cc.Type, cc.NodeID, err)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
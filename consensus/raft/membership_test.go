@@ -0,0 +1,223 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func leaderOf(t *testing.T, net *simNetwork) *RaftConsensus {
+	t.Helper()
+
+	leaders := net.leaders(true)
+	require.Len(t, leaders, 1)
+	return net.nodes[leaders[0]]
+}
+
+func TestRaft_AddNodePromotesAfterCatchUp(t *testing.T) {
+	net := newSimNetwork(3)
+	net.run(electionTicks)
+	for i := 0; i < 10; i++ {
+		propose(t, net, fmt.Sprintf("tx%d", i))
+	}
+	net.run(5)
+
+	learner := net.join("node3")
+	require.NoError(t, leaderOf(t, net).AddNode("node3"))
+	assert.Equal(t, []string{"node3"}, leaderOf(t, net).Membership().Learners)
+
+	net.run(10)
+
+	want := []string{"node0", "node1", "node2", "node3"}
+	for _, id := range net.order {
+		assert.Equal(t, want, net.nodes[id].Membership().Voters, id)
+		assert.Empty(t, net.nodes[id].Membership().Learners, id)
+	}
+	assert.Len(t, collectApplied(t, learner, 10), 10)
+	learner.Stop()
+
+	// The new voter counts towards quorum: with two voters cut off, the
+	// remaining two of four cannot commit
+	leader := leaderOf(t, net)
+	cut := 0
+	for _, id := range net.order {
+		if id != leader.ID() && id != "node3" && cut < 2 {
+			net.isolate(id)
+			cut++
+		}
+	}
+	before := leader.CommitIndex()
+	_, _, err := leader.Propose([]byte("stalled"))
+	require.NoError(t, err)
+	net.run(5)
+	assert.Equal(t, before, leader.CommitIndex())
+}
+
+func TestRaft_LearnerDoesNotCountForQuorum(t *testing.T) {
+	net := newSimNetwork(3)
+	net.run(electionTicks)
+	net.join("node3")
+
+	leader := leaderOf(t, net)
+	require.NoError(t, leader.ProposeConfChange(ConfChange{Type: ConfChangeAddLearner, NodeID: "node3"}))
+	net.run(5)
+
+	// Leader plus learner is not a majority of the three voters
+	for _, id := range net.order {
+		if id != leader.ID() && id != "node3" {
+			net.isolate(id)
+		}
+	}
+	before := leader.CommitIndex()
+	_, _, err := leader.Propose([]byte("tx"))
+	require.NoError(t, err)
+	net.run(5)
+	assert.Equal(t, before, leader.CommitIndex())
+
+	// The learner still receives the entry and never campaigns
+	assert.Equal(t, leader.log.lastIndex(), net.nodes["node3"].log.lastIndex())
+	_, role, _ := net.nodes["node3"].State()
+	assert.Equal(t, Follower, role)
+}
+
+func TestRaft_RemoveFollowerAndLeader(t *testing.T) {
+	net := newSimNetwork(5)
+	net.run(electionTicks)
+	leader := leaderOf(t, net)
+
+	removed := ""
+	for _, id := range net.order {
+		if id != leader.ID() {
+			removed = id
+			break
+		}
+	}
+	require.NoError(t, leader.ProposeConfChange(ConfChange{Type: ConfChangeRemoveNode, NodeID: removed}))
+
+	// Only one change may be in flight
+	assert.Error(t, leader.ProposeConfChange(ConfChange{Type: ConfChangeRemoveNode, NodeID: leader.ID()}))
+	net.run(5)
+	assert.NotContains(t, leader.Membership().Voters, removed)
+
+	// The leader removes itself, steps down once committed, and the rest elect
+	require.NoError(t, leader.ProposeConfChange(ConfChange{Type: ConfChangeRemoveNode, NodeID: leader.ID()}))
+	net.run(electionTicks)
+
+	_, role, _ := leader.State()
+	assert.Equal(t, Follower, role)
+	newLeader := leaderOf(t, net)
+	assert.NotEqual(t, leader.ID(), newLeader.ID())
+	assert.NotEqual(t, removed, newLeader.ID())
+	assert.Len(t, newLeader.Membership().Voters, 3)
+
+	// The three remaining voters keep committing
+	propose(t, net, "after-removal")
+	net.run(5)
+	assert.Equal(t, newLeader.log.lastIndex(), newLeader.CommitIndex())
+}
+
+func TestRaft_PromoteRequiresCaughtUpLearner(t *testing.T) {
+	net := newSimNetwork(3)
+	net.run(electionTicks)
+	propose(t, net, "tx")
+	net.run(5)
+
+	net.join("node3")
+	net.isolate("node3")
+	leader := leaderOf(t, net)
+	require.NoError(t, leader.ProposeConfChange(ConfChange{Type: ConfChangeAddLearner, NodeID: "node3"}))
+	net.run(5)
+
+	err := leader.ProposeConfChange(ConfChange{Type: ConfChangePromoteLearner, NodeID: "node3"})
+	assert.ErrorContains(t, err, "not caught up")
+
+	net.heal()
+	net.run(5)
+	assert.NoError(t, leader.ProposeConfChange(ConfChange{Type: ConfChangePromoteLearner, NodeID: "node3"}))
+}
+
+// mockStakingKeeper returns a fixed bonded validator set
+type mockStakingKeeper struct {
+	bonded []string
+}
+
+func (m *mockStakingKeeper) GetBondedValidatorsByPower(ctx context.Context) ([]stakingtypes.Validator, error) {
+	var vals []stakingtypes.Validator
+	for _, addr := range m.bonded {
+		vals = append(vals, stakingtypes.Validator{OperatorAddress: addr})
+	}
+	return vals, nil
+}
+
+func TestRaft_MembershipTracksBondedValidators(t *testing.T) {
+	net := newSimNetwork(3)
+	keeper := &mockStakingKeeper{bonded: []string{"node0", "node1", "node2", "node3"}}
+	for _, id := range net.order {
+		net.nodes[id].SetStakingKeeper(keeper)
+	}
+	net.run(electionTicks)
+	net.join("node3").SetStakingKeeper(keeper)
+
+	endBlocks := func(n int) {
+		for i := 0; i < n; i++ {
+			for _, id := range net.order {
+				net.nodes[id].EndBlock(sdk.Context{})
+			}
+			net.run(5)
+		}
+	}
+
+	// A newly bonded validator joins as a learner and is promoted
+	endBlocks(3)
+	assert.Equal(t, keeper.bonded, leaderOf(t, net).Membership().Voters)
+
+	// An unbonded validator is removed once the staking module reports the
+	// change
+	bonded := keeper.bonded
+	keeper.bonded = []string{"node0", "node1", "node3"}
+	if leaderOf(t, net).ID() == "node2" {
+		keeper.bonded = []string{"node0", "node1", "node2"}
+	}
+	endBlocks(3)
+	assert.Equal(t, bonded, leaderOf(t, net).Membership().Voters)
+	for _, id := range net.order {
+		require.NoError(t, net.nodes[id].OnValidatorUpdates(sdk.Context{}, []abci.ValidatorUpdate{{Power: 0}}))
+	}
+	endBlocks(3)
+	assert.Equal(t, keeper.bonded, leaderOf(t, net).Membership().Voters)
+}
+
+func TestRaft_SideCarTracksBondedValidators(t *testing.T) {
+	// The app's node has no transport, so the bonded validators join as
+	// learners and never count towards its quorum
+	r := NewRaftConsensus()
+	keeper := &mockStakingKeeper{bonded: []string{"val0", "val1"}}
+	r.SetStakingKeeper(keeper)
+	for i := 0; i < 3*electionTicks && r.Status().Leader != r.ID(); i++ {
+		r.Tick()
+	}
+	require.Equal(t, r.ID(), r.Status().Leader)
+
+	for i := 0; i < 3; i++ {
+		_, err := r.EndBlock(sdk.Context{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, Membership{Voters: []string{r.ID()}, Learners: []string{"val0", "val1"}}, r.Membership())
+
+	keeper.bonded = []string{"val1"}
+	require.NoError(t, r.OnValidatorUpdates(sdk.Context{}, []abci.ValidatorUpdate{{Power: 0}}))
+	for i := 0; i < 3; i++ {
+		_, err := r.EndBlock(sdk.Context{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, Membership{Voters: []string{r.ID()}, Learners: []string{"val1"}}, r.Membership())
+	index, _, err := r.Propose([]byte("tx"))
+	require.NoError(t, err)
+	assert.Equal(t, index, r.Status().Committed)
+}
@@ -0,0 +1,75 @@
+package raft
+
+// MessageType represents the type of Raft RPC message
+type MessageType int
+
+const (
+	MessageTypeRequestVote MessageType = iota
+	MessageTypeRequestVoteResponse
+	MessageTypeAppendEntries
+	MessageTypeAppendEntriesResponse
+	MessageTypeInstallSnapshot // Answered with an AppendEntriesResponse
+	MessageTypePreVote         // Carries the term the sender would campaign in
+	MessageTypePreVoteResponse
+)
+
+func (t MessageType) String() string {
+	switch t {
+	case MessageTypeRequestVote:
+		return "RequestVote"
+	case MessageTypeRequestVoteResponse:
+		return "RequestVoteResponse"
+	case MessageTypeAppendEntries:
+		return "AppendEntries"
+	case MessageTypeAppendEntriesResponse:
+		return "AppendEntriesResponse"
+	case MessageTypeInstallSnapshot:
+		return "InstallSnapshot"
+	case MessageTypePreVote:
+		return "PreVote"
+	case MessageTypePreVoteResponse:
+		return "PreVoteResponse"
+	}
+	return "Unknown"
+}
+
+// Message represents a generic Raft RPC request or response
+type Message struct {
+	Type MessageType
+	Term uint64 // Sender's current term
+	From string // Sender ID
+	To   string // Recipient ID
+
+	// RequestVote: the candidate's last log position
+	// AppendEntries: the position preceding Entries
+	LogIndex uint64
+	LogTerm  uint64
+
+	// AppendEntries payload and the leader's commit index
+	Entries []LogEntry
+	Commit  uint64
+
+	// InstallSnapshot payload
+	Snapshot *Snapshot
+
+	// Reject is set on responses that deny a vote or an append
+	Reject bool
+
+	// AppendEntriesResponse: highest index known to match on success,
+	// or the follower's last index as a hint on rejection
+	Index uint64
+
+	// Round is the leader's heartbeat round, echoed back in responses so the
+	// leader knows how recently a follower acknowledged it
+	Round uint64
+
+	// AppendEntries: W3C trace context of the leader's span for the first
+	// traced entry
+	TraceContext map[string]string
+}
+
+// Transport delivers Raft messages to peers. Send is called with the node's
+// lock held, so implementations must not block or call back into the node.
+type Transport interface {
+	Send(msg *Message)
+}
@@ -0,0 +1,140 @@
+package raft
+
+import (
+	"fmt"
+
+	dbm "github.com/cosmos/cosmos-db"
+)
+
+// simNetwork is an in-memory transport that delivers messages in FIFO order
+// and can isolate nodes to simulate partitions
+type simNetwork struct {
+	nodes    map[string]*RaftConsensus
+	storages map[string]*DBStorage // Survive node restarts
+	order    []string
+	queue    []*Message
+	isolated map[string]bool
+	config   Config
+}
+
+func newSimNetwork(size int) *simNetwork {
+	return newSimNetworkWithConfig(size, DefaultConfig())
+}
+
+func newSimNetworkWithConfig(size int, cfg Config) *simNetwork {
+	n := &simNetwork{
+		config:   cfg,
+		nodes:    make(map[string]*RaftConsensus),
+		storages: make(map[string]*DBStorage),
+		isolated: make(map[string]bool),
+	}
+
+	for i := 0; i < size; i++ {
+		n.order = append(n.order, fmt.Sprintf("node%d", i))
+	}
+	for _, id := range n.order {
+		n.storages[id] = NewDBStorage(dbm.NewMemDB())
+		n.start(id)
+	}
+	return n
+}
+
+// start creates the node from its persisted state and joins it to the network
+func (n *simNetwork) start(id string) {
+	var peers []string
+	for _, pid := range n.order {
+		if pid != id {
+			peers = append(peers, pid)
+		}
+	}
+
+	node := NewRaftNode(id, peers)
+	if err := node.SetConfig(n.config); err != nil {
+		panic(err)
+	}
+	if err := node.SetStorage(n.storages[id]); err != nil {
+		panic(err)
+	}
+	node.SetTransport(n)
+	n.nodes[id] = node
+}
+
+// join adds a new node that waits to be added by the leader as a learner
+func (n *simNetwork) join(id string) *RaftConsensus {
+	voters := n.nodes[n.order[0]].Membership().Voters
+	n.order = append(n.order, id)
+	n.storages[id] = NewDBStorage(dbm.NewMemDB())
+
+	node := NewRaftLearner(id, voters)
+	if err := node.SetConfig(n.config); err != nil {
+		panic(err)
+	}
+	if err := node.SetStorage(n.storages[id]); err != nil {
+		panic(err)
+	}
+	node.SetTransport(n)
+	n.nodes[id] = node
+	return node
+}
+
+// restart simulates a crash: all volatile state is lost and in-flight
+// messages to the node are dropped
+func (n *simNetwork) restart(id string) {
+	n.nodes[id].Stop()
+	var queue []*Message
+	for _, msg := range n.queue {
+		if msg.To != id {
+			queue = append(queue, msg)
+		}
+	}
+	n.queue = queue
+	n.start(id)
+}
+
+// Send implements Transport
+func (n *simNetwork) Send(msg *Message) {
+	n.queue = append(n.queue, msg)
+}
+
+// deliver drains the queue, dropping messages to or from isolated nodes
+func (n *simNetwork) deliver() {
+	for len(n.queue) > 0 {
+		msg := n.queue[0]
+		n.queue = n.queue[1:]
+		if n.isolated[msg.From] || n.isolated[msg.To] {
+			continue
+		}
+		if node, ok := n.nodes[msg.To]; ok {
+			node.HandleMessage(msg)
+		}
+	}
+}
+
+// run advances every node by the given number of ticks, delivering
+// messages after each tick
+func (n *simNetwork) run(ticks int) {
+	for i := 0; i < ticks; i++ {
+		for _, id := range n.order {
+			n.nodes[id].tick()
+		}
+		n.deliver()
+	}
+}
+
+func (n *simNetwork) isolate(id string) { n.isolated[id] = true }
+func (n *simNetwork) heal()             { n.isolated = make(map[string]bool) }
+
+// leaders returns the IDs of nodes that currently believe they lead,
+// optionally skipping isolated nodes
+func (n *simNetwork) leaders(skipIsolated bool) []string {
+	var ids []string
+	for _, id := range n.order {
+		if skipIsolated && n.isolated[id] {
+			continue
+		}
+		if _, role, _ := n.nodes[id].State(); role == Leader {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,110 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+)
+
+// readRequest is a read waiting for a majority to confirm the leader
+type readRequest struct {
+	index uint64 // Commit index when the read arrived
+	round uint64 // First heartbeat round that confirms it
+	acks  map[string]bool
+	done  chan struct{}
+	err   error // Set before done is closed
+}
+
+// ReadIndex returns an index for a linearizable read: once the application
+// has applied up to it, local state reflects every write committed before
+// the call. Only the leader serves reads. With LeaseRead it answers at once
+// while its lease holds; otherwise it waits for a majority to acknowledge a
+// heartbeat round (Raft thesis §6.4).
+func (r *RaftConsensus) ReadIndex(ctx context.Context) (uint64, error) {
+	r.mu.Lock()
+	req, err := r.requestRead()
+	r.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	select {
+	case <-req.done:
+		if req.err != nil {
+			return 0, req.err
+		}
+		return req.index, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (r *RaftConsensus) requestRead() (*readRequest, error) {
+	if r.role != Leader {
+		return nil, fmt.Errorf("node %s is not the leader (leader: %q)", r.id, r.leaderID)
+	}
+	// Until an entry of its own term commits, a new leader may not know
+	// the latest commit index
+	if !r.log.matchTerm(r.log.committed, r.currentTerm) {
+		return nil, fmt.Errorf("leader %s has not committed an entry in term %d yet", r.id, r.currentTerm)
+	}
+
+	req := &readRequest{
+		index: r.log.committed,
+		acks:  map[string]bool{r.id: true},
+		done:  make(chan struct{}),
+	}
+	if r.quorum() == 1 || (r.config.LeaseRead && r.leaseValid()) {
+		close(req.done)
+		return req, nil
+	}
+
+	req.round = r.heartbeatSeq + 1
+	r.pendingReads = append(r.pendingReads, req)
+	r.broadcastAppend()
+	return req, nil
+}
+
+// leaseValid reports whether a majority of voters acknowledged a heartbeat
+// round sent less than an election timeout ago. Those voters reject other
+// candidates until then, so no other leader can exist.
+func (r *RaftConsensus) leaseValid() bool {
+	acked := 0
+	for id := range r.voters {
+		if id == r.id {
+			acked++
+			continue
+		}
+		sentAt, ok := r.roundSentAt[r.ackedRound[id]]
+		if ok && r.clock-sentAt < r.config.ElectionTimeout {
+			acked++
+		}
+	}
+	return acked >= r.quorum()
+}
+
+// confirmReads completes the reads acknowledged by a majority
+func (r *RaftConsensus) confirmReads() {
+	remaining := r.pendingReads[:0]
+	for _, req := range r.pendingReads {
+		for id := range r.voters {
+			if r.ackedRound[id] >= req.round {
+				req.acks[id] = true
+			}
+		}
+		if len(req.acks) >= r.quorum() {
+			close(req.done)
+			continue
+		}
+		remaining = append(remaining, req)
+	}
+	r.pendingReads = remaining
+}
+
+// failReads aborts every pending read
+func (r *RaftConsensus) failReads(err error) {
+	for _, req := range r.pendingReads {
+		req.err = err
+		close(req.done)
+	}
+	r.pendingReads = nil
+}
@@ -0,0 +1,106 @@
+package raft
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startRead registers a read on the node without blocking the simulation
+func startRead(t *testing.T, node *RaftConsensus) *readRequest {
+	t.Helper()
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	req, err := node.requestRead()
+	require.NoError(t, err)
+	return req
+}
+
+func isDone(req *readRequest) bool {
+	select {
+	case <-req.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestRaft_ReadIndexWaitsForHeartbeatRound(t *testing.T) {
+	net := newSimNetwork(3)
+	net.run(electionTicks)
+	leader := net.nodes[net.leaders(false)[0]]
+	propose(t, net, "tx")
+	net.run(5)
+
+	req := startRead(t, leader)
+	assert.False(t, isDone(req), "needs a majority to confirm leadership")
+
+	net.deliver()
+	require.True(t, isDone(req))
+	require.NoError(t, req.err)
+	assert.Equal(t, leader.CommitIndex(), req.index)
+}
+
+func TestRaft_ReadIndexFailsWhenLeaderDeposed(t *testing.T) {
+	net := newSimNetwork(3)
+	net.run(electionTicks)
+	leaderID := net.leaders(false)[0]
+	leader := net.nodes[leaderID]
+
+	net.isolate(leaderID)
+	req := startRead(t, leader)
+	net.run(electionTicks)
+	assert.False(t, isDone(req), "a partitioned leader cannot confirm reads")
+
+	// Learning of the new term aborts the read
+	net.heal()
+	net.run(electionTicks)
+	require.True(t, isDone(req))
+	assert.Error(t, req.err)
+}
+
+func TestRaft_LeaseReadServedLocally(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CheckQuorum = true
+	cfg.LeaseRead = true
+	net := newSimNetworkWithConfig(3, cfg)
+	net.run(electionTicks)
+	leaderID := net.leaders(false)[0]
+	leader := net.nodes[leaderID]
+
+	index, err := leader.ReadIndex(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, leader.CommitIndex(), index)
+
+	// Cut off from the majority, the leader loses its lease within an
+	// election timeout and stops serving reads
+	net.isolate(leaderID)
+	for i := 0; i < int(cfg.ElectionTimeout/cfg.TickInterval); i++ {
+		leader.tick()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = leader.ReadIndex(ctx)
+	assert.Error(t, err)
+}
+
+func TestRaft_ReadRequiresCommitInTerm(t *testing.T) {
+	net := newSimNetwork(3)
+	net.run(electionTicks)
+	leader := net.nodes[net.leaders(false)[0]]
+
+	// Simulate a leader whose no-op has not committed yet
+	leader.mu.Lock()
+	leader.currentTerm++
+	_, err := leader.requestRead()
+	leader.mu.Unlock()
+	assert.ErrorContains(t, err, "has not committed")
+
+	_, err = NewRaftNode("node9", []string{"node0"}).ReadIndex(context.Background())
+	assert.ErrorContains(t, err, "not the leader")
+}
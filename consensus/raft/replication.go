@@ -0,0 +1,258 @@
+package raft
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Propose appends data to the log if this node is the leader and returns
+// the index and term the entry will be committed at
+func (r *RaftConsensus) Propose(data []byte) (index, term uint64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.role != Leader {
+		return 0, 0, fmt.Errorf("node %s is not the leader (leader: %q)", r.id, r.leaderID)
+	}
+
+	index = r.appendEntry(EntryNormal, data)
+	r.broadcastAppend()
+	return index, r.currentTerm, nil
+}
+
+// CommitIndex returns the highest log index known to be committed
+func (r *RaftConsensus) CommitIndex() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.log.committed
+}
+
+// Entries returns a copy of the entries the log holds, from the first after
+// the latest snapshot to the last
+func (r *RaftConsensus) Entries() []LogEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]LogEntry(nil), r.log.entries[1:]...)
+}
+
+// ApplyCh returns a channel on which committed entries are delivered in log
+// order, starting after the last applied index. The channel is unbuffered.
+func (r *RaftConsensus) ApplyCh() <-chan LogEntry {
+	r.applyOnce.Do(func() {
+		r.applyCh = make(chan LogEntry)
+		go r.applyLoop()
+	})
+	return r.applyCh
+}
+
+// applyLoop waits for the commit index to advance and delivers the newly
+// committed entries without holding the lock
+func (r *RaftConsensus) applyLoop() {
+	for {
+		r.mu.Lock()
+		for !r.applyStopped && r.pendingSnapshot == nil && r.log.applied >= r.log.committed {
+			r.applyCond.Wait()
+		}
+		if r.applyStopped {
+			r.mu.Unlock()
+			close(r.applyCh)
+			return
+		}
+
+		var entries []LogEntry
+		if snap := r.pendingSnapshot; snap != nil {
+			// State restored from a snapshot precedes the entries after it
+			r.pendingSnapshot = nil
+			entries = append(entries, LogEntry{Index: snap.Index, Term: snap.Term, Type: EntrySnapshot, Data: snap.Data})
+			r.log.applied = snap.Index
+		}
+		if r.log.committed > r.log.applied {
+			entries = append(entries, r.log.entriesFrom(r.log.applied+1, int(r.log.committed-r.log.applied))...)
+			r.log.applied = r.log.committed
+		}
+		r.mu.Unlock()
+
+		for _, entry := range entries {
+			r.applyCh <- entry
+		}
+	}
+}
+
+// appendEntry appends an entry for the current term to the leader's log
+func (r *RaftConsensus) appendEntry(typ EntryType, data []byte) uint64 {
+	index := r.log.lastIndex() + 1
+	entry := LogEntry{Index: index, Term: r.currentTerm, Type: typ, Data: data}
+	r.saveEntries([]LogEntry{entry})
+	r.log.append(entry)
+	r.traceAppend(entry)
+	if typ == EntryConfChange {
+		// Membership changes take effect on append, not on commit
+		r.recomputeMembership()
+	}
+
+	// A single-node cluster commits on append
+	r.maybeCommit()
+	r.observeReplication()
+	return index
+}
+
+// observeReplication reports how far each peer trails the leader's log
+func (r *RaftConsensus) observeReplication() {
+	if r.role != Leader {
+		return
+	}
+	last := r.log.lastIndex()
+	for _, peer := range r.peers() {
+		r.metrics.ReplicationLag.With("peer", peer).Set(float64(last - min(r.matchIndex[peer], last)))
+	}
+}
+
+// broadcastAppend starts a heartbeat round, sending AppendEntries (or a
+// heartbeat) to every peer
+func (r *RaftConsensus) broadcastAppend() {
+	r.heartbeatSeq++
+	r.roundSentAt[r.heartbeatSeq] = r.clock
+	for round, sentAt := range r.roundSentAt {
+		if r.clock-sentAt >= r.config.ElectionTimeout {
+			delete(r.roundSentAt, round)
+		}
+	}
+
+	for _, peer := range r.peers() {
+		r.sendAppend(peer)
+	}
+}
+
+// sendAppend sends the entries a peer is missing, starting at nextIndex
+func (r *RaftConsensus) sendAppend(peer string) {
+	next := r.nextIndex[peer]
+	if next < r.log.firstIndex() {
+		// The entries the peer needs were compacted away
+		r.sendSnapshot(peer)
+		return
+	}
+
+	prevIndex := next - 1
+	prevTerm, _ := r.log.term(prevIndex)
+
+	entries := r.log.entriesFrom(next, r.config.MaxEntriesPerMsg)
+	r.send(&Message{
+		Type:         MessageTypeAppendEntries,
+		To:           peer,
+		LogIndex:     prevIndex,
+		LogTerm:      prevTerm,
+		Entries:      entries,
+		Commit:       r.log.committed,
+		Round:        r.heartbeatSeq,
+		TraceContext: r.traceContext(entries),
+	})
+}
+
+func (r *RaftConsensus) handleAppendEntries(msg *Message) {
+	// A valid leader exists for this term
+	r.becomeFollower(msg.Term, msg.From)
+
+	// Consistency check: our log must contain the entry preceding Entries
+	if !r.log.matchTerm(msg.LogIndex, msg.LogTerm) {
+		r.send(&Message{
+			Type:   MessageTypeAppendEntriesResponse,
+			To:     msg.From,
+			Reject: true,
+			Index:  r.log.lastIndex(),
+			Round:  msg.Round,
+		})
+		return
+	}
+
+	for i, entry := range msg.Entries {
+		if entry.Index <= r.log.lastIndex() {
+			if r.log.matchTerm(entry.Index, entry.Term) {
+				continue
+			}
+			// Conflicting suffix; committed entries never conflict
+			r.log.truncateFrom(entry.Index)
+		}
+		r.saveEntries(msg.Entries[i:])
+		for _, e := range msg.Entries[i:] {
+			r.log.append(e)
+		}
+		r.recomputeMembership()
+		r.traceReplicate(msg)
+		break
+	}
+
+	lastNew := msg.LogIndex + uint64(len(msg.Entries))
+	if msg.Commit > r.log.committed {
+		r.commitTo(min(msg.Commit, lastNew))
+	}
+
+	r.send(&Message{Type: MessageTypeAppendEntriesResponse, To: msg.From, Index: lastNew, Round: msg.Round})
+}
+
+func (r *RaftConsensus) handleAppendEntriesResponse(msg *Message) {
+	if r.role != Leader || (!r.voters[msg.From] && !r.learners[msg.From]) {
+		return
+	}
+
+	r.recentActive[msg.From] = true
+	if r.voters[msg.From] && msg.Round > r.ackedRound[msg.From] {
+		r.ackedRound[msg.From] = msg.Round
+		r.confirmReads()
+	}
+
+	if msg.Reject {
+		// Back off, jumping straight past the follower's last index
+		next := r.nextIndex[msg.From] - 1
+		if msg.Index+1 < next {
+			next = msg.Index + 1
+		}
+		r.nextIndex[msg.From] = max(next, 1)
+		r.sendAppend(msg.From)
+		return
+	}
+
+	if msg.Index > r.matchIndex[msg.From] {
+		r.matchIndex[msg.From] = msg.Index
+		r.nextIndex[msg.From] = msg.Index + 1
+		r.maybeCommit()
+		r.maybePromoteLearners()
+		r.observeReplication()
+	}
+
+	if r.nextIndex[msg.From] <= r.log.lastIndex() {
+		r.sendAppend(msg.From)
+	}
+}
+
+// maybeCommit advances the commit index to the highest entry of the current
+// term stored on a majority of the cluster
+func (r *RaftConsensus) maybeCommit() {
+	var matched []uint64
+	for id := range r.voters {
+		if id == r.id {
+			matched = append(matched, r.log.lastIndex())
+		} else {
+			matched = append(matched, r.matchIndex[id])
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i] > matched[j] })
+
+	n := matched[r.quorum()-1]
+	if n > r.log.committed && r.log.matchTerm(n, r.currentTerm) {
+		r.commitTo(n)
+		r.checkRemoved()
+		r.maybePromoteLearners()
+	}
+}
+
+// commitTo advances the commit index and wakes the apply loop
+func (r *RaftConsensus) commitTo(index uint64) {
+	if index <= r.log.committed {
+		return
+	}
+	r.log.committed = index
+	r.traceCommit()
+	r.applyCond.Broadcast()
+}
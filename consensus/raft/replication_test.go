@@ -0,0 +1,199 @@
+package raft
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+// collectApplied reads n normal entries from the node's apply channel
+func collectApplied(t *testing.T, node *RaftConsensus, n int) []string {
+	t.Helper()
+
+	var data []string
+	timeout := time.After(time.Second)
+	for len(data) < n {
+		select {
+		case entry, ok := <-node.ApplyCh():
+			require.True(t, ok, "apply channel closed")
+			if entry.Type == EntryNormal {
+				data = append(data, string(entry.Data))
+			}
+		case <-timeout:
+			t.Fatalf("node %s applied %d of %d entries", node.ID(), len(data), n)
+		}
+	}
+	return data
+}
+
+// propose submits a proposal through the current leader
+func propose(t *testing.T, net *simNetwork, data string) {
+	t.Helper()
+
+	leaders := net.leaders(true)
+	require.Len(t, leaders, 1)
+	_, _, err := net.nodes[leaders[0]].Propose([]byte(data))
+	require.NoError(t, err)
+}
+
+func TestRaft_ReplicatesAndAppliesInOrder(t *testing.T) {
+	for _, size := range []int{3, 5, 7} {
+		t.Run(fmt.Sprintf("%d nodes", size), func(t *testing.T) {
+			net := newSimNetwork(size)
+			net.run(electionTicks)
+
+			var want []string
+			for i := 0; i < 10; i++ {
+				want = append(want, fmt.Sprintf("tx%d", i))
+				propose(t, net, want[i])
+			}
+			net.run(10)
+
+			for _, id := range net.order {
+				node := net.nodes[id]
+				assert.Equal(t, want, collectApplied(t, node, len(want)), id)
+				assert.Equal(t, node.log.lastIndex(), node.CommitIndex(), id)
+				node.Stop()
+			}
+		})
+	}
+}
+
+func TestRaft_FollowerCatchesUp(t *testing.T) {
+	net := newSimNetwork(5)
+	net.run(electionTicks)
+	leader := net.leaders(false)[0]
+
+	lagging := ""
+	for _, id := range net.order {
+		if id != leader {
+			lagging = id
+			break
+		}
+	}
+
+	// The lagging follower misses a batch larger than one message
+	net.isolate(lagging)
+	for i := 0; i < 100; i++ {
+		propose(t, net, fmt.Sprintf("tx%d", i))
+	}
+	net.run(10)
+	assert.Less(t, net.nodes[lagging].CommitIndex(), net.nodes[leader].CommitIndex())
+
+	net.heal()
+	net.run(20)
+	assert.Equal(t, net.nodes[leader].CommitIndex(), net.nodes[lagging].CommitIndex())
+	assert.Len(t, collectApplied(t, net.nodes[lagging], 100), 100)
+	net.nodes[lagging].Stop()
+}
+
+func TestRaft_TruncatesDivergentFollowerLog(t *testing.T) {
+	net := newSimNetwork(3)
+	net.run(electionTicks)
+	oldLeader := net.leaders(false)[0]
+
+	propose(t, net, "committed")
+	net.run(5)
+
+	// Entries appended by a partitioned leader never commit...
+	net.isolate(oldLeader)
+	for i := 0; i < 3; i++ {
+		_, _, err := net.nodes[oldLeader].Propose([]byte(fmt.Sprintf("lost%d", i)))
+		require.NoError(t, err)
+	}
+	net.run(electionTicks)
+	propose(t, net, "new-term")
+	net.run(5)
+
+	// ...and are replaced by the new leader's log once the partition heals
+	net.heal()
+	net.run(electionTicks)
+
+	node := net.nodes[oldLeader]
+	assert.Equal(t, []string{"committed", "new-term"}, collectApplied(t, node, 2))
+	for _, entry := range node.log.entriesFrom(node.log.firstIndex(), 100) {
+		assert.NotContains(t, string(entry.Data), "lost")
+	}
+	node.Stop()
+}
+
+func TestRaft_CommitsOnlyCurrentTermByCounting(t *testing.T) {
+	leader := NewRaftNode("node0", []string{"node1", "node2"})
+	leader.currentTerm = 3
+	leader.role = Leader
+	leader.log.append(LogEntry{Index: 1, Term: 2})
+	leader.matchIndex = map[string]uint64{"node1": 1}
+	leader.nextIndex = map[string]uint64{"node1": 2, "node2": 1}
+
+	// Majority holds index 1, but it belongs to an earlier term
+	leader.maybeCommit()
+	assert.Equal(t, uint64(0), leader.log.committed)
+
+	// Once a current-term entry is replicated, both commit
+	leader.log.append(LogEntry{Index: 2, Term: 3})
+	leader.matchIndex["node1"] = 2
+	leader.maybeCommit()
+	assert.Equal(t, uint64(2), leader.log.committed)
+}
+
+func TestRaft_RejectsVoteForStaleLog(t *testing.T) {
+	node := NewRaftNode("node0", []string{"node1"})
+	net := &simNetwork{nodes: map[string]*RaftConsensus{}, isolated: map[string]bool{}}
+	node.SetTransport(net)
+	node.currentTerm = 2
+	node.log.append(LogEntry{Index: 1, Term: 2})
+
+	// Candidate in a newer term, but with an older last log term
+	require.NoError(t, node.HandleMessage(&Message{
+		Type: MessageTypeRequestVote, Term: 3, From: "node1", To: "node0", LogIndex: 5, LogTerm: 1,
+	}))
+	require.Len(t, net.queue, 1)
+	assert.True(t, net.queue[0].Reject)
+	assert.Equal(t, uint64(3), node.currentTerm, "term still advances")
+}
+
+func TestRaft_ProposeRequiresLeader(t *testing.T) {
+	node := NewRaftNode("node0", []string{"node1", "node2"})
+	_, _, err := node.Propose([]byte("tx"))
+	assert.Error(t, err)
+}
+
+func TestRaft_TracesEntries(t *testing.T) {
+	net := newSimNetwork(3)
+	net.run(electionTicks)
+
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer(common.TracerName)
+	for _, node := range net.nodes {
+		node.SetTracer(tracer)
+	}
+	propose(t, net, "tx")
+	net.run(10)
+
+	var entry sdktrace.ReadOnlySpan
+	var appends []sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		switch s.Name() {
+		case "raft.entry":
+			entry = s
+		case "raft.append":
+			appends = append(appends, s)
+		}
+	}
+
+	// The leader's span runs until commit; each follower's append joins it
+	require.NotNil(t, entry)
+	assert.Contains(t, entry.Attributes(), common.AttributeSeq.Int64(int64(net.nodes[net.leaders(true)[0]].CommitIndex())))
+	require.Len(t, appends, 2)
+	for _, s := range appends {
+		assert.Equal(t, entry.SpanContext().TraceID(), s.SpanContext().TraceID())
+		assert.Equal(t, entry.SpanContext().SpanID(), s.Parent().SpanID())
+	}
+}
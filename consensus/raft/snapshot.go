@@ -0,0 +1,130 @@
+package raft
+
+import "fmt"
+
+// SetStorage attaches stable storage and restores any state persisted by a
+// previous run. It must be called before the node starts handling messages.
+func (r *RaftConsensus) SetStorage(s Storage) error {
+	hs, snap, entries, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load raft state: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.storage = s
+	r.currentTerm = hs.Term
+	r.votedFor = hs.VotedFor
+	r.observeState()
+
+	r.log = newRaftLog()
+	if snap.Index > 0 {
+		r.log.restore(snap)
+		r.snapshot = &snap
+		r.baseMembership = snap.Membership
+		// The application rebuilds its state from the snapshot first
+		r.pendingSnapshot = &snap
+	}
+	for _, entry := range entries {
+		r.log.append(entry)
+	}
+	r.recomputeMembership()
+	return nil
+}
+
+// CompactLog records an application snapshot taken at index and discards the
+// log entries it covers. The index must already have been applied.
+func (r *RaftConsensus) CompactLog(index uint64, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if index > r.log.applied {
+		return fmt.Errorf("cannot snapshot at %d beyond applied index %d", index, r.log.applied)
+	}
+	term, ok := r.log.term(index)
+	if !ok {
+		return fmt.Errorf("log index %d already compacted", index)
+	}
+
+	voters, learners := r.membershipAt(index)
+	snap := Snapshot{
+		Index:      index,
+		Term:       term,
+		Membership: Membership{Voters: sortedKeys(voters), Learners: sortedKeys(learners)},
+		Data:       data,
+	}
+	if r.storage != nil {
+		if err := r.storage.SaveSnapshot(snap); err != nil {
+			return err
+		}
+	}
+	r.snapshot = &snap
+	r.baseMembership = snap.Membership
+	r.log.compact(index, term)
+	return nil
+}
+
+// sendSnapshot sends the latest snapshot to a peer whose next entry is compacted
+func (r *RaftConsensus) sendSnapshot(peer string) {
+	r.send(&Message{
+		Type:     MessageTypeInstallSnapshot,
+		To:       peer,
+		Snapshot: r.snapshot,
+		Round:    r.heartbeatSeq,
+	})
+}
+
+func (r *RaftConsensus) handleInstallSnapshot(msg *Message) {
+	// A valid leader exists for this term
+	r.becomeFollower(msg.Term, msg.From)
+
+	snap := *msg.Snapshot
+	if snap.Index <= r.log.committed {
+		// Already have everything it covers
+		r.send(&Message{Type: MessageTypeAppendEntriesResponse, To: msg.From, Index: r.log.committed, Round: msg.Round})
+		return
+	}
+
+	if r.storage != nil {
+		if err := r.storage.SaveSnapshot(snap); err != nil {
+			panic(fmt.Errorf("raft node %s failed to persist snapshot: %w", r.id, err))
+		}
+	}
+
+	if r.log.matchTerm(snap.Index, snap.Term) {
+		// Keep the entries following the snapshot (Raft Figure 13, step 6)
+		r.log.compact(snap.Index, snap.Term)
+		r.log.committed = snap.Index
+	} else {
+		r.log.restore(snap)
+	}
+	r.baseMembership = snap.Membership
+	r.recomputeMembership()
+	r.snapshot = &snap
+	r.pendingSnapshot = &snap
+	r.applyCond.Broadcast()
+
+	r.send(&Message{Type: MessageTypeAppendEntriesResponse, To: msg.From, Index: snap.Index, Round: msg.Round})
+}
+
+// saveHardState persists the current term and vote
+func (r *RaftConsensus) saveHardState() {
+	if r.storage == nil {
+		return
+	}
+	if err := r.storage.SaveHardState(HardState{Term: r.currentTerm, VotedFor: r.votedFor}); err != nil {
+		// Acting on unpersisted votes breaks election safety
+		panic(fmt.Errorf("raft node %s failed to persist hard state: %w", r.id, err))
+	}
+}
+
+// saveEntries persists entries appended to the log
+func (r *RaftConsensus) saveEntries(entries []LogEntry) {
+	if r.storage == nil || len(entries) == 0 {
+		return
+	}
+	if err := r.storage.SaveEntries(entries); err != nil {
+		panic(fmt.Errorf("raft node %s failed to persist log entries: %w", r.id, err))
+	}
+}
@@ -0,0 +1,167 @@
+package raft
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	dbm "github.com/cosmos/cosmos-db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextApplied reads the next entry of any type from the node's apply channel
+func nextApplied(t *testing.T, node *RaftConsensus) LogEntry {
+	t.Helper()
+
+	select {
+	case entry, ok := <-node.ApplyCh():
+		require.True(t, ok, "apply channel closed")
+		return entry
+	case <-time.After(time.Second):
+		t.Fatalf("node %s applied nothing", node.ID())
+	}
+	return LogEntry{}
+}
+
+func TestRaft_RestartKeepsVote(t *testing.T) {
+	net := newSimNetwork(3)
+	node := net.nodes["node0"]
+
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeRequestVote, Term: 5, From: "node1", To: "node0"}))
+	net.queue = nil
+
+	net.restart("node0")
+	node = net.nodes["node0"]
+	term, _, _ := node.State()
+	assert.Equal(t, uint64(5), term)
+
+	// The vote given before the crash still counts
+	require.NoError(t, node.HandleMessage(&Message{Type: MessageTypeRequestVote, Term: 5, From: "node2", To: "node0"}))
+	require.Len(t, net.queue, 1)
+	assert.True(t, net.queue[0].Reject)
+}
+
+func TestRaft_ClusterRestartRecoversLog(t *testing.T) {
+	net := newSimNetwork(5)
+	net.run(electionTicks)
+
+	var want []string
+	for i := 0; i < 5; i++ {
+		want = append(want, fmt.Sprintf("tx%d", i))
+		propose(t, net, want[i])
+	}
+	net.run(10)
+	oldTerm, _, _ := net.nodes[net.leaders(false)[0]].State()
+
+	for _, id := range net.order {
+		net.restart(id)
+	}
+	for _, id := range net.order {
+		term, role, _ := net.nodes[id].State()
+		assert.Equal(t, oldTerm, term, id)
+		assert.Equal(t, Follower, role, id)
+	}
+
+	// A new leader re-commits the recovered log and every node replays it
+	net.run(electionTicks)
+	require.Len(t, net.leaders(false), 1)
+	for _, id := range net.order {
+		assert.Equal(t, want, collectApplied(t, net.nodes[id], len(want)), id)
+		net.nodes[id].Stop()
+	}
+}
+
+func TestRaft_InstallSnapshotOnLaggingFollower(t *testing.T) {
+	net := newSimNetwork(3)
+	net.run(electionTicks)
+	leaderID := net.leaders(false)[0]
+	leader := net.nodes[leaderID]
+
+	lagging := "node0"
+	if lagging == leaderID {
+		lagging = "node1"
+	}
+	net.isolate(lagging)
+
+	for i := 0; i < 20; i++ {
+		propose(t, net, fmt.Sprintf("tx%d", i))
+	}
+	net.run(10)
+
+	// The leader snapshots its state and drops the entries behind it
+	collectApplied(t, leader, 20)
+	snapIndex := leader.CommitIndex()
+	require.NoError(t, leader.CompactLog(snapIndex, []byte("state@20")))
+	assert.Equal(t, snapIndex+1, leader.log.firstIndex())
+
+	net.heal()
+	net.run(10)
+
+	// The follower restores from the snapshot, then applies later entries
+	follower := net.nodes[lagging]
+	entry := nextApplied(t, follower)
+	assert.Equal(t, EntrySnapshot, entry.Type)
+	assert.Equal(t, "state@20", string(entry.Data))
+	assert.Equal(t, snapIndex, entry.Index)
+
+	propose(t, net, "after-snapshot")
+	net.run(5)
+	assert.Equal(t, []string{"after-snapshot"}, collectApplied(t, follower, 1))
+
+	// The snapshot is durable: a restart replays it before the log
+	net.restart(lagging)
+	net.run(5)
+	follower = net.nodes[lagging]
+	assert.Equal(t, EntrySnapshot, nextApplied(t, follower).Type)
+	assert.Equal(t, []string{"after-snapshot"}, collectApplied(t, follower, 1))
+
+	leader.Stop()
+	follower.Stop()
+}
+
+func TestRaft_CompactLogRequiresApplied(t *testing.T) {
+	node := NewRaftConsensus()
+	for i := 0; i < electionTicks; i++ {
+		node.tick()
+	}
+	_, _, err := node.Propose([]byte("tx"))
+	require.NoError(t, err)
+
+	assert.Error(t, node.CompactLog(node.CommitIndex(), nil), "nothing applied yet")
+
+	collectApplied(t, node, 1)
+	assert.NoError(t, node.CompactLog(node.CommitIndex(), nil))
+	assert.Error(t, node.CompactLog(1, nil), "already compacted")
+	node.Stop()
+}
+
+func TestDBStorage_EntriesAndSnapshots(t *testing.T) {
+	s := NewDBStorage(dbm.NewMemDB())
+
+	require.NoError(t, s.SaveHardState(HardState{Term: 2, VotedFor: "node1"}))
+	require.NoError(t, s.SaveEntries([]LogEntry{{Index: 1, Term: 1}, {Index: 2, Term: 1}, {Index: 3, Term: 1}}))
+
+	// Overwriting from index 2 drops the old suffix
+	require.NoError(t, s.SaveEntries([]LogEntry{{Index: 2, Term: 2}}))
+	hs, snap, entries, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, HardState{Term: 2, VotedFor: "node1"}, hs)
+	assert.Equal(t, Snapshot{}, snap)
+	assert.Equal(t, []LogEntry{{Index: 1, Term: 1}, {Index: 2, Term: 2}}, entries)
+
+	// A snapshot matching the log keeps the entries after it
+	require.NoError(t, s.SaveEntries([]LogEntry{{Index: 3, Term: 2}}))
+	require.NoError(t, s.SaveSnapshot(Snapshot{Index: 2, Term: 2, Data: []byte("s")}))
+	_, snap, entries, err = s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), snap.Index)
+	assert.Equal(t, []LogEntry{{Index: 3, Term: 2}}, entries)
+
+	// A conflicting snapshot replaces the whole log
+	require.NoError(t, s.SaveSnapshot(Snapshot{Index: 3, Term: 3}))
+	_, snap, entries, err = s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), snap.Index)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,187 @@
+package raft
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	dbm "github.com/cosmos/cosmos-db"
+)
+
+// HardState is the Raft state that must survive restarts (Raft Figure 2)
+type HardState struct {
+	Term     uint64 `json:"term"`
+	VotedFor string `json:"voted_for"`
+}
+
+// Snapshot is an application snapshot covering the log up to Index
+type Snapshot struct {
+	Index      uint64     `json:"index"`
+	Term       uint64     `json:"term"`
+	Membership Membership `json:"membership"` // Configuration as of Index
+	Data       []byte     `json:"data"`
+}
+
+// Storage is the stable storage of a Raft node. Every method must be durable
+// when it returns, since the node acts on the state right after saving it.
+type Storage interface {
+	// SaveHardState persists the current term and vote
+	SaveHardState(hs HardState) error
+
+	// SaveEntries persists entries, replacing any stored entries at or after
+	// the index of the first one
+	SaveEntries(entries []LogEntry) error
+
+	// SaveSnapshot persists a snapshot and discards the entries it covers.
+	// Later entries are kept only if the stored entry at the snapshot index
+	// has the snapshot's term.
+	SaveSnapshot(snap Snapshot) error
+
+	// Load returns the persisted state; all values are zero for a new node
+	Load() (HardState, Snapshot, []LogEntry, error)
+}
+
+var (
+	hardStateKey   = []byte{0x01}
+	snapshotKey    = []byte{0x02}
+	entryKeyPrefix = []byte{0x03}
+)
+
+func entryKey(index uint64) []byte {
+	key := make([]byte, len(entryKeyPrefix)+8)
+	copy(key, entryKeyPrefix)
+	binary.BigEndian.PutUint64(key[len(entryKeyPrefix):], index)
+	return key
+}
+
+// entryKeyEnd is the exclusive upper bound of all entry keys
+var entryKeyEnd = []byte{entryKeyPrefix[0] + 1}
+
+// DBStorage implements Storage on top of a cosmos-db database
+type DBStorage struct {
+	db dbm.DB
+}
+
+// NewDBStorage creates a storage backed by db
+func NewDBStorage(db dbm.DB) *DBStorage {
+	return &DBStorage{db: db}
+}
+
+// SaveHardState implements Storage
+func (s *DBStorage) SaveHardState(hs HardState) error {
+	bz, err := json.Marshal(hs)
+	if err != nil {
+		return err
+	}
+	return s.db.SetSync(hardStateKey, bz)
+}
+
+// SaveEntries implements Storage
+func (s *DBStorage) SaveEntries(entries []LogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	batch := s.db.NewBatch()
+	defer batch.Close()
+
+	// Drop a conflicting suffix left from an earlier term
+	if err := s.deleteRange(batch, entryKey(entries[0].Index), entryKeyEnd); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		bz, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(entryKey(entry.Index), bz); err != nil {
+			return err
+		}
+	}
+	return batch.WriteSync()
+}
+
+// SaveSnapshot implements Storage
+func (s *DBStorage) SaveSnapshot(snap Snapshot) error {
+	bz, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	batch := s.db.NewBatch()
+	defer batch.Close()
+
+	if err := batch.Set(snapshotKey, bz); err != nil {
+		return err
+	}
+
+	// Entries after the snapshot survive only if the log agrees with it at
+	// its index; otherwise the snapshot replaces the whole log
+	end := entryKeyEnd
+	if stored, err := s.db.Get(entryKey(snap.Index)); err != nil {
+		return err
+	} else if stored != nil {
+		var entry LogEntry
+		if err := json.Unmarshal(stored, &entry); err != nil {
+			return fmt.Errorf("failed to decode raft log entry: %w", err)
+		}
+		if entry.Term == snap.Term {
+			end = entryKey(snap.Index + 1)
+		}
+	}
+	if err := s.deleteRange(batch, entryKey(0), end); err != nil {
+		return err
+	}
+	return batch.WriteSync()
+}
+
+// Load implements Storage
+func (s *DBStorage) Load() (hs HardState, snap Snapshot, entries []LogEntry, err error) {
+	if bz, err := s.db.Get(hardStateKey); err != nil {
+		return hs, snap, nil, err
+	} else if bz != nil {
+		if err := json.Unmarshal(bz, &hs); err != nil {
+			return hs, snap, nil, fmt.Errorf("failed to decode raft hard state: %w", err)
+		}
+	}
+
+	if bz, err := s.db.Get(snapshotKey); err != nil {
+		return hs, snap, nil, err
+	} else if bz != nil {
+		if err := json.Unmarshal(bz, &snap); err != nil {
+			return hs, snap, nil, fmt.Errorf("failed to decode raft snapshot: %w", err)
+		}
+	}
+
+	iter, err := s.db.Iterator(entryKey(snap.Index+1), entryKeyEnd)
+	if err != nil {
+		return hs, snap, nil, err
+	}
+	defer iter.Close()
+
+	for ; iter.Valid(); iter.Next() {
+		var entry LogEntry
+		if err := json.Unmarshal(iter.Value(), &entry); err != nil {
+			return hs, snap, nil, fmt.Errorf("failed to decode raft log entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return hs, snap, entries, iter.Error()
+}
+
+// deleteRange adds deletions of all keys in [start, end) to the batch
+func (s *DBStorage) deleteRange(batch dbm.Batch, start, end []byte) error {
+	iter, err := s.db.Iterator(start, end)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for ; iter.Valid(); iter.Next() {
+		if err := batch.Delete(iter.Key()); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
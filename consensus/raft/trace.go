@@ -0,0 +1,93 @@
+package raft
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+// entryTrace is the leader's span of an entry, from append to commit
+type entryTrace struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+// SetTracer sets the tracer the node records entry spans with
+func (r *RaftConsensus) SetTracer(t trace.Tracer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tracer = t
+}
+
+// traceAppend starts the span of an entry the leader appended
+func (r *RaftConsensus) traceAppend(entry LogEntry) {
+	ctx, span := r.tracer.Start(context.Background(), "raft.entry",
+		trace.WithTimestamp(r.now()),
+		trace.WithAttributes(
+			common.AttributeEngine.String(EngineName),
+			common.AttributeNodeID.String(r.id),
+			common.AttributeView.Int64(int64(entry.Term)),
+			common.AttributeSeq.Int64(int64(entry.Index)),
+			attribute.String("type", entry.Type.String()),
+		),
+	)
+	r.entryTraces[entry.Index] = entryTrace{ctx: ctx, span: span}
+}
+
+// traceContext returns the trace context carried by an AppendEntries
+// message: that of its first traced entry
+func (r *RaftConsensus) traceContext(entries []LogEntry) map[string]string {
+	for _, e := range entries {
+		if et, ok := r.entryTraces[e.Index]; ok {
+			return common.InjectTrace(et.ctx)
+		}
+	}
+	return nil
+}
+
+// traceCommit ends the spans of entries up to the commit index
+func (r *RaftConsensus) traceCommit() {
+	now := r.now()
+	for index, et := range r.entryTraces {
+		if index <= r.log.committed {
+			et.span.End(trace.WithTimestamp(now))
+			delete(r.entryTraces, index)
+		}
+	}
+}
+
+// traceAbandon ends the spans of entries left uncommitted when the node
+// stops leading; a later leader may still commit them
+func (r *RaftConsensus) traceAbandon() {
+	now := r.now()
+	for index, et := range r.entryTraces {
+		et.span.SetAttributes(attribute.Bool("lost_leadership", true))
+		et.span.End(trace.WithTimestamp(now))
+		delete(r.entryTraces, index)
+	}
+}
+
+// traceReplicate records a follower appending entries, in the trace of the
+// leader's entry span
+func (r *RaftConsensus) traceReplicate(msg *Message) {
+	if len(msg.Entries) == 0 || len(msg.TraceContext) == 0 {
+		return
+	}
+	now := r.now()
+	_, span := r.tracer.Start(common.ExtractTrace(msg.TraceContext), "raft.append",
+		trace.WithTimestamp(now),
+		trace.WithAttributes(
+			common.AttributeEngine.String(EngineName),
+			common.AttributeNodeID.String(r.id),
+			common.AttributeView.Int64(int64(msg.Term)),
+			common.AttributeSeq.Int64(int64(msg.Entries[0].Index)),
+			common.AttributeFrom.String(msg.From),
+			attribute.Int("entries", len(msg.Entries)),
+		),
+	)
+	span.End(trace.WithTimestamp(now))
+}
@@ -0,0 +1,155 @@
+package sim
+
+import (
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// Behaviour decides what a Byzantine tPBFT replica sends to another
+// replica. Messages a replica sends itself bypass it.
+type Behaviour interface {
+	// Send is handed each message the replica would send to a replica, and
+	// sends whatever it likes in its place through n.Send
+	Send(n *TPBFTNode, to string, msg tpbft.ConsensusMessage)
+}
+
+// Ticker is a Behaviour that also acts on its own, on every tick
+type Ticker interface {
+	Tick(n *TPBFTNode)
+}
+
+// isVote reports whether msg is a prepare or commit vote
+func isVote(msg tpbft.ConsensusMessage) bool {
+	return msg.Type == tpbft.MessageTypePrepare || msg.Type == tpbft.MessageTypeCommit
+}
+
+// Silent is a replica that sends nothing
+type Silent struct{}
+
+// Send implements Behaviour
+func (Silent) Send(*TPBFTNode, string, tpbft.ConsensusMessage) {}
+
+// WithholdVotes is a replica that withholds a share of its votes
+type WithholdVotes struct {
+	Types []tpbft.MessageType // Votes withheld; all if empty
+	Rate  float64             // Probability a vote to a replica is withheld
+}
+
+// Send implements Behaviour
+func (b WithholdVotes) Send(n *TPBFTNode, to string, msg tpbft.ConsensusMessage) {
+	withheld := isVote(msg) && (len(b.Types) == 0 || slices.Contains(b.Types, msg.Type))
+	if withheld && n.sim.rand.Float64() < b.Rate {
+		return
+	}
+	n.Send(to, &msg)
+}
+
+// DelayVotes is a replica that holds its votes back before sending them
+type DelayVotes struct {
+	Delay time.Duration
+}
+
+// Send implements Behaviour
+func (b DelayVotes) Send(n *TPBFTNode, to string, msg tpbft.ConsensusMessage) {
+	if !isVote(msg) {
+		n.Send(to, &msg)
+		return
+	}
+	n.sim.After(b.Delay, func() { n.Send(to, &msg) })
+}
+
+// Equivocate is a primary that pre-prepares a different block for the
+// upper half of the replicas, in ID order. It votes for whichever block each
+// replica was sent, committing along with its prepare so that either block
+// can gather a quorum.
+type Equivocate struct {
+	forks map[uint64]tpbft.ConsensusMessage // Sequence -> conflicting pre-prepare
+}
+
+// Send implements Behaviour
+func (b *Equivocate) Send(n *TPBFTNode, to string, msg tpbft.ConsensusMessage) {
+	replicas := n.Replicas()
+	if b.forks == nil {
+		b.forks = make(map[uint64]tpbft.ConsensusMessage)
+	}
+
+	upper := slices.Index(replicas, to) >= len(replicas)/2
+	fork, ok := b.forks[msg.SequenceNumber]
+	if !ok && upper && msg.Type == tpbft.MessageTypePrePrepare {
+		fork = msg
+		fork.Data = append(append([]byte(nil), msg.Data...), " (fork)"...)
+		fork.Digest = tpbft.Digest(fork.Data)
+		n.Node.Sign(&fork)
+		n.sim.recordProposal(n.ID(), fork.Data)
+		b.forks[msg.SequenceNumber], ok = fork, true
+	}
+	if ok && upper {
+		switch msg.Type {
+		case tpbft.MessageTypePrePrepare:
+			msg = fork
+		default:
+			msg.Digest = fork.Digest
+			msg.PrePrepareSignature = fork.Signature
+			n.Node.Sign(&msg)
+		}
+	}
+	n.Send(to, &msg)
+
+	if msg.Type == tpbft.MessageTypePrepare {
+		commit := msg
+		commit.Type = tpbft.MessageTypeCommit
+		commit.PrePrepareSignature = nil
+		n.Node.Sign(&commit)
+		n.Send(to, &commit)
+	}
+}
+
+// ForgeSignatures is a replica whose messages carry signatures that do not
+// verify
+type ForgeSignatures struct{}
+
+// Send implements Behaviour
+func (ForgeSignatures) Send(n *TPBFTNode, to string, msg tpbft.ConsensusMessage) {
+	sig := make([]byte, 64)
+	n.sim.rand.Read(sig)
+	msg.Signature = sig
+	n.Send(to, &msg)
+}
+
+// StaleViewSpam is a replica that, besides taking part honestly, floods
+// others with validly signed messages for views before the current one. It
+// sends none in view 0.
+type StaleViewSpam struct {
+	Rate int // Messages per tick
+}
+
+// Send implements Behaviour
+func (StaleViewSpam) Send(n *TPBFTNode, to string, msg tpbft.ConsensusMessage) {
+	n.Send(to, &msg)
+}
+
+// Tick implements Ticker
+func (b StaleViewSpam) Tick(n *TPBFTNode) {
+	view := n.Node.View
+	if view == 0 {
+		return
+	}
+	r := n.sim.rand
+	others := slices.DeleteFunc(slices.Clone(n.Replicas()), func(id string) bool { return id == n.ID() })
+	for i := 0; i < b.Rate; i++ {
+		digest := make([]byte, 32)
+		r.Read(digest)
+		msg := &tpbft.ConsensusMessage{
+			Type:           tpbft.MessageType(r.Intn(3)),
+			View:           uint64(r.Int63n(int64(view))),
+			SequenceNumber: uint64(r.Intn(1000)),
+			Digest:         hex.EncodeToString(digest),
+			NodeID:         n.ID(),
+		}
+		n.Node.Sign(msg)
+		n.Send(others[r.Intn(len(others))], msg)
+	}
+}
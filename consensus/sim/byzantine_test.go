@@ -0,0 +1,94 @@
+package sim
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// byzantineCluster runs four signing tPBFT replicas, one of them Byzantine,
+// through 20 sequences. They start in view 4, where node0 is primary again,
+// so that there are past views. Each replica scores the others on its own
+// scorer.
+func byzantineCluster(t *testing.T, byzantine string, b Behaviour) (*Sim, map[string]*tpbft.TrustScorer) {
+	s := New(1, NetworkConfig{Latency: Uniform(time.Millisecond, 20*time.Millisecond), Reorder: 0.2})
+	ids := nodeIDs(4)
+	stake := func(string) (float64, float64) { return 1, float64(len(ids)) }
+
+	nodes := make(map[string]*TPBFTNode)
+	scorers := make(map[string]*tpbft.TrustScorer)
+	var all []*TPBFTNode
+	for _, id := range ids {
+		n := NewTPBFTNode(s, id, others(ids, id))
+		scorers[id] = tpbft.NewTrustScorer()
+		scorers[id].SetClock(s.Now)
+		n.Node.SetObserver(tpbft.ScoreObserver{Scorer: scorers[id], Stake: stake})
+		require.NoError(t, n.Node.SetView(4))
+		nodes[id] = n
+		all = append(all, n)
+	}
+	nodes[byzantine].Behaviour = b
+	DistributeKeys(s, all...)
+
+	primary := nodes[nodes["node0"].Primary(4)]
+	for seq := uint64(1); seq <= 20; seq++ {
+		s.At(time.Duration(seq)*50*time.Millisecond, func() {
+			primary.Propose(seq, []byte(fmt.Sprintf("block%d", seq)))
+		})
+	}
+	// Long enough for every sequence to be swept
+	s.Run(3 * time.Second)
+	return s, scorers
+}
+
+func TestSim_TPBFTByzantine(t *testing.T) {
+	ids := nodeIDs(4)
+	for _, tc := range []struct {
+		name      string
+		byzantine string
+		behaviour Behaviour
+		live      bool // Honest replicas commit every sequence
+	}{
+		{"equivocating primary", "node0", &Equivocate{}, false},
+		{"silent replica", "node3", Silent{}, true},
+		{"withholding votes", "node3", WithholdVotes{Rate: 0.8}, true},
+		{"withholding commits", "node3", WithholdVotes{Types: []tpbft.MessageType{tpbft.MessageTypeCommit}, Rate: 1}, true},
+		{"delaying votes", "node3", DelayVotes{Delay: 800 * time.Millisecond}, true},
+		{"forging signatures", "node3", ForgeSignatures{}, true},
+		{"spamming stale views", "node3", StaleViewSpam{Rate: 2}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, scorers := byzantineCluster(t, tc.byzantine, tc.behaviour)
+			honest := others(ids, tc.byzantine)
+
+			// Safety: honest replicas never commit different blocks at a sequence
+			committed := make(map[uint64]string)
+			for _, id := range honest {
+				for _, c := range s.Commits(id) {
+					if d, ok := committed[c.Seq]; ok {
+						require.Equal(t, d, c.Digest, "%s committed a conflicting block at %d", id, c.Seq)
+					}
+					committed[c.Seq] = c.Digest
+				}
+				if tc.live {
+					assert.Len(t, s.Commits(id), 20, id)
+				}
+			}
+			assert.Len(t, committed, 20)
+
+			// Each honest replica's scores drive the Byzantine one out of the
+			// selected set, and keep the others in
+			for _, id := range honest {
+				scorer := scorers[id]
+				assert.Less(t, scorer.GetScore(tc.byzantine).TotalScore, 0.6, id)
+				selected := tpbft.NewValidatorSelector(scorer, 0.6, 100).SelectValidators(ids, 3)
+				assert.ElementsMatch(t, honest, selected, id)
+			}
+		})
+	}
+}
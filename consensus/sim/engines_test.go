@@ -0,0 +1,172 @@
+package sim
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/hotstuff"
+	"github.com/fffeng99999/hcp-consensus/consensus/raft"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// lossy is a network that jitters, drops, duplicates and reorders messages
+var lossy = NetworkConfig{
+	Latency:   Normal(5*time.Millisecond, 2*time.Millisecond),
+	Drop:      0.05,
+	Duplicate: 0.05,
+	Reorder:   0.1,
+}
+
+func nodeIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("node%d", i)
+	}
+	return ids
+}
+
+func others(ids []string, id string) []string {
+	var peers []string
+	for _, p := range ids {
+		if p != id {
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+// payloads returns the non-empty payloads a node committed, in order
+func payloads(s *Sim, id string) []string {
+	var out []string
+	for _, c := range s.Commits(id) {
+		if len(c.Data) > 0 {
+			out = append(out, string(c.Data))
+		}
+	}
+	return out
+}
+
+// raftCluster starts a Raft cluster and proposes txs through whichever node
+// leads, retrying until each is committed
+func raftCluster(t *testing.T, seed int64, size, txs int) *Sim {
+	s := New(seed, lossy)
+	ids := nodeIDs(size)
+	nodes := make(map[string]*RaftNode)
+	for _, id := range ids {
+		n, err := NewRaftNode(s, id, others(ids, id), raft.DefaultConfig())
+		require.NoError(t, err)
+		nodes[id] = n
+	}
+
+	for i := 0; i < txs; i++ {
+		tx := fmt.Sprintf("tx%d", i)
+		committed := func() bool {
+			for _, id := range ids {
+				if len(payloads(s, id)) <= i {
+					return false
+				}
+			}
+			return true
+		}
+		for !committed() {
+			for _, id := range ids {
+				if _, _, err := nodes[id].Propose([]byte(tx)); err == nil {
+					break
+				}
+			}
+			if s.RunUntil(committed, time.Second) {
+				break
+			}
+			require.Less(t, s.Elapsed(), time.Minute, "%s not committed", tx)
+		}
+	}
+	return s
+}
+
+func TestSim_Raft(t *testing.T) {
+	s := raftCluster(t, 1, 5, 20)
+	want := payloads(s, "node0")
+	require.GreaterOrEqual(t, len(want), 20)
+	for _, id := range s.Nodes() {
+		assert.Equal(t, want, payloads(s, id), id)
+	}
+}
+
+func TestSim_Reproducible(t *testing.T) {
+	a, b := raftCluster(t, 7, 3, 10), raftCluster(t, 7, 3, 10)
+	assert.Equal(t, a.Elapsed(), b.Elapsed())
+	for _, id := range a.Nodes() {
+		assert.Equal(t, a.Commits(id), b.Commits(id), id)
+	}
+
+	c := raftCluster(t, 8, 3, 10)
+	assert.NotEqual(t, a.Commits("node0"), c.Commits("node0"), "another seed takes another course")
+}
+
+func TestSim_HotStuff(t *testing.T) {
+	s := New(1, lossy)
+	ids := nodeIDs(4)
+	nodes := make(map[string]*HotStuffNode)
+	cfg := hotstuff.DefaultConfig()
+	cfg.BaseTimeout = 100 * time.Millisecond
+	for _, id := range ids {
+		n, err := NewHotStuffNode(s, id, others(ids, id), cfg)
+		require.NoError(t, err)
+		nodes[id] = n
+	}
+
+	// The proposer keeps its payloads queued until they commit, proposing
+	// them again in its next view when a lost message times a view out
+	var want []string
+	for i := 0; i < 10; i++ {
+		want = append(want, fmt.Sprintf("tx%d", i))
+		require.NoError(t, nodes["node1"].Propose([]byte(want[i])))
+	}
+	done := func() bool {
+		for _, id := range ids {
+			if len(payloads(s, id)) < len(want) {
+				return false
+			}
+		}
+		return true
+	}
+	require.True(t, s.RunUntil(done, time.Minute))
+
+	for _, id := range ids {
+		assert.Equal(t, want, payloads(s, id), id)
+	}
+}
+
+func TestSim_TPBFT(t *testing.T) {
+	s := New(1, NetworkConfig{Latency: Uniform(time.Millisecond, 20*time.Millisecond), Duplicate: 0.1, Reorder: 0.2})
+	ids := nodeIDs(4)
+	nodes := make(map[string]*TPBFTNode)
+	for _, id := range ids {
+		nodes[id] = NewTPBFTNode(s, id, others(ids, id))
+	}
+
+	primary := nodes[nodes["node0"].Primary(0)]
+	for seq := uint64(1); seq <= 10; seq++ {
+		primary.Propose(seq, []byte(fmt.Sprintf("block%d", seq)))
+	}
+	require.True(t, s.RunUntil(func() bool { return len(s.Committed(10)) == len(ids) }, time.Second))
+
+	// Sequences commit in order, each to the primary's block
+	for _, id := range ids {
+		for i, c := range s.Commits(id) {
+			assert.EqualValues(t, i+1, c.Seq, id)
+			assert.Equal(t, fmt.Sprintf("block%d", c.Seq), string(c.Data), id)
+			assert.Equal(t, tpbft.Digest(c.Data), c.Digest)
+		}
+	}
+
+	// A replica cut off from the rest cannot commit
+	s.Network().Isolate("node3")
+	primary.Propose(11, []byte("block11"))
+	s.Run(time.Second)
+	assert.ElementsMatch(t, []string{"node0", "node1", "node2"}, s.Committed(11))
+}
@@ -0,0 +1,70 @@
+package sim
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/hotstuff"
+)
+
+// HotStuffNode runs a HotStuff replica in a simulation. Every block it
+// commits is recorded as a commit at its height.
+type HotStuffNode struct {
+	*hotstuff.HotStuffConsensus
+
+	sim      *Sim
+	interval time.Duration
+
+	// Errors returned by the replica for messages it rejected
+	Errors []error
+}
+
+// NewHotStuffNode creates a HotStuff replica configured by cfg and adds it
+// to the simulation
+func NewHotStuffNode(s *Sim, id string, peers []string, cfg hotstuff.Config) (*HotStuffNode, error) {
+	h := hotstuff.NewHotStuffNode(id, peers)
+	if err := h.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	h.SetClock(s.Now)
+
+	n := &HotStuffNode{HotStuffConsensus: h, sim: s, interval: cfg.TickInterval}
+	h.SetTransport(n)
+	h.SetCommitCallback(func(b *hotstuff.Block) {
+		hash := b.Hash()
+		s.recordCommit(Commit{Node: id, Seq: b.Height, Digest: hex.EncodeToString(hash[:]), Data: b.Payload})
+	})
+	s.Add(n)
+	return n, nil
+}
+
+// Propose queues payload on the replica, recording the proposal
+func (n *HotStuffNode) Propose(payload []byte) error {
+	if err := n.HotStuffConsensus.Propose(payload); err != nil {
+		return err
+	}
+	n.sim.recordProposal(n.ID(), payload)
+	return nil
+}
+
+// Send implements hotstuff.Transport
+func (n *HotStuffNode) Send(msg *hotstuff.Message) {
+	n.sim.Send(Message{From: msg.From, To: msg.To, Payload: msg})
+}
+
+// Deliver implements Node
+func (n *HotStuffNode) Deliver(msg Message) {
+	if err := n.HandleMessage(msg.Payload.(*hotstuff.Message)); err != nil {
+		n.Errors = append(n.Errors, err)
+	}
+}
+
+// Tick implements Node
+func (n *HotStuffNode) Tick() {
+	n.HotStuffConsensus.Tick()
+}
+
+// TickInterval implements Node
+func (n *HotStuffNode) TickInterval() time.Duration {
+	return n.interval
+}
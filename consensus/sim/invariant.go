@@ -0,0 +1,297 @@
+package sim
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Invariants checked by Checker
+const (
+	InvariantAgreement   = "agreement"    // Honest replicas commit the same value at a sequence
+	InvariantValidity    = "validity"     // Committed values were proposed
+	InvariantTotalOrder  = "total-order"  // A replica commits sequences once each, in increasing order
+	InvariantProgress    = "progress"     // After GST, proposals commit everywhere within a bound
+	InvariantLogMatching = "log-matching" // Raft logs agreeing on an entry agree on all before it
+)
+
+// Checker checks safety and liveness invariants on a simulation run
+type Checker struct {
+	// Byzantine replicas are exempt from every invariant
+	Byzantine []string
+	// GST is the time since the start of the simulation from which the
+	// network is synchronous
+	GST time.Duration
+	// Bound is how long after GST, or after being proposed if later, a
+	// value may take to commit on every honest replica that has not
+	// crashed. Zero skips the progress check.
+	Bound time.Duration
+}
+
+// Violation is a broken invariant
+type Violation struct {
+	Invariant string        `json:"invariant"`
+	Node      string        `json:"node"`
+	Seq       uint64        `json:"seq"`
+	At        time.Duration `json:"at"` // When it was broken, since the start of the simulation
+	Detail    string        `json:"detail"`
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s violated by %s at sequence %d after %v: %s", v.Invariant, v.Node, v.Seq, v.At, v.Detail)
+}
+
+// Failure is a run that broke invariants. It holds what is needed to
+// replay the run: its seed, and the event log up to the first violation.
+type Failure struct {
+	Seed       int64       `json:"seed"`
+	Violations []Violation `json:"violations"`
+	Events     []Event     `json:"events"`
+}
+
+func (f *Failure) Error() string {
+	msgs := make([]string, len(f.Violations))
+	for i, v := range f.Violations {
+		msgs[i] = v.Error()
+	}
+	return fmt.Sprintf("seed %d: %s", f.Seed, strings.Join(msgs, "; "))
+}
+
+// Dump writes the failure as JSON
+func (f *Failure) Dump(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// LoadFailure reads a failure written by Dump
+func LoadFailure(r io.Reader) (*Failure, error) {
+	var f Failure
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to decode failure: %w", err)
+	}
+	return &f, nil
+}
+
+// Scenario builds and runs a simulation from a seed
+type Scenario func(seed int64) *Sim
+
+// Replay runs a scenario again with a failure's seed and checks that it
+// takes the same course up to the failure. The returned simulation can be
+// inspected or checked again.
+func Replay(f *Failure, scenario Scenario) (*Sim, error) {
+	s := scenario(f.Seed)
+	events := s.Events()
+	for i, want := range f.Events {
+		if i >= len(events) {
+			return s, fmt.Errorf("replay ended after %d of %d events", len(events), len(f.Events))
+		}
+		if events[i] != want {
+			return s, fmt.Errorf("replay diverged at event %d: got %v, want %v", i, events[i], want)
+		}
+	}
+	return s, nil
+}
+
+// Check checks every invariant on the run so far, returning a *Failure if
+// any is broken
+func (c Checker) Check(s *Sim) error {
+	var violations []Violation
+	for _, check := range []func(*Sim) []Violation{c.agreement, c.validity, c.totalOrder, c.progress, c.logMatching} {
+		violations = append(violations, check(s)...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	first := slices.MinFunc(violations, func(a, b Violation) int { return cmp.Compare(a.At, b.At) })
+	var events []Event
+	for _, e := range s.Events() {
+		if e.At > first.At {
+			break
+		}
+		events = append(events, e)
+	}
+	return &Failure{Seed: s.Seed(), Violations: violations, Events: events}
+}
+
+// honest returns the replicas the invariants apply to, in the order they
+// were added
+func (c Checker) honest(s *Sim) []string {
+	return slices.DeleteFunc(s.Nodes(), func(id string) bool { return slices.Contains(c.Byzantine, id) })
+}
+
+// agreement checks that no two honest replicas commit different values at
+// the same sequence: neither digests nor data may differ
+func (c Checker) agreement(s *Sim) []Violation {
+	var violations []Violation
+	first := make(map[uint64]Commit)
+	for _, id := range c.honest(s) {
+		for _, commit := range s.Commits(id) {
+			prev, ok := first[commit.Seq]
+			if !ok {
+				first[commit.Seq] = commit
+				continue
+			}
+			if prev.Digest != commit.Digest || !bytes.Equal(prev.Data, commit.Data) {
+				violations = append(violations, Violation{
+					Invariant: InvariantAgreement,
+					Node:      id,
+					Seq:       commit.Seq,
+					At:        max(commit.Time.Sub(Epoch), prev.Time.Sub(Epoch)),
+					Detail:    fmt.Sprintf("committed %s (%q) where %s committed %s (%q)", commit.Digest, commit.Data, prev.Node, prev.Digest, prev.Data),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// validity checks that every value honest replicas commit was proposed
+// before. Commits without data, such as no-ops, are not values.
+func (c Checker) validity(s *Sim) []Violation {
+	var violations []Violation
+	for _, id := range c.honest(s) {
+		for _, commit := range s.Commits(id) {
+			if len(commit.Data) == 0 {
+				continue
+			}
+			proposed := slices.ContainsFunc(s.Proposals(), func(p Proposal) bool {
+				return !p.Time.After(commit.Time) && bytes.Equal(p.Data, commit.Data)
+			})
+			if !proposed {
+				violations = append(violations, Violation{
+					Invariant: InvariantValidity,
+					Node:      id,
+					Seq:       commit.Seq,
+					At:        commit.Time.Sub(Epoch),
+					Detail:    fmt.Sprintf("committed %q, which was never proposed", commit.Data),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// totalOrder checks that each honest replica commits sequences in
+// increasing order, none twice
+func (c Checker) totalOrder(s *Sim) []Violation {
+	var violations []Violation
+	for _, id := range c.honest(s) {
+		commits := s.Commits(id)
+		for i := 1; i < len(commits); i++ {
+			if commits[i].Seq <= commits[i-1].Seq {
+				violations = append(violations, Violation{
+					Invariant: InvariantTotalOrder,
+					Node:      id,
+					Seq:       commits[i].Seq,
+					At:        commits[i].Time.Sub(Epoch),
+					Detail:    fmt.Sprintf("committed after sequence %d", commits[i-1].Seq),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// progress checks that every value proposed commits on each honest replica
+// that is up within Bound of GST or of its proposal, whichever is later
+func (c Checker) progress(s *Sim) []Violation {
+	if c.Bound <= 0 {
+		return nil
+	}
+	gst := Epoch.Add(c.GST)
+	var violations []Violation
+	for _, p := range s.Proposals() {
+		if len(p.Data) == 0 {
+			continue
+		}
+		deadline := p.Time
+		if deadline.Before(gst) {
+			deadline = gst
+		}
+		deadline = deadline.Add(c.Bound)
+		if deadline.After(s.Now()) {
+			continue // Still has time
+		}
+
+		for _, id := range c.honest(s) {
+			if s.Crashed(id) {
+				continue
+			}
+			committed := slices.ContainsFunc(s.Commits(id), func(commit Commit) bool {
+				return !commit.Time.After(deadline) && bytes.Equal(commit.Data, p.Data)
+			})
+			if !committed {
+				violations = append(violations, Violation{
+					Invariant: InvariantProgress,
+					Node:      id,
+					At:        deadline.Sub(Epoch),
+					Detail:    fmt.Sprintf("%q proposed through %s after %v not committed within %v", p.Data, p.Node, p.Time.Sub(Epoch), c.Bound),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// logMatching checks that whenever two honest Raft nodes hold an entry with
+// the same index and term, they hold the same entries up to it
+func (c Checker) logMatching(s *Sim) []Violation {
+	logs := make(map[string]map[uint64]string) // Node -> index -> entry digest
+	terms := make(map[string]map[uint64]uint64)
+	var ids []string
+	for _, id := range c.honest(s) {
+		n, ok := s.Node(id).(*RaftNode)
+		if !ok {
+			continue
+		}
+		ids = append(ids, id)
+		logs[id] = make(map[uint64]string)
+		terms[id] = make(map[uint64]uint64)
+		for _, e := range n.Entries() {
+			logs[id][e.Index] = entryDigest(e)
+			terms[id][e.Index] = e.Term
+		}
+	}
+
+	var violations []Violation
+	for i, a := range ids {
+		for _, b := range ids[i+1:] {
+			// The highest index both hold in the same term vouches for
+			// itself and every index both hold below it
+			var common []uint64
+			for index := range logs[a] {
+				if _, ok := logs[b][index]; ok {
+					common = append(common, index)
+				}
+			}
+			slices.Sort(common)
+			matched := false
+			for j := len(common) - 1; j >= 0; j-- {
+				index := common[j]
+				if !matched {
+					if matched = terms[a][index] == terms[b][index]; !matched {
+						continue
+					}
+				}
+				if logs[a][index] != logs[b][index] {
+					violations = append(violations, Violation{
+						Invariant: InvariantLogMatching,
+						Node:      b,
+						Seq:       index,
+						At:        s.Elapsed(),
+						Detail:    fmt.Sprintf("entry differs from %s's, though an entry of the same term at or after it matches", a),
+					})
+					break
+				}
+			}
+		}
+	}
+	return violations
+}
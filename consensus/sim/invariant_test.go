@@ -0,0 +1,182 @@
+package sim
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/raft"
+)
+
+// firstWins is a broken replica that commits the first value it is sent
+type firstWins struct {
+	id   string
+	sim  *Sim
+	done bool
+}
+
+func (n *firstWins) ID() string                  { return n.id }
+func (n *firstWins) Tick()                       {}
+func (n *firstWins) TickInterval() time.Duration { return 0 }
+
+func (n *firstWins) Deliver(msg Message) {
+	if !n.done {
+		n.done = true
+		data := msg.Payload.(string)
+		n.sim.recordCommit(Commit{Node: n.id, Seq: 1, Digest: data, Data: []byte(data)})
+	}
+}
+
+// forking has two proposers race their values to two firstWins replicas,
+// which commit different values when the network orders them differently
+func forking(latency Latency) Scenario {
+	return func(seed int64) *Sim {
+		s := New(seed, NetworkConfig{Latency: latency})
+		for _, id := range []string{"r1", "r2"} {
+			s.Add(&firstWins{id: id, sim: s})
+		}
+		for _, p := range []string{"x", "y"} {
+			s.recordProposal("p"+p, []byte(p))
+			for _, to := range []string{"r1", "r2"} {
+				s.Send(Message{From: "p" + p, To: to, Payload: p})
+			}
+		}
+		s.Run(time.Second)
+		return s
+	}
+}
+
+func TestChecker_ReplaysFailures(t *testing.T) {
+	scenario := forking(Uniform(time.Millisecond, 100*time.Millisecond))
+
+	// Find a seed the replicas disagree under
+	var failure *Failure
+	for seed := int64(1); failure == nil; seed++ {
+		require.Less(t, seed, int64(100))
+		if err := (Checker{}).Check(scenario(seed)); err != nil {
+			require.ErrorAs(t, err, &failure)
+		}
+	}
+	require.Len(t, failure.Violations, 1)
+	v := failure.Violations[0]
+	assert.Equal(t, InvariantAgreement, v.Invariant)
+	assert.EqualValues(t, 1, v.Seq)
+
+	// The trace ends with the violation
+	last := failure.Events[len(failure.Events)-1]
+	assert.Equal(t, EventCommit, last.Kind)
+	assert.Equal(t, v.At, last.At)
+	assert.Less(t, len(failure.Events), len(scenario(failure.Seed).Events()))
+
+	var buf bytes.Buffer
+	require.NoError(t, failure.Dump(&buf))
+	loaded, err := LoadFailure(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, failure, loaded)
+
+	s, err := Replay(loaded, scenario)
+	require.NoError(t, err)
+	assert.Equal(t, failure, (Checker{}).Check(s))
+
+	_, err = Replay(loaded, forking(Fixed(time.Millisecond)))
+	assert.ErrorContains(t, err, "diverged")
+}
+
+func TestChecker_Violations(t *testing.T) {
+	s := New(1, NetworkConfig{})
+	for _, id := range []string{"a", "b", "c"} {
+		newRecorder(s, id)
+	}
+	commit := func(at time.Duration, id string, seq uint64, data string) {
+		s.At(at, func() { s.recordCommit(Commit{Node: id, Seq: seq, Digest: data, Data: []byte(data)}) })
+	}
+	s.recordProposal("a", []byte("v1"))
+	s.recordProposal("a", []byte("v2"))
+	commit(10*time.Millisecond, "a", 1, "v1")
+	commit(20*time.Millisecond, "b", 1, "v1")
+	commit(30*time.Millisecond, "b", 1, "v1")     // Twice
+	commit(40*time.Millisecond, "a", 2, "forged") // Never proposed
+	commit(50*time.Millisecond, "c", 1, "v2")     // Disagrees, but c is Byzantine
+	s.Run(2 * time.Second)
+
+	var failure *Failure
+	require.ErrorAs(t, Checker{Byzantine: []string{"c"}, GST: 500 * time.Millisecond, Bound: time.Second}.Check(s), &failure)
+	got := make(map[string][]string)
+	for _, v := range failure.Violations {
+		got[v.Invariant] = append(got[v.Invariant], v.Node)
+	}
+	assert.Equal(t, map[string][]string{
+		InvariantValidity:   {"a"},
+		InvariantTotalOrder: {"b"},
+		InvariantProgress:   {"a", "b"}, // v2 never committed by an honest replica
+	}, got)
+	assert.Equal(t, 30*time.Millisecond, failure.Events[len(failure.Events)-1].At, "the trace ends at the first violation")
+
+	// Progress is only due once the bound has passed
+	s = New(1, NetworkConfig{})
+	newRecorder(s, "a")
+	s.recordProposal("a", []byte("v1"))
+	s.Run(time.Second)
+	assert.NoError(t, Checker{GST: 500 * time.Millisecond, Bound: time.Second}.Check(s))
+}
+
+func TestChecker_AgreementOnData(t *testing.T) {
+	// Replicas committing the same digest with different data disagree
+	s := New(1, NetworkConfig{})
+	newRecorder(s, "a")
+	newRecorder(s, "b")
+	s.recordProposal("a", []byte("v1"))
+	s.recordProposal("a", []byte("v2"))
+	s.At(10*time.Millisecond, func() { s.recordCommit(Commit{Node: "a", Seq: 1, Digest: "d", Data: []byte("v1")}) })
+	s.At(20*time.Millisecond, func() { s.recordCommit(Commit{Node: "b", Seq: 1, Digest: "d", Data: []byte("v2")}) })
+	s.Run(time.Second)
+
+	var failure *Failure
+	require.ErrorAs(t, Checker{}.Check(s), &failure)
+	require.Len(t, failure.Violations, 1)
+	assert.Equal(t, InvariantAgreement, failure.Violations[0].Invariant)
+	assert.Equal(t, "b", failure.Violations[0].Node)
+}
+
+func TestChecker_LogMatching(t *testing.T) {
+	s := New(1, NetworkConfig{})
+	ids := nodeIDs(3)
+	nodes := make(map[string]*RaftNode)
+	for _, id := range ids {
+		n, err := NewRaftNode(s, id, others(ids, id), raft.DefaultConfig())
+		require.NoError(t, err)
+		nodes[id] = n
+	}
+
+	// A broken leader sends followers logs that match at index 2 but not 1
+	appendEntries := func(to string, first string) {
+		require.NoError(t, nodes[to].HandleMessage(&raft.Message{
+			Type: raft.MessageTypeAppendEntries, Term: 1, From: "node2", To: to,
+			Entries: []raft.LogEntry{{Index: 1, Term: 1, Data: []byte(first)}, {Index: 2, Term: 1, Data: []byte("tx")}},
+		}))
+	}
+	appendEntries("node0", "a")
+	require.NoError(t, Checker{}.Check(s))
+	appendEntries("node1", "b")
+
+	var failure *Failure
+	require.ErrorAs(t, Checker{}.Check(s), &failure)
+	require.Len(t, failure.Violations, 1)
+	assert.Equal(t, InvariantLogMatching, failure.Violations[0].Invariant)
+	assert.EqualValues(t, 1, failure.Violations[0].Seq)
+}
+
+func TestChecker_Engines(t *testing.T) {
+	// Raft: proposals are retried until they commit, well within the bound
+	assert.NoError(t, Checker{Bound: 10 * time.Second}.Check(raftCluster(t, 1, 5, 10)))
+
+	// tPBFT keeps every invariant with a silent replica, and stays safe
+	// under an equivocating primary
+	s, _ := byzantineCluster(t, "node3", Silent{})
+	assert.NoError(t, Checker{Byzantine: []string{"node3"}, Bound: time.Second}.Check(s))
+	s, _ = byzantineCluster(t, "node0", &Equivocate{})
+	assert.NoError(t, Checker{Byzantine: []string{"node0"}}.Check(s))
+}
@@ -0,0 +1,187 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Latency is a distribution of one-way message delays
+type Latency interface {
+	Sample(r *rand.Rand) time.Duration
+}
+
+type fixedLatency time.Duration
+
+func (l fixedLatency) Sample(*rand.Rand) time.Duration { return time.Duration(l) }
+
+// Fixed delays every message by d
+func Fixed(d time.Duration) Latency {
+	return fixedLatency(d)
+}
+
+type uniformLatency struct{ min, max time.Duration }
+
+func (l uniformLatency) Sample(r *rand.Rand) time.Duration {
+	return l.min + time.Duration(r.Int63n(int64(l.max-l.min)+1))
+}
+
+// Uniform delays messages uniformly between min and max
+func Uniform(min, max time.Duration) Latency {
+	if max < min {
+		min, max = max, min
+	}
+	return uniformLatency{min, max}
+}
+
+type normalLatency struct{ mean, stddev time.Duration }
+
+func (l normalLatency) Sample(r *rand.Rand) time.Duration {
+	return max(time.Duration(r.NormFloat64()*float64(l.stddev))+l.mean, 0)
+}
+
+// Normal delays messages by a normal distribution, cut off at zero
+func Normal(mean, stddev time.Duration) Latency {
+	return normalLatency{mean, stddev}
+}
+
+type exponentialLatency struct{ min, mean time.Duration }
+
+func (l exponentialLatency) Sample(r *rand.Rand) time.Duration {
+	d := r.ExpFloat64() * float64(l.mean-l.min)
+	return l.min + time.Duration(math.Min(d, math.MaxInt64/2))
+}
+
+// Exponential delays messages by at least min, with a long exponential tail
+// averaging mean
+func Exponential(min, mean time.Duration) Latency {
+	return exponentialLatency{min, max(mean, min)}
+}
+
+// NetworkConfig describes how the network treats messages. Rates are
+// probabilities per message.
+type NetworkConfig struct {
+	Latency Latency // Defaults to Fixed(0)
+
+	Drop      float64 // Message is lost
+	Duplicate float64 // Message is delivered twice, with independent delays
+	Reorder   float64 // Message is held back by ReorderDelay, so later ones overtake it
+
+	ReorderDelay time.Duration // Defaults to defaultReorderDelay
+}
+
+// defaultReorderDelay is how long reordered messages are held back unless
+// configured otherwise
+const defaultReorderDelay = 10 * time.Millisecond
+
+// Network carries messages between replicas. Links are unordered: messages
+// with different delays arrive out of order.
+type Network struct {
+	sim       *Sim
+	cfg       NetworkConfig
+	partition map[string]int // Node -> group; nodes in different groups are cut off
+
+	sent, dropped, delivered uint64
+}
+
+func newNetwork(s *Sim, cfg NetworkConfig) *Network {
+	n := &Network{sim: s}
+	n.SetConfig(cfg)
+	return n
+}
+
+// SetConfig changes how messages sent from now on are treated
+func (n *Network) SetConfig(cfg NetworkConfig) {
+	if cfg.Latency == nil {
+		cfg.Latency = Fixed(0)
+	}
+	if cfg.ReorderDelay == 0 {
+		cfg.ReorderDelay = defaultReorderDelay
+	}
+	n.cfg = cfg
+}
+
+// Config returns the network configuration
+func (n *Network) Config() NetworkConfig {
+	return n.cfg
+}
+
+// Partition splits the network into groups that cannot reach each other.
+// Nodes left out of every group form one more group. Messages in flight
+// across the cut are lost.
+func (n *Network) Partition(groups ...[]string) {
+	n.partition = make(map[string]int)
+	for i, group := range groups {
+		for _, id := range group {
+			n.partition[id] = i + 1
+		}
+	}
+	n.sim.record(Event{Kind: EventPartition, Detail: fmt.Sprint(groups)})
+}
+
+// Isolate cuts a single node off from every other
+func (n *Network) Isolate(id string) {
+	if n.partition == nil {
+		n.partition = make(map[string]int)
+	}
+	n.partition[id] = -len(n.partition) - 1
+	n.sim.record(Event{Kind: EventPartition, Node: id, Detail: "isolated"})
+}
+
+// Heal removes every partition
+func (n *Network) Heal() {
+	n.partition = nil
+	n.sim.record(Event{Kind: EventHeal})
+}
+
+// Connected reports whether messages can pass between two nodes
+func (n *Network) Connected(a, b string) bool {
+	return n.partition[a] == n.partition[b]
+}
+
+// Stats returns the number of messages sent, dropped (including by
+// partitions and crashes) and delivered
+func (n *Network) Stats() (sent, dropped, delivered uint64) {
+	return n.sent, n.dropped, n.delivered
+}
+
+// send schedules a message's delivery, drawing its fate from the
+// simulation's random source
+func (n *Network) send(msg Message) {
+	n.sent++
+	r := n.sim.rand
+	if r.Float64() < n.cfg.Drop {
+		n.drop(msg)
+		return
+	}
+
+	copies := 1
+	if r.Float64() < n.cfg.Duplicate {
+		copies = 2
+	}
+	for i := 0; i < copies; i++ {
+		delay := n.cfg.Latency.Sample(r)
+		if r.Float64() < n.cfg.Reorder {
+			delay += n.cfg.ReorderDelay
+		}
+		n.sim.After(delay, func() { n.deliver(msg) })
+	}
+}
+
+// deliver hands a message over unless a partition or crash now stands in
+// its way
+func (n *Network) deliver(msg Message) {
+	if !n.Connected(msg.From, msg.To) || n.sim.crashed[msg.To] {
+		n.drop(msg)
+		return
+	}
+	n.delivered++
+	n.sim.deliver(msg)
+}
+
+// drop loses a message
+func (n *Network) drop(msg Message) {
+	n.dropped++
+	n.sim.record(Event{Kind: EventDrop, Node: msg.To, From: msg.From, Detail: describe(msg.Payload)})
+}
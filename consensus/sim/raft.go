@@ -0,0 +1,107 @@
+package sim
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/raft"
+)
+
+// RaftNode runs a Raft node in a simulation. Every entry it applies is
+// recorded as a commit at its log index, carrying data only for normal
+// entries.
+type RaftNode struct {
+	*raft.RaftConsensus
+
+	sim      *Sim
+	interval time.Duration
+	apply    <-chan raft.LogEntry
+	applied  uint64
+
+	// Errors returned by the node for messages it rejected
+	Errors []error
+}
+
+// NewRaftNode creates a Raft node configured by cfg and adds it to the
+// simulation
+func NewRaftNode(s *Sim, id string, peers []string, cfg raft.Config) (*RaftNode, error) {
+	r := raft.NewRaftNode(id, peers)
+	if err := r.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	r.SetClock(s.Now)
+
+	n := &RaftNode{RaftConsensus: r, sim: s, interval: cfg.TickInterval, apply: r.ApplyCh()}
+	r.SetTransport(n)
+	s.Add(n)
+	return n, nil
+}
+
+// Propose proposes data through the node, recording the proposal if the
+// node leads
+func (n *RaftNode) Propose(data []byte) (index, term uint64, err error) {
+	index, term, err = n.RaftConsensus.Propose(data)
+	if err == nil {
+		n.sim.recordProposal(n.ID(), data)
+	}
+	return index, term, err
+}
+
+// Send implements raft.Transport
+func (n *RaftNode) Send(msg *raft.Message) {
+	n.sim.Send(Message{From: msg.From, To: msg.To, Payload: msg})
+}
+
+// Deliver implements Node
+func (n *RaftNode) Deliver(msg Message) {
+	if err := n.HandleMessage(msg.Payload.(*raft.Message)); err != nil {
+		n.Errors = append(n.Errors, err)
+	}
+	n.collect()
+}
+
+// Tick implements Node
+func (n *RaftNode) Tick() {
+	n.RaftConsensus.Tick()
+	n.collect()
+}
+
+// TickInterval implements Node
+func (n *RaftNode) TickInterval() time.Duration {
+	return n.interval
+}
+
+// collect records the entries committed since the last event. The apply
+// loop delivers them on its own goroutine, so wait for all of them to keep
+// the simulation deterministic.
+func (n *RaftNode) collect() {
+	for commit := n.CommitIndex(); n.applied < commit; {
+		entry, ok := <-n.apply
+		if !ok {
+			return // Stopped
+		}
+		n.applied = entry.Index
+		if entry.Type == raft.EntrySnapshot {
+			continue
+		}
+		c := Commit{Node: n.ID(), Seq: entry.Index, Digest: entryDigest(entry)}
+		if entry.Type == raft.EntryNormal {
+			c.Data = entry.Data
+		}
+		n.sim.recordCommit(c)
+	}
+}
+
+// entryDigest identifies an entry by its term, type and data
+func entryDigest(e raft.LogEntry) string {
+	h := sha256.New()
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], e.Term)
+	h.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(e.Type))
+	h.Write(buf[:])
+	h.Write(e.Data)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,278 @@
+// Package sim runs consensus replicas over a virtual clock and network.
+// Everything that happens is an event on a single queue, and every random
+// choice is drawn from one seeded source, so a run is fully determined by
+// its seed and configuration.
+package sim
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Epoch is the virtual time a simulation starts at
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Node is a replica driven by the simulator. Adapters wrap each engine's
+// replica, routing its transport through Sim.Send.
+type Node interface {
+	ID() string
+	// Deliver hands the replica a message from the network
+	Deliver(msg Message)
+	// Tick advances the replica's timers by one TickInterval
+	Tick()
+	// TickInterval is how often Tick is called; zero means never
+	TickInterval() time.Duration
+}
+
+// Message is a message in flight between replicas. Payload is the engine's
+// own message type.
+type Message struct {
+	From    string
+	To      string
+	Payload any
+}
+
+// Commit is a value a replica committed
+type Commit struct {
+	Node   string
+	Seq    uint64 // Sequence, log index or height
+	Digest string // Identifies the committed value
+	Data   []byte // Payload, as proposed
+	Time   time.Time
+}
+
+// Proposal is a value proposed through a replica
+type Proposal struct {
+	Node string
+	Data []byte
+	Time time.Time
+}
+
+// Sim is a discrete-event simulation of replicas on a network
+type Sim struct {
+	seed  int64
+	rand  *rand.Rand
+	now   time.Time
+	queue eventQueue
+	seq   uint64 // Orders events scheduled for the same time
+
+	net       *Network
+	nodes     map[string]Node
+	order     []string // Node IDs in the order they were added
+	crashed   map[string]bool
+	commits   map[string][]Commit
+	proposals []Proposal
+	events    []Event
+}
+
+// New creates a simulation seeded with seed over a network configured by cfg
+func New(seed int64, cfg NetworkConfig) *Sim {
+	s := &Sim{
+		seed:    seed,
+		rand:    rand.New(rand.NewSource(seed)),
+		now:     Epoch,
+		nodes:   make(map[string]Node),
+		crashed: make(map[string]bool),
+		commits: make(map[string][]Commit),
+	}
+	s.net = newNetwork(s, cfg)
+	return s
+}
+
+// Seed returns the seed the simulation was created with
+func (s *Sim) Seed() int64 {
+	return s.seed
+}
+
+// Now returns the virtual time. Adapters hand it to engines as their clock.
+func (s *Sim) Now() time.Time {
+	return s.now
+}
+
+// Elapsed returns the virtual time since the simulation started
+func (s *Sim) Elapsed() time.Duration {
+	return s.now.Sub(Epoch)
+}
+
+// Rand returns the simulation's random source. Behaviours drawing from it
+// stay reproducible.
+func (s *Sim) Rand() *rand.Rand {
+	return s.rand
+}
+
+// Network returns the simulated network
+func (s *Sim) Network() *Network {
+	return s.net
+}
+
+// Add joins a replica to the simulation and starts its timer
+func (s *Sim) Add(n Node) {
+	id := n.ID()
+	if _, ok := s.nodes[id]; ok {
+		panic(fmt.Errorf("sim: duplicate node %s", id))
+	}
+	s.nodes[id] = n
+	s.order = append(s.order, id)
+
+	if interval := n.TickInterval(); interval > 0 {
+		var tick func()
+		tick = func() {
+			if !s.crashed[id] {
+				n.Tick()
+			}
+			s.After(interval, tick)
+		}
+		s.After(interval, tick)
+	}
+}
+
+// Node returns the replica with the given ID, or nil
+func (s *Sim) Node(id string) Node {
+	return s.nodes[id]
+}
+
+// Nodes returns the IDs of every replica, in the order they were added
+func (s *Sim) Nodes() []string {
+	return append([]string(nil), s.order...)
+}
+
+// Crash stops a replica: it no longer ticks, and messages to it are lost
+func (s *Sim) Crash(id string) {
+	s.crashed[id] = true
+	s.record(Event{Kind: EventCrash, Node: id})
+}
+
+// Recover resumes a crashed replica with the state it had when it crashed
+func (s *Sim) Recover(id string) {
+	delete(s.crashed, id)
+	s.record(Event{Kind: EventRecover, Node: id})
+}
+
+// Crashed reports whether a replica is crashed
+func (s *Sim) Crashed(id string) bool {
+	return s.crashed[id]
+}
+
+// Send puts a message on the network. Messages a replica sends itself are
+// delivered at once and never lost.
+func (s *Sim) Send(msg Message) {
+	if msg.From == msg.To {
+		s.After(0, func() { s.deliver(msg) })
+		return
+	}
+	s.net.send(msg)
+}
+
+// After schedules fn to run after d of virtual time
+func (s *Sim) After(d time.Duration, fn func()) {
+	s.seq++
+	heap.Push(&s.queue, &event{at: s.now.Add(d), seq: s.seq, fn: fn})
+}
+
+// At schedules fn to run at the given time since the start of the
+// simulation, or at once if that time has passed
+func (s *Sim) At(elapsed time.Duration, fn func()) {
+	s.After(max(Epoch.Add(elapsed).Sub(s.now), 0), fn)
+}
+
+// Run processes events for d of virtual time
+func (s *Sim) Run(d time.Duration) {
+	s.RunUntil(func() bool { return false }, d)
+}
+
+// RunUntil processes events until cond holds, checking it after every
+// event, or until d of virtual time has passed. It reports whether cond held.
+func (s *Sim) RunUntil(cond func() bool, d time.Duration) bool {
+	end := s.now.Add(d)
+	for {
+		if cond() {
+			return true
+		}
+		if len(s.queue) == 0 || s.queue[0].at.After(end) {
+			s.now = end
+			return cond()
+		}
+		ev := heap.Pop(&s.queue).(*event)
+		s.now = ev.at
+		ev.fn()
+	}
+}
+
+// deliver hands a message to its recipient unless the recipient crashed
+func (s *Sim) deliver(msg Message) {
+	n, ok := s.nodes[msg.To]
+	if !ok || s.crashed[msg.To] {
+		return
+	}
+	s.record(Event{Kind: EventDeliver, Node: msg.To, From: msg.From, Detail: describe(msg.Payload)})
+	n.Deliver(msg)
+}
+
+// recordCommit records a value a replica committed, at the current time
+func (s *Sim) recordCommit(c Commit) {
+	c.Time = s.now
+	s.commits[c.Node] = append(s.commits[c.Node], c)
+	s.record(Event{Kind: EventCommit, Node: c.Node, Detail: fmt.Sprintf("seq=%d digest=%s", c.Seq, c.Digest)})
+}
+
+// recordProposal records a value proposed through a replica
+func (s *Sim) recordProposal(node string, data []byte) {
+	s.proposals = append(s.proposals, Proposal{Node: node, Data: data, Time: s.now})
+	s.record(Event{Kind: EventPropose, Node: node, Detail: fmt.Sprintf("%q", data)})
+}
+
+// Proposals returns the values proposed, in proposal order
+func (s *Sim) Proposals() []Proposal {
+	return s.proposals
+}
+
+// Commits returns the values a replica committed, in commit order
+func (s *Sim) Commits(id string) []Commit {
+	return s.commits[id]
+}
+
+// Committed returns the IDs of replicas that committed at least n values,
+// sorted
+func (s *Sim) Committed(n int) []string {
+	var ids []string
+	for id, commits := range s.commits {
+		if len(commits) >= n {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// event is a scheduled action
+type event struct {
+	at  time.Time
+	seq uint64
+	fn  func()
+}
+
+// eventQueue is a min-heap of events by time, then by scheduling order
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x any) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() any {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,173 @@
+package sim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a node that records when each message arrives
+type recorder struct {
+	id       string
+	interval time.Duration
+	sim      *Sim
+	got      []Message
+	at       []time.Duration
+	ticks    int
+}
+
+func newRecorder(s *Sim, id string) *recorder {
+	r := &recorder{id: id, sim: s}
+	s.Add(r)
+	return r
+}
+
+func (r *recorder) ID() string                  { return r.id }
+func (r *recorder) Tick()                       { r.ticks++ }
+func (r *recorder) TickInterval() time.Duration { return r.interval }
+
+func (r *recorder) Deliver(msg Message) {
+	r.got = append(r.got, msg)
+	r.at = append(r.at, r.sim.Elapsed())
+}
+
+func TestSim_EventsInTimeOrder(t *testing.T) {
+	s := New(1, NetworkConfig{Latency: Fixed(10 * time.Millisecond)})
+	a, b := newRecorder(s, "a"), newRecorder(s, "b")
+
+	var order []string
+	s.At(30*time.Millisecond, func() { order = append(order, "30ms") })
+	s.At(5*time.Millisecond, func() {
+		order = append(order, "5ms")
+		s.Send(Message{From: "a", To: "b", Payload: 1})
+	})
+	s.At(5*time.Millisecond, func() { order = append(order, "5ms, scheduled later") })
+	s.Send(Message{From: "b", To: "b", Payload: "self"})
+
+	s.Run(time.Second)
+	assert.Equal(t, []string{"5ms", "5ms, scheduled later", "30ms"}, order)
+	assert.Empty(t, a.got)
+	require.Len(t, b.got, 2)
+	assert.Equal(t, []time.Duration{0, 15 * time.Millisecond}, b.at, "self messages are immediate")
+	assert.Equal(t, time.Second, s.Elapsed())
+}
+
+func TestSim_Ticks(t *testing.T) {
+	s := New(1, NetworkConfig{})
+	r := &recorder{id: "a", interval: 10 * time.Millisecond, sim: s}
+	s.Add(r)
+
+	s.Run(100 * time.Millisecond)
+	assert.Equal(t, 10, r.ticks)
+
+	// A crashed node neither ticks nor receives
+	s.Crash("a")
+	s.Send(Message{From: "b", To: "a"})
+	s.Run(100 * time.Millisecond)
+	assert.Equal(t, 10, r.ticks)
+	assert.Empty(t, r.got)
+
+	s.Recover("a")
+	s.Run(100 * time.Millisecond)
+	assert.Equal(t, 20, r.ticks)
+}
+
+func TestNetwork_Faults(t *testing.T) {
+	const n = 10000
+	s := New(42, NetworkConfig{Latency: Uniform(time.Millisecond, 100*time.Millisecond), Drop: 0.1, Duplicate: 0.2, Reorder: 0.1})
+	newRecorder(s, "a")
+	b := newRecorder(s, "b")
+	for i := 0; i < n; i++ {
+		s.Send(Message{From: "a", To: "b", Payload: i})
+	}
+	s.Run(time.Second)
+
+	// Roughly 90% survive, a fifth of those twice
+	sent, dropped, delivered := s.Network().Stats()
+	assert.EqualValues(t, n, sent)
+	assert.InDelta(t, 0.1*n, dropped, 0.02*n)
+	assert.InDelta(t, 0.9*1.2*n, delivered, 0.03*n)
+	assert.Len(t, b.got, int(delivered))
+
+	reordered := 0
+	for i := 1; i < len(b.got); i++ {
+		if b.got[i].Payload.(int) < b.got[i-1].Payload.(int) {
+			reordered++
+		}
+	}
+	assert.Greater(t, reordered, 0)
+	for _, at := range b.at {
+		assert.GreaterOrEqual(t, at, time.Millisecond)
+		assert.LessOrEqual(t, at, 200*time.Millisecond)
+	}
+}
+
+func TestNetwork_Reorder(t *testing.T) {
+	// Without jitter, only reordering lets a message overtake another
+	s := New(1, NetworkConfig{Latency: Fixed(5 * time.Millisecond), Reorder: 0.3, ReorderDelay: 20 * time.Millisecond})
+	newRecorder(s, "a")
+	b := newRecorder(s, "b")
+	for i := 0; i < 100; i++ {
+		s.At(time.Duration(i)*time.Millisecond, func() { s.Send(Message{From: "a", To: "b", Payload: i}) })
+	}
+	s.Run(time.Second)
+
+	require.Len(t, b.got, 100)
+	overtaken := 0
+	for i, msg := range b.got {
+		sentAt := time.Duration(msg.Payload.(int)) * time.Millisecond
+		assert.Contains(t, []time.Duration{5 * time.Millisecond, 25 * time.Millisecond}, b.at[i]-sentAt)
+		if i > 0 && msg.Payload.(int) < b.got[i-1].Payload.(int) {
+			overtaken++
+		}
+	}
+	assert.Greater(t, overtaken, 0)
+}
+
+func TestNetwork_Partitions(t *testing.T) {
+	s := New(1, NetworkConfig{Latency: Fixed(10 * time.Millisecond)})
+	a, b, c := newRecorder(s, "a"), newRecorder(s, "b"), newRecorder(s, "c")
+	net := s.Network()
+
+	net.Partition([]string{"a", "b"})
+	assert.True(t, net.Connected("a", "b"))
+	assert.False(t, net.Connected("a", "c"))
+	s.Send(Message{From: "a", To: "b"})
+	s.Send(Message{From: "a", To: "c"})
+	s.Run(time.Second)
+	assert.Len(t, b.got, 1)
+	assert.Empty(t, c.got)
+
+	// Messages in flight when the cut happens are lost
+	net.Heal()
+	s.Send(Message{From: "c", To: "a"})
+	s.After(5*time.Millisecond, func() { net.Isolate("a") })
+	s.Run(time.Second)
+	assert.Empty(t, a.got)
+
+	net.Isolate("b")
+	assert.False(t, net.Connected("a", "b"))
+	net.Heal()
+	assert.True(t, net.Connected("a", "b"))
+}
+
+func TestLatency_Distributions(t *testing.T) {
+	s := New(3, NetworkConfig{})
+	r := s.Rand()
+	mean := func(l Latency) time.Duration {
+		var sum time.Duration
+		for i := 0; i < 10000; i++ {
+			d := l.Sample(r)
+			require.GreaterOrEqual(t, d, time.Duration(0))
+			sum += d
+		}
+		return sum / 10000
+	}
+
+	assert.Equal(t, 5*time.Millisecond, mean(Fixed(5*time.Millisecond)))
+	assert.InDelta(t, 50*time.Millisecond, mean(Uniform(0, 100*time.Millisecond)), float64(2*time.Millisecond))
+	assert.InDelta(t, 50*time.Millisecond, mean(Normal(50*time.Millisecond, 10*time.Millisecond)), float64(time.Millisecond))
+	assert.InDelta(t, 30*time.Millisecond, mean(Exponential(10*time.Millisecond, 30*time.Millisecond)), float64(2*time.Millisecond))
+}
@@ -0,0 +1,186 @@
+package sim
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// TPBFTNode runs a tPBFT replica in a simulation. PBFTNode only counts
+// votes, so the adapter plays the rest of the normal case: it prepares the
+// first pre-prepare of a sequence, commits once prepared, and records
+// committed sequences in sequence order, as it would execute them. A
+// Behaviour makes the replica Byzantine.
+type TPBFTNode struct {
+	Node *tpbft.PBFTNode
+
+	// Behaviour decides what the replica sends to others; nil is honest
+	Behaviour Behaviour
+
+	sim      *Sim
+	replicas []string
+
+	prePrepares map[uint64]*tpbft.ConsensusMessage // Accepted pre-prepare per sequence
+	sent        map[uint64]map[tpbft.MessageType]bool
+	executed    uint64 // Last sequence recorded
+
+	// Errors returned by the replica for messages it rejected
+	Errors []error
+}
+
+// NewTPBFTNode creates a tPBFT replica and adds it to the simulation
+func NewTPBFTNode(s *Sim, id string, peers []string) *TPBFTNode {
+	node := tpbft.NewPBFTNode(id, peers)
+	node.SetClock(s.Now)
+
+	replicas := append([]string{id}, peers...)
+	sort.Strings(replicas)
+	n := &TPBFTNode{
+		Node:        node,
+		sim:         s,
+		replicas:    replicas,
+		prePrepares: make(map[uint64]*tpbft.ConsensusMessage),
+		sent:        make(map[uint64]map[tpbft.MessageType]bool),
+	}
+	s.Add(n)
+	return n
+}
+
+// ID implements Node
+func (n *TPBFTNode) ID() string {
+	return n.Node.ID
+}
+
+// Replicas returns every replica's ID, sorted
+func (n *TPBFTNode) Replicas() []string {
+	return n.replicas
+}
+
+// Primary returns the primary of a view, rotating through the replicas
+func (n *TPBFTNode) Primary(view uint64) string {
+	return n.replicas[view%uint64(len(n.replicas))]
+}
+
+// Propose broadcasts a pre-prepare for data at seq in the node's view
+func (n *TPBFTNode) Propose(seq uint64, data []byte) {
+	n.sim.recordProposal(n.ID(), data)
+	n.Broadcast(&tpbft.ConsensusMessage{
+		Type:           tpbft.MessageTypePrePrepare,
+		View:           n.Node.View,
+		SequenceNumber: seq,
+		Digest:         tpbft.Digest(data),
+		Data:           data,
+	})
+}
+
+// Broadcast signs msg and sends it from this node to every replica, itself
+// included. Messages to others go through the node's Behaviour.
+func (n *TPBFTNode) Broadcast(msg *tpbft.ConsensusMessage) {
+	msg.NodeID = n.ID()
+	if msg.TraceContext == nil {
+		msg.TraceContext = n.Node.TraceContext(msg.SequenceNumber)
+	}
+	n.Node.Sign(msg)
+	for _, id := range n.replicas {
+		if n.Behaviour == nil || id == n.ID() {
+			n.Send(id, msg)
+			continue
+		}
+		n.Behaviour.Send(n, id, *msg)
+	}
+}
+
+// Send puts msg on the network as it is
+func (n *TPBFTNode) Send(to string, msg *tpbft.ConsensusMessage) {
+	n.sim.Send(Message{From: n.ID(), To: to, Payload: msg})
+}
+
+// Deliver implements Node. The network authenticates senders, so a message
+// claiming another sender is rejected. Messages for other views are left to
+// the replica, which ignores stale ones and buffers future ones.
+func (n *TPBFTNode) Deliver(msg Message) {
+	m := msg.Payload.(*tpbft.ConsensusMessage)
+	if m.NodeID != msg.From {
+		n.Errors = append(n.Errors, fmt.Errorf("%s sent a message as %s", msg.From, m.NodeID))
+		return
+	}
+	if err := n.Node.HandleMessage(m); err != nil {
+		n.Errors = append(n.Errors, err)
+		return
+	}
+	if m.View != n.Node.View {
+		return
+	}
+	if _, ok := n.prePrepares[m.SequenceNumber]; !ok && m.Type == tpbft.MessageTypePrePrepare {
+		n.prePrepares[m.SequenceNumber] = m
+	}
+	n.step(m.SequenceNumber)
+}
+
+// step sends the votes a sequence's state calls for and records the
+// sequences it lets execute
+func (n *TPBFTNode) step(seq uint64) {
+	pp, ok := n.prePrepares[seq]
+	if !ok {
+		return
+	}
+	if n.sent[seq] == nil {
+		n.sent[seq] = make(map[tpbft.MessageType]bool)
+	}
+
+	vote := func(typ tpbft.MessageType) {
+		if !n.sent[seq][typ] {
+			n.sent[seq][typ] = true
+			n.Broadcast(&tpbft.ConsensusMessage{Type: typ, View: pp.View, SequenceNumber: seq, Digest: pp.Digest, PrePrepareSignature: pp.Signature})
+		}
+	}
+	vote(tpbft.MessageTypePrepare)
+	if n.Node.Prepared[seq] {
+		vote(tpbft.MessageTypeCommit)
+	}
+
+	for {
+		next, ok := n.prePrepares[n.executed+1]
+		if !ok || !n.Node.Committed[n.executed+1] {
+			return
+		}
+		n.executed++
+		n.sim.recordCommit(Commit{Node: n.ID(), Seq: n.executed, Digest: next.Digest, Data: next.Data})
+	}
+}
+
+// Tick implements Node. It sweeps the replica's vote bookkeeping and lets
+// the behaviour act.
+func (n *TPBFTNode) Tick() {
+	n.Node.Sweep()
+	if t, ok := n.Behaviour.(Ticker); ok {
+		t.Tick(n)
+	}
+}
+
+// TickInterval implements Node
+func (n *TPBFTNode) TickInterval() time.Duration {
+	return 10 * time.Millisecond
+}
+
+// DistributeKeys gives each replica a key drawn from the simulation's random
+// source and the public keys of all, so that they sign and verify messages
+func DistributeKeys(s *Sim, nodes ...*TPBFTNode) {
+	nodes = append([]*TPBFTNode(nil), nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID() < nodes[j].ID() })
+
+	keys := make([]ed25519.PrivateKey, len(nodes))
+	pubs := make(map[string]ed25519.PublicKey, len(nodes))
+	for i, n := range nodes {
+		seed := make([]byte, ed25519.SeedSize)
+		s.rand.Read(seed)
+		keys[i] = ed25519.NewKeyFromSeed(seed)
+		pubs[n.ID()] = keys[i].Public().(ed25519.PublicKey)
+	}
+	for i, n := range nodes {
+		n.Node.SetKeys(keys[i], pubs)
+	}
+}
@@ -0,0 +1,79 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/hotstuff"
+	"github.com/fffeng99999/hcp-consensus/consensus/raft"
+	"github.com/fffeng99999/hcp-consensus/consensus/tpbft"
+)
+
+// EventKind names what happened in an Event
+type EventKind string
+
+const (
+	EventDeliver   EventKind = "deliver"
+	EventDrop      EventKind = "drop"
+	EventCrash     EventKind = "crash"
+	EventRecover   EventKind = "recover"
+	EventPartition EventKind = "partition"
+	EventHeal      EventKind = "heal"
+	EventPropose   EventKind = "propose"
+	EventCommit    EventKind = "commit"
+)
+
+// Event is an entry in the simulation's event log. The log holds nothing
+// that differs between runs of the same seed, so replays can be compared
+// with it.
+type Event struct {
+	At     time.Duration `json:"at"` // Since the start of the simulation
+	Kind   EventKind     `json:"kind"`
+	Node   string        `json:"node,omitempty"` // Recipient of messages
+	From   string        `json:"from,omitempty"`
+	Detail string        `json:"detail,omitempty"`
+}
+
+func (e Event) String() string {
+	s := fmt.Sprintf("%v %s %s", e.At, e.Kind, e.Node)
+	if e.From != "" {
+		s += " from " + e.From
+	}
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	return s
+}
+
+// record appends an event to the log at the current time
+func (s *Sim) record(e Event) {
+	e.At = s.Elapsed()
+	s.events = append(s.events, e)
+}
+
+// Events returns the event log
+func (s *Sim) Events() []Event {
+	return s.events
+}
+
+// describe summarises a message payload for the event log
+func describe(payload any) string {
+	switch m := payload.(type) {
+	case *tpbft.ConsensusMessage:
+		return fmt.Sprintf("%s view=%d seq=%d digest=%.8s", m.Type, m.View, m.SequenceNumber, m.Digest)
+	case *raft.Message:
+		return fmt.Sprintf("%s term=%d index=%d entries=%d commit=%d reject=%t", m.Type, m.Term, m.LogIndex, len(m.Entries), m.Commit, m.Reject)
+	case *hotstuff.Message:
+		s := fmt.Sprintf("%s view=%d", m.Type, m.View)
+		if m.Block != nil {
+			hash := m.Block.Hash()
+			s += fmt.Sprintf(" height=%d block=%x", m.Block.Height, hash[:4])
+		}
+		return s
+	case fmt.Stringer:
+		return m.String()
+	case string, int:
+		return fmt.Sprint(m)
+	}
+	return fmt.Sprintf("%T", payload)
+}
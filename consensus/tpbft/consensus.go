@@ -6,15 +6,23 @@ import (
 	"sync"
 	"time"
 
+	"cosmossdk.io/log"
 	"cosmossdk.io/math"
+	storetypes "cosmossdk.io/store/types"
 	abci "github.com/cometbft/cometbft/abci/types"
 	tmcrypto "github.com/cometbft/cometbft/proto/tendermint/crypto"
 	tmproto "github.com/cometbft/cometbft/proto/tendermint/types"
 	crypto "github.com/cosmos/cosmos-sdk/crypto/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
 )
 
+// EngineName is the name selecting tPBFT in the consensus-engine option
+const EngineName = "tpbft"
+
 // StakingKeeper defines the interface needed from the staking module
 type StakingKeeper interface {
 	GetValidatorByConsAddr(ctx context.Context, consAddr sdk.ConsAddress) (stakingtypes.Validator, error)
@@ -29,16 +37,24 @@ type TPBFT struct {
 	TrustScorer       *TrustScorer
 	ValidatorSelector *ValidatorSelector
 	Node              *PBFTNode
-	running           bool
+	loop              common.Loop
 
 	stakingKeeper StakingKeeper
+	storeKey      storetypes.StoreKey
+	params        Params // Loaded from the store every block
+
+	metrics  *common.TPBFTMetrics
+	selected map[string]bool // Last selected set, for churn
+	gauged   map[string]bool // Validators with a trust score series
+
+	logger log.Logger
 }
 
 // NewTPBFT creates a new tPBFT consensus instance
 func NewTPBFT() *TPBFT {
 	scorer := NewTrustScorer()
-	// Default config: minTrust=0.6, maxValidators=100
-	selector := NewValidatorSelector(scorer, 0.6, 100)
+	params := DefaultParams()
+	selector := NewValidatorSelector(scorer, params.MinTrustThreshold, params.ValidatorSelectionCount)
 
 	// Node initialized with empty config, to be configured if running standalone
 	node := NewPBFTNode("local-node", []string{})
@@ -47,55 +63,96 @@ func NewTPBFT() *TPBFT {
 		TrustScorer:       scorer,
 		ValidatorSelector: selector,
 		Node:              node,
+		params:            params,
+		metrics:           common.NopMetrics().TPBFT,
+		logger:            log.NewNopLogger(),
 	}
 }
 
+// SetLogger sets the logger of the engine and its node
+func (t *TPBFT) SetLogger(l log.Logger) {
+	t.Node.SetLogger(l)
+	t.logger = t.Node.logger
+}
+
+// SetMetrics sets the metrics the engine and its node report to
+func (t *TPBFT) SetMetrics(m *common.TPBFTMetrics) {
+	t.metrics = m
+	t.Node.SetMetrics(m)
+}
+
+// SetTracer sets the tracer the engine's node records sequence spans with
+func (t *TPBFT) SetTracer(tr trace.Tracer) {
+	t.Node.SetTracer(tr)
+}
+
 // SetStakingKeeper sets the staking keeper dependency
 func (t *TPBFT) SetStakingKeeper(k StakingKeeper) {
 	t.stakingKeeper = k
 }
 
-// Start starts the consensus engine
-func (t *TPBFT) Start() error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+// Name implements ConsensusEngine
+func (t *TPBFT) Name() string {
+	return EngineName
+}
 
-	if t.running {
-		return fmt.Errorf("tPBFT engine already running")
+// Start starts the consensus engine
+func (t *TPBFT) Start(ctx context.Context) error {
+	if err := t.loop.Start(ctx, t.consensusLoop); err != nil {
+		return fmt.Errorf("tPBFT engine %w", err)
 	}
-
-	t.running = true
-	// Start background tasks
-	go t.consensusLoop()
-
 	return nil
 }
 
 // consensusLoop handles background tasks
-func (t *TPBFT) consensusLoop() {
+func (t *TPBFT) consensusLoop(ctx context.Context) {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
-	for t.running {
+	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-ticker.C:
 			// Periodic tasks (e.g. trust decay if needed)
-		default:
-			time.Sleep(100 * time.Millisecond)
 		}
 	}
 }
 
-// Stop stops the consensus engine
+// Stop stops the consensus engine and waits for its background tasks
 func (t *TPBFT) Stop() error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	t.loop.Stop()
+	return nil
+}
 
-	if !t.running {
-		return nil
+// Status implements ConsensusEngine
+func (t *TPBFT) Status() common.Status {
+	t.Node.mu.RLock()
+	defer t.Node.mu.RUnlock()
+
+	var committed uint64
+	for seq, ok := range t.Node.Committed {
+		if ok && seq > committed {
+			committed = seq
+		}
+	}
+	return common.Status{
+		Engine:    EngineName,
+		NodeID:    t.Node.ID,
+		Running:   t.loop.Running(),
+		Round:     t.Node.View,
+		Committed: committed,
 	}
+}
 
-	t.running = false
+// Health implements ConsensusEngine
+func (t *TPBFT) Health() error {
+	if !t.loop.Running() {
+		return fmt.Errorf("tPBFT engine not running")
+	}
+	if t.stakingKeeper == nil {
+		return fmt.Errorf("tPBFT engine has no staking keeper")
+	}
 	return nil
 }
 
@@ -143,14 +200,26 @@ func (t *TPBFT) handleCommit(msg *ConsensusMessage) error {
 }
 
 // BeginBlock implements ConsensusEngine
-func (t *TPBFT) BeginBlock(ctx sdk.Context) {
+func (t *TPBFT) BeginBlock(ctx sdk.Context) error {
+	// CometBFT runs the rounds, so a block committed in round r took r view
+	// changes
+	if info := ctx.CometInfo(); info != nil {
+		if round := info.GetLastCommit().Round(); round > 0 {
+			t.metrics.ViewChanges.Add(float64(round))
+			t.logger.Info("last block took view changes", "height", ctx.BlockHeight()-1, "view", round)
+		}
+	}
+
 	if t.stakingKeeper == nil {
-		return
+		return nil
 	}
 
+	// Timestamp scores with block time so persisted state is deterministic
+	t.TrustScorer.SetClock(ctx.BlockTime)
+
 	proposerAddr := ctx.BlockHeader().ProposerAddress
 	if len(proposerAddr) == 0 {
-		return
+		return nil
 	}
 
 	// Calculate response time
@@ -158,7 +227,7 @@ func (t *TPBFT) BeginBlock(ctx sdk.Context) {
 
 	val, err := t.stakingKeeper.GetValidatorByConsAddr(ctx, proposerAddr)
 	if err != nil || val.OperatorAddress == "" {
-		return
+		return nil
 	}
 	valAddr := val.OperatorAddress
 
@@ -172,25 +241,75 @@ func (t *TPBFT) BeginBlock(ctx sdk.Context) {
 		stake,
 		totalStake,
 	)
+	return nil
 }
 
 // EndBlock implements ConsensusEngine
-func (t *TPBFT) EndBlock(ctx sdk.Context) []abci.ValidatorUpdate {
+func (t *TPBFT) EndBlock(ctx sdk.Context) ([]abci.ValidatorUpdate, error) {
 	if t.stakingKeeper == nil {
-		return nil
+		return nil, nil
 	}
 
-	// 1. Update trust scores for all validators
-	t.updateTrustScores(ctx)
+	t.TrustScorer.SetClock(ctx.BlockTime)
+	if err := t.loadParams(ctx); err != nil {
+		return nil, err
+	}
+
+	// 1. Update trust scores for all validators, every trust update interval
+	if ctx.BlockHeight()%t.params.TrustUpdateInterval == 0 {
+		t.updateTrustScores(ctx)
+	}
+	if err := t.saveTrustState(ctx); err != nil {
+		return nil, fmt.Errorf("failed to persist trust state: %w", err)
+	}
+	if err := t.reportTrustScores(ctx); err != nil {
+		return nil, err
+	}
 
-	// 2. Select next validators
-	newValidators := t.selectNextValidators(ctx)
+	// 2. Select next validators, unless the staking module's set stands
+	if !t.params.DynamicValidatorSelection {
+		return nil, nil
+	}
+	newValidators, err := t.selectNextValidators(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	// 3. Return validator updates if changed
 	if t.validatorsChanged(ctx, newValidators) {
-		return t.toABCIValidators(newValidators)
+		return t.toABCIValidators(newValidators), nil
 	}
 
+	return nil, nil
+}
+
+// reportTrustScores sets the trust score of every bonded validator, and
+// removes the series of validators that left the bonded set
+func (t *TPBFT) reportTrustScores(ctx sdk.Context) error {
+	validators, err := t.stakingKeeper.GetAllValidators(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list validators: %w", err)
+	}
+	bonded := make(map[string]bool, len(validators))
+	for _, v := range validators {
+		if v.IsBonded() {
+			bonded[v.OperatorAddress] = true
+		}
+	}
+
+	gauged := make(map[string]bool, len(bonded))
+	for _, rec := range t.TrustScorer.ExportGenesis().Scores {
+		if bonded[rec.ValidatorAddress] {
+			t.metrics.TrustScore.With("validator", rec.ValidatorAddress).Set(rec.TotalScore)
+			gauged[rec.ValidatorAddress] = true
+		}
+	}
+	for addr := range t.gauged {
+		if !gauged[addr] {
+			t.metrics.TrustScore.Delete("validator", addr)
+		}
+	}
+	t.gauged = gauged
 	return nil
 }
 
@@ -222,10 +341,10 @@ func (t *TPBFT) updateTrustScores(ctx sdk.Context) {
 	}
 }
 
-func (t *TPBFT) selectNextValidators(ctx sdk.Context) []stakingtypes.Validator {
+func (t *TPBFT) selectNextValidators(ctx sdk.Context) ([]stakingtypes.Validator, error) {
 	allValidators, err := t.stakingKeeper.GetAllValidators(ctx)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to list validators: %w", err)
 	}
 
 	var allAddrs []string
@@ -252,7 +371,37 @@ func (t *TPBFT) selectNextValidators(ctx sdk.Context) []stakingtypes.Validator {
 			selected = append(selected, val)
 		}
 	}
-	return selected
+	if churn := t.observeSelection(selectedAddrs); churn > 0 {
+		t.logger.Info("selected validator set changed", "height", ctx.BlockHeight(), "validators", len(selectedAddrs), "churn", churn)
+	}
+	return selected, nil
+}
+
+// observeSelection reports the selected set's size and how many validators
+// entered or left it since the last selection, returning that churn
+func (t *TPBFT) observeSelection(addrs []string) int {
+	selected := make(map[string]bool, len(addrs))
+	churn := 0
+	for _, addr := range addrs {
+		selected[addr] = true
+		if !t.selected[addr] {
+			churn++
+		}
+	}
+	for addr := range t.selected {
+		if !selected[addr] {
+			churn++
+		}
+	}
+
+	// The first selection after startup is not churn
+	if t.selected == nil {
+		churn = 0
+	}
+	t.metrics.SelectionChurn.Add(float64(churn))
+	t.metrics.SelectedValidators.Set(float64(len(addrs)))
+	t.selected = selected
+	return churn
 }
 
 func (t *TPBFT) validatorsChanged(ctx sdk.Context, newValidators []stakingtypes.Validator) bool {
@@ -0,0 +1,146 @@
+package tpbft
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+func TestTPBFT_Lifecycle(t *testing.T) {
+	engine := NewTPBFT()
+	assert.Equal(t, "tpbft", engine.Name())
+	assert.ErrorContains(t, engine.Health(), "not running")
+
+	require.NoError(t, engine.Start(context.Background()))
+	assert.Error(t, engine.Start(context.Background()))
+	assert.True(t, engine.Status().Running)
+	assert.ErrorContains(t, engine.Health(), "staking keeper")
+
+	require.NoError(t, engine.Stop())
+	assert.False(t, engine.Status().Running)
+	require.NoError(t, engine.Stop())
+
+	// Cancelling the start context also ends the loop
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, engine.Start(ctx))
+	cancel()
+	assert.Eventually(t, func() bool { return !engine.Status().Running }, time.Second, time.Millisecond)
+}
+
+// phaseHistogram records observations by phase label
+type phaseHistogram struct {
+	phase string
+	obs   map[string][]float64
+}
+
+func (h *phaseHistogram) With(labelValues ...string) metrics.Histogram {
+	return &phaseHistogram{phase: labelValues[len(labelValues)-1], obs: h.obs}
+}
+
+func (h *phaseHistogram) Observe(value float64) {
+	h.obs[h.phase] = append(h.obs[h.phase], value)
+}
+
+func TestTPBFT_Metrics(t *testing.T) {
+	m := common.NopMetrics().TPBFT
+	churn := generic.NewCounter("churn")
+	selected := generic.NewGauge("selected")
+	phases := &phaseHistogram{obs: make(map[string][]float64)}
+	m.SelectionChurn, m.SelectedValidators, m.PhaseLatency = churn, selected, phases
+
+	engine := NewTPBFT()
+	engine.SetMetrics(m)
+
+	// The first selection is not churn; swapping one validator is two moves
+	engine.observeSelection([]string{"val0", "val1", "val2"})
+	assert.Equal(t, 0.0, churn.Value())
+	engine.observeSelection([]string{"val0", "val1", "val3"})
+	assert.Equal(t, 2.0, churn.Value())
+	assert.Equal(t, 3.0, selected.Value())
+
+	// Phase latencies run from the pre-prepare
+	node := NewPBFTNode("node0", []string{"node1", "node2", "node3"})
+	node.SetMetrics(m)
+	now := time.Unix(1700000000, 0)
+	node.now = func() time.Time { return now }
+
+	send := func(typ MessageType, from string) {
+		require.NoError(t, node.HandleMessage(&ConsensusMessage{Type: typ, SequenceNumber: 1, Digest: Digest(nil), NodeID: from}))
+	}
+	send(MessageTypePrePrepare, "node0")
+	now = now.Add(10 * time.Millisecond)
+	for _, id := range []string{"node0", "node1", "node2"} {
+		send(MessageTypePrepare, id)
+	}
+	now = now.Add(20 * time.Millisecond)
+	for _, id := range []string{"node0", "node1", "node2"} {
+		send(MessageTypeCommit, id)
+	}
+
+	assert.InDeltaSlice(t, []float64{0.01}, phases.obs["prepared"], 1e-9)
+	assert.InDeltaSlice(t, []float64{0.03}, phases.obs["committed"], 1e-9)
+}
+
+// validatorList is a staking keeper that only lists validators
+type validatorList struct {
+	StakingKeeper
+	validators []stakingtypes.Validator
+}
+
+func (l *validatorList) GetAllValidators(context.Context) ([]stakingtypes.Validator, error) {
+	return l.validators, nil
+}
+
+// scoreGauge records gauge series by their last label value
+type scoreGauge struct {
+	label  string
+	series map[string]float64
+}
+
+func (g *scoreGauge) With(labelValues ...string) metrics.Gauge {
+	return &scoreGauge{label: labelValues[len(labelValues)-1], series: g.series}
+}
+
+func (g *scoreGauge) Set(value float64) { g.series[g.label] = value }
+
+func (g *scoreGauge) Add(delta float64) { g.series[g.label] += delta }
+
+func (g *scoreGauge) Delete(labelValues ...string) {
+	delete(g.series, labelValues[len(labelValues)-1])
+}
+
+func TestTPBFT_TrustScoreSeries(t *testing.T) {
+	m := common.NopMetrics().TPBFT
+	gauge := &scoreGauge{series: make(map[string]float64)}
+	m.TrustScore = gauge
+
+	staking := &validatorList{validators: []stakingtypes.Validator{
+		{OperatorAddress: "val0", Status: stakingtypes.Bonded},
+		{OperatorAddress: "val1", Status: stakingtypes.Bonded},
+	}}
+	engine := NewTPBFT()
+	engine.SetMetrics(m)
+	engine.SetStakingKeeper(staking)
+	engine.TrustScorer.UpdateScore("val0", true, 0, 1, 2)
+	engine.TrustScorer.UpdateScore("val1", true, 0, 1, 2)
+
+	require.NoError(t, engine.reportTrustScores(sdk.Context{}))
+	assert.Len(t, gauge.series, 2)
+
+	// A validator leaving the bonded set loses its series, though the
+	// scorer keeps its score
+	staking.validators[1].Status = stakingtypes.Unbonding
+	require.NoError(t, engine.reportTrustScores(sdk.Context{}))
+	assert.Contains(t, gauge.series, "val0")
+	assert.NotContains(t, gauge.series, "val1")
+	assert.NotNil(t, engine.TrustScorer.GetScore("val1"))
+}
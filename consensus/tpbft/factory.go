@@ -0,0 +1,29 @@
+package tpbft
+
+import "github.com/fffeng99999/hcp-consensus/consensus/common"
+
+func init() {
+	common.RegisterEngine(EngineName, newEngine)
+}
+
+// newEngine builds a tPBFT engine wired to the app's staking keeper and
+// store, which holds its parameters
+func newEngine(deps common.Dependencies) (common.ConsensusEngine, error) {
+	engine := NewTPBFT()
+	if deps.StakingKeeper != nil {
+		engine.SetStakingKeeper(deps.StakingKeeper)
+	}
+	if deps.StoreKey != nil {
+		engine.SetStoreKey(deps.StoreKey)
+	}
+	if deps.Metrics != nil {
+		engine.SetMetrics(deps.Metrics.TPBFT)
+	}
+	if deps.Logger != nil {
+		engine.SetLogger(deps.Logger)
+	}
+	if deps.TracerProvider != nil {
+		engine.SetTracer(deps.TracerProvider.Tracer(common.TracerName))
+	}
+	return engine, nil
+}
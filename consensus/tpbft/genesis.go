@@ -0,0 +1,164 @@
+package tpbft
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GenesisState defines the tPBFT parameters and trust state carried in the
+// app genesis. Trust state handed between engines leaves the parameters out.
+type GenesisState struct {
+	Params *Params            `json:"params,omitempty"`
+	Scores []TrustScoreRecord `json:"scores"`
+}
+
+// TrustScoreRecord is the genesis representation of a validator's trust state.
+// A record without histories seeds the score only; the next update recomputes it
+// from the (empty) history window.
+type TrustScoreRecord struct {
+	ValidatorAddress string    `json:"validator_address"`
+	SuccessRate      float64   `json:"success_rate"`
+	StakeWeight      float64   `json:"stake_weight"`
+	ResponseSpeed    float64   `json:"response_speed"`
+	TotalScore       float64   `json:"total_score"`
+	LastUpdated      time.Time `json:"last_updated"`
+
+	SuccessHistory  []bool          `json:"success_history,omitempty"`
+	ResponseHistory []time.Duration `json:"response_history,omitempty"` // Nanoseconds
+}
+
+// DefaultGenesis returns the default tPBFT genesis state (default parameters,
+// no seeded scores)
+func DefaultGenesis() *GenesisState {
+	params := DefaultParams()
+	return &GenesisState{Params: &params, Scores: []TrustScoreRecord{}}
+}
+
+// Validate performs basic validation of the tPBFT genesis state
+func (gs GenesisState) Validate() error {
+	if gs.Params != nil {
+		if err := gs.Params.Validate(); err != nil {
+			return fmt.Errorf("invalid params: %w", err)
+		}
+	}
+
+	seen := make(map[string]bool, len(gs.Scores))
+	for i, rec := range gs.Scores {
+		if rec.ValidatorAddress == "" {
+			return fmt.Errorf("trust score %d: empty validator address", i)
+		}
+		if seen[rec.ValidatorAddress] {
+			return fmt.Errorf("duplicate trust score for validator %s", rec.ValidatorAddress)
+		}
+		seen[rec.ValidatorAddress] = true
+
+		for name, v := range map[string]float64{
+			"success_rate":   rec.SuccessRate,
+			"stake_weight":   rec.StakeWeight,
+			"response_speed": rec.ResponseSpeed,
+			"total_score":    rec.TotalScore,
+		} {
+			if v < 0 || v > 1 {
+				return fmt.Errorf("validator %s: %s %f out of range [0, 1]", rec.ValidatorAddress, name, v)
+			}
+		}
+
+		for _, d := range rec.ResponseHistory {
+			if d < 0 {
+				return fmt.Errorf("validator %s: negative response time %s", rec.ValidatorAddress, d)
+			}
+		}
+	}
+	return nil
+}
+
+// ResetHistory drops the success and response time histories of every
+// record, keeping the scores as seeds
+func (gs *GenesisState) ResetHistory() {
+	for i := range gs.Scores {
+		gs.Scores[i].SuccessHistory = nil
+		gs.Scores[i].ResponseHistory = nil
+	}
+}
+
+// UnmarshalGenesis decodes a raw genesis message, treating an empty message as
+// the default and missing parameters as the default parameters
+func UnmarshalGenesis(bz json.RawMessage) (*GenesisState, error) {
+	gs := DefaultGenesis()
+	if len(bz) == 0 || string(bz) == "null" {
+		return gs, nil
+	}
+	if err := json.Unmarshal(bz, gs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s genesis state: %w", ModuleName, err)
+	}
+	if gs.Params == nil {
+		gs.Params = DefaultGenesis().Params
+	}
+	return gs, nil
+}
+
+// ExportGenesis returns a snapshot of all trust scores and their histories,
+// sorted by validator address so the output is deterministic. The parameters
+// are left out.
+func (ts *TrustScorer) ExportGenesis() *GenesisState {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	gs := &GenesisState{Scores: []TrustScoreRecord{}}
+	for addr, score := range ts.scores {
+		gs.Scores = append(gs.Scores, TrustScoreRecord{
+			ValidatorAddress: addr,
+			SuccessRate:      score.SuccessRate,
+			StakeWeight:      score.StakeWeight,
+			ResponseSpeed:    score.ResponseSpeed,
+			TotalScore:       score.TotalScore,
+			LastUpdated:      score.LastUpdated,
+			SuccessHistory:   append([]bool(nil), ts.successHistory[addr]...),
+			ResponseHistory:  append([]time.Duration(nil), ts.responseHistory[addr]...),
+		})
+	}
+
+	sort.Slice(gs.Scores, func(i, j int) bool {
+		return gs.Scores[i].ValidatorAddress < gs.Scores[j].ValidatorAddress
+	})
+	return gs
+}
+
+// ImportGenesis replaces all trust state with the given genesis state.
+// Histories longer than the window keep only their most recent entries.
+func (ts *TrustScorer) ImportGenesis(gs *GenesisState) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.scores = make(map[string]*TrustScore)
+	ts.successHistory = make(map[string][]bool)
+	ts.responseHistory = make(map[string][]time.Duration)
+
+	for _, rec := range gs.Scores {
+		ts.scores[rec.ValidatorAddress] = &TrustScore{
+			ValidatorAddress: rec.ValidatorAddress,
+			SuccessRate:      rec.SuccessRate,
+			StakeWeight:      rec.StakeWeight,
+			ResponseSpeed:    rec.ResponseSpeed,
+			TotalScore:       rec.TotalScore,
+			LastUpdated:      rec.LastUpdated,
+		}
+
+		if n := len(rec.SuccessHistory); n > 0 {
+			history := rec.SuccessHistory
+			if n > ts.historyWindow {
+				history = history[n-ts.historyWindow:]
+			}
+			ts.successHistory[rec.ValidatorAddress] = append([]bool(nil), history...)
+		}
+		if n := len(rec.ResponseHistory); n > 0 {
+			history := rec.ResponseHistory
+			if n > ts.historyWindow {
+				history = history[n-ts.historyWindow:]
+			}
+			ts.responseHistory[rec.ValidatorAddress] = append([]time.Duration(nil), history...)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package tpbft
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cosmossdk.io/store/prefix"
+	storetypes "cosmossdk.io/store/types"
+	"github.com/cosmos/cosmos-sdk/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+func TestTrustScorer_GenesisRoundTrip(t *testing.T) {
+	ts := NewTrustScorer()
+	ts.SetClock(func() time.Time { return time.Unix(1700000000, 0).UTC() })
+
+	ts.UpdateScore("val1", true, 100*time.Millisecond, 1000, 10000)
+	ts.UpdateScore("val1", false, 300*time.Millisecond, 1000, 10000)
+	ts.UpdateScore("val0", true, 50*time.Millisecond, 5000, 10000)
+
+	gs := ts.ExportGenesis()
+	require.NoError(t, gs.Validate())
+	require.Len(t, gs.Scores, 2)
+	assert.Equal(t, "val0", gs.Scores[0].ValidatorAddress, "records should be sorted")
+	assert.Equal(t, []bool{true, false}, gs.Scores[1].SuccessHistory)
+
+	// Survive a JSON round trip as the app genesis would
+	bz, err := json.Marshal(gs)
+	require.NoError(t, err)
+	decoded, err := UnmarshalGenesis(bz)
+	require.NoError(t, err)
+
+	imported := NewTrustScorer()
+	imported.ImportGenesis(decoded)
+	assert.Equal(t, gs, imported.ExportGenesis())
+	assert.Equal(t, ts.GetScore("val1").TotalScore, imported.GetScore("val1").TotalScore)
+
+	// The next update must continue from the imported history
+	ts.UpdateScore("val1", true, 100*time.Millisecond, 1000, 10000)
+	imported.UpdateScore("val1", true, 100*time.Millisecond, 1000, 10000)
+	assert.Equal(t, ts.GetScore("val1").SuccessRate, imported.GetScore("val1").SuccessRate)
+}
+
+func TestTrustScorer_ImportGenesisTrimsHistory(t *testing.T) {
+	ts := NewTrustScorer()
+	ts.historyWindow = 3
+
+	ts.ImportGenesis(&GenesisState{Scores: []TrustScoreRecord{{
+		ValidatorAddress: "val0",
+		TotalScore:       0.5,
+		SuccessHistory:   []bool{true, true, false, false, false},
+	}}})
+
+	assert.Equal(t, []bool{false, false, false}, ts.successHistory["val0"])
+	assert.Equal(t, 0.5, ts.GetScore("val0").TotalScore)
+}
+
+func TestGenesisState_Validate(t *testing.T) {
+	assert.NoError(t, DefaultGenesis().Validate())
+
+	empty, err := UnmarshalGenesis(nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultGenesis(), empty)
+
+	cases := map[string]GenesisState{
+		"empty address": {Scores: []TrustScoreRecord{{TotalScore: 0.5}}},
+		"duplicate": {Scores: []TrustScoreRecord{
+			{ValidatorAddress: "val0"}, {ValidatorAddress: "val0"},
+		}},
+		"score out of range": {Scores: []TrustScoreRecord{{ValidatorAddress: "val0", TotalScore: 1.5}}},
+		"negative response": {Scores: []TrustScoreRecord{{
+			ValidatorAddress: "val0", ResponseHistory: []time.Duration{-1},
+		}}},
+	}
+	for name, gs := range cases {
+		assert.Error(t, gs.Validate(), name)
+	}
+}
+
+func TestTPBFT_TrustStatePersistence(t *testing.T) {
+	key := storetypes.NewKVStoreKey(StoreKey)
+	ctx := testutil.DefaultContext(key, storetypes.NewTransientStoreKey("transient_test"))
+
+	engine := NewTPBFT()
+	engine.SetStoreKey(key)
+
+	seed := &GenesisState{Scores: []TrustScoreRecord{
+		{ValidatorAddress: "val0", TotalScore: 0.9, SuccessHistory: []bool{true}},
+		{ValidatorAddress: "val1", TotalScore: 0.2, SuccessHistory: []bool{false}},
+	}}
+	require.NoError(t, engine.InitGenesis(ctx, seed))
+
+	// A restarted engine sees the same trust conditions
+	restarted := NewTPBFT()
+	restarted.SetStoreKey(key)
+	require.NoError(t, restarted.LoadTrustState(ctx))
+
+	assert.Equal(t, engine.ExportGenesis(ctx), restarted.ExportGenesis(ctx))
+	assert.Equal(t, 0.2, restarted.TrustScorer.GetScore("val1").TotalScore)
+}
+
+func TestTPBFT_SaveTrustState(t *testing.T) {
+	key := storetypes.NewKVStoreKey(StoreKey)
+	ctx := testutil.DefaultContext(key, storetypes.NewTransientStoreKey("transient_test"))
+	engine := NewTPBFT()
+	engine.SetStoreKey(key)
+	engine.TrustScorer.SetClock(func() time.Time { return time.Unix(1700000000, 0).UTC() })
+	engine.TrustScorer.UpdateScore("val0", true, 100*time.Millisecond, 1, 4)
+	engine.TrustScorer.UpdateScore("val1", true, 100*time.Millisecond, 3, 4)
+	require.NoError(t, engine.saveTrustState(ctx))
+
+	// Scores are stored as fixed-point decimals, and the scorer keeps the
+	// stored values
+	store := prefix.NewStore(ctx.KVStore(key), TrustScoreKeyPrefix)
+	var stored map[string]any
+	require.NoError(t, json.Unmarshal(store.Get([]byte("val0")), &stored))
+	assert.Equal(t, "0.250000000000000000", stored["stake_weight"])
+	restarted := NewTPBFT()
+	restarted.SetStoreKey(key)
+	require.NoError(t, restarted.LoadTrustState(ctx))
+	assert.Equal(t, engine.ExportGenesis(ctx), restarted.ExportGenesis(ctx))
+
+	// Validators the scorer dropped are deleted
+	require.NoError(t, engine.ImportHandoff(ctx, common.HandoffState{Trust: json.RawMessage(`{"scores":[{"validator_address":"val1","total_score":0.5}]}`)}))
+	assert.Nil(t, store.Get([]byte("val0")))
+	assert.NotNil(t, store.Get([]byte("val1")))
+}
+
+func TestToFixed(t *testing.T) {
+	for v, want := range map[float64]string{
+		0:       "0.000000000000000000",
+		1:       "1.000000000000000000",
+		1.0 / 3: "0.333333333333333315", // The float's exact value, rounded
+		10:      "10.000000000000000000",
+	} {
+		assert.Equal(t, want, toFixed(v).String(), "%v", v)
+	}
+}
+
+func TestTPBFT_Handoff(t *testing.T) {
+	key := storetypes.NewKVStoreKey(StoreKey)
+	ctx := testutil.DefaultContext(key, storetypes.NewTransientStoreKey("transient_test")).WithBlockHeight(42)
+
+	engine := NewTPBFT()
+	engine.SetStoreKey(key)
+	require.NoError(t, engine.InitGenesis(ctx, &GenesisState{Scores: []TrustScoreRecord{
+		{ValidatorAddress: "val0", TotalScore: 0.9, SuccessHistory: []bool{true}},
+	}}))
+
+	state, err := engine.ExportHandoff(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, EngineName, state.From)
+	assert.Equal(t, int64(42), state.Height)
+
+	next := NewTPBFT()
+	require.NoError(t, next.ImportHandoff(ctx, state))
+	assert.Equal(t, 0.9, next.TrustScorer.GetScore("val0").TotalScore)
+
+	// Without trust records the persisted state is used
+	fallback := NewTPBFT()
+	fallback.SetStoreKey(key)
+	state.Trust = nil
+	require.NoError(t, fallback.ImportHandoff(ctx, state))
+	assert.Equal(t, 0.9, fallback.TrustScorer.GetScore("val0").TotalScore)
+
+	state.Trust = json.RawMessage(`{"scores":[{"validator_address":""}]}`)
+	assert.Error(t, next.ImportHandoff(ctx, state))
+}
@@ -1,5 +1,11 @@
 package tpbft
 
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
 // MessageType represents the type of PBFT message
 type MessageType int
 
@@ -11,6 +17,22 @@ const (
 	MessageTypeReply
 )
 
+func (t MessageType) String() string {
+	switch t {
+	case MessageTypePrePrepare:
+		return "PrePrepare"
+	case MessageTypePrepare:
+		return "Prepare"
+	case MessageTypeCommit:
+		return "Commit"
+	case MessageTypeRequest:
+		return "Request"
+	case MessageTypeReply:
+		return "Reply"
+	}
+	return "Unknown"
+}
+
 // ConsensusMessage represents a generic PBFT message
 type ConsensusMessage struct {
 	Type           MessageType
@@ -20,6 +42,37 @@ type ConsensusMessage struct {
 	NodeID         string // Sender ID
 	Signature      []byte // Signature of the sender
 	Data           []byte // Payload (e.g. block data for PrePrepare)
+
+	// Primary's signature on the pre-prepare a Prepare votes for, so that
+	// replicas can prove the primary equivocated
+	PrePrepareSignature []byte
+
+	// W3C trace context of the sender's span for this sequence, if traced
+	TraceContext map[string]string
+}
+
+// signDomain separates tPBFT message signatures from other uses of the key
+const signDomain = "hcp-consensus/tpbft/message"
+
+// Digest returns the hex SHA-256 digest of data, which pre-prepares carry
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SignBytes returns the bytes a message's signature covers: its type, view,
+// sequence, digest and sender. The digest binds a pre-prepare's data, as
+// replicas reject data it is not the digest of.
+func (m *ConsensusMessage) SignBytes() []byte {
+	b := []byte(signDomain)
+	b = binary.BigEndian.AppendUint64(b, uint64(m.Type))
+	b = binary.BigEndian.AppendUint64(b, m.View)
+	b = binary.BigEndian.AppendUint64(b, m.SequenceNumber)
+	for _, s := range []string{m.Digest, m.NodeID} {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+		b = append(b, s...)
+	}
+	return b
 }
 
 // RequestMessage represents a client request
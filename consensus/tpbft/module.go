@@ -0,0 +1,55 @@
+package tpbft
+
+import (
+	"encoding/json"
+	"fmt"
+
+	gwruntime "github.com/grpc-ecosystem/grpc-gateway/runtime"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/types/module"
+)
+
+var (
+	_ module.AppModuleBasic   = AppModuleBasic{}
+	_ module.HasGenesisBasics = AppModuleBasic{}
+)
+
+// AppModuleBasic exposes the tPBFT trust genesis to the app's BasicManager so
+// that `init` writes a default section and `validate-genesis` checks it
+type AppModuleBasic struct{}
+
+// Name returns the tPBFT module name
+func (AppModuleBasic) Name() string { return ModuleName }
+
+// RegisterLegacyAminoCodec is a no-op; tPBFT has no amino types
+func (AppModuleBasic) RegisterLegacyAminoCodec(*codec.LegacyAmino) {}
+
+// RegisterInterfaces is a no-op; tPBFT has no interface types
+func (AppModuleBasic) RegisterInterfaces(codectypes.InterfaceRegistry) {}
+
+// RegisterGRPCGatewayRoutes is a no-op; tPBFT has no query service
+func (AppModuleBasic) RegisterGRPCGatewayRoutes(client.Context, *gwruntime.ServeMux) {}
+
+// DefaultGenesis returns the default tPBFT genesis state as raw JSON
+func (AppModuleBasic) DefaultGenesis(codec.JSONCodec) json.RawMessage {
+	bz, err := json.Marshal(DefaultGenesis())
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+// ValidateGenesis validates the tPBFT genesis section
+func (AppModuleBasic) ValidateGenesis(_ codec.JSONCodec, _ client.TxEncodingConfig, bz json.RawMessage) error {
+	gs, err := UnmarshalGenesis(bz)
+	if err != nil {
+		return err
+	}
+	if err := gs.Validate(); err != nil {
+		return fmt.Errorf("invalid %s genesis state: %w", ModuleName, err)
+	}
+	return nil
+}
@@ -1,8 +1,53 @@
 package tpbft
 
 import (
+	"crypto/ed25519"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
+	"time"
+
+	"cosmossdk.io/log"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+// ErrProtocolViolation marks a message no correct replica sends
+var ErrProtocolViolation = errors.New("protocol violation")
+
+// ErrStaleView marks a message for a view the node has left, reported to the
+// observer as a StaleViewError: it no longer counts towards any quorum
+var ErrStaleView = errors.New("stale view")
+
+// StaleViewError is the fault of a message for a view the node has left
+type StaleViewError struct {
+	Type    MessageType
+	View    uint64 // Of the message
+	Current uint64 // The node's view
+}
+
+func (e *StaleViewError) Error() string {
+	return fmt.Sprintf("%s: %s for view %d, current view is %d", ErrStaleView, e.Type, e.View, e.Current)
+}
+
+// Unwrap makes the error match ErrStaleView
+func (e *StaleViewError) Unwrap() error { return ErrStaleView }
+
+// Lag returns how many views behind the node's the message is
+func (e *StaleViewError) Lag() uint64 { return e.Current - e.View }
+
+const (
+	// futureViews is how many views ahead of the current one messages are
+	// buffered for
+	futureViews = 2
+	// maxBuffered is how many future-view messages are buffered per replica
+	maxBuffered = 256
+	// logWindow is how many sequences below the last committed one keep
+	// their bookkeeping
+	logWindow = 256
 )
 
 // PBFTNode represents a node in the tPBFT consensus network
@@ -19,6 +64,34 @@ type PBFTNode struct {
 	Prepared  map[uint64]bool // Sequence -> bool
 	Committed map[uint64]bool // Sequence -> bool
 
+	// Phase latencies, measured from each sequence's pre-prepare
+	metrics     *common.TPBFTMetrics
+	now         func() time.Time
+	prePrepared map[uint64]time.Time
+
+	// Spans per sequence, with phases pre-prepare, prepare and commit
+	tracer trace.Tracer
+	traces map[uint64]*seqTrace
+
+	logger log.Logger
+
+	// Replica keys; messages are signed and verified once set
+	key  ed25519.PrivateKey
+	keys map[string]ed25519.PublicKey
+
+	// Behaviour of other replicas, reported to the observer. Votes are judged
+	// once voteWindow has passed since a sequence committed.
+	observer    Observer
+	voteWindow  time.Duration
+	views       map[uint64]uint64               // Sequence -> view of its pre-prepare
+	committedAt map[uint64]time.Time            // Sequence -> when it committed
+	voteAt      map[uint64]map[string]time.Time // Sequence -> replica -> when its commit arrived
+	equivocated map[uint64]bool                 // Sequences the primary equivocated on
+
+	// Messages for views not yet entered, handled once the node enters them
+	future   map[uint64][]*ConsensusMessage // View -> messages
+	buffered map[string]int                 // Replica -> messages buffered
+
 	// State
 	mu sync.RWMutex
 }
@@ -33,21 +106,134 @@ func NewPBFTNode(id string, peers []string) *PBFTNode {
 		MsgLog:    make(map[uint64]map[uint64]map[MessageType]map[string]*ConsensusMessage),
 		Prepared:  make(map[uint64]bool),
 		Committed: make(map[uint64]bool),
+
+		metrics:     common.NopMetrics().TPBFT,
+		now:         time.Now,
+		prePrepared: make(map[uint64]time.Time),
+		tracer:      common.NopTracer(),
+		traces:      make(map[uint64]*seqTrace),
+		logger:      log.NewNopLogger(),
+		voteWindow:  time.Second,
+		views:       make(map[uint64]uint64),
+		committedAt: make(map[uint64]time.Time),
+		voteAt:      make(map[uint64]map[string]time.Time),
+		equivocated: make(map[uint64]bool),
+		future:      make(map[uint64][]*ConsensusMessage),
+		buffered:    make(map[string]int),
 	}
 }
 
-// HandleMessage processes an incoming consensus message
+// SetLogger sets the logger phase transitions are logged to, scoped to the
+// engine and node
+func (n *PBFTNode) SetLogger(l log.Logger) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.logger = l.With("engine", EngineName, "node_id", n.ID)
+}
+
+// SetClock sets the clock phase latencies and span timestamps are measured on
+func (n *PBFTNode) SetClock(now func() time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.now = now
+}
+
+// SetMetrics sets the metrics phase latencies are reported to
+func (n *PBFTNode) SetMetrics(m *common.TPBFTMetrics) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.metrics = m
+}
+
+// observePhase reports the time since a sequence's pre-prepare, if seen
+func (n *PBFTNode) observePhase(seq uint64, phase string) {
+	if start, ok := n.prePrepared[seq]; ok {
+		n.metrics.PhaseLatency.With("phase", phase).Observe(n.now().Sub(start).Seconds())
+	}
+}
+
+// HandleMessage processes an incoming consensus message. The transport is
+// expected to authenticate peers, so NodeID is the sender; with keys set,
+// the sender's signature is checked as well. Messages no correct replica
+// sends are rejected with an error wrapping ErrProtocolViolation and
+// reported to the observer. Messages for an earlier view are reported and
+// ignored; those for the next views are buffered until SetView enters them.
 func (n *PBFTNode) HandleMessage(msg *ConsensusMessage) error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	// Basic validation
-	if msg.View < n.View {
-		return nil // Ignore old view messages
+	if err := n.authenticate(msg); err != nil {
+		n.fault(msg.NodeID, msg.SequenceNumber, err)
+		return err
+	}
+	switch {
+	case msg.View < n.View:
+		n.fault(msg.NodeID, msg.SequenceNumber, &StaleViewError{Type: msg.Type, View: msg.View, Current: n.View})
+		return nil
+	case msg.View > n.View:
+		n.buffer(msg)
+		return nil
+	}
+	return n.handle(msg)
+}
+
+// SetView enters a view, dropping the messages buffered for earlier views and
+// handling those buffered for it. Views are only entered in order.
+func (n *PBFTNode) SetView(view uint64) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if view <= n.View {
+		return nil
+	}
+	n.View = view
+	var replay []*ConsensusMessage
+	for v, msgs := range n.future {
+		if v > view {
+			continue
+		}
+		delete(n.future, v)
+		for _, msg := range msgs {
+			n.buffered[msg.NodeID]--
+		}
+		if v == view {
+			replay = msgs
+		}
+	}
+
+	var errs []error
+	for _, msg := range replay {
+		if err := n.handle(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// buffer keeps a message for a view not yet entered, unless it is too far
+// ahead or its sender has too many buffered
+func (n *PBFTNode) buffer(msg *ConsensusMessage) {
+	if msg.View-n.View > futureViews || n.buffered[msg.NodeID] >= maxBuffered {
+		n.logger.Debug("dropped future view message", "type", msg.Type, "view", msg.View, "seq", msg.SequenceNumber, "from", msg.NodeID)
+		return
+	}
+	n.future[msg.View] = append(n.future[msg.View], msg)
+	n.buffered[msg.NodeID]++
+}
+
+// handle processes an authenticated message for the current view
+func (n *PBFTNode) handle(msg *ConsensusMessage) error {
+	if err := n.validate(msg); err != nil {
+		n.fault(msg.NodeID, msg.SequenceNumber, err)
+		return err
 	}
 
 	// Store message
 	n.storeMessage(msg)
+	n.traceMessage(msg)
 
 	switch msg.Type {
 	case MessageTypePrePrepare:
@@ -60,6 +246,35 @@ func (n *PBFTNode) HandleMessage(msg *ConsensusMessage) error {
 	return nil
 }
 
+// authenticate rejects messages from outside the replica set or badly signed
+func (n *PBFTNode) authenticate(msg *ConsensusMessage) error {
+	if !slices.Contains(n.replicas(), msg.NodeID) {
+		return fmt.Errorf("%w: message from unknown replica %s", ErrProtocolViolation, msg.NodeID)
+	}
+	if n.keys != nil {
+		if err := n.verify(msg.NodeID, msg.SignBytes(), msg.Signature); err != nil {
+			return fmt.Errorf("%w: %s %v", ErrProtocolViolation, msg.Type, err)
+		}
+	}
+	return nil
+}
+
+// validate rejects messages in the current view no correct replica sends:
+// pre-prepares from a backup or whose digest is not that of their data, and
+// messages conflicting with one the sender sent before
+func (n *PBFTNode) validate(msg *ConsensusMessage) error {
+	if msg.Type == MessageTypePrePrepare && msg.NodeID != n.Primary(msg.View) {
+		return fmt.Errorf("%w: pre-prepare from backup %s", ErrProtocolViolation, msg.NodeID)
+	}
+	if msg.Type == MessageTypePrePrepare && msg.Digest != Digest(msg.Data) {
+		return fmt.Errorf("%w: pre-prepare digest %s does not match its data", ErrProtocolViolation, msg.Digest)
+	}
+	if prev, ok := n.MsgLog[msg.SequenceNumber][msg.View][msg.Type][msg.NodeID]; ok && prev.Digest != msg.Digest {
+		return fmt.Errorf("%w: %s sent conflicting %s messages for sequence %d", ErrProtocolViolation, msg.NodeID, msg.Type, msg.SequenceNumber)
+	}
+	return nil
+}
+
 func (n *PBFTNode) storeMessage(msg *ConsensusMessage) {
 	if _, ok := n.MsgLog[msg.SequenceNumber]; !ok {
 		n.MsgLog[msg.SequenceNumber] = make(map[uint64]map[MessageType]map[string]*ConsensusMessage)
@@ -70,7 +285,12 @@ func (n *PBFTNode) storeMessage(msg *ConsensusMessage) {
 	if _, ok := n.MsgLog[msg.SequenceNumber][msg.View][msg.Type]; !ok {
 		n.MsgLog[msg.SequenceNumber][msg.View][msg.Type] = make(map[string]*ConsensusMessage)
 	}
-	n.MsgLog[msg.SequenceNumber][msg.View][msg.Type][msg.NodeID] = msg
+	if _, ok := n.MsgLog[msg.SequenceNumber][msg.View][msg.Type][msg.NodeID]; !ok {
+		n.MsgLog[msg.SequenceNumber][msg.View][msg.Type][msg.NodeID] = msg
+		if msg.Type == MessageTypeCommit {
+			n.recordVote(msg)
+		}
+	}
 }
 
 func (n *PBFTNode) handlePrePrepare(msg *ConsensusMessage) error {
@@ -78,45 +298,124 @@ func (n *PBFTNode) handlePrePrepare(msg *ConsensusMessage) error {
 	// For now, we assume it's valid and broadcast a PREPARE message.
 	// Note: The actual broadcast would happen via a callback or channel.
 	// Here we just update state.
+	if _, ok := n.prePrepared[msg.SequenceNumber]; !ok && !n.Committed[msg.SequenceNumber] {
+		n.prePrepared[msg.SequenceNumber] = n.now()
+		n.views[msg.SequenceNumber] = msg.View
+		n.enterPhase(msg.SequenceNumber, "tpbft.prepare")
+	}
+
+	n.logger.Debug("received pre-prepare", "view", msg.View, "seq", msg.SequenceNumber, "from", msg.NodeID)
 
-	fmt.Printf("Node %s received PrePrepare for Seq %d View %d\n", n.ID, msg.SequenceNumber, msg.View)
+	// Votes may have arrived first
+	n.checkEquivocation(msg.SequenceNumber, msg.View)
+	n.advance(msg.SequenceNumber, msg.View)
 	return nil
 }
 
 func (n *PBFTNode) handlePrepare(msg *ConsensusMessage) error {
-	votes := n.countVotes(msg.SequenceNumber, msg.View, MessageTypePrepare)
-	quorum := n.getQuorum()
-
-	if votes >= quorum {
-		if !n.Prepared[msg.SequenceNumber] {
-			n.Prepared[msg.SequenceNumber] = true
-			fmt.Printf("Node %s PREPARED for Seq %d (Votes: %d)\n", n.ID, msg.SequenceNumber, votes)
-			// Should broadcast COMMIT here
-		}
-	}
+	n.checkEquivocation(msg.SequenceNumber, msg.View)
+	n.advance(msg.SequenceNumber, msg.View)
 	return nil
 }
 
 func (n *PBFTNode) handleCommit(msg *ConsensusMessage) error {
-	votes := n.countVotes(msg.SequenceNumber, msg.View, MessageTypeCommit)
+	n.advance(msg.SequenceNumber, msg.View)
+	return nil
+}
+
+// advance moves a sequence to prepared once a quorum prepared the digest of
+// its pre-prepare, and to committed once prepared and a quorum committed it
+func (n *PBFTNode) advance(seq, view uint64) {
+	pp := n.prePrepare(seq, view)
+	if pp == nil {
+		return
+	}
 	quorum := n.getQuorum()
 
-	if votes >= quorum {
-		if !n.Committed[msg.SequenceNumber] {
-			n.Committed[msg.SequenceNumber] = true
-			fmt.Printf("Node %s COMMITTED for Seq %d (Votes: %d)\n", n.ID, msg.SequenceNumber, votes)
-			// Should Execute block here
+	if !n.Prepared[seq] {
+		votes := n.countVotes(seq, view, MessageTypePrepare, pp.Digest)
+		if votes < quorum {
+			return
 		}
+		n.Prepared[seq] = true
+		n.observePhase(seq, "prepared")
+		n.enterPhase(seq, "tpbft.commit")
+		n.logger.Debug("prepared", "view", view, "seq", seq, "votes", votes)
+		// Should broadcast COMMIT here
+	}
+
+	if !n.Committed[seq] {
+		votes := n.countVotes(seq, view, MessageTypeCommit, pp.Digest)
+		if votes < quorum {
+			return
+		}
+		n.Committed[seq] = true
+		n.observePhase(seq, "committed")
+		if _, ok := n.prePrepared[seq]; ok && n.observer != nil {
+			n.committedAt[seq] = n.now() // Until swept
+		} else {
+			n.forget(seq)
+		}
+		n.endTrace(seq)
+		n.logger.Debug("committed", "view", view, "seq", seq, "votes", votes)
+		n.checkpoint(seq)
+		// Should Execute block here
 	}
-	return nil
 }
 
-// Helper to count votes
-func (n *PBFTNode) countVotes(seq, view uint64, msgType MessageType) int {
-	if msgs, ok := n.MsgLog[seq][view][msgType]; ok {
-		return len(msgs)
+// checkpoint forgets the bookkeeping of sequences more than logWindow below
+// a committed one, whether or not Sweep runs: votes that late are not
+// reported, and a pre-prepare that old that has not committed never will
+func (n *PBFTNode) checkpoint(seq uint64) {
+	if seq <= logWindow {
+		return
+	}
+	low := seq - logWindow
+	for old := range n.prePrepared {
+		if old <= low {
+			n.forget(old)
+		}
+	}
+	for old := range n.voteAt {
+		if old <= low {
+			n.forget(old)
+		}
 	}
-	return 0
+	for old := range n.traces {
+		if old <= low {
+			n.endTrace(old)
+		}
+	}
+}
+
+// prePrepare returns the primary's pre-prepare for a sequence, if received
+func (n *PBFTNode) prePrepare(seq, view uint64) *ConsensusMessage {
+	return n.MsgLog[seq][view][MessageTypePrePrepare][n.Primary(view)]
+}
+
+// Helper to count votes for a digest
+func (n *PBFTNode) countVotes(seq, view uint64, msgType MessageType, digest string) int {
+	votes := 0
+	for _, msg := range n.MsgLog[seq][view][msgType] {
+		if msg.Digest == digest {
+			votes++
+		}
+	}
+	return votes
+}
+
+// Primary returns the primary of a view, rotating through the replicas in
+// ID order
+func (n *PBFTNode) Primary(view uint64) string {
+	replicas := n.replicas()
+	return replicas[view%uint64(len(replicas))]
+}
+
+// replicas returns every replica's ID, sorted
+func (n *PBFTNode) replicas() []string {
+	replicas := append([]string{n.ID}, n.Peers...)
+	sort.Strings(replicas)
+	return replicas
 }
 
 // getQuorum returns the required number of votes (2f + 1)
@@ -1,9 +1,17 @@
 package tpbft
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
 
+	"cosmossdk.io/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
 )
 
 func TestPBFTNode_ConsensusFlow(t *testing.T) {
@@ -24,6 +32,7 @@ func TestPBFTNode_ConsensusFlow(t *testing.T) {
 
 	seq := uint64(1)
 	view := uint64(0)
+	data := []byte("block-data")
 
 	// 1. PrePrepare Phase
 	// Leader (node0) proposes
@@ -31,9 +40,9 @@ func TestPBFTNode_ConsensusFlow(t *testing.T) {
 		Type:           MessageTypePrePrepare,
 		View:           view,
 		SequenceNumber: seq,
-		Digest:         "block-hash-1",
+		Digest:         Digest(data),
 		NodeID:         "node0",
-		Data:           []byte("block-data"),
+		Data:           data,
 	}
 
 	// All nodes receive PrePrepare
@@ -50,7 +59,7 @@ func TestPBFTNode_ConsensusFlow(t *testing.T) {
 			Type:           MessageTypePrepare,
 			View:           view,
 			SequenceNumber: seq,
-			Digest:         "block-hash-1",
+			Digest:         Digest(data),
 			NodeID:         id,
 		}
 	}
@@ -78,7 +87,7 @@ func TestPBFTNode_ConsensusFlow(t *testing.T) {
 			Type:           MessageTypeCommit,
 			View:           view,
 			SequenceNumber: seq,
-			Digest:         "block-hash-1",
+			Digest:         Digest(data),
 			NodeID:         id,
 		}
 	}
@@ -94,3 +103,70 @@ func TestPBFTNode_ConsensusFlow(t *testing.T) {
 	// Check after 3 votes
 	assert.True(t, nodes[3].Committed[seq], "Node3 should be COMMITTED")
 }
+
+func TestPBFTNode_Trace(t *testing.T) {
+	ids := []string{"node0", "node1", "node2", "node3"}
+	nodes := make([]*PBFTNode, len(ids))
+	recorders := make([]*tracetest.SpanRecorder, len(ids))
+	for i, id := range ids {
+		peers := append(append([]string{}, ids[:i]...), ids[i+1:]...)
+		nodes[i] = NewPBFTNode(id, peers)
+		recorders[i] = tracetest.NewSpanRecorder()
+		nodes[i].SetTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorders[i])).Tracer(common.TracerName))
+	}
+
+	// Senders handle their own message first, then send it with their
+	// context, so the primary starts the trace the others join
+	deliver := func(typ MessageType, from int) {
+		msg := &ConsensusMessage{Type: typ, SequenceNumber: 1, Digest: Digest(nil), NodeID: ids[from]}
+		require.NoError(t, nodes[from].HandleMessage(msg))
+		msg.TraceContext = nodes[from].TraceContext(1)
+		for i, node := range nodes {
+			if i != from {
+				require.NoError(t, node.HandleMessage(msg))
+			}
+		}
+	}
+	deliver(MessageTypePrePrepare, 0)
+	for _, typ := range []MessageType{MessageTypePrepare, MessageTypeCommit} {
+		for i := range ids {
+			deliver(typ, i)
+		}
+	}
+	assert.Nil(t, nodes[0].TraceContext(1), "committed sequences are no longer traced")
+
+	root := recorders[0].Ended()
+	require.Len(t, root, 4)
+	seqSpan := root[len(root)-1]
+	require.Equal(t, "tpbft.sequence", seqSpan.Name())
+	traceID := seqSpan.SpanContext().TraceID()
+
+	for i, rec := range recorders {
+		var names []string
+		for _, s := range rec.Ended() {
+			names = append(names, s.Name())
+			assert.Equal(t, traceID, s.SpanContext().TraceID(), "node%d spans join the primary's trace", i)
+		}
+		assert.Equal(t, []string{"tpbft.pre-prepare", "tpbft.prepare", "tpbft.commit", "tpbft.sequence"}, names)
+		if i > 0 {
+			assert.Equal(t, seqSpan.SpanContext().SpanID(), rec.Ended()[3].Parent().SpanID())
+		}
+	}
+}
+
+func TestPBFTNode_Logs(t *testing.T) {
+	var buf bytes.Buffer
+	node := NewPBFTNode("node0", nil)
+	node.View = 2
+	node.SetLogger(log.NewLogger(&buf, log.OutputJSONOption()))
+
+	require.NoError(t, node.HandleMessage(&ConsensusMessage{Type: MessageTypePrePrepare, View: 2, SequenceNumber: 5, Digest: Digest(nil), NodeID: "node0"}))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "received pre-prepare", entry["message"])
+	assert.Equal(t, "tpbft", entry["engine"])
+	assert.Equal(t, "node0", entry["node_id"])
+	assert.EqualValues(t, 2, entry["view"])
+	assert.EqualValues(t, 5, entry["seq"])
+}
@@ -0,0 +1,157 @@
+package tpbft
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Observer is told how other replicas behave, as seen by one node
+type Observer interface {
+	// ObserveVote reports a replica's commit vote for a sequence, delay
+	// after the node received its pre-prepare
+	ObserveVote(replica string, seq uint64, delay time.Duration)
+	// ObserveFault reports a replica misbehaving or failing to vote
+	ObserveFault(replica string, seq uint64, err error)
+}
+
+// ScoreObserver feeds a node's observations to a trust scorer. The
+// observations are local to the node, so the scorer must not be the one
+// whose scores are persisted on chain: nodes would disagree on them.
+type ScoreObserver struct {
+	Scorer *TrustScorer
+	// Stake returns a replica's stake and the total stake
+	Stake func(replica string) (stake, total float64)
+}
+
+const (
+	// faultResponseTime is the response time a fault is scored with, the
+	// slowest the scorer tells apart
+	faultResponseTime = time.Second
+	// staleViewGrace is how many views behind messages may be without
+	// counting as a fault: honest replicas' votes sent before a view change
+	// arrive after it
+	staleViewGrace = 1
+)
+
+// ObserveVote implements Observer
+func (o ScoreObserver) ObserveVote(replica string, _ uint64, delay time.Duration) {
+	stake, total := o.Stake(replica)
+	o.Scorer.UpdateScore(replica, true, delay, stake, total)
+}
+
+// ObserveFault implements Observer. Messages for the view just left are
+// not scored.
+func (o ScoreObserver) ObserveFault(replica string, _ uint64, err error) {
+	var stale *StaleViewError
+	if errors.As(err, &stale) && stale.Lag() <= staleViewGrace {
+		return
+	}
+	stake, total := o.Stake(replica)
+	o.Scorer.UpdateScore(replica, false, faultResponseTime, stake, total)
+}
+
+// SetObserver sets the observer replicas' behaviour is reported to. Votes
+// are only reported by Sweep.
+func (n *PBFTNode) SetObserver(o Observer) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.observer = o
+}
+
+// SetVoteWindow sets how long after a sequence commits replicas may still
+// vote for it, and how long a pre-prepare may take to commit
+func (n *PBFTNode) SetVoteWindow(d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.voteWindow = d
+}
+
+// Sweep reports the votes for sequences committed more than the vote window
+// ago, faulting replicas whose commit vote is missing, and faults the
+// primary of sequences pre-prepared that long ago that did not commit.
+// Swept sequences are forgotten.
+func (n *PBFTNode) Sweep() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.observer == nil {
+		return
+	}
+
+	now := n.now()
+	seqs := make([]uint64, 0, len(n.prePrepared))
+	for seq := range n.prePrepared {
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+
+	for _, seq := range seqs {
+		view := n.views[seq]
+		if !n.Committed[seq] {
+			if now.Sub(n.prePrepared[seq]) >= n.voteWindow {
+				primary := n.Primary(view)
+				n.fault(primary, seq, fmt.Errorf("sequence %d from primary %s not committed within %s", seq, primary, n.voteWindow))
+				n.forget(seq)
+			}
+			continue
+		}
+
+		committed, ok := n.committedAt[seq]
+		if ok && now.Sub(committed) < n.voteWindow {
+			continue
+		}
+		if ok && !n.equivocated[seq] {
+			n.sweepVotes(seq, view)
+		}
+		n.forget(seq)
+	}
+}
+
+// sweepVotes reports every replica's commit vote for a sequence
+func (n *PBFTNode) sweepVotes(seq, view uint64) {
+	digest := n.prePrepare(seq, view).Digest
+	start := n.prePrepared[seq]
+	for _, replica := range n.replicas() {
+		vote, ok := n.MsgLog[seq][view][MessageTypeCommit][replica]
+		at, voted := n.voteAt[seq][replica]
+		if !ok || !voted || vote.Digest != digest || at.Sub(n.committedAt[seq]) > n.voteWindow {
+			n.observer.ObserveFault(replica, seq, fmt.Errorf("%s did not vote to commit sequence %d", replica, seq))
+			continue
+		}
+		n.observer.ObserveVote(replica, seq, max(at.Sub(start), 0))
+	}
+}
+
+// fault reports a replica's fault to the observer. Senders outside the
+// replica set are not reported.
+func (n *PBFTNode) fault(replica string, seq uint64, err error) {
+	if n.observer == nil || !slices.Contains(n.replicas(), replica) {
+		return
+	}
+	n.logger.Debug("replica fault", "replica", replica, "seq", seq, "err", err)
+	n.observer.ObserveFault(replica, seq, err)
+}
+
+// recordVote records when a replica's commit vote arrived
+func (n *PBFTNode) recordVote(msg *ConsensusMessage) {
+	if n.observer == nil || n.Committed[msg.SequenceNumber] && n.committedAt[msg.SequenceNumber].IsZero() {
+		return // Already swept
+	}
+	if n.voteAt[msg.SequenceNumber] == nil {
+		n.voteAt[msg.SequenceNumber] = make(map[string]time.Time)
+	}
+	n.voteAt[msg.SequenceNumber][msg.NodeID] = n.now()
+}
+
+// forget drops the bookkeeping of a sequence that needs no more attention
+func (n *PBFTNode) forget(seq uint64) {
+	delete(n.prePrepared, seq)
+	delete(n.views, seq)
+	delete(n.committedAt, seq)
+	delete(n.voteAt, seq)
+	delete(n.equivocated, seq)
+}
@@ -0,0 +1,244 @@
+package tpbft
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver records votes and faults by replica
+type recordingObserver struct {
+	votes  map[string][]time.Duration
+	faults map[string][]error
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{votes: make(map[string][]time.Duration), faults: make(map[string][]error)}
+}
+
+func (o *recordingObserver) ObserveVote(replica string, _ uint64, delay time.Duration) {
+	o.votes[replica] = append(o.votes[replica], delay)
+}
+
+func (o *recordingObserver) ObserveFault(replica string, _ uint64, err error) {
+	o.faults[replica] = append(o.faults[replica], err)
+}
+
+// signedNodes creates four replicas sharing keys, and a signer for each
+func signedNodes() ([]*PBFTNode, func(msg *ConsensusMessage)) {
+	ids := []string{"node0", "node1", "node2", "node3"}
+	privs := make(map[string]ed25519.PrivateKey)
+	pubs := make(map[string]ed25519.PublicKey)
+	for i, id := range ids {
+		seed := make([]byte, ed25519.SeedSize)
+		seed[0] = byte(i)
+		privs[id] = ed25519.NewKeyFromSeed(seed)
+		pubs[id] = privs[id].Public().(ed25519.PublicKey)
+	}
+	nodes := make([]*PBFTNode, len(ids))
+	for i, id := range ids {
+		peers := append(append([]string{}, ids[:i]...), ids[i+1:]...)
+		nodes[i] = NewPBFTNode(id, peers)
+		nodes[i].SetKeys(privs[id], pubs)
+	}
+	sign := func(msg *ConsensusMessage) {
+		msg.Signature = ed25519.Sign(privs[msg.NodeID], msg.SignBytes())
+	}
+	return nodes, sign
+}
+
+func TestPBFTNode_RejectsViolations(t *testing.T) {
+	nodes, sign := signedNodes()
+	node := nodes[1]
+	obs := newRecordingObserver()
+	node.SetObserver(obs)
+
+	send := func(msg *ConsensusMessage) error {
+		sign(msg)
+		return node.HandleMessage(msg)
+	}
+	a, b := Digest([]byte("a")), Digest([]byte("b"))
+	require.NoError(t, send(&ConsensusMessage{Type: MessageTypePrePrepare, SequenceNumber: 1, Digest: a, Data: []byte("a"), NodeID: "node0"}))
+	require.NoError(t, send(&ConsensusMessage{Type: MessageTypePrepare, SequenceNumber: 1, Digest: a, NodeID: "node2"}))
+
+	for _, tc := range []struct {
+		name string
+		msg  *ConsensusMessage
+		sign bool
+	}{
+		{"pre-prepare from a backup", &ConsensusMessage{Type: MessageTypePrePrepare, SequenceNumber: 2, Digest: b, Data: []byte("b"), NodeID: "node2"}, true},
+		{"conflicting prepare", &ConsensusMessage{Type: MessageTypePrepare, SequenceNumber: 1, Digest: b, NodeID: "node2"}, true},
+		{"bad signature", &ConsensusMessage{Type: MessageTypeCommit, SequenceNumber: 1, Digest: a, NodeID: "node2", Signature: []byte("forged")}, false},
+	} {
+		if tc.sign {
+			sign(tc.msg)
+		}
+		assert.ErrorIs(t, node.HandleMessage(tc.msg), ErrProtocolViolation, tc.name)
+	}
+	assert.Len(t, obs.faults["node2"], 3)
+	assert.Equal(t, a, node.MsgLog[1][0][MessageTypePrepare]["node2"].Digest, "the first message stands")
+
+	// A prepare proving the primary signed another pre-prepare faults it
+	other := &ConsensusMessage{Type: MessageTypePrePrepare, SequenceNumber: 1, Digest: b, NodeID: "node0"}
+	sign(other)
+	require.NoError(t, send(&ConsensusMessage{Type: MessageTypePrepare, SequenceNumber: 1, Digest: b, NodeID: "node3", PrePrepareSignature: other.Signature}))
+	require.Len(t, obs.faults["node0"], 1)
+	assert.ErrorContains(t, obs.faults["node0"][0], "conflicting pre-prepares")
+
+	// The digest signed for a pre-prepare binds its data
+	forged := &ConsensusMessage{Type: MessageTypePrePrepare, SequenceNumber: 2, Digest: a, Data: []byte("b"), NodeID: "node0"}
+	sign(forged)
+	assert.ErrorIs(t, node.HandleMessage(forged), ErrProtocolViolation)
+	assert.Len(t, obs.faults["node0"], 2)
+	assert.NotContains(t, node.MsgLog, uint64(2))
+}
+
+func TestPBFTNode_Views(t *testing.T) {
+	nodes, sign := signedNodes()
+	node := nodes[1]
+	obs := newRecordingObserver()
+	node.SetObserver(obs)
+	require.NoError(t, node.SetView(1))
+
+	send := func(msg *ConsensusMessage) error {
+		sign(msg)
+		return node.HandleMessage(msg)
+	}
+
+	// Stale messages are reported and ignored
+	require.NoError(t, send(&ConsensusMessage{Type: MessageTypeCommit, View: 0, SequenceNumber: 1, Digest: "a", NodeID: "node2"}))
+	require.Len(t, obs.faults["node2"], 1)
+	assert.ErrorIs(t, obs.faults["node2"][0], ErrStaleView)
+	assert.Empty(t, node.MsgLog)
+
+	// Forged stale messages are violations
+	err := node.HandleMessage(&ConsensusMessage{Type: MessageTypeCommit, View: 0, SequenceNumber: 1, Digest: "a", NodeID: "node3", Signature: []byte("forged")})
+	assert.ErrorIs(t, err, ErrProtocolViolation)
+
+	// Messages for the next views wait for the node to enter them
+	b := Digest([]byte("b"))
+	require.NoError(t, send(&ConsensusMessage{Type: MessageTypePrePrepare, View: 2, SequenceNumber: 1, Digest: b, Data: []byte("b"), NodeID: "node2"}))
+	require.NoError(t, send(&ConsensusMessage{Type: MessageTypePrepare, View: 3, SequenceNumber: 1, Digest: "c", NodeID: "node3"}))
+	require.NoError(t, send(&ConsensusMessage{Type: MessageTypePrepare, View: 4, SequenceNumber: 1, Digest: "d", NodeID: "node3"}))
+	assert.Empty(t, node.MsgLog)
+	assert.Len(t, obs.faults["node3"], 1, "only the forged message")
+
+	require.NoError(t, node.SetView(2))
+	assert.Equal(t, b, node.MsgLog[1][2][MessageTypePrePrepare]["node2"].Digest)
+
+	// Skipping a view drops what was buffered for it, and what was too far
+	// ahead was never buffered
+	require.NoError(t, node.SetView(4))
+	assert.NotContains(t, node.MsgLog[1], uint64(3))
+	assert.NotContains(t, node.MsgLog[1], uint64(4))
+	assert.Empty(t, node.future)
+	assert.Zero(t, node.buffered["node3"])
+}
+
+func TestPBFTNode_Checkpoint(t *testing.T) {
+	node := NewPBFTNode("node1", []string{"node0", "node2", "node3"})
+	node.SetObserver(newRecordingObserver())
+	send := func(typ MessageType, seq uint64, from string) {
+		require.NoError(t, node.HandleMessage(&ConsensusMessage{Type: typ, SequenceNumber: seq, Digest: Digest(nil), NodeID: from}))
+	}
+
+	// A pre-prepare that never commits, and a vote without a pre-prepare
+	send(MessageTypePrePrepare, 1, "node0")
+	send(MessageTypeCommit, 2, "node2")
+
+	// Committing far enough ahead forgets them without a sweep
+	seq := uint64(logWindow + 2)
+	send(MessageTypePrePrepare, seq, "node0")
+	for _, typ := range []MessageType{MessageTypePrepare, MessageTypeCommit} {
+		for _, id := range []string{"node0", "node1", "node2"} {
+			send(typ, seq, id)
+		}
+	}
+	require.True(t, node.Committed[seq])
+	assert.Equal(t, []uint64{seq}, keys(node.prePrepared))
+	assert.Equal(t, []uint64{seq}, keys(node.views))
+	assert.Equal(t, []uint64{seq}, keys(node.voteAt))
+	assert.Empty(t, node.traces)
+}
+
+// keys returns a map's keys
+func keys[V any](m map[uint64]V) []uint64 {
+	var ks []uint64
+	for k := range m {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+func TestPBFTNode_Sweep(t *testing.T) {
+	nodes, sign := signedNodes()
+	node := nodes[1]
+	obs := newRecordingObserver()
+	node.SetObserver(obs)
+	now := time.Unix(1700000000, 0)
+	node.SetClock(func() time.Time { return now })
+
+	send := func(typ MessageType, seq uint64, from string) {
+		data := []byte(fmt.Sprint(seq))
+		msg := &ConsensusMessage{Type: typ, SequenceNumber: seq, Digest: Digest(data), Data: data, NodeID: from}
+		sign(msg)
+		require.NoError(t, node.HandleMessage(msg))
+	}
+	send(MessageTypePrePrepare, 1, "node0")
+	send(MessageTypePrePrepare, 2, "node0")
+	for _, id := range []string{"node0", "node1", "node2"} {
+		send(MessageTypePrepare, 1, id)
+	}
+	now = now.Add(30 * time.Millisecond)
+	for _, id := range []string{"node0", "node1", "node2"} {
+		send(MessageTypeCommit, 1, id)
+	}
+	require.True(t, node.Committed[1])
+
+	// Votes are judged once the window has passed
+	node.Sweep()
+	assert.Empty(t, obs.votes)
+	now = now.Add(time.Second)
+	node.Sweep()
+
+	for _, id := range []string{"node0", "node1", "node2"} {
+		assert.Equal(t, []time.Duration{30 * time.Millisecond}, obs.votes[id], id)
+	}
+	require.Len(t, obs.faults["node3"], 1, "node3 never voted")
+	// Sequence 2 never committed, which is held against the primary
+	require.Len(t, obs.faults["node0"], 1)
+	assert.ErrorContains(t, obs.faults["node0"][0], "sequence 2")
+
+	// Swept sequences are not judged again
+	now = now.Add(time.Second)
+	node.Sweep()
+	assert.Len(t, obs.votes["node0"], 1)
+	assert.Len(t, obs.faults["node0"], 1)
+}
+
+func TestScoreObserver(t *testing.T) {
+	scorer := NewTrustScorer()
+	obs := ScoreObserver{Scorer: scorer, Stake: func(string) (float64, float64) { return 1, 4 }}
+	for i := 0; i < 10; i++ {
+		obs.ObserveVote("node0", uint64(i), 10*time.Millisecond)
+		obs.ObserveFault("node1", uint64(i), ErrProtocolViolation)
+	}
+	assert.InDelta(t, 0.775, scorer.GetScore("node0").TotalScore, 1e-9)
+	assert.InDelta(t, 0.105, scorer.GetScore("node1").TotalScore, 1e-9)
+}
+
+func TestScoreObserver_StaleViews(t *testing.T) {
+	scorer := NewTrustScorer()
+	obs := ScoreObserver{Scorer: scorer, Stake: func(string) (float64, float64) { return 1, 4 }}
+
+	// Votes sent before a view change are not held against the replica
+	obs.ObserveFault("node0", 1, &StaleViewError{Type: MessageTypeCommit, View: 3, Current: 4})
+	assert.Empty(t, scorer.successHistory["node0"])
+
+	obs.ObserveFault("node0", 1, &StaleViewError{Type: MessageTypeCommit, View: 2, Current: 4})
+	assert.Equal(t, []bool{false}, scorer.successHistory["node0"])
+}
@@ -0,0 +1,112 @@
+package tpbft
+
+import (
+	"encoding/json"
+	"fmt"
+
+	storetypes "cosmossdk.io/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Params are the tunable parameters of the tPBFT engine. They decide what
+// the engine writes to the store and which validator updates it returns, so
+// they are chain state, set in genesis and changed by governance.
+type Params struct {
+	// TrustUpdateInterval is the number of blocks between updates of the
+	// trust scores from commit votes
+	TrustUpdateInterval int64 `json:"trust_update_interval"`
+
+	// MinTrustThreshold is the trust score validators need to be selected,
+	// unless too few have it
+	MinTrustThreshold float64 `json:"min_trust_threshold"`
+
+	// DynamicValidatorSelection makes the engine return the validators it
+	// selects by trust as the next validator set. Without it, the staking
+	// module's set stands.
+	DynamicValidatorSelection bool `json:"dynamic_validator_selection"`
+
+	// ValidatorSelectionCount is the most validators selected
+	ValidatorSelectionCount int `json:"validator_selection_count"`
+}
+
+// ParamsKey holds the parameters in the store
+var ParamsKey = []byte{0x02}
+
+// DefaultParams returns the parameters of a chain whose genesis sets none
+func DefaultParams() Params {
+	return Params{
+		TrustUpdateInterval:       1,
+		MinTrustThreshold:         0.6,
+		DynamicValidatorSelection: true,
+		ValidatorSelectionCount:   100,
+	}
+}
+
+// Validate checks the parameters for consistency
+func (p Params) Validate() error {
+	if p.TrustUpdateInterval <= 0 {
+		return fmt.Errorf("trust update interval must be positive")
+	}
+	if p.MinTrustThreshold < 0 || p.MinTrustThreshold > 1 {
+		return fmt.Errorf("min trust threshold %v is outside [0, 1]", p.MinTrustThreshold)
+	}
+	if p.ValidatorSelectionCount <= 0 {
+		return fmt.Errorf("validator selection count must be positive")
+	}
+	return nil
+}
+
+// GetParams returns the parameters stored under key, the defaults if none
+// are. Other engines' chains keep them for a switch to tPBFT.
+func GetParams(ctx sdk.Context, key storetypes.StoreKey) (Params, error) {
+	bz := ctx.KVStore(key).Get(ParamsKey)
+	if bz == nil {
+		return DefaultParams(), nil
+	}
+
+	var p Params
+	if err := json.Unmarshal(bz, &p); err != nil {
+		return p, fmt.Errorf("failed to decode %s params: %w", ModuleName, err)
+	}
+	return p, nil
+}
+
+// SetParams validates the parameters and stores them under key
+func SetParams(ctx sdk.Context, key storetypes.StoreKey, p Params) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	bz, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	ctx.KVStore(key).Set(ParamsKey, bz)
+	return nil
+}
+
+// loadParams makes the engine run with the stored parameters. Without a
+// store it keeps its own.
+func (t *TPBFT) loadParams(ctx sdk.Context) error {
+	if t.storeKey == nil {
+		return nil
+	}
+
+	p, err := GetParams(ctx, t.storeKey)
+	if err != nil {
+		return err
+	}
+	t.applyParams(p)
+	return nil
+}
+
+// applyParams sets the parameters the engine runs with
+func (t *TPBFT) applyParams(p Params) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if p.MinTrustThreshold != t.params.MinTrustThreshold || p.ValidatorSelectionCount != t.params.ValidatorSelectionCount {
+		t.ValidatorSelector = NewValidatorSelector(t.TrustScorer, p.MinTrustThreshold, p.ValidatorSelectionCount)
+	}
+	t.params = p
+}
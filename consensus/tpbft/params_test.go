@@ -0,0 +1,77 @@
+package tpbft
+
+import (
+	"testing"
+
+	storetypes "cosmossdk.io/store/types"
+	"github.com/cosmos/cosmos-sdk/testutil"
+	simtestutil "github.com/cosmos/cosmos-sdk/testutil/sims"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+func TestParams_Validate(t *testing.T) {
+	assert.NoError(t, DefaultParams().Validate())
+
+	cases := map[string]func(*Params){
+		"zero interval":      func(p *Params) { p.TrustUpdateInterval = 0 },
+		"threshold above 1":  func(p *Params) { p.MinTrustThreshold = 1.5 },
+		"negative threshold": func(p *Params) { p.MinTrustThreshold = -0.1 },
+		"no selection":       func(p *Params) { p.ValidatorSelectionCount = 0 },
+	}
+	for name, modify := range cases {
+		p := DefaultParams()
+		modify(&p)
+		assert.Error(t, p.Validate(), name)
+	}
+
+	gs := DefaultGenesis()
+	gs.Params.TrustUpdateInterval = -1
+	assert.ErrorContains(t, gs.Validate(), "invalid params")
+}
+
+func TestTPBFT_Params(t *testing.T) {
+	key := storetypes.NewKVStoreKey(StoreKey)
+	ctx := testutil.DefaultContext(key, storetypes.NewTransientStoreKey("transient_test"))
+
+	p, err := GetParams(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultParams(), p)
+
+	// Genesis parameters are stored and applied
+	engine := NewTPBFT()
+	engine.SetStoreKey(key)
+	genesis := Params{TrustUpdateInterval: 10, MinTrustThreshold: 0.5, ValidatorSelectionCount: 4}
+	require.NoError(t, engine.InitGenesis(ctx, &GenesisState{Params: &genesis}))
+	assert.Equal(t, 0.5, engine.ValidatorSelector.minTrustScore)
+	assert.Equal(t, 4, engine.ValidatorSelector.maxValidators)
+	assert.Equal(t, &genesis, engine.ExportGenesis(ctx).Params)
+
+	// A restarted engine runs with the stored parameters, including those
+	// governance changed since genesis
+	updated := genesis
+	updated.ValidatorSelectionCount = 7
+	require.NoError(t, SetParams(ctx, key, updated))
+	restarted := NewTPBFT()
+	restarted.SetStoreKey(key)
+	require.NoError(t, restarted.LoadTrustState(ctx))
+	assert.Equal(t, 7, restarted.ValidatorSelector.maxValidators)
+	assert.Equal(t, &updated, restarted.ExportGenesis(ctx).Params)
+
+	updated.MinTrustThreshold = 2
+	assert.Error(t, SetParams(ctx, key, updated))
+}
+
+func TestRegisteredEngine(t *testing.T) {
+	// The parameters are chain state, so the node's configuration has no
+	// say in them
+	engine, err := common.NewEngine(EngineName, common.Dependencies{
+		AppOptions: simtestutil.AppOptionsMap{"tpbft.validator_selection_count": 4},
+	})
+	require.NoError(t, err)
+	selector := engine.(*TPBFT).ValidatorSelector
+	assert.Equal(t, DefaultParams().MinTrustThreshold, selector.minTrustScore)
+	assert.Equal(t, DefaultParams().ValidatorSelectionCount, selector.maxValidators)
+}
@@ -0,0 +1,66 @@
+package tpbft
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+// SetKeys sets the key the node signs with and the public keys of every
+// replica. From then on, messages without a valid signature are rejected.
+func (n *PBFTNode) SetKeys(key ed25519.PrivateKey, replicas map[string]ed25519.PublicKey) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.key = key
+	n.keys = replicas
+}
+
+// Sign signs a message the node sends. It is a no-op without keys.
+func (n *PBFTNode) Sign(msg *ConsensusMessage) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.key != nil {
+		msg.Signature = ed25519.Sign(n.key, msg.SignBytes())
+	}
+}
+
+// verify checks a replica's signature
+func (n *PBFTNode) verify(replica string, msg, sig []byte) error {
+	pub, ok := n.keys[replica]
+	if !ok {
+		return fmt.Errorf("no key for %s", replica)
+	}
+	if !ed25519.Verify(pub, msg, sig) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// checkEquivocation looks for a prepare that carries the primary's
+// signature on a pre-prepare other than the one this node accepted. Such a
+// prepare proves the primary sent conflicting pre-prepares: the primary is
+// reported, and the sequence is not held against the replicas.
+func (n *PBFTNode) checkEquivocation(seq, view uint64) {
+	if n.keys == nil || n.equivocated[seq] {
+		return
+	}
+	pp := n.prePrepare(seq, view)
+	if pp == nil {
+		return
+	}
+	primary := n.Primary(view)
+	for _, vote := range n.MsgLog[seq][view][MessageTypePrepare] {
+		if vote.Digest == pp.Digest || vote.PrePrepareSignature == nil {
+			continue
+		}
+		other := &ConsensusMessage{Type: MessageTypePrePrepare, View: view, SequenceNumber: seq, Digest: vote.Digest, NodeID: primary}
+		if n.verify(primary, other.SignBytes(), vote.PrePrepareSignature) != nil {
+			continue
+		}
+		n.equivocated[seq] = true
+		n.fault(primary, seq, fmt.Errorf("%w: primary %s sent conflicting pre-prepares for sequence %d", ErrProtocolViolation, primary, seq))
+		return
+	}
+}
@@ -0,0 +1,208 @@
+package tpbft
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cosmossdk.io/math"
+	"cosmossdk.io/store/prefix"
+	storetypes "cosmossdk.io/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+const (
+	// ModuleName is the name used for the tPBFT genesis section
+	ModuleName = "tpbft"
+
+	// StoreKey is the KV store key holding persisted trust state
+	StoreKey = ModuleName
+)
+
+// TrustScoreKeyPrefix prefixes per-validator trust records in the store
+var TrustScoreKeyPrefix = []byte{0x01}
+
+// storedTrustRecord is a trust record as persisted, keyed by validator
+// address. Scores are fixed-point decimals, so equal scores store equal
+// bytes, which the app hash covers. That only makes the stored bytes as
+// deterministic as the float arithmetic computing the scores.
+type storedTrustRecord struct {
+	SuccessRate   math.LegacyDec `json:"success_rate"`
+	StakeWeight   math.LegacyDec `json:"stake_weight"`
+	ResponseSpeed math.LegacyDec `json:"response_speed"`
+	TotalScore    math.LegacyDec `json:"total_score"`
+	LastUpdated   time.Time      `json:"last_updated"`
+
+	SuccessHistory  []bool          `json:"success_history,omitempty"`
+	ResponseHistory []time.Duration `json:"response_history,omitempty"`
+}
+
+// toFixed returns v rounded to a decimal of math.LegacyPrecision places
+func toFixed(v float64) math.LegacyDec {
+	return math.LegacyMustNewDecFromStr(strconv.FormatFloat(v, 'f', math.LegacyPrecision, 64))
+}
+
+func newStoredTrustRecord(rec TrustScoreRecord) storedTrustRecord {
+	return storedTrustRecord{
+		SuccessRate:     toFixed(rec.SuccessRate),
+		StakeWeight:     toFixed(rec.StakeWeight),
+		ResponseSpeed:   toFixed(rec.ResponseSpeed),
+		TotalScore:      toFixed(rec.TotalScore),
+		LastUpdated:     rec.LastUpdated,
+		SuccessHistory:  rec.SuccessHistory,
+		ResponseHistory: rec.ResponseHistory,
+	}
+}
+
+func (s storedTrustRecord) record(addr string) TrustScoreRecord {
+	return TrustScoreRecord{
+		ValidatorAddress: addr,
+		SuccessRate:      s.SuccessRate.MustFloat64(),
+		StakeWeight:      s.StakeWeight.MustFloat64(),
+		ResponseSpeed:    s.ResponseSpeed.MustFloat64(),
+		TotalScore:       s.TotalScore.MustFloat64(),
+		LastUpdated:      s.LastUpdated,
+		SuccessHistory:   s.SuccessHistory,
+		ResponseHistory:  s.ResponseHistory,
+	}
+}
+
+// SetStoreKey sets the store used to persist trust state across restarts
+func (t *TPBFT) SetStoreKey(key storetypes.StoreKey) {
+	t.storeKey = key
+}
+
+// InitGenesis stores the parameters from genesis, seeds the trust scorer and
+// persists the result
+func (t *TPBFT) InitGenesis(ctx sdk.Context, gs *GenesisState) error {
+	if err := gs.Validate(); err != nil {
+		return err
+	}
+
+	if gs.Params != nil {
+		if t.storeKey == nil {
+			t.applyParams(*gs.Params)
+		} else if err := SetParams(ctx, t.storeKey, *gs.Params); err != nil {
+			return err
+		}
+	}
+	if err := t.loadParams(ctx); err != nil {
+		return err
+	}
+	t.TrustScorer.ImportGenesis(gs)
+	return t.saveTrustState(ctx)
+}
+
+// ExportGenesis exports the parameters and the current trust scores and
+// histories
+func (t *TPBFT) ExportGenesis(ctx sdk.Context) *GenesisState {
+	gs := t.TrustScorer.ExportGenesis()
+	t.mu.RLock()
+	params := t.params
+	t.mu.RUnlock()
+	gs.Params = &params
+	return gs
+}
+
+// LoadTrustState restores the parameters and the trust scorer from the
+// store, replacing any in-memory state. It is a no-op when no store key is
+// configured.
+func (t *TPBFT) LoadTrustState(ctx sdk.Context) error {
+	if t.storeKey == nil {
+		return nil
+	}
+	if err := t.loadParams(ctx); err != nil {
+		return err
+	}
+
+	store := prefix.NewStore(ctx.KVStore(t.storeKey), TrustScoreKeyPrefix)
+	iter := store.Iterator(nil, nil)
+	defer iter.Close()
+
+	gs := DefaultGenesis()
+	for ; iter.Valid(); iter.Next() {
+		var rec storedTrustRecord
+		if err := json.Unmarshal(iter.Value(), &rec); err != nil {
+			return fmt.Errorf("failed to decode trust record %q: %w", iter.Key(), err)
+		}
+		gs.Scores = append(gs.Scores, rec.record(string(iter.Key())))
+	}
+
+	t.TrustScorer.ImportGenesis(gs)
+	return nil
+}
+
+// saveTrustState writes every trust record to the store and deletes the
+// records of validators the scorer no longer tracks. The scorer then takes
+// the stored values, so nodes that restart from the store keep scoring like
+// those that did not.
+func (t *TPBFT) saveTrustState(ctx sdk.Context) error {
+	if t.storeKey == nil {
+		return nil
+	}
+
+	store := prefix.NewStore(ctx.KVStore(t.storeKey), TrustScoreKeyPrefix)
+	gs := t.TrustScorer.ExportGenesis()
+	tracked := make(map[string]bool, len(gs.Scores))
+	for i, rec := range gs.Scores {
+		tracked[rec.ValidatorAddress] = true
+		stored := newStoredTrustRecord(rec)
+		bz, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		store.Set([]byte(rec.ValidatorAddress), bz)
+		gs.Scores[i] = stored.record(rec.ValidatorAddress)
+	}
+
+	var stale [][]byte
+	iter := store.Iterator(nil, nil)
+	for ; iter.Valid(); iter.Next() {
+		if !tracked[string(iter.Key())] {
+			stale = append(stale, iter.Key())
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	for _, key := range stale {
+		store.Delete(key)
+	}
+
+	t.TrustScorer.ImportGenesis(gs)
+	return nil
+}
+
+// ExportHandoff implements common.HandoffExporter, passing trust scores on
+// to the next engine
+func (t *TPBFT) ExportHandoff(ctx sdk.Context) (common.HandoffState, error) {
+	bz, err := json.Marshal(t.TrustScorer.ExportGenesis())
+	if err != nil {
+		return common.HandoffState{}, fmt.Errorf("failed to encode trust scores: %w", err)
+	}
+	return common.HandoffState{From: EngineName, Height: ctx.BlockHeight(), Trust: bz}, nil
+}
+
+// ImportHandoff implements common.HandoffImporter. Without trust records from
+// the previous engine it falls back to the persisted trust state.
+func (t *TPBFT) ImportHandoff(ctx sdk.Context, state common.HandoffState) error {
+	if len(state.Trust) == 0 {
+		return t.LoadTrustState(ctx)
+	}
+
+	gs, err := UnmarshalGenesis(state.Trust)
+	if err != nil {
+		return err
+	}
+	if err := gs.Validate(); err != nil {
+		return err
+	}
+	if err := t.loadParams(ctx); err != nil {
+		return err
+	}
+	t.TrustScorer.ImportGenesis(gs)
+	return t.saveTrustState(ctx)
+}
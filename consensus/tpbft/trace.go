@@ -0,0 +1,94 @@
+package tpbft
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fffeng99999/hcp-consensus/consensus/common"
+)
+
+// seqTrace holds a sequence's span on this node and the span of its current
+// phase
+type seqTrace struct {
+	ctx   context.Context
+	span  trace.Span
+	phase trace.Span
+}
+
+// SetTracer sets the tracer the node records sequence spans with
+func (n *PBFTNode) SetTracer(t trace.Tracer) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.tracer = t
+}
+
+// TraceContext returns the trace context to carry in messages the node sends
+// for a sequence, or nil if the sequence is not being traced
+func (n *PBFTNode) TraceContext(seq uint64) map[string]string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if st, ok := n.traces[seq]; ok {
+		return common.InjectTrace(st.ctx)
+	}
+	return nil
+}
+
+// traceMessage records msg on its sequence's span, starting the span with
+// the sequence's first message. The span continues the trace carried by
+// that message, so a sequence forms one trace across nodes.
+func (n *PBFTNode) traceMessage(msg *ConsensusMessage) {
+	if n.Committed[msg.SequenceNumber] {
+		return
+	}
+
+	now := n.now()
+	st, ok := n.traces[msg.SequenceNumber]
+	if !ok {
+		ctx, span := n.tracer.Start(common.ExtractTrace(msg.TraceContext), "tpbft.sequence",
+			trace.WithTimestamp(now),
+			trace.WithAttributes(
+				common.AttributeEngine.String(EngineName),
+				common.AttributeNodeID.String(n.ID),
+				common.AttributeView.Int64(int64(msg.View)),
+				common.AttributeSeq.Int64(int64(msg.SequenceNumber)),
+			),
+		)
+		st = &seqTrace{ctx: ctx, span: span}
+		n.traces[msg.SequenceNumber] = st
+		n.enterPhase(msg.SequenceNumber, "tpbft.pre-prepare")
+	}
+	st.span.AddEvent(msg.Type.String(),
+		trace.WithTimestamp(now),
+		trace.WithAttributes(common.AttributeFrom.String(msg.NodeID)),
+	)
+}
+
+// enterPhase ends a sequence's current phase span and starts the next one
+func (n *PBFTNode) enterPhase(seq uint64, name string) {
+	st, ok := n.traces[seq]
+	if !ok {
+		return
+	}
+
+	now := n.now()
+	if st.phase != nil {
+		st.phase.End(trace.WithTimestamp(now))
+	}
+	_, st.phase = n.tracer.Start(st.ctx, name, trace.WithTimestamp(now))
+}
+
+// endTrace ends the spans of a committed or forgotten sequence
+func (n *PBFTNode) endTrace(seq uint64) {
+	st, ok := n.traces[seq]
+	if !ok {
+		return
+	}
+
+	now := n.now()
+	st.phase.End(trace.WithTimestamp(now))
+	st.span.End(trace.WithTimestamp(now))
+	delete(n.traces, seq)
+}
@@ -30,6 +30,9 @@ type TrustScorer struct {
 
 	// History window size
 	historyWindow int // Default 100
+
+	// Clock used for LastUpdated timestamps
+	now func() time.Time // Default time.Now
 }
 
 // NewTrustScorer creates a new trust scorer
@@ -42,9 +45,19 @@ func NewTrustScorer() *TrustScorer {
 		stakeWeight:     0.3,
 		speedWeight:     0.3,
 		historyWindow:   100,
+		now:             time.Now,
 	}
 }
 
+// SetClock sets the clock used for score timestamps. On-chain callers pass the
+// block time so that persisted scores are identical across nodes.
+func (ts *TrustScorer) SetClock(now func() time.Time) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.now = now
+}
+
 // UpdateScore updates trust score for a validator
 func (ts *TrustScorer) UpdateScore(
 	validatorAddr string,
@@ -83,7 +96,7 @@ func (ts *TrustScorer) UpdateScore(
 		StakeWeight:      stakeWeight,
 		ResponseSpeed:    speedScore,
 		TotalScore:       totalScore,
-		LastUpdated:      time.Now(),
+		LastUpdated:      ts.now(),
 	}
 }
 
@@ -205,6 +218,16 @@ func (ts *TrustScorer) GetScore(validatorAddr string) *TrustScore {
 		StakeWeight:      0.0,
 		ResponseSpeed:    1.0,
 		TotalScore:       0.7, // Default medium trust
-		LastUpdated:      time.Now(),
+		LastUpdated:      ts.now(),
 	}
 }
+
+// ResetHistory clears the success and response time windows of every
+// validator while keeping their current scores
+func (ts *TrustScorer) ResetHistory() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.successHistory = make(map[string][]bool)
+	ts.responseHistory = make(map[string][]time.Duration)
+}
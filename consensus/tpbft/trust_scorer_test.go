@@ -64,3 +64,20 @@ func TestTrustScorer_HistoryWindow(t *testing.T) {
 	// Before: T T T T T
 	// After: T T T T F
 }
+
+func TestTrustScorer_ResetHistory(t *testing.T) {
+	ts := NewTrustScorer()
+	valAddr := "validator1"
+
+	ts.UpdateScore(valAddr, false, 100*time.Millisecond, 1000, 10000)
+	before := ts.GetScore(valAddr).TotalScore
+
+	ts.ResetHistory()
+
+	if len(ts.successHistory[valAddr]) != 0 || len(ts.responseHistory[valAddr]) != 0 {
+		t.Errorf("Expected empty history windows after reset")
+	}
+	if ts.GetScore(valAddr).TotalScore != before {
+		t.Errorf("Expected score %f to be kept, got %f", before, ts.GetScore(valAddr).TotalScore)
+	}
+}
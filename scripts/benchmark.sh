@@ -7,7 +7,7 @@ echo "========================================"
 echo ""
 
 BINARY="./build/hcpd"
-NODE0_HOME="./testnet/node0"
+NODE0_HOME="./build/testnet/node0"
 CHAIN_ID="hcp-testnet"
 VALIDATOR="validator0"
 
@@ -27,7 +27,7 @@ test_consensus() {
     
     # Apply configuration
     if [ -f "$config" ]; then
-        cp "$config" "./testnet/node0/config/config.toml"
+        cp "$config" "./build/testnet/node0/config/config.toml"
         echo "  Config applied: $config"
     else
         echo "  ${YELLOW}Warning: Config not found, using default${NC}"
@@ -0,0 +1,185 @@
+package testnet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	cmtcfg "github.com/cometbft/cometbft/config"
+	srvconfig "github.com/cosmos/cosmos-sdk/server/config"
+	"github.com/spf13/viper"
+)
+
+// appConfig is the content of app.toml: the SDK's configuration and the
+// consensus engine
+type appConfig struct {
+	srvconfig.Config
+	ConsensusEngine string
+}
+
+// appConfigTemplate puts the consensus engine among the SDK's top-level
+// options, ahead of its sections
+var appConfigTemplate = template.Must(template.New("app.toml").Parse(`###############################################################################
+###                          Consensus Engine                               ###
+###############################################################################
+
+# Consensus engine run by the app
+consensus-engine = "{{ .ConsensusEngine }}"
+
+` + srvconfig.DefaultConfigTemplate))
+
+// sectionHeader matches the header of a TOML table
+var sectionHeader = regexp.MustCompile(`^\s*\[\s*([^\]\s]+)\s*\]`)
+
+// Overlay is a per-engine configuration from configs/<engine>-config.toml.
+// Sections CometBFT knows are merged into config.toml, [genesis.<section>]
+// tables into that section of the genesis app state, and any others, such as
+// the engine's own, are copied to app.toml. Options CometBFT does not know
+// in its own sections are dropped.
+type Overlay struct {
+	Comet   []byte // CometBFT sections
+	Genesis []byte // Genesis tables
+	App     []byte // Other sections, as written
+}
+
+// LoadOverlay reads the overlay of an engine from dir
+func LoadOverlay(dir, engine string) (*Overlay, error) {
+	path := filepath.Join(dir, engine+"-config.toml")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overlay of %s: %w", engine, err)
+	}
+	return splitOverlay(content, cometSections), nil
+}
+
+// cometSections are the sections of CometBFT's config.toml
+var cometSections = map[string]bool{
+	"rpc": true, "p2p": true, "mempool": true, "statesync": true, "blocksync": true,
+	"consensus": true, "storage": true, "tx_index": true, "instrumentation": true,
+}
+
+// splitOverlay splits an overlay into the sections CometBFT knows, the
+// genesis tables and the rest, keeping comments with the section they
+// precede
+func splitOverlay(content []byte, comet map[string]bool) *Overlay {
+	var o Overlay
+	var pending bytes.Buffer // Comments and blank lines ahead of a section
+	current := &o.Comet
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			pending.WriteString(line + "\n")
+			continue
+		}
+		if m := sectionHeader.FindStringSubmatch(line); m != nil {
+			switch top := strings.SplitN(m[1], ".", 2)[0]; {
+			case comet[top]:
+				current = &o.Comet
+			case top == "genesis":
+				current = &o.Genesis
+			default:
+				current = &o.App
+			}
+		}
+		*current = append(*current, pending.Bytes()...)
+		*current = append(*current, line+"\n"...)
+		pending.Reset()
+	}
+	return &o
+}
+
+// applyComet merges the CometBFT sections of the overlay into cfg
+func (o *Overlay) applyComet(cfg *cmtcfg.Config) error {
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(bytes.NewReader(o.Comet)); err != nil {
+		return fmt.Errorf("failed to parse overlay: %w", err)
+	}
+	root := cfg.RootDir
+	if err := v.Unmarshal(cfg); err != nil {
+		return fmt.Errorf("failed to apply overlay: %w", err)
+	}
+	cfg.SetRoot(root)
+	return cfg.ValidateBasic()
+}
+
+// applyGenesis merges the genesis tables of the overlay into the app state,
+// keeping the options they leave out
+func (o *Overlay) applyGenesis(state map[string]json.RawMessage) error {
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(bytes.NewReader(o.Genesis)); err != nil {
+		return fmt.Errorf("failed to parse overlay: %w", err)
+	}
+	for name, table := range v.GetStringMap("genesis") {
+		overlay, ok := table.(map[string]any)
+		if !ok {
+			return fmt.Errorf("overlay genesis.%s is not a table", name)
+		}
+		section := make(map[string]any)
+		if bz := state[name]; len(bz) > 0 {
+			dec := json.NewDecoder(bytes.NewReader(bz))
+			dec.UseNumber()
+			if err := dec.Decode(&section); err != nil {
+				return fmt.Errorf("failed to decode genesis section %s: %w", name, err)
+			}
+		}
+		bz, err := json.Marshal(merge(section, overlay))
+		if err != nil {
+			return err
+		}
+		state[name] = bz
+	}
+	return nil
+}
+
+// merge sets the options of src in dst, merging tables present in both
+func merge(dst, src map[string]any) map[string]any {
+	for k, v := range src {
+		if table, ok := v.(map[string]any); ok {
+			if existing, ok := dst[k].(map[string]any); ok {
+				dst[k] = merge(existing, table)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// writeConfigs applies the overlay to the CometBFT configuration of a node
+// and writes its config.toml and app.toml
+func writeConfigs(cfg Config, n *Node) error {
+	if cfg.Overlay != nil {
+		if err := cfg.Overlay.applyComet(n.Config); err != nil {
+			return err
+		}
+	}
+	cmtcfg.WriteConfigFile(filepath.Join(n.Home, "config", "config.toml"), n.Config)
+
+	app := appConfig{Config: *srvconfig.DefaultConfig(), ConsensusEngine: cfg.Engine}
+	app.MinGasPrices = "0" + cfg.Denom
+	// Nodes on one host would compete for the API and gRPC ports
+	app.API.Enable = cfg.Hostnames
+	app.API.Address = "tcp://0.0.0.0:1317"
+	app.GRPC.Enable = cfg.Hostnames
+	app.GRPC.Address = "0.0.0.0:9090"
+
+	var buf bytes.Buffer
+	if err := appConfigTemplate.Execute(&buf, app); err != nil {
+		return err
+	}
+	if cfg.Overlay != nil && len(cfg.Overlay.App) > 0 {
+		buf.WriteString("\n")
+		buf.Write(cfg.Overlay.App)
+	}
+	return os.WriteFile(filepath.Join(n.Home, "config", "app.toml"), buf.Bytes(), 0o644)
+}
@@ -0,0 +1,86 @@
+package testnet
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	cmtcfg "github.com/cometbft/cometbft/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOverlay = `# Engine configuration
+
+[consensus]
+# Faster blocks
+timeout_commit = "250ms"
+create_empty_blocks = false
+
+[p2p]
+send_rate = 1024
+
+[mempool]
+size = 10
+unknown_option = 1
+
+# Engine section
+[raft]
+election_timeout = "150ms"
+
+[raft.advanced]
+pre_vote = true
+
+[genesis.tpbft.params]
+trust_update_interval = 10
+`
+
+func TestOverlay(t *testing.T) {
+	o := splitOverlay([]byte(testOverlay), cometSections)
+	assert.Equal(t, `# Engine configuration
+
+[consensus]
+# Faster blocks
+timeout_commit = "250ms"
+create_empty_blocks = false
+
+[p2p]
+send_rate = 1024
+
+[mempool]
+size = 10
+unknown_option = 1
+`, string(o.Comet))
+	assert.Equal(t, `
+# Engine section
+[raft]
+election_timeout = "150ms"
+
+[raft.advanced]
+pre_vote = true
+`, string(o.App))
+	assert.Equal(t, "\n[genesis.tpbft.params]\ntrust_update_interval = 10\n", string(o.Genesis))
+
+	cfg := cmtcfg.DefaultConfig()
+	cfg.SetRoot("/home/node0")
+	cfg.P2P.PersistentPeers = "id@node1:26656"
+	require.NoError(t, o.applyComet(cfg))
+	assert.Equal(t, 250*time.Millisecond, cfg.Consensus.TimeoutCommit)
+	assert.False(t, cfg.Consensus.CreateEmptyBlocks)
+	assert.EqualValues(t, 1024, cfg.P2P.SendRate)
+	assert.Equal(t, 10, cfg.Mempool.Size)
+	// Options the overlay does not set are kept
+	assert.Equal(t, "id@node1:26656", cfg.P2P.PersistentPeers)
+	assert.Equal(t, cmtcfg.DefaultConfig().Consensus.TimeoutPropose, cfg.Consensus.TimeoutPropose)
+	assert.Equal(t, "/home/node0", cfg.RootDir)
+}
+
+func TestOverlay_Genesis(t *testing.T) {
+	o := splitOverlay([]byte(testOverlay), cometSections)
+	state := map[string]json.RawMessage{
+		"tpbft": json.RawMessage(`{"params":{"trust_update_interval":1,"min_trust_threshold":0.6},"scores":[]}`),
+	}
+	require.NoError(t, o.applyGenesis(state))
+	// Options the overlay does not set are kept
+	assert.JSONEq(t, `{"params":{"trust_update_interval":10,"min_trust_threshold":0.6},"scores":[]}`, string(state["tpbft"]))
+}
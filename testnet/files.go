@@ -0,0 +1,280 @@
+package testnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"cosmossdk.io/math"
+	cmtcfg "github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/p2p"
+	"github.com/cometbft/cometbft/privval"
+	clienttx "github.com/cosmos/cosmos-sdk/client/tx"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil"
+	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// Node is a validator node of a testnet
+type Node struct {
+	Moniker    string
+	Home       string
+	NodeID     string
+	P2PAddress string // host:port
+	RPCAddress string // tcp://host:port
+	Operator   sdk.AccAddress
+	Config     *cmtcfg.Config
+}
+
+// nodeHome matches the name of a node home InitFiles writes
+var nodeHome = regexp.MustCompile(`^node\d+$`)
+
+// Files returns the paths in dir InitFiles writes, the node homes and the
+// gentxs, leaving out anything else dir holds
+func Files(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() && (e.Name() == "gentxs" || nodeHome.MatchString(e.Name())) {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// InitFiles writes the home of every node: its keys, its config.toml with the
+// other nodes as persistent peers, its app.toml and a genesis in which every
+// node is a validator through a gentx. Given the genesis time, the files are
+// the same for the same configuration.
+func InitFiles(cfg Config) ([]*Node, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	genesisTime := cfg.GenesisTime
+	if genesisTime.IsZero() {
+		genesisTime = time.Now()
+	}
+	gentxsDir := filepath.Join(cfg.Dir, "gentxs")
+	if err := os.MkdirAll(gentxsDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*Node, cfg.Nodes)
+	var accounts []authtypes.GenesisAccount
+	var balances []banktypes.Balance
+	fund := func(addr sdk.AccAddress, amount sdk.Coins) error {
+		s, err := cfg.TxConfig.SigningContext().AddressCodec().BytesToString(addr)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, authtypes.NewBaseAccount(addr, nil, 0, 0))
+		balances = append(balances, banktypes.Balance{Address: s, Coins: amount})
+		return nil
+	}
+	for i := range nodes {
+		n, err := initNode(cfg, i, gentxsDir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", moniker(i), err)
+		}
+		nodes[i] = n
+		if err := fund(n.Operator, sdk.NewCoins(sdk.NewCoin(cfg.Denom, cfg.Balance))); err != nil {
+			return nil, err
+		}
+	}
+	for _, addr := range cfg.Accounts {
+		if err := fund(addr, sdk.NewCoins(sdk.NewCoin(cfg.Denom, cfg.AccountBalance))); err != nil {
+			return nil, err
+		}
+	}
+
+	appState, err := genesisState(cfg, accounts, balances)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		appGenesis := &genutiltypes.AppGenesis{
+			ChainID:     cfg.ChainID,
+			GenesisTime: genesisTime,
+			AppState:    appState,
+			Consensus:   &genutiltypes.ConsensusGenesis{},
+		}
+		pv := privval.LoadFilePV(n.Config.PrivValidatorKeyFile(), n.Config.PrivValidatorStateFile())
+		valPubKey, err := cryptocodec.FromCmtPubKeyInterface(pv.Key.PubKey)
+		if err != nil {
+			return nil, err
+		}
+		initCfg := genutiltypes.NewInitConfig(cfg.ChainID, gentxsDir, n.NodeID, valPubKey)
+
+		// Collecting the gentxs also sets the persistent peers and writes
+		// config.toml
+		_, err = genutil.GenAppStateFromConfig(cfg.Codec, cfg.TxConfig, n.Config, initCfg, appGenesis,
+			banktypes.GenesisBalancesIterator{}, genutiltypes.DefaultMessageValidator, cfg.TxConfig.SigningContext().ValidatorAddressCodec())
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to collect gentxs: %w", n.Moniker, err)
+		}
+		if err := writeConfigs(cfg, n); err != nil {
+			return nil, fmt.Errorf("%s: %w", n.Moniker, err)
+		}
+	}
+	return nodes, nil
+}
+
+// initNode writes the keys of node i and its gentx
+func initNode(cfg Config, i int, gentxsDir string) (*Node, error) {
+	home := cfg.home(i)
+	host, listenHost := cfg.Host, cfg.Host
+	p2pPort, rpcPort := cfg.BasePort+2*i, cfg.BasePort+2*i+1
+	if cfg.Hostnames {
+		host, listenHost = moniker(i), "0.0.0.0"
+		p2pPort, rpcPort = cfg.BasePort, cfg.BasePort+1
+	}
+
+	cmtCfg := cmtcfg.DefaultConfig()
+	cmtCfg.SetRoot(home)
+	cmtCfg.Moniker = moniker(i)
+	cmtCfg.P2P.ListenAddress = fmt.Sprintf("tcp://%s:%d", listenHost, p2pPort)
+	cmtCfg.RPC.ListenAddress = fmt.Sprintf("tcp://%s:%d", listenHost, rpcPort)
+	cmtCfg.P2P.AllowDuplicateIP = true
+	cmtCfg.P2P.AddrBookStrict = false
+	cmtCfg.Consensus.TimeoutCommit = cfg.TimeoutCommit
+	// Nodes on one host would compete for the metrics port, and in one
+	// process would register the same collectors
+	cmtCfg.Instrumentation.Prometheus = cfg.Hostnames
+	cmtcfg.EnsureRoot(home)
+
+	nodeKey := &p2p.NodeKey{PrivKey: ed25519.GenPrivKeyFromSecret(secret(cfg, "node", i))}
+	if err := nodeKey.SaveAs(cmtCfg.NodeKeyFile()); err != nil {
+		return nil, err
+	}
+	pv := privval.NewFilePV(ed25519.GenPrivKeyFromSecret(secret(cfg, "validator", i)), cmtCfg.PrivValidatorKeyFile(), cmtCfg.PrivValidatorStateFile())
+	pv.Save()
+
+	n := &Node{
+		Moniker:    cmtCfg.Moniker,
+		Home:       home,
+		NodeID:     string(nodeKey.ID()),
+		P2PAddress: fmt.Sprintf("%s:%d", host, p2pPort),
+		RPCAddress: cmtCfg.RPC.ListenAddress,
+		Config:     cmtCfg,
+	}
+	operator := secp256k1.GenPrivKeyFromSecret(secret(cfg, "operator", i))
+	n.Operator = sdk.AccAddress(operator.PubKey().Address())
+
+	gentx, err := signGentx(cfg, n, operator, pv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign gentx: %w", err)
+	}
+	return n, os.WriteFile(filepath.Join(gentxsDir, fmt.Sprintf("gentx-%s.json", n.NodeID)), gentx, 0o644)
+}
+
+// secret returns the secret a key of node i is derived from
+func secret(cfg Config, kind string, i int) []byte {
+	return []byte(fmt.Sprintf("%s/%s/%d", cfg.Seed, kind, i))
+}
+
+// signGentx returns the JSON of a transaction creating the validator of a
+// node, signed by its operator. Its memo is the node's address, from which
+// the others' persistent peers are collected.
+func signGentx(cfg Config, n *Node, operator *secp256k1.PrivKey, pv *privval.FilePV) ([]byte, error) {
+	valAddr, err := cfg.TxConfig.SigningContext().ValidatorAddressCodec().BytesToString(n.Operator)
+	if err != nil {
+		return nil, err
+	}
+	valPubKey, err := cryptocodec.FromCmtPubKeyInterface(pv.Key.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := stakingtypes.NewMsgCreateValidator(
+		valAddr,
+		valPubKey,
+		sdk.NewCoin(cfg.Denom, cfg.SelfDelegated),
+		stakingtypes.NewDescription(n.Moniker, "", "", "", ""),
+		stakingtypes.NewCommissionRates(math.LegacyNewDecWithPrec(1, 1), math.LegacyNewDecWithPrec(2, 1), math.LegacyNewDecWithPrec(1, 2)),
+		math.OneInt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := cfg.TxConfig.NewTxBuilder()
+	if err := builder.SetMsgs(msg); err != nil {
+		return nil, err
+	}
+	builder.SetMemo(fmt.Sprintf("%s@%s", n.NodeID, n.P2PAddress))
+	builder.SetGasLimit(200000)
+
+	// Gentxs are signed at account number and sequence 0, with the signer
+	// info set first as it is part of the signed bytes
+	signMode := signing.SignMode_SIGN_MODE_DIRECT
+	sig := signing.SignatureV2{
+		PubKey: operator.PubKey(),
+		Data:   &signing.SingleSignatureData{SignMode: signMode},
+	}
+	if err := builder.SetSignatures(sig); err != nil {
+		return nil, err
+	}
+	addr, err := cfg.TxConfig.SigningContext().AddressCodec().BytesToString(n.Operator)
+	if err != nil {
+		return nil, err
+	}
+	signerData := authsigning.SignerData{Address: addr, ChainID: cfg.ChainID, PubKey: operator.PubKey()}
+	if sig, err = clienttx.SignWithPrivKey(context.Background(), signMode, signerData, builder, operator, cfg.TxConfig, 0); err != nil {
+		return nil, err
+	}
+	if err := builder.SetSignatures(sig); err != nil {
+		return nil, err
+	}
+	return cfg.TxConfig.TxJSONEncoder()(builder.GetTx())
+}
+
+// genesisState returns the app state of the genesis, before the gentxs are
+// collected: the modules' defaults with the accounts funded, the bond
+// denomination set and the overlay's genesis tables applied
+func genesisState(cfg Config, accounts []authtypes.GenesisAccount, balances []banktypes.Balance) (json.RawMessage, error) {
+	state := cfg.ModuleBasics.DefaultGenesis(cfg.Codec)
+
+	var authGenesis authtypes.GenesisState
+	cfg.Codec.MustUnmarshalJSON(state[authtypes.ModuleName], &authGenesis)
+	packed, err := authtypes.PackAccounts(accounts)
+	if err != nil {
+		return nil, err
+	}
+	authGenesis.Accounts = append(authGenesis.Accounts, packed...)
+	state[authtypes.ModuleName] = cfg.Codec.MustMarshalJSON(&authGenesis)
+
+	var bankGenesis banktypes.GenesisState
+	cfg.Codec.MustUnmarshalJSON(state[banktypes.ModuleName], &bankGenesis)
+	bankGenesis.Balances = append(bankGenesis.Balances, balances...)
+	state[banktypes.ModuleName] = cfg.Codec.MustMarshalJSON(&bankGenesis)
+
+	var stakingGenesis stakingtypes.GenesisState
+	cfg.Codec.MustUnmarshalJSON(state[stakingtypes.ModuleName], &stakingGenesis)
+	stakingGenesis.Params.BondDenom = cfg.Denom
+	state[stakingtypes.ModuleName] = cfg.Codec.MustMarshalJSON(&stakingGenesis)
+
+	if cfg.Overlay != nil {
+		if err := cfg.Overlay.applyGenesis(state); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.MarshalIndent(state, "", "  ")
+}
@@ -0,0 +1,153 @@
+package testnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"cosmossdk.io/log"
+	cmtcfg "github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/node"
+	"github.com/cometbft/cometbft/p2p"
+	"github.com/cometbft/cometbft/privval"
+	"github.com/cometbft/cometbft/proxy"
+	"github.com/cometbft/cometbft/rpc/client/local"
+	cmttypes "github.com/cometbft/cometbft/types"
+	dbm "github.com/cosmos/cosmos-db"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+	servercmtlog "github.com/cosmos/cosmos-sdk/server/log"
+	servertypes "github.com/cosmos/cosmos-sdk/server/types"
+	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/spf13/viper"
+)
+
+// Network is a testnet running in this process
+type Network struct {
+	Nodes []*Node
+
+	running []*running
+}
+
+// running is a node started by the network
+type running struct {
+	app  servertypes.Application
+	node *node.Node
+}
+
+// Start writes the files of a testnet to cfg.Dir and starts each of its
+// nodes with an app from newApp
+func Start(logger log.Logger, cfg Config, newApp servertypes.AppCreator) (*Network, error) {
+	if cfg.Hostnames {
+		return nil, errors.New("nodes reached by hostname cannot run in one process")
+	}
+	nodes, err := InitFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	net := &Network{Nodes: nodes}
+	for _, n := range nodes {
+		r, err := startNode(logger.With("node", n.Moniker), n, cfg, newApp)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: %w", n.Moniker, err), net.Stop())
+		}
+		net.running = append(net.running, r)
+	}
+	return net, nil
+}
+
+// startNode starts the app and CometBFT node of n
+func startNode(logger log.Logger, n *Node, cfg Config, newApp servertypes.AppCreator) (*running, error) {
+	db, err := dbm.NewDB("application", dbm.GoLevelDBBackend, filepath.Join(n.Home, "data"))
+	if err != nil {
+		return nil, err
+	}
+	// The app reads its options from both files, as under hcpd start
+	appOpts := viper.New()
+	appOpts.SetConfigFile(filepath.Join(n.Home, "config", "config.toml"))
+	if err := appOpts.ReadInConfig(); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	appOpts.SetConfigFile(filepath.Join(n.Home, "config", "app.toml"))
+	if err := appOpts.MergeInConfig(); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	appOpts.Set(flags.FlagHome, n.Home)
+	appOpts.Set(flags.FlagChainID, cfg.ChainID)
+	app := newApp(logger, db, nil, appOpts)
+
+	nodeKey, err := p2p.LoadNodeKey(n.Config.NodeKeyFile())
+	if err != nil {
+		return nil, errors.Join(err, app.Close())
+	}
+	genesis := func() (*cmttypes.GenesisDoc, error) {
+		appGenesis, err := genutiltypes.AppGenesisFromFile(n.Config.GenesisFile())
+		if err != nil {
+			return nil, err
+		}
+		return appGenesis.ToGenesisDoc()
+	}
+	cmtNode, err := node.NewNode(
+		n.Config,
+		privval.LoadFilePV(n.Config.PrivValidatorKeyFile(), n.Config.PrivValidatorStateFile()),
+		nodeKey,
+		proxy.NewLocalClientCreator(server.NewCometABCIWrapper(app)),
+		genesis,
+		cmtcfg.DefaultDBProvider,
+		node.DefaultMetricsProvider(n.Config.Instrumentation),
+		servercmtlog.CometLoggerWrapper{Logger: logger},
+	)
+	if err != nil {
+		return nil, errors.Join(err, app.Close())
+	}
+	if err := cmtNode.Start(); err != nil {
+		return nil, errors.Join(err, app.Close())
+	}
+	return &running{app: app, node: cmtNode}, nil
+}
+
+// Client returns an RPC client of node i that calls it in process
+func (net *Network) Client(i int) *local.Local {
+	return local.New(net.running[i].node)
+}
+
+// WaitForHeight waits until every node has committed the block at height
+func (net *Network) WaitForHeight(ctx context.Context, height int64) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		done := true
+		for _, r := range net.running {
+			if r.node.BlockStore().Height() < height {
+				done = false
+			}
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for height %d: %w", height, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops every node and closes its app
+func (net *Network) Stop() error {
+	var errs []error
+	for _, r := range net.running {
+		if err := r.node.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+		r.node.Wait()
+		if err := r.app.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	net.running = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,86 @@
+// Package testnet generates the files of a local multi-validator testnet
+// and runs its nodes, app and CometBFT, in one process on loopback ports.
+package testnet
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/module"
+)
+
+// Config describes a testnet
+type Config struct {
+	ChainID string
+	Nodes   int
+	Engine  string // consensus-engine app option of every node
+	Dir     string // Node homes are created in it as node0, node1, ...
+	Seed    string // Node, validator and operator keys are derived from it
+
+	// Node i listens on Host for P2P on BasePort+2i and RPC on BasePort+2i+1.
+	// With Hostnames, node i is instead reached at host node<i>, as in
+	// docker-compose.yml, and listens on all interfaces for P2P on BasePort
+	// and RPC on BasePort+1, serving metrics, the API and gRPC.
+	Host      string
+	BasePort  int
+	Hostnames bool
+
+	Denom         string
+	Balance       math.Int // Of each validator operator
+	SelfDelegated math.Int // Of each validator
+	// Accounts funded in genesis besides the operators, such as the
+	// benchmark accounts
+	Accounts       []sdk.AccAddress
+	AccountBalance math.Int
+
+	TimeoutCommit time.Duration
+	GenesisTime   time.Time // Now if zero
+	Overlay       *Overlay  // Per-engine configuration applied to every node
+
+	Codec        codec.Codec
+	TxConfig     client.TxConfig
+	ModuleBasics module.BasicManager
+}
+
+// DefaultConfig returns the configuration of a 4-node tPBFT testnet, short of
+// the app's codecs
+func DefaultConfig() Config {
+	return Config{
+		ChainID:        "hcp-testnet",
+		Nodes:          4,
+		Engine:         "tpbft",
+		Seed:           "hcp-testnet",
+		Host:           "127.0.0.1",
+		BasePort:       26656,
+		Denom:          "stake",
+		Balance:        sdk.TokensFromConsensusPower(1000, sdk.DefaultPowerReduction),
+		SelfDelegated:  sdk.TokensFromConsensusPower(100, sdk.DefaultPowerReduction),
+		AccountBalance: sdk.TokensFromConsensusPower(1000, sdk.DefaultPowerReduction),
+		TimeoutCommit:  time.Second,
+	}
+}
+
+func (c Config) validate() error {
+	if c.Nodes <= 0 {
+		return fmt.Errorf("need at least one node, got %d", c.Nodes)
+	}
+	if c.Codec == nil || c.TxConfig == nil || c.ModuleBasics == nil {
+		return fmt.Errorf("codec, tx config and module basics are required")
+	}
+	return nil
+}
+
+// home returns the home of node i
+func (c Config) home(i int) string {
+	return filepath.Join(c.Dir, moniker(i))
+}
+
+// moniker returns the moniker of node i
+func moniker(i int) string {
+	return fmt.Sprintf("node%d", i)
+}